@@ -0,0 +1,288 @@
+package merkle
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+)
+
+var ErrAggregatesMismatch = errors.New("number of aggregates does not match number of values")
+
+// Monoid describes how to aggregate values over a range of leaves,
+// e.g. a sum, a count or a minimum.
+// Combine must be associative and Encode must be canonical,
+// since the encoding is committed to in the node hashes.
+type Monoid[A any] struct {
+	Combine func(left, right A) A
+	Encode  func(a A) []byte
+}
+
+// SumMonoid sums uint64 aggregates. Use an aggregate of 1
+// for every leaf to count leaves.
+func SumMonoid() Monoid[uint64] {
+	return Monoid[uint64]{
+		Combine: func(left, right uint64) uint64 { return left + right },
+		Encode:  encodeUint64,
+	}
+}
+
+// MinMonoid keeps the smallest uint64 aggregate.
+func MinMonoid() Monoid[uint64] {
+	return Monoid[uint64]{
+		Combine: func(left, right uint64) uint64 { return min(left, right) },
+		Encode:  encodeUint64,
+	}
+}
+
+// MaxMonoid keeps the largest uint64 aggregate.
+func MaxMonoid() Monoid[uint64] {
+	return Monoid[uint64]{
+		Combine: func(left, right uint64) uint64 { return max(left, right) },
+		Encode:  encodeUint64,
+	}
+}
+
+func encodeUint64(v uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, v)
+}
+
+// AggregateTree is a Merkle tree where every node also carries an aggregate
+// of the leaves below it, like a segment tree. Each node hash commits to the
+// node's aggregate, so proofs authenticate the aggregates on the path too.
+type AggregateTree[A any] struct {
+	tree       *Tree
+	aggregates map[*Node]A
+	monoid     Monoid[A]
+}
+
+// AggregateProof is an inclusion proof for a leaf of an aggregate tree,
+// holding the aggregate of every sibling next to its hash.
+type AggregateProof[A any] struct {
+	Hashes     [][]byte
+	Aggregates []A
+	Index      int
+}
+
+// Leaves and nodes are hashed with distinct tags, so the encoding
+// of a node can't be passed off as a leaf or the other way around.
+const (
+	aggregateLeafTag = 0x00
+	aggregateNodeTag = 0x01
+)
+
+// NewAggregateTree creates a new Merkle tree where leaf i has the value
+// values[i] and the aggregate aggregates[i]. The options that change
+// how leaves and nodes are hashed, like WithDomainPrefixes, WithHasher
+// and WithLevelTags, apply to the hashes.
+func NewAggregateTree[A any](values [][]byte, aggregates []A, monoid Monoid[A], newHashFunc func() hash.Hash, opts ...Option) (*AggregateTree[A], error) {
+	if len(values) == 0 {
+		return nil, ErrNoLeaves
+	}
+	if len(values) != len(aggregates) {
+		return nil, fmt.Errorf("%w: %d values and %d aggregates",
+			ErrAggregatesMismatch, len(values), len(aggregates))
+	}
+
+	cfg := newConfig(opts, newHashFunc)
+	t := &AggregateTree[A]{
+		tree: &Tree{
+			HashFunc:     cfg.hasher.NewNodeHasher(),
+			Leaves:       make([]*Node, len(values)),
+			leafHashFunc: cfg.hasher.NewLeafHasher(),
+			newHashFunc:  newHashFunc,
+			cfg:          cfg,
+		},
+		aggregates: make(map[*Node]A),
+		monoid:     monoid,
+	}
+
+	for i, value := range values {
+		leaf := NewNode(aggregateLeafHash(value, monoid.Encode(aggregates[i]), t.tree.leafHashFunc), value)
+		t.tree.Leaves[i] = leaf
+		t.aggregates[leaf] = aggregates[i]
+	}
+
+	nodes := t.tree.Leaves
+	for level := 1; len(nodes) > 1; level++ {
+		parents := make([]*Node, (len(nodes)+1)/2)
+		for i := 0; i < len(nodes); i += 2 {
+			if i+1 == len(nodes) {
+				// If right is nil, carry the left node up without hashing
+				parents[i/2] = nodes[i]
+				continue
+			}
+
+			parent := &Node{Left: nodes[i], Right: nodes[i+1]}
+			nodes[i].Parent = parent
+			nodes[i+1].Parent = parent
+			t.rehash(parent, level)
+			parents[i/2] = parent
+		}
+		nodes = parents
+	}
+	t.tree.Root = nodes[0]
+
+	return t, nil
+}
+
+// rehash recomputes the aggregate and hash of an internal node
+// at the given level from its children.
+func (t *AggregateTree[A]) rehash(node *Node, level int) {
+	agg := t.monoid.Combine(t.aggregates[node.Left], t.aggregates[node.Right])
+	t.aggregates[node] = agg
+	node.Hash = aggregateNodeHash(level, node.Left.Hash, node.Right.Hash, t.monoid.Encode(agg), t.tree.HashFunc, &t.tree.cfg)
+}
+
+// aggregateLeafHash hashes a leaf value together with its encoded aggregate.
+func aggregateLeafHash(value, encodedAgg []byte, hashFunc hash.Hash) []byte {
+	hashFunc.Reset()
+	hashFunc.Write([]byte{aggregateLeafTag})
+	hashFunc.Write(binary.BigEndian.AppendUint64(nil, uint64(len(encodedAgg))))
+	hashFunc.Write(encodedAgg)
+	hashFunc.Write(value)
+	return hashFunc.Sum(nil)
+}
+
+// aggregateNodeHash hashes two child hashes together with the
+// encoded aggregate of the node at the given level.
+func aggregateNodeHash(level int, leftHash, rightHash, encodedAgg []byte, hashFunc hash.Hash, cfg *config) []byte {
+	hashFunc.Reset()
+	hashFunc.Write([]byte{aggregateNodeTag})
+	if cfg.levelTag != nil {
+		hashFunc.Write(cfg.levelTag(level))
+	}
+	hashFunc.Write(binary.BigEndian.AppendUint64(nil, uint64(len(encodedAgg))))
+	hashFunc.Write(encodedAgg)
+	hashFunc.Write(leftHash)
+	hashFunc.Write(rightHash)
+	return hashFunc.Sum(nil)
+}
+
+// Len returns the number of leaves in the tree.
+func (t *AggregateTree[A]) Len() int {
+	return len(t.tree.Leaves)
+}
+
+// RootHash returns the root hash of the tree.
+func (t *AggregateTree[A]) RootHash() []byte {
+	return t.tree.Root.Hash
+}
+
+// Aggregate returns the aggregate of the leaf at the given index.
+func (t *AggregateTree[A]) Aggregate(index int) (A, error) {
+	if index < 0 || index >= len(t.tree.Leaves) {
+		var zero A
+		return zero, indexOutOfBounds(index, len(t.tree.Leaves))
+	}
+	return t.aggregates[t.tree.Leaves[index]], nil
+}
+
+// RootAggregate returns the aggregate over all leaves.
+func (t *AggregateTree[A]) RootAggregate() A {
+	return t.aggregates[t.tree.Root]
+}
+
+// UpdateLeaf updates the value and aggregate of the leaf at the given index
+// and recalculates the aggregates and hashes up to the root.
+func (t *AggregateTree[A]) UpdateLeaf(index int, value []byte, agg A) error {
+	if index < 0 || index >= len(t.tree.Leaves) {
+		return indexOutOfBounds(index, len(t.tree.Leaves))
+	}
+
+	leaf := t.tree.Leaves[index]
+	leaf.Value = value
+	leaf.Hash = aggregateLeafHash(value, t.monoid.Encode(agg), t.tree.leafHashFunc)
+	t.aggregates[leaf] = agg
+
+	for current := leaf.Parent; current != nil; current = current.Parent {
+		t.rehash(current, current.Level())
+	}
+
+	return nil
+}
+
+// GenerateProof generates a proof for the leaf at the given index.
+func (t *AggregateTree[A]) GenerateProof(index int) (*AggregateProof[A], error) {
+	if index < 0 || index >= len(t.tree.Leaves) {
+		return nil, indexOutOfBounds(index, len(t.tree.Leaves))
+	}
+
+	proof := &AggregateProof[A]{Index: index}
+	for current := t.tree.Leaves[index]; current.Parent != nil; current = current.Parent {
+		sibling := current.Parent.Left
+		if sibling == current {
+			sibling = current.Parent.Right
+		}
+		proof.Hashes = append(proof.Hashes, sibling.Hash)
+		proof.Aggregates = append(proof.Aggregates, t.aggregates[sibling])
+	}
+
+	return proof, nil
+}
+
+// VerifyProof verifies that value with the aggregate agg is part of the tree.
+func (t *AggregateTree[A]) VerifyProof(proof *AggregateProof[A], value []byte, agg A) (bool, error) {
+	return verifyAggregateProof(t.tree.Root.Hash, t.RootAggregate(), len(t.tree.Leaves),
+		proof, value, agg, t.monoid, &t.tree.cfg)
+}
+
+// VerifyAggregateProof verifies that value with the aggregate agg is part of
+// a tree with size leaves, the given root hash and the given root aggregate.
+// The options have to match the options of the tree.
+func VerifyAggregateProof[A any](root []byte, rootAgg A, size int, proof *AggregateProof[A], value []byte, agg A, monoid Monoid[A], newHashFunc func() hash.Hash, opts ...Option) (bool, error) {
+	cfg := newConfig(opts, newHashFunc)
+	return verifyAggregateProof(root, rootAgg, size, proof, value, agg, monoid, &cfg)
+}
+
+func verifyAggregateProof[A any](root []byte, rootAgg A, size int, proof *AggregateProof[A], value []byte, agg A, monoid Monoid[A], cfg *config) (bool, error) {
+	if len(proof.Hashes) != len(proof.Aggregates) {
+		return false, fmt.Errorf("%w: %d hashes and %d aggregates",
+			ErrProofVerificationFailed, len(proof.Hashes), len(proof.Aggregates))
+	}
+	if proof.Index < 0 || proof.Index >= size {
+		return false, fmt.Errorf("%w: index %d in a tree with %d leaves",
+			ErrProofVerificationFailed, proof.Index, size)
+	}
+
+	hashFunc := cfg.hasher.NewNodeHasher()
+	currentHash := aggregateLeafHash(value, monoid.Encode(agg), cfg.hasher.NewLeafHasher())
+	currentAgg := agg
+
+	index, levelSize, next := proof.Index, size, 0
+	for level := 1; levelSize > 1; level++ {
+		// The last node on a level without a sibling
+		// is carried up without hashing.
+		if index%2 == 1 || index+1 < levelSize {
+			if next == len(proof.Hashes) {
+				return false, fmt.Errorf("%w: proof is too short", ErrProofVerificationFailed)
+			}
+			siblingHash, siblingAgg := proof.Hashes[next], proof.Aggregates[next]
+			next++
+
+			if index%2 == 0 {
+				currentAgg = monoid.Combine(currentAgg, siblingAgg)
+				currentHash = aggregateNodeHash(level, currentHash, siblingHash, monoid.Encode(currentAgg), hashFunc, cfg)
+			} else {
+				currentAgg = monoid.Combine(siblingAgg, currentAgg)
+				currentHash = aggregateNodeHash(level, siblingHash, currentHash, monoid.Encode(currentAgg), hashFunc, cfg)
+			}
+		}
+		index /= 2
+		levelSize = (levelSize + 1) / 2
+	}
+
+	if next != len(proof.Hashes) {
+		return false, fmt.Errorf("%w: proof is too long", ErrProofVerificationFailed)
+	}
+
+	if !bytes.Equal(currentHash, root) {
+		return false, &RootMismatchError{Expected: root, Actual: currentHash}
+	}
+	if !bytes.Equal(monoid.Encode(currentAgg), monoid.Encode(rootAgg)) {
+		return false, fmt.Errorf("%w: root aggregate mismatch", ErrProofVerificationFailed)
+	}
+
+	return true, nil
+}
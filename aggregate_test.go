@@ -0,0 +1,212 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAggregateTree(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		monoid Monoid[uint64]
+		aggs   []uint64
+		exp    uint64
+	}{
+		{
+			name:   "Sum",
+			monoid: SumMonoid(),
+			aggs:   []uint64{5, 3, 9, 1, 7},
+			exp:    25,
+		},
+		{
+			name:   "Min",
+			monoid: MinMonoid(),
+			aggs:   []uint64{5, 3, 9, 1, 7},
+			exp:    1,
+		},
+		{
+			name:   "Max",
+			monoid: MaxMonoid(),
+			aggs:   []uint64{5, 3, 9, 1, 7},
+			exp:    9,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			values := generateDummyData(len(tc.aggs))
+			tree, err := NewAggregateTree(values, tc.aggs, tc.monoid, sha256.New)
+			require.NoError(t, err)
+			assert.Equal(t, tc.exp, tree.RootAggregate())
+
+			for i, value := range values {
+				proof, err := tree.GenerateProof(i)
+				require.NoError(t, err)
+
+				isValid, err := tree.VerifyProof(proof, value, tc.aggs[i])
+				require.NoError(t, err)
+				assert.True(t, isValid, "Proof for leaf %d should be valid", i)
+
+				// Lying about the aggregate of the leaf must fail.
+				isValid, err = tree.VerifyProof(proof, value, tc.aggs[i]+1)
+				require.ErrorIs(t, err, ErrProofVerificationFailed)
+				assert.False(t, isValid)
+			}
+		})
+	}
+}
+
+func TestAggregateTreeDomainTags(t *testing.T) {
+	t.Parallel()
+
+	sum := func(data ...[]byte) []byte {
+		h := sha256.New()
+		for _, b := range data {
+			h.Write(b)
+		}
+		return h.Sum(nil)
+	}
+
+	values := [][]byte{[]byte("a"), []byte("b")}
+	tree, err := NewAggregateTree(values, []uint64{2, 3}, SumMonoid(), sha256.New)
+	require.NoError(t, err)
+
+	aggLen := encodeUint64(8)
+	left := sum([]byte{0}, aggLen, encodeUint64(2), values[0])
+	right := sum([]byte{0}, aggLen, encodeUint64(3), values[1])
+	assert.Equal(t, sum([]byte{1}, aggLen, encodeUint64(5), left, right), tree.RootHash())
+}
+
+func TestAggregateTreeWithOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{
+			name: "Domain prefixes",
+			opts: []Option{WithDomainPrefixes([]byte{0}, []byte{1})},
+		},
+		{
+			name: "Level tags",
+			opts: []Option{WithLevelTags(LevelIndexTag)},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			values := generateDummyData(7)
+			aggs := []uint64{5, 3, 9, 1, 7, 2, 4}
+			tree, err := NewAggregateTree(values, aggs, SumMonoid(), sha256.New, tc.opts...)
+			require.NoError(t, err)
+
+			plain, err := NewAggregateTree(values, aggs, SumMonoid(), sha256.New)
+			require.NoError(t, err)
+			assert.NotEqual(t, plain.RootHash(), tree.RootHash())
+
+			// Updates hash the nodes on the path at their levels.
+			aggs[6] = 10
+			require.NoError(t, tree.UpdateLeaf(6, values[6], aggs[6]))
+
+			for i, value := range values {
+				proof, err := tree.GenerateProof(i)
+				require.NoError(t, err)
+
+				isValid, err := VerifyAggregateProof(tree.RootHash(), tree.RootAggregate(), tree.Len(),
+					proof, value, aggs[i], SumMonoid(), sha256.New, tc.opts...)
+				require.NoError(t, err)
+				assert.True(t, isValid, "Proof for leaf %d should be valid", i)
+
+				_, err = VerifyAggregateProof(tree.RootHash(), tree.RootAggregate(), tree.Len(),
+					proof, value, aggs[i], SumMonoid(), sha256.New)
+				require.ErrorIs(t, err, ErrProofVerificationFailed)
+			}
+		})
+	}
+}
+
+func TestAggregateTreeTamperedSibling(t *testing.T) {
+	t.Parallel()
+
+	values := generateDummyData(4)
+	aggs := []uint64{1, 2, 3, 4}
+	tree, err := NewAggregateTree(values, aggs, SumMonoid(), sha256.New)
+	require.NoError(t, err)
+
+	proof, err := tree.GenerateProof(0)
+	require.NoError(t, err)
+	proof.Aggregates[1]++
+
+	isValid, err := tree.VerifyProof(proof, values[0], aggs[0])
+	require.ErrorIs(t, err, ErrProofVerificationFailed)
+	assert.False(t, isValid)
+}
+
+func TestAggregateTreeUpdateLeaf(t *testing.T) {
+	t.Parallel()
+
+	values := generateDummyData(7)
+	aggs := []uint64{1, 2, 3, 4, 5, 6, 7}
+	tree, err := NewAggregateTree(values, aggs, SumMonoid(), sha256.New)
+	require.NoError(t, err)
+
+	require.NoError(t, tree.UpdateLeaf(6, []byte("new"), 100))
+	assert.Equal(t, uint64(121), tree.RootAggregate())
+
+	// The updated tree matches a tree built from scratch.
+	values[6], aggs[6] = []byte("new"), 100
+	expTree, err := NewAggregateTree(values, aggs, SumMonoid(), sha256.New)
+	require.NoError(t, err)
+	assert.Equal(t, expTree.RootHash(), tree.RootHash())
+	assert.Equal(t, 7, tree.Len())
+
+	agg, err := tree.Aggregate(6)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(100), agg)
+	_, err = tree.Aggregate(7)
+	require.ErrorIs(t, err, ErrIndexOutOfBounds)
+
+	err = tree.UpdateLeaf(7, []byte("x"), 1)
+	require.ErrorIs(t, err, ErrIndexOutOfBounds)
+}
+
+func TestNewAggregateTreeErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		values [][]byte
+		aggs   []uint64
+		err    error
+	}{
+		{
+			name: "No values should fail",
+			err:  ErrNoLeaves,
+		},
+		{
+			name:   "Mismatched aggregates should fail",
+			values: generateDummyData(2),
+			aggs:   []uint64{1},
+			err:    ErrAggregatesMismatch,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewAggregateTree(tc.values, tc.aggs, SumMonoid(), sha256.New)
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package merkle
+
+import (
+	"context"
+	"math/bits"
+	"slices"
+)
+
+// AppendLeaf appends a leaf with the given value to the tree.
+// Only the nodes on the right edge of the tree are rehashed,
+// so appending takes O(log n) hashes instead of rebuilding the tree.
+func (t *Tree) AppendLeaf(value []byte) error {
+	t.flush()
+	if err := t.checkAppend(); err != nil {
+		return err
+	}
+	value, err := t.cfg.leafValue(value)
+	if err != nil {
+		return err
+	}
+
+	t.leafHashFunc.Reset()
+	t.leafHashFunc.Write(value)
+	leaf := t.newNode()
+	leaf.Hash = t.leafHashFunc.Sum(nil)
+	leaf.Value = t.storedValue(value)
+	t.appendNodes([]*Node{leaf})
+	t.rootChanged(MutationAppend)
+	return nil
+}
+
+// AppendLeaves appends leaves with the given values to the tree.
+// The values are hashed in parallel and the right edge of the tree
+// is only rebuilt once for all of them.
+func (t *Tree) AppendLeaves(values [][]byte) error {
+	return t.AppendLeavesContext(context.Background(), values)
+}
+
+// AppendLeavesContext appends leaves like AppendLeaves, but stops hashing
+// and returns the context error once ctx is done. The tree is only
+// changed if all values have been hashed.
+func (t *Tree) AppendLeavesContext(ctx context.Context, values [][]byte) error {
+	t.flush()
+	if err := t.checkAppend(); err != nil {
+		return err
+	}
+	values, err := t.cfg.leafValues(values)
+	if err != nil {
+		return err
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	hashes, err := t.cfg.parallelism.preHashLeavesContext(ctx, values, t.cfg.hasher.NewLeafHasher)
+	if err != nil {
+		return err
+	}
+	leaves := make([]*Node, len(values))
+	for i, hash := range hashes {
+		leaves[i] = t.newNode()
+		leaves[i].Hash = hash
+		leaves[i].Value = t.storedValue(values[i])
+	}
+	t.appendNodes(leaves)
+	t.rootChanged(MutationAppend)
+	return nil
+}
+
+// checkAppend returns an error if the right edge of the tree
+// has been pruned.
+func (t *Tree) checkAppend() error {
+	if n := len(t.Leaves); n > 0 {
+		// The right edge is the path of the last leaf.
+		return t.checkLeaf(n - 1)
+	}
+	return nil
+}
+
+// appendNodes appends the leaves to the tree and rebuilds its right edge.
+func (t *Tree) appendNodes(leaves []*Node) {
+	peaks, heights := t.pushPeaks(t.peaks(), peakHeights(len(t.Leaves)), leaves)
+	for i, leaf := range leaves {
+		t.addToIndex(len(t.Leaves)+i, leaf.Hash)
+	}
+	if t.cfg.capacity > 0 && cap(t.Leaves)-len(t.Leaves) < len(leaves) {
+		// Double the storage of a tree that has outgrown its capacity.
+		t.Leaves = slices.Grow(t.Leaves, max(len(leaves), len(t.Leaves)))
+	}
+	t.Leaves = append(t.Leaves, leaves...)
+	t.Root = t.joinPeaks(peaks, heights)
+}
+
+// newNode returns a new node. Trees with a capacity take it from
+// their preallocated nodes, which are refilled in proportion to the tree.
+func (t *Tree) newNode() *Node {
+	if t.cfg.capacity == 0 {
+		return &Node{}
+	}
+	if len(t.free) == 0 {
+		t.free = make([]Node, max(len(t.Leaves), 64))
+	}
+	node := &t.free[0]
+	t.free = t.free[1:]
+	return node
+}
+
+// peaks returns the roots of the complete subtrees of the tree,
+// from the largest to the smallest.
+func (t *Tree) peaks() []*Node {
+	if len(t.Leaves) == 0 {
+		return nil
+	}
+
+	var peaks []*Node
+	node := t.Root
+	for size := len(t.Leaves); size&(size-1) != 0; {
+		// The left child is the largest complete subtree.
+		peaks = append(peaks, node.Left)
+		size -= 1 << (bits.Len(uint(size)) - 1)
+		node = node.Right
+	}
+	return append(peaks, node)
+}
+
+// prefixPeaks returns the roots of the complete subtrees that hold
+// the first size leaves of the tree, from the largest to the smallest.
+func (t *Tree) prefixPeaks(size int) []*Node {
+	var peaks []*Node
+	node, start, n := t.Root, 0, len(t.Leaves)
+	for start < size {
+		// The left child is the largest complete subtree.
+		half := 1 << (bits.Len(uint(n-1)) - 1)
+		if start+half <= size {
+			peaks = append(peaks, node.Left)
+			node, start, n = node.Right, start+half, n-half
+		} else {
+			node, n = node.Left, half
+		}
+	}
+	return peaks
+}
+
+// pushPeaks adds the leaves after the complete subtrees and merges
+// the subtrees of equal height like a binary counter.
+func (t *Tree) pushPeaks(peaks []*Node, heights []int, leaves []*Node) ([]*Node, []int) {
+	for _, leaf := range leaves {
+		peaks = append(peaks, leaf)
+		heights = append(heights, 0)
+
+		for n := len(peaks); n > 1 && heights[n-2] == heights[n-1]; n-- {
+			peaks = append(peaks[:n-2], t.newParent(heights[n-1]+1, peaks[n-2], peaks[n-1]))
+			heights = append(heights[:n-2], heights[n-1]+1)
+		}
+	}
+	return peaks, heights
+}
+
+// peakHeights returns the heights of the complete subtrees of a tree
+// with size leaves, from the largest to the smallest.
+func peakHeights(size int) []int {
+	var heights []int
+	for height := bits.Len(uint(size)) - 1; height >= 0; height-- {
+		if size&(1<<height) != 0 {
+			heights = append(heights, height)
+		}
+	}
+	return heights
+}
+
+// joinPeaks hashes the complete subtrees together from right to left,
+// which gives the root of the tree.
+func (t *Tree) joinPeaks(peaks []*Node, heights []int) *Node {
+	root := peaks[len(peaks)-1]
+	root.Parent = nil
+	for i := len(peaks) - 2; i >= 0; i-- {
+		root = t.newParent(heights[i]+1, peaks[i], root)
+	}
+	return root
+}
+
+// newParent creates the parent at the given level of left and right.
+func (t *Tree) newParent(level int, left, right *Node) *Node {
+	parent := t.newNode()
+	parent.Hash = combineLevelHashes(level, left.Hash, right.Hash, t.HashFunc, &t.cfg)
+	parent.Left = left
+	parent.Right = right
+	left.Parent = parent
+	right.Parent = parent
+	return parent
+}
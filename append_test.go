@@ -0,0 +1,270 @@
+package merkle
+
+import (
+	"context"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendLeaf(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		initial int
+		opts    []Option
+	}{
+		{
+			name:    "From empty tree",
+			initial: 0,
+			opts:    []Option{WithEmptyTree()},
+		},
+		{
+			name:    "From single leaf",
+			initial: 1,
+		},
+		{
+			name:    "From odd number of leaves",
+			initial: 5,
+		},
+		{
+			name:    "Level tags",
+			initial: 3,
+			opts:    []Option{WithLevelTags(LevelIndexTag)},
+		},
+		{
+			name:    "Domain prefixes",
+			initial: 2,
+			opts:    []Option{WithDomainPrefixes([]byte{0}, []byte{1}), WithEmptyTree()},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := generateDummyData(40)
+			tree, err := NewTree(data[:tc.initial], sha256.New, tc.opts...)
+			require.NoError(t, err)
+
+			for n := tc.initial + 1; n <= len(data); n++ {
+				require.NoError(t, tree.AppendLeaf(data[n-1]))
+
+				expTree, err := NewTree(data[:n], sha256.New, tc.opts...)
+				require.NoError(t, err)
+				require.Equal(t, expTree.Root.Hash, tree.Root.Hash, "Root mismatch with %d leaves", n)
+				require.Equal(t, expTree.Levels(), tree.Levels(), "Levels mismatch with %d leaves", n)
+				assert.Nil(t, tree.Root.Parent)
+			}
+
+			for i, value := range data {
+				proof, err := tree.GenerateProofByIndex(i)
+				require.NoError(t, err)
+
+				isValid, err := tree.VerifyProof(proof, value)
+				require.NoError(t, err)
+				assert.True(t, isValid)
+			}
+
+			// Updates after appending reach the new root.
+			require.NoError(t, tree.UpdateLeaf(0, []byte("updated")))
+			data[0] = []byte("updated")
+			expTree, err := NewTree(data, sha256.New, tc.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, expTree.Root.Hash, tree.Root.Hash)
+		})
+	}
+}
+
+func TestAppendLeaves(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		initial int
+		batches []int
+		opts    []Option
+	}{
+		{
+			name:    "From empty tree",
+			initial: 0,
+			batches: []int{1, 7, 8, 100},
+			opts:    []Option{WithEmptyTree()},
+		},
+		{
+			name:    "Empty batch",
+			initial: 3,
+			batches: []int{0, 2},
+		},
+		{
+			name:    "Odd batches",
+			initial: 5,
+			batches: []int{3, 9, 17},
+		},
+		{
+			name:    "Level tags",
+			initial: 6,
+			batches: []int{10},
+			opts:    []Option{WithLevelTags(LevelIndexTag)},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := generateDummyData(200)
+			tree, err := NewTree(data[:tc.initial], sha256.New, tc.opts...)
+			require.NoError(t, err)
+
+			n := tc.initial
+			for _, batch := range tc.batches {
+				require.NoError(t, tree.AppendLeaves(data[n:n+batch]))
+				n += batch
+
+				expTree, err := NewTree(data[:n], sha256.New, append(tc.opts, WithEmptyTree())...)
+				require.NoError(t, err)
+				require.Equal(t, expTree.Root.Hash, tree.Root.Hash, "Root mismatch with %d leaves", n)
+				require.Equal(t, expTree.Levels(), tree.Levels(), "Levels mismatch with %d leaves", n)
+			}
+
+			for i := 0; i < n; i++ {
+				assert.Equal(t, data[i], tree.Leaves[i].Value)
+
+				proof, err := tree.GenerateProofByIndex(i)
+				require.NoError(t, err)
+
+				isValid, err := tree.VerifyProof(proof, data[i])
+				require.NoError(t, err)
+				assert.True(t, isValid)
+			}
+		})
+	}
+}
+
+func TestAppendLeavesContext(t *testing.T) {
+	t.Parallel()
+
+	data := generateDummyData(10)
+	tree, err := NewTree(data[:3], sha256.New)
+	require.NoError(t, err)
+	root := tree.Root.Hash
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = tree.AppendLeavesContext(ctx, data[3:])
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, root, tree.Root.Hash, "Root should not change")
+	assert.Equal(t, 3, tree.Len())
+
+	require.NoError(t, tree.AppendLeavesContext(context.Background(), data[3:]))
+	expTree, err := NewTree(data, sha256.New)
+	require.NoError(t, err)
+	assert.Equal(t, expTree.Root.Hash, tree.Root.Hash)
+}
+
+func TestAppendLeafErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		newTree func(t *testing.T) *Tree
+		value   []byte
+		err     error
+	}{
+		{
+			name: "Pruned last leaf",
+			newTree: func(t *testing.T) *Tree {
+				tree, err := NewTree(generateDummyData(4), sha256.New)
+				require.NoError(t, err)
+				require.NoError(t, tree.Prune([]int{0}))
+				return tree
+			},
+			value: []byte("e"),
+			err:   ErrLeafPruned,
+		},
+		{
+			name: "Invalid leaf size",
+			newTree: func(t *testing.T) *Tree {
+				tree, err := NewTree(generateDummyData(4), sha256.New, WithFixedLeafSize(32))
+				require.NoError(t, err)
+				return tree
+			},
+			value: []byte("e"),
+			err:   ErrInvalidLeafSize,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree := tc.newTree(t)
+			root := tree.Root.Hash
+
+			err := tree.AppendLeaf(tc.value)
+			require.ErrorIs(t, err, tc.err)
+
+			err = tree.AppendLeaves([][]byte{make([]byte, 32), tc.value})
+			require.ErrorIs(t, err, tc.err)
+
+			assert.Equal(t, root, tree.Root.Hash, "Root should not change")
+			assert.Len(t, tree.Leaves, 4)
+		})
+	}
+}
+
+func BenchmarkAppendLeaf(b *testing.B) {
+	data := generateDummyData(b.N)
+	tree, err := NewTree(nil, sha256.New, WithEmptyTree())
+	require.NoError(b, err)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := tree.AppendLeaf(data[i]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkAppendLeaves(b *testing.B) {
+	data := generateDummyData(1000)
+
+	b.Run("AppendLeaf", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			tree, err := NewTree(data[:1], sha256.New)
+			require.NoError(b, err)
+			for _, value := range data[1:] {
+				if err := tree.AppendLeaf(value); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+
+	b.Run("AppendLeaves", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			tree, err := NewTree(data[:1], sha256.New)
+			require.NoError(b, err)
+			if err := tree.AppendLeaves(data[1:]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkAppendLeafWithCapacity(b *testing.B) {
+	data := generateDummyData(b.N)
+	tree, err := NewTree(nil, sha256.New, WithEmptyTree(), WithCapacity(b.N))
+	require.NoError(b, err)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := tree.AppendLeaf(data[i]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
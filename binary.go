@@ -0,0 +1,292 @@
+package merkle
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"slices"
+)
+
+var ErrInvalidEncoding = errors.New("invalid tree encoding")
+
+// treeMagic identifies an encoded tree, and treeVersion is the version
+// of the format that follows it.
+const (
+	treeMagic   = "MKTR"
+	treeVersion = 2
+)
+
+// maxHashSize bounds the size of encoded hashes.
+const maxHashSize = 1 << 10
+
+// treeHasValues is the flag of encoded trees that hold their leaf values.
+const treeHasValues = 1
+
+// hashProbe is hashed to identify hash functions.
+var hashProbe = []byte("merkle hash probe")
+
+// optionsFingerprintSize is the size of the fingerprint of the options
+// that change how a tree is hashed.
+const optionsFingerprintSize = 8
+
+// MarshalBinary encodes the tree in a compact, versioned format: a header
+// with the id of the hash function, the sizes of the hashes, a fingerprint
+// of the hashing options and the number of leaves, followed by the hashes
+// of all nodes from the leaves up and the leaf values, if the tree holds
+// them. Leaf keys and metadata are not encoded, and pruned trees can't
+// be encoded.
+func (t *Tree) MarshalBinary() ([]byte, error) {
+	t.flush()
+	for i, leaf := range t.Leaves {
+		if leaf == nil {
+			return nil, &IndexError{Index: i, Size: len(t.Leaves), Err: ErrLeafPruned}
+		}
+	}
+
+	var flags byte
+	if !t.hashedLeaves {
+		flags |= treeHasValues
+	}
+	leafSize, nodeSize := t.leafHashFunc.Size(), t.HashFunc.Size()
+	buf := append([]byte(treeMagic), treeVersion, flags)
+	buf = binary.AppendUvarint(buf, uint64(hashID(t.newHashFunc)))
+	buf = binary.AppendUvarint(buf, uint64(leafSize))
+	buf = binary.AppendUvarint(buf, uint64(nodeSize))
+	buf = append(buf, optionsFingerprint(&t.cfg)...)
+	buf = binary.AppendUvarint(buf, uint64(len(t.Leaves)))
+
+	for _, leaf := range t.Leaves {
+		if len(leaf.Hash) != leafSize {
+			return nil, fmt.Errorf("%w: leaf hash of %d bytes, expected %d", ErrHashSizeMismatch, len(leaf.Hash), leafSize)
+		}
+		buf = append(buf, leaf.Hash...)
+	}
+	// The nodes above the leaves are encoded level by level. A node
+	// without a sibling is carried up, so every level only holds
+	// the parents of the pairs of the level below.
+	nodes := slices.Clone(t.Leaves)
+	for len(nodes) > 1 {
+		numParents := len(nodes) / 2
+		for i := range numParents {
+			left, right := nodes[2*i], nodes[2*i+1]
+			parent := left.Parent
+			if parent == nil || parent.Left != left || parent.Right != right {
+				return nil, fmt.Errorf("%w: nodes are not paired as in a built tree", ErrInvalidTree)
+			}
+			if len(parent.Hash) != nodeSize {
+				return nil, fmt.Errorf("%w: node hash of %d bytes, expected %d", ErrHashSizeMismatch, len(parent.Hash), nodeSize)
+			}
+			buf = append(buf, parent.Hash...)
+			nodes[i] = parent
+		}
+		if len(nodes)%2 == 1 {
+			nodes[numParents] = nodes[len(nodes)-1]
+			numParents++
+		}
+		nodes = nodes[:numParents]
+	}
+
+	if flags&treeHasValues != 0 {
+		for _, leaf := range t.Leaves {
+			buf = binary.AppendUvarint(buf, uint64(len(leaf.Value)))
+			buf = append(buf, leaf.Value...)
+		}
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary replaces the tree with a tree encoded by MarshalBinary.
+// The tree keeps its hash function and options, which have to match
+// the encoded tree, or ErrInvalidEncoding is returned. A zero Tree uses
+// the hash function from the encoding, which works for the hash functions
+// of the standard library.
+// The hashes are recomputed with Validate, so a corrupted encoding is
+// rejected and leaves the tree unchanged.
+func (t *Tree) UnmarshalBinary(data []byte) error {
+	if len(data) < len(treeMagic)+2 || string(data[:len(treeMagic)]) != treeMagic {
+		return fmt.Errorf("%w: bad header", ErrInvalidEncoding)
+	}
+	if version := data[len(treeMagic)]; version != treeVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidEncoding, version)
+	}
+	flags := data[len(treeMagic)+1]
+	if flags&^treeHasValues != 0 {
+		return fmt.Errorf("%w: unknown flags %#x", ErrInvalidEncoding, flags)
+	}
+	d := decoder{data: data[len(treeMagic)+2:]}
+	id := crypto.Hash(d.uvarint())
+	leafSize, nodeSize := int(d.uvarint()), int(d.uvarint())
+	fingerprint := d.bytes(optionsFingerprintSize)
+	size := d.uvarint()
+	if d.err != nil {
+		return d.err
+	}
+
+	newHashFunc, cfg := t.newHashFunc, t.cfg
+	if newHashFunc == nil {
+		if id == 0 || id >= maxHashID || !id.Available() {
+			return fmt.Errorf("%w: unknown hash function %d", ErrInvalidEncoding, id)
+		}
+		newHashFunc = id.New
+		cfg = newConfig(nil, newHashFunc)
+	} else if id != 0 && hashID(newHashFunc) != id {
+		return fmt.Errorf("%w: encoded with hash function %v", ErrInvalidEncoding, id)
+	}
+	if !bytes.Equal(fingerprint, optionsFingerprint(&cfg)) {
+		return fmt.Errorf("%w: encoded with other hashing options", ErrInvalidEncoding)
+	}
+	hashFunc := cfg.hasher.NewNodeHasher()
+	if cfg.combine == nil && (leafSize != cfg.hasher.NewLeafHasher().Size() || nodeSize != hashFunc.Size()) {
+		return fmt.Errorf("%w: hashes of %d and %d bytes", ErrInvalidEncoding, leafSize, nodeSize)
+	}
+	// Every leaf takes at least its hash, so the size can't be larger
+	// than the data, which bounds the allocations below.
+	if leafSize <= 0 || leafSize > maxHashSize || nodeSize <= 0 || nodeSize > maxHashSize ||
+		size > uint64(len(d.data)/leafSize) {
+		return fmt.Errorf("%w: bad size", ErrInvalidEncoding)
+	}
+
+	n := int(size)
+	leaves := make([]*Node, n)
+	hashes := d.bytes(n * leafSize)
+	for i := range leaves {
+		leaves[i] = &Node{Hash: hashes[i*leafSize : (i+1)*leafSize : (i+1)*leafSize]}
+	}
+	nodes := slices.Clone(leaves)
+	for len(nodes) > 1 && d.err == nil {
+		numParents := len(nodes) / 2
+		hashes := d.bytes(numParents * nodeSize)
+		if d.err != nil {
+			break
+		}
+		for i := range numParents {
+			left, right := nodes[2*i], nodes[2*i+1]
+			parent := &Node{
+				Hash:  hashes[i*nodeSize : (i+1)*nodeSize : (i+1)*nodeSize],
+				Left:  left,
+				Right: right,
+			}
+			left.Parent = parent
+			right.Parent = parent
+			nodes[i] = parent
+		}
+		if len(nodes)%2 == 1 {
+			nodes[numParents] = nodes[len(nodes)-1]
+			numParents++
+		}
+		nodes = nodes[:numParents]
+	}
+
+	if flags&treeHasValues != 0 {
+		for _, leaf := range leaves {
+			leaf.Value = d.bytes(int(min(d.uvarint(), uint64(len(d.data)+1))))
+		}
+	}
+	if d.err != nil {
+		return d.err
+	}
+	if len(d.data) > 0 {
+		return fmt.Errorf("%w: %d bytes after the tree", ErrInvalidEncoding, len(d.data))
+	}
+
+	var root *Node
+	if n > 0 {
+		root = nodes[0]
+	} else {
+		cfg.allowEmpty = true
+	}
+	tree := newTreeFromRoot(root, leaves, hashFunc, newHashFunc, cfg)
+	tree.hashedLeaves = tree.hashedLeaves || flags&treeHasValues == 0
+	tree.buildIndex()
+	if err := tree.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidEncoding, err)
+	}
+	tree.generation = t.generation + 1
+	*t = *tree
+	return nil
+}
+
+// decoder reads the parts of an encoded tree from data. Once a read
+// fails, err is set and all further reads return zero values.
+type decoder struct {
+	data []byte
+	err  error
+}
+
+// uvarint reads a varint-encoded integer.
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.err = fmt.Errorf("%w: bad varint", ErrInvalidEncoding)
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+// bytes reads a copy of the next n bytes, so the tree doesn't keep
+// the encoded data alive.
+func (d *decoder) bytes(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n > len(d.data) {
+		d.err = fmt.Errorf("%w: unexpected end of data", ErrInvalidEncoding)
+		return nil
+	}
+	b := bytes.Clone(d.data[:n:n])
+	d.data = d.data[n:]
+	return b
+}
+
+// optionsFingerprint returns a fingerprint of the options that change
+// how leaves and nodes are hashed, such as HMAC keys, prefixes, level tags
+// and combine functions. Options can hold functions, so instead of the
+// options, the hashes of a probe leaf and of nodes above it are fingerprinted.
+func optionsFingerprint(cfg *config) []byte {
+	leafHashFunc := cfg.hasher.NewLeafHasher()
+	leafHashFunc.Write(hashProbe)
+	leaf := leafHashFunc.Sum(nil)
+
+	nodeHashFunc := cfg.hasher.NewNodeHasher()
+	node := combineLevelHashes(1, leaf, leaf, nodeHashFunc, cfg)
+	node = combineLevelHashes(2, node, leaf, nodeHashFunc, cfg)
+
+	h := sha256.New()
+	h.Write(leaf)
+	h.Write(node)
+	return h.Sum(nil)[:optionsFingerprintSize]
+}
+
+// maxHashID bounds the ids of the hash functions of the standard library.
+const maxHashID = crypto.BLAKE2b_512 + 1
+
+// hashID returns the id of the standard library hash function that
+// newHashFunc creates, or 0 if it creates another hash function.
+// Hash functions are identified by hashing a probe with them.
+func hashID(newHashFunc func() hash.Hash) crypto.Hash {
+	if newHashFunc == nil {
+		return 0
+	}
+	h := newHashFunc()
+	h.Write(hashProbe)
+	sum := h.Sum(nil)
+	for id := crypto.MD4; id < maxHashID; id++ {
+		if !id.Available() || id.Size() != len(sum) {
+			continue
+		}
+		other := id.New()
+		other.Write(hashProbe)
+		if bytes.Equal(other.Sum(nil), sum) {
+			return id
+		}
+	}
+	return 0
+}
@@ -0,0 +1,392 @@
+package merkle
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding"
+	"hash"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/sha3"
+)
+
+var (
+	_ encoding.BinaryMarshaler   = (*Tree)(nil)
+	_ encoding.BinaryUnmarshaler = (*Tree)(nil)
+)
+
+func TestMarshalBinary(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		size      int
+		newTree   func(data [][]byte) (*Tree, error)
+		expValues bool
+	}{
+		{
+			name: "Single leaf",
+			size: 1,
+			newTree: func(data [][]byte) (*Tree, error) {
+				return NewTree(data, sha256.New)
+			},
+			expValues: true,
+		},
+		{
+			name: "Odd size",
+			size: 13,
+			newTree: func(data [][]byte) (*Tree, error) {
+				return NewTree(data, sha256.New)
+			},
+			expValues: true,
+		},
+		{
+			name: "Power of two with SHA-512",
+			size: 64,
+			newTree: func(data [][]byte) (*Tree, error) {
+				return NewTree(data, sha512.New)
+			},
+			expValues: true,
+		},
+		{
+			name: "Without leaf values",
+			size: 7,
+			newTree: func(data [][]byte) (*Tree, error) {
+				return NewTree(data, sha256.New, WithoutLeafValues(nil))
+			},
+		},
+		{
+			name: "Leaf hashes",
+			size: 5,
+			newTree: func(data [][]byte) (*Tree, error) {
+				hashes := make([][]byte, len(data))
+				for i, value := range data {
+					hash := sha3.Sum256(value)
+					hashes[i] = hash[:]
+				}
+				return NewTreeFromHashes(hashes, sha3.New256)
+			},
+		},
+		{
+			name: "Empty tree",
+			newTree: func(data [][]byte) (*Tree, error) {
+				return NewTree(data, sha256.New, WithEmptyTree())
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := generateDummyData(tc.size)
+			tree, err := tc.newTree(data)
+			require.NoError(t, err)
+			encoded, err := tree.MarshalBinary()
+			require.NoError(t, err)
+
+			var decoded Tree
+			require.NoError(t, decoded.UnmarshalBinary(encoded))
+			require.NoError(t, decoded.Validate())
+			assert.Equal(t, tree.Root.Hash, decoded.Root.Hash)
+			require.Equal(t, tree.Len(), decoded.Len())
+
+			for i, value := range data {
+				proof, err := decoded.GenerateProofByIndex(i)
+				require.NoError(t, err)
+				expProof, err := tree.GenerateProofByIndex(i)
+				require.NoError(t, err)
+				assert.Equal(t, expProof, proof)
+
+				index, found := decoded.IndexOf(value)
+				assert.Equal(t, tc.expValues, found)
+				if tc.expValues {
+					assert.Equal(t, i, index)
+					assert.Equal(t, value, decoded.Leaves[i].Value)
+				}
+			}
+
+			// The decoded tree is a copy that can be changed on its own.
+			if tc.size > 0 {
+				require.NoError(t, decoded.AppendLeaf([]byte("new")))
+				require.NoError(t, tree.AppendLeaf([]byte("new")))
+				assert.Equal(t, tree.Root.Hash, decoded.Root.Hash)
+			}
+		})
+	}
+}
+
+func TestUnmarshalBinaryOptions(t *testing.T) {
+	t.Parallel()
+
+	opts := []Option{WithLevelTags(LevelIndexTag), WithDomainPrefixes([]byte{0}, []byte{1})}
+	data := generateDummyData(9)
+	tree, err := NewTree(data, sha256.New, opts...)
+	require.NoError(t, err)
+	encoded, err := tree.MarshalBinary()
+	require.NoError(t, err)
+
+	// A zero tree has no options, so it can't decode the tree.
+	var zero Tree
+	require.ErrorIs(t, zero.UnmarshalBinary(encoded), ErrInvalidEncoding)
+
+	decoded, err := NewTree(nil, sha256.New, append(opts, WithEmptyTree())...)
+	require.NoError(t, err)
+	require.NoError(t, decoded.UnmarshalBinary(encoded))
+	require.NoError(t, decoded.Validate())
+	require.NoError(t, decoded.UpdateLeaf(3, []byte("updated")))
+	require.NoError(t, tree.UpdateLeaf(3, []byte("updated")))
+	assert.Equal(t, tree.Root.Hash, decoded.Root.Hash)
+}
+
+func TestUnmarshalBinaryOtherOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		expOpts []Option
+	}{
+		{
+			name:    "Missing options",
+			opts:    []Option{WithLevelTags(LevelIndexTag)},
+			expOpts: nil,
+		},
+		{
+			name:    "Other HMAC key",
+			opts:    []Option{WithHMACLeaves([]byte("key"))},
+			expOpts: []Option{WithHMACLeaves([]byte("other key"))},
+		},
+		{
+			name:    "Other prefixes",
+			opts:    []Option{WithDomainPrefixes([]byte{0}, []byte{1})},
+			expOpts: []Option{WithDomainPrefixes([]byte{1}, []byte{0})},
+		},
+		{
+			name:    "Length-prefixed leaves",
+			opts:    nil,
+			expOpts: []Option{WithLengthPrefixedLeaves()},
+		},
+		{
+			name: "Other combine function",
+			opts: []Option{WithCombine(func(left, right []byte) []byte {
+				h := sha256.Sum256(append(append([]byte(nil), right...), left...))
+				return h[:]
+			})},
+			expOpts: nil,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := NewTree(generateDummyData(6), sha256.New, tc.opts...)
+			require.NoError(t, err)
+			encoded, err := tree.MarshalBinary()
+			require.NoError(t, err)
+
+			decoded, err := NewTree(nil, sha256.New, append(tc.expOpts, WithEmptyTree())...)
+			require.NoError(t, err)
+			require.ErrorIs(t, decoded.UnmarshalBinary(encoded), ErrInvalidEncoding)
+		})
+	}
+}
+
+func TestUnmarshalBinaryErrors(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewTree(generateDummyData(5), sha256.New)
+	require.NoError(t, err)
+	encoded, err := tree.MarshalBinary()
+	require.NoError(t, err)
+	// The header is the magic, the version, the flags, the options
+	// fingerprint and four varints that fit into a byte each.
+	header := len(treeMagic) + 2 + optionsFingerprintSize + 4
+
+	replace := func(i int, b byte) []byte {
+		data := append([]byte(nil), encoded...)
+		data[i] = b
+		return data
+	}
+
+	tests := []struct {
+		name        string
+		data        []byte
+		newHashFunc func() hash.Hash
+		err         error
+	}{
+		{
+			name: "Empty",
+			data: nil,
+			err:  ErrInvalidEncoding,
+		},
+		{
+			name: "Bad magic",
+			data: replace(0, 'X'),
+			err:  ErrInvalidEncoding,
+		},
+		{
+			name: "Unsupported version",
+			data: replace(len(treeMagic), treeVersion+1),
+			err:  ErrInvalidEncoding,
+		},
+		{
+			name: "Unknown flags",
+			data: replace(len(treeMagic)+1, 0x80),
+			err:  ErrInvalidEncoding,
+		},
+		{
+			name: "Unknown hash function",
+			data: replace(len(treeMagic)+2, 0),
+			err:  ErrInvalidEncoding,
+		},
+		{
+			name:        "Other hash function",
+			data:        encoded,
+			newHashFunc: sha512.New,
+			err:         ErrInvalidEncoding,
+		},
+		{
+			name: "Wrong hash size",
+			data: replace(len(treeMagic)+3, 20),
+			err:  ErrInvalidEncoding,
+		},
+		{
+			name: "Other options",
+			data: replace(header-2, encoded[header-2]^0xff),
+			err:  ErrInvalidEncoding,
+		},
+		{
+			name: "Corrupted leaf hash",
+			data: replace(header, encoded[header]^0xff),
+			err:  ErrInvalidTree,
+		},
+		{
+			name: "Corrupted node hash",
+			data: replace(header+5*sha256.Size, encoded[header+5*sha256.Size]^0xff),
+			err:  ErrInvalidTree,
+		},
+		{
+			name: "Corrupted root hash",
+			data: replace(header+9*sha256.Size-1, encoded[header+9*sha256.Size-1]^0xff),
+			err:  ErrInvalidTree,
+		},
+		{
+			name: "Corrupted value",
+			data: replace(len(encoded)-1, encoded[len(encoded)-1]^0xff),
+			err:  ErrInvalidTree,
+		},
+		{
+			name: "Too many leaves",
+			data: replace(header-1, 100),
+			err:  ErrInvalidEncoding,
+		},
+		{
+			name: "Truncated header",
+			data: encoded[:header-1],
+			err:  ErrInvalidEncoding,
+		},
+		{
+			name: "Truncated hashes",
+			data: encoded[:header+7*sha256.Size],
+			err:  ErrInvalidEncoding,
+		},
+		{
+			name: "Truncated values",
+			data: encoded[:len(encoded)-1],
+			err:  ErrInvalidEncoding,
+		},
+		{
+			name: "Trailing data",
+			data: append(append([]byte(nil), encoded...), 0),
+			err:  ErrInvalidEncoding,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			decoded := &Tree{}
+			if tc.newHashFunc != nil {
+				var err error
+				decoded, err = NewTree(nil, tc.newHashFunc, WithEmptyTree())
+				require.NoError(t, err)
+			}
+			require.ErrorIs(t, decoded.UnmarshalBinary(tc.data), tc.err)
+		})
+	}
+}
+
+func TestUnmarshalBinaryKeepsTreeOnError(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewTree(generateDummyData(5), sha256.New)
+	require.NoError(t, err)
+	encoded, err := tree.MarshalBinary()
+	require.NoError(t, err)
+
+	other, err := NewTree(generateDummyData(3), sha256.New)
+	require.NoError(t, err)
+	root := other.RootHash()
+
+	encoded[len(encoded)-1] ^= 0xff
+	require.ErrorIs(t, other.UnmarshalBinary(encoded), ErrInvalidTree)
+	assert.Equal(t, root, other.RootHash())
+	assert.Equal(t, 3, other.Len())
+}
+
+func TestMarshalBinaryPruned(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewTree(generateDummyData(8), sha256.New)
+	require.NoError(t, err)
+	require.NoError(t, tree.Prune([]int{1}))
+	_, err = tree.MarshalBinary()
+	assert.ErrorIs(t, err, ErrLeafPruned)
+}
+
+func TestHashID(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		newHashFunc func() hash.Hash
+		expID       crypto.Hash
+	}{
+		{
+			name:        "SHA-256",
+			newHashFunc: sha256.New,
+			expID:       crypto.SHA256,
+		},
+		{
+			name:        "SHA-224",
+			newHashFunc: sha256.New224,
+			expID:       crypto.SHA224,
+		},
+		{
+			name:        "SHA3-256",
+			newHashFunc: sha3.New256,
+			expID:       crypto.SHA3_256,
+		},
+		{
+			name:        "Not in the standard library",
+			newHashFunc: NewDoubleSHA256,
+		},
+		{
+			name: "No hash function",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expID, hashID(tc.newHashFunc))
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package merkle
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"math/bits"
+)
+
+var ErrInvalidBLAKE2bConfig = errors.New("invalid blake2b config")
+
+const (
+	blake2bBlockSize    = 128
+	blake2bMaxSize      = 64
+	blake2bMaxKeySize   = 64
+	blake2bSaltSize     = 16
+	blake2bPersonalSize = 16
+)
+
+var blake2bIV = [8]uint64{
+	0x6a09e667f3bcc908, 0xbb67ae8584caa73b, 0x3c6ef372fe94f82b, 0xa54ff53a5f1d36f1,
+	0x510e527fade682d1, 0x9b05688c2b3e6c1f, 0x1f83d9abfb41bd6b, 0x5be0cd19137e2179,
+}
+
+var blake2bSigma = [12][16]byte{
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+	{14, 10, 4, 8, 9, 15, 13, 6, 1, 12, 0, 2, 11, 7, 5, 3},
+	{11, 8, 12, 0, 5, 2, 15, 13, 10, 14, 3, 6, 7, 1, 9, 4},
+	{7, 9, 3, 1, 13, 12, 11, 14, 2, 6, 5, 10, 4, 0, 15, 8},
+	{9, 0, 5, 7, 2, 4, 10, 15, 14, 1, 11, 12, 6, 8, 3, 13},
+	{2, 12, 6, 10, 0, 11, 8, 3, 4, 13, 7, 5, 15, 14, 1, 9},
+	{12, 5, 1, 15, 14, 13, 4, 10, 0, 7, 6, 3, 9, 2, 8, 11},
+	{13, 11, 7, 14, 12, 1, 3, 9, 5, 0, 15, 4, 8, 6, 2, 10},
+	{6, 15, 14, 9, 11, 3, 0, 8, 12, 2, 13, 7, 1, 4, 10, 5},
+	{10, 2, 8, 4, 7, 6, 1, 5, 15, 11, 9, 14, 3, 12, 13, 0},
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+	{14, 10, 4, 8, 9, 15, 13, 6, 1, 12, 0, 2, 11, 7, 5, 3},
+}
+
+// BLAKE2bConfig holds the parameters of a BLAKE2b hash as defined
+// in RFC 7693 and the BLAKE2 paper. golang.org/x/crypto/blake2b only
+// sets the digest size and key, so the parameter block is built here.
+type BLAKE2bConfig struct {
+	// Size is the digest size in bytes, up to 64.
+	// A Size of 0 means 32 bytes.
+	Size int
+	// Key turns BLAKE2b into a MAC. It can be up to 64 bytes.
+	Key []byte
+	// Salt and Personal are up to 16 bytes each and are padded with zeros.
+	// Trees that use different personalization strings have
+	// unrelated hashes, even when they hold the same leaves.
+	Salt     []byte
+	Personal []byte
+}
+
+// blake2bHash is a BLAKE2b hash with a parameter block.
+type blake2bHash struct {
+	init  [8]uint64
+	key   [blake2bBlockSize]byte
+	keyed bool
+	size  int
+
+	h     [8]uint64
+	t     [2]uint64
+	block [blake2bBlockSize]byte
+	n     int
+}
+
+// NewBLAKE2bFunc returns a constructor for BLAKE2b hashes with the given
+// digest size, key, salt and personalization string. Use it with SplitHasher
+// and WithHasher to personalize leaves and nodes differently, or use
+// WithBLAKE2b.
+func NewBLAKE2bFunc(cfg BLAKE2bConfig) (func() hash.Hash, error) {
+	size := cfg.Size
+	if size == 0 {
+		size = 32
+	}
+	if size < 0 || size > blake2bMaxSize {
+		return nil, fmt.Errorf("%w: digest size %d", ErrInvalidBLAKE2bConfig, cfg.Size)
+	}
+	if len(cfg.Key) > blake2bMaxKeySize {
+		return nil, fmt.Errorf("%w: key is %d bytes", ErrInvalidBLAKE2bConfig, len(cfg.Key))
+	}
+	if len(cfg.Salt) > blake2bSaltSize {
+		return nil, fmt.Errorf("%w: salt is %d bytes", ErrInvalidBLAKE2bConfig, len(cfg.Salt))
+	}
+	if len(cfg.Personal) > blake2bPersonalSize {
+		return nil, fmt.Errorf("%w: personalization is %d bytes", ErrInvalidBLAKE2bConfig, len(cfg.Personal))
+	}
+
+	// The parameter block is XORed into the IV. The fanout
+	// and depth are 1, which is sequential hashing.
+	var params [64]byte
+	params[0] = byte(size)
+	params[1] = byte(len(cfg.Key))
+	params[2] = 1
+	params[3] = 1
+	copy(params[32:48], cfg.Salt)
+	copy(params[48:64], cfg.Personal)
+
+	var init [8]uint64
+	for i := range init {
+		init[i] = blake2bIV[i] ^ binary.LittleEndian.Uint64(params[i*8:])
+	}
+
+	var key [blake2bBlockSize]byte
+	copy(key[:], cfg.Key)
+	keyed := len(cfg.Key) > 0
+
+	return func() hash.Hash {
+		h := &blake2bHash{init: init, key: key, keyed: keyed, size: size}
+		h.Reset()
+		return h
+	}, nil
+}
+
+func (h *blake2bHash) Reset() {
+	h.h = h.init
+	h.t = [2]uint64{}
+	h.block = [blake2bBlockSize]byte{}
+	h.n = 0
+
+	// A key is processed as the first block of the message.
+	if h.keyed {
+		h.block = h.key
+		h.n = blake2bBlockSize
+	}
+}
+
+func (h *blake2bHash) Write(p []byte) (int, error) {
+	written := len(p)
+	for len(p) > 0 {
+		// The last block is compressed by Sum, so a full block
+		// is only compressed once more data arrives.
+		if h.n == blake2bBlockSize {
+			h.compress(blake2bBlockSize, false)
+			h.n = 0
+		}
+		n := copy(h.block[h.n:], p)
+		h.n += n
+		p = p[n:]
+	}
+	return written, nil
+}
+
+func (h *blake2bHash) Sum(b []byte) []byte {
+	d := *h
+	clear(d.block[d.n:])
+	d.compress(d.n, true)
+
+	var digest [blake2bMaxSize]byte
+	for i, v := range d.h {
+		binary.LittleEndian.PutUint64(digest[i*8:], v)
+	}
+	return append(b, digest[:h.size]...)
+}
+
+func (h *blake2bHash) Size() int {
+	return h.size
+}
+
+func (h *blake2bHash) BlockSize() int {
+	return blake2bBlockSize
+}
+
+// compress mixes the buffered block, which holds n new bytes, into the state.
+func (h *blake2bHash) compress(n int, last bool) {
+	var carry uint64
+	h.t[0], carry = bits.Add64(h.t[0], uint64(n), 0)
+	h.t[1] += carry
+
+	var m [16]uint64
+	for i := range m {
+		m[i] = binary.LittleEndian.Uint64(h.block[i*8:])
+	}
+
+	var v [16]uint64
+	copy(v[:8], h.h[:])
+	copy(v[8:], blake2bIV[:])
+	v[12] ^= h.t[0]
+	v[13] ^= h.t[1]
+	if last {
+		v[14] = ^v[14]
+	}
+
+	g := func(a, b, c, d int, x, y uint64) {
+		v[a] += v[b] + x
+		v[d] = bits.RotateLeft64(v[d]^v[a], -32)
+		v[c] += v[d]
+		v[b] = bits.RotateLeft64(v[b]^v[c], -24)
+		v[a] += v[b] + y
+		v[d] = bits.RotateLeft64(v[d]^v[a], -16)
+		v[c] += v[d]
+		v[b] = bits.RotateLeft64(v[b]^v[c], -63)
+	}
+
+	for _, s := range blake2bSigma {
+		g(0, 4, 8, 12, m[s[0]], m[s[1]])
+		g(1, 5, 9, 13, m[s[2]], m[s[3]])
+		g(2, 6, 10, 14, m[s[4]], m[s[5]])
+		g(3, 7, 11, 15, m[s[6]], m[s[7]])
+		g(0, 5, 10, 15, m[s[8]], m[s[9]])
+		g(1, 6, 11, 12, m[s[10]], m[s[11]])
+		g(2, 7, 8, 13, m[s[12]], m[s[13]])
+		g(3, 4, 9, 14, m[s[14]], m[s[15]])
+	}
+
+	for i := range h.h {
+		h.h[i] ^= v[i] ^ v[i+8]
+	}
+}
+
+// newKeyedBLAKE2b returns a constructor for BLAKE2b-256 hashes with
+// the given key. Keys longer than 64 bytes are replaced by their
+// BLAKE2b-512 hash, like HMAC does with long keys.
+func newKeyedBLAKE2b(key []byte) func() hash.Hash {
+	if len(key) > blake2bMaxKeySize {
+		// A 64 byte digest without a key is always a valid config.
+		newHashFunc, _ := NewBLAKE2bFunc(BLAKE2bConfig{Size: blake2bMaxSize})
+		h := newHashFunc()
+		h.Write(key)
+		key = h.Sum(nil)
+	}
+
+	// The key is at most 64 bytes, so the config is valid.
+	newHashFunc, _ := NewBLAKE2bFunc(BLAKE2bConfig{Key: key})
+	return newHashFunc
+}
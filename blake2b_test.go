@@ -0,0 +1,225 @@
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/blake2b"
+)
+
+func TestBLAKE2b(t *testing.T) {
+	t.Parallel()
+
+	sequence := func(n int) []byte {
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = byte(i)
+		}
+		return b
+	}
+
+	tests := []struct {
+		name      string
+		cfg       BLAKE2bConfig
+		data      []byte
+		expDigest string
+	}{
+		{
+			// RFC 7693, Appendix A.
+			name: "Unkeyed BLAKE2b-512",
+			cfg:  BLAKE2bConfig{Size: 64},
+			data: []byte("abc"),
+			expDigest: "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d1" +
+				"7d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923",
+		},
+		{
+			// The first keyed test vector of the BLAKE2 reference implementation.
+			name: "Keyed BLAKE2b-512",
+			cfg:  BLAKE2bConfig{Size: 64, Key: sequence(64)},
+			data: []byte{},
+			expDigest: "10ebb67700b1868efb4417987acf4690ae9d972fb7a590c2f02871799aaa4786" +
+				"b5e996e8f0f4eb981fc214b005f42d2ff4233499391653df7aefcbc13fc51568",
+		},
+		{
+			// The remaining vectors are from the reference implementation,
+			// as exposed by Python's hashlib.blake2b.
+			name:      "Key, salt and personalization",
+			cfg:       BLAKE2bConfig{Key: []byte("key"), Salt: []byte("salt"), Personal: []byte("merkle-leaf")},
+			data:      []byte("abc"),
+			expDigest: "a386f665300e1f52365698afaf3effb9c79dd776445a90c22a2fa3fda149993c",
+		},
+		{
+			name:      "Personalization over several blocks",
+			cfg:       BLAKE2bConfig{Size: 20, Personal: []byte("merkle-node")},
+			data:      bytes.Repeat([]byte("a"), 300),
+			expDigest: "50b9c3f05a694f7e61e88236352ba249c88d8ac2",
+		},
+		{
+			name: "Maximum sizes",
+			cfg: BLAKE2bConfig{
+				Size:     64,
+				Key:      bytes.Repeat([]byte("k"), 64),
+				Salt:     bytes.Repeat([]byte("s"), 16),
+				Personal: bytes.Repeat([]byte("p"), 16),
+			},
+			data: []byte{},
+			expDigest: "083163de2239e61a1ff9429bdf15d96685949860ba8cc90615e9c0ecaa4ae08d" +
+				"1a8b78650d9b9a50d7b951e199a7750e04605a51d070ba0132044fcb9bbcce5a",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			newHashFunc, err := NewBLAKE2bFunc(tc.cfg)
+			require.NoError(t, err)
+
+			h := newHashFunc()
+			h.Write(tc.data)
+			assert.Equal(t, tc.expDigest, hex.EncodeToString(h.Sum(nil)))
+
+			// Reset restores the key block.
+			h.Reset()
+			h.Write(tc.data)
+			assert.Equal(t, tc.expDigest, hex.EncodeToString(h.Sum(nil)))
+		})
+	}
+}
+
+func TestBLAKE2bMatchesXCrypto(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("secret")
+	for _, size := range []int{0, 1, 127, 128, 129, 256, 1000} {
+		data := bytes.Repeat([]byte{0xab}, size)
+
+		// A zero Size is BLAKE2b-256.
+		newHashFunc, err := NewBLAKE2bFunc(BLAKE2bConfig{Key: key})
+		require.NoError(t, err)
+		exp, err := blake2b.New256(key)
+		require.NoError(t, err)
+
+		h := newHashFunc()
+		// Write in pieces that cross block boundaries.
+		for i := 0; i < len(data); i += 50 {
+			h.Write(data[i:min(i+50, len(data))])
+		}
+		exp.Write(data)
+		assert.Equal(t, exp.Sum(nil), h.Sum(nil), "Digest mismatch for %d bytes", size)
+	}
+}
+
+func TestBLAKE2bPersonalizedTree(t *testing.T) {
+	t.Parallel()
+
+	newLeafHash, err := NewBLAKE2bFunc(BLAKE2bConfig{Personal: []byte("leaf")})
+	require.NoError(t, err)
+	newNodeHash, err := NewBLAKE2bFunc(BLAKE2bConfig{Personal: []byte("node")})
+	require.NoError(t, err)
+
+	values := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+	tree, err := NewTree(values, nil, WithHasher(SplitHasher(newLeafHash, newNodeHash)))
+	require.NoError(t, err)
+	assert.Equal(t, "80310effb2f9c40fb00698de5cdb9c910d221fef60f6bb46158996d6db6c6831",
+		hex.EncodeToString(tree.Root.Hash))
+
+	for i, value := range values {
+		proof, err := tree.GenerateProofByIndex(i)
+		require.NoError(t, err)
+
+		isValid, err := tree.VerifyProof(proof, value)
+		require.NoError(t, err)
+		assert.True(t, isValid)
+	}
+}
+
+func TestWithBLAKE2b(t *testing.T) {
+	t.Parallel()
+
+	keyedSum := func(key []byte, data ...[]byte) []byte {
+		h, err := blake2b.New256(key)
+		require.NoError(t, err)
+		for _, b := range data {
+			h.Write(b)
+		}
+		return h.Sum(nil)
+	}
+
+	leafKey, nodeKey := []byte("leaf key"), []byte("node key")
+	values := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+	tree, err := NewTree(values, nil, WithBLAKE2b(leafKey, nodeKey))
+	require.NoError(t, err)
+
+	left := keyedSum(nodeKey, keyedSum(leafKey, values[0]), keyedSum(leafKey, values[1]))
+	assert.Equal(t, keyedSum(nodeKey, left, keyedSum(leafKey, values[2])), tree.Root.Hash)
+
+	verifier := NewVerifier(nil, WithBLAKE2b(leafKey, nodeKey))
+	for i, value := range values {
+		proof, err := tree.GenerateProofByIndex(i)
+		require.NoError(t, err)
+
+		isValid, err := tree.VerifyProof(proof, value)
+		require.NoError(t, err)
+		assert.True(t, isValid)
+
+		isValid, err = verifier.Verify(tree.Root.Hash, len(values), proof, value)
+		require.NoError(t, err)
+		assert.True(t, isValid)
+	}
+
+	// Trees with other keys have unrelated roots.
+	other, err := NewTree(values, nil, WithBLAKE2b(leafKey, []byte("other key")))
+	require.NoError(t, err)
+	assert.NotEqual(t, tree.Root.Hash, other.Root.Hash)
+
+	// Long keys are hashed to 64 bytes.
+	longKey := bytes.Repeat([]byte("k"), 100)
+	long, err := NewTree(values, sha256.New, WithBLAKE2b(longKey, nil))
+	require.NoError(t, err)
+	hashedKey := blake2b.Sum512(longKey)
+	assert.Equal(t, keyedSum(hashedKey[:], values[0]), long.Leaves[0].Hash)
+}
+
+func TestBLAKE2bInvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		cfg  BLAKE2bConfig
+	}{
+		{
+			name: "Digest too large",
+			cfg:  BLAKE2bConfig{Size: 65},
+		},
+		{
+			name: "Negative digest size",
+			cfg:  BLAKE2bConfig{Size: -1},
+		},
+		{
+			name: "Key too long",
+			cfg:  BLAKE2bConfig{Key: make([]byte, 65)},
+		},
+		{
+			name: "Salt too long",
+			cfg:  BLAKE2bConfig{Salt: make([]byte, 17)},
+		},
+		{
+			name: "Personalization too long",
+			cfg:  BLAKE2bConfig{Personal: make([]byte, 17)},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewBLAKE2bFunc(tc.cfg)
+			require.ErrorIs(t, err, ErrInvalidBLAKE2bConfig)
+		})
+	}
+}
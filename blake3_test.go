@@ -0,0 +1,97 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBLAKE3(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		value   []byte
+		expHash string
+	}{
+		{
+			name:    "Empty",
+			value:   []byte{},
+			expHash: "af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262",
+		},
+		{
+			name:    "abc",
+			value:   []byte("abc"),
+			expHash: "6437b3ac38465133ffb63b75273a8db548c558465d79db03fd359c6cd5bd9d85",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := NewTree([][]byte{tc.value}, NewBLAKE3)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expHash, hex.EncodeToString(tree.Root.Hash))
+		})
+	}
+}
+
+func TestBLAKE3LargeLeaves(t *testing.T) {
+	t.Parallel()
+
+	data := generateLargeLeaves(5, 1<<16)
+	tree, err := NewTree(data, NewBLAKE3)
+	require.NoError(t, err)
+
+	for i, value := range data {
+		proof, err := tree.GenerateProofByIndex(i)
+		require.NoError(t, err)
+
+		isValid, err := tree.VerifyProof(proof, value)
+		require.NoError(t, err)
+		assert.True(t, isValid)
+	}
+}
+
+func BenchmarkLargeLeaves(b *testing.B) {
+	hashFuncs := []struct {
+		name        string
+		newHashFunc func() hash.Hash
+	}{
+		{name: "SHA-256", newHashFunc: sha256.New},
+		{name: "BLAKE3", newHashFunc: NewBLAKE3},
+	}
+
+	for _, leafSize := range []int{1 << 12, 1 << 16, 1 << 20} {
+		data := generateLargeLeaves(64, leafSize)
+		for _, hf := range hashFuncs {
+			b.Run(fmt.Sprintf("%s %d byte leaves", hf.name, leafSize), func(b *testing.B) {
+				b.SetBytes(int64(len(data) * leafSize))
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					_, err := NewTree(data, hf.newHashFunc)
+					if err != nil {
+						b.Errorf("Error creating Merkle tree: %v", err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func generateLargeLeaves(count, size int) [][]byte {
+	data := make([][]byte, count)
+	for i := range data {
+		data[i] = make([]byte, size)
+		for j := range data[i] {
+			data[i][j] = byte(i + j)
+		}
+	}
+	return data
+}
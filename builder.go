@@ -0,0 +1,214 @@
+package merkle
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+var ErrInvalidCheckpoint = errors.New("invalid checkpoint")
+
+// checkpointMagic identifies a builder checkpoint.
+var checkpointMagic = []byte("MKCP\x01")
+
+// Builder builds a tree incrementally and can be checkpointed
+// and resumed, e.g. on another machine, for inputs that take too long
+// to hash in one go. It keeps the hashes of all complete subtrees,
+// so resuming continues where the checkpoint left off.
+// Leaf values are not retained.
+type Builder struct {
+	newHashFunc func() hash.Hash
+	hashFunc    hash.Hash
+	cfg         config
+
+	// levels[0] holds the leaf hashes and levels[l] holds the hashes
+	// of the complete subtrees with 2^l leaves.
+	levels [][][]byte
+}
+
+// NewBuilder creates an empty builder. The options apply
+// to the hashes of the builder and to the built trees.
+func NewBuilder(newHashFunc func() hash.Hash, opts ...Option) *Builder {
+	cfg := newConfig(opts, newHashFunc)
+	return &Builder{
+		newHashFunc: newHashFunc,
+		hashFunc:    cfg.hasher.NewNodeHasher(),
+		cfg:         cfg,
+		levels:      [][][]byte{{}},
+	}
+}
+
+// Len returns the number of leaves added so far.
+func (b *Builder) Len() int {
+	return len(b.levels[0])
+}
+
+// Add hashes the values in parallel and adds them as leaves.
+// No value is added if any of them is invalid.
+func (b *Builder) Add(values ...[]byte) error {
+	values, err := b.cfg.leafValues(values)
+	if err != nil {
+		return err
+	}
+	hashes, err := b.cfg.parallelism.preHashLeavesContext(context.Background(), values, b.cfg.hasher.NewLeafHasher)
+	if err != nil {
+		return err
+	}
+	for _, hash := range hashes {
+		b.AddHash(hash)
+	}
+	return nil
+}
+
+// AddHash adds an already hashed leaf.
+func (b *Builder) AddHash(hash []byte) {
+	b.levels[0] = append(b.levels[0], hash)
+
+	// Hash every pair that has been completed by the new leaf.
+	for level := 0; len(b.levels[level])%2 == 0; level++ {
+		if level+1 == len(b.levels) {
+			b.levels = append(b.levels, nil)
+		}
+
+		n := len(b.levels[level])
+		parentHash := combineLevelHashes(level+1, b.levels[level][n-2], b.levels[level][n-1], b.hashFunc, &b.cfg)
+		b.levels[level+1] = append(b.levels[level+1], parentHash)
+	}
+}
+
+// Tree builds the tree over the leaves added so far,
+// reusing the hashes of the complete subtrees.
+func (b *Builder) Tree() (*Tree, error) {
+	if b.Len() == 0 && !b.cfg.allowEmpty {
+		return nil, ErrNoLeaves
+	}
+
+	leaves := make([]*Node, b.Len())
+	for i, hash := range b.levels[0] {
+		leaves[i] = NewNode(hash, nil)
+	}
+
+	var root *Node
+	nodes := leaves
+	for level := 0; len(nodes) > 1; level++ {
+		parents := make([]*Node, (len(nodes)+1)/2)
+		for i := 0; i < len(nodes); i += 2 {
+			left := nodes[i]
+			if i+1 == len(nodes) {
+				// If right is nil, carry the left node up without hashing
+				parents[i/2] = left
+				continue
+			}
+			right := nodes[i+1]
+
+			// Only the right edge of the tree has to be hashed.
+			var parentHash []byte
+			if level+1 < len(b.levels) && i/2 < len(b.levels[level+1]) {
+				parentHash = b.levels[level+1][i/2]
+			} else {
+				parentHash = combineLevelHashes(level+1, left.Hash, right.Hash, b.hashFunc, &b.cfg)
+			}
+
+			parent := &Node{
+				Hash:  parentHash,
+				Left:  left,
+				Right: right,
+			}
+			left.Parent = parent
+			right.Parent = parent
+			parents[i/2] = parent
+		}
+		nodes = parents
+	}
+
+	if len(nodes) > 0 {
+		root = nodes[0]
+	}
+
+	tree := newTreeFromRoot(root, leaves, b.cfg.hasher.NewNodeHasher(), b.newHashFunc, b.cfg)
+	tree.hashedLeaves = true
+	tree.buildIndex()
+	return tree, nil
+}
+
+// Checkpoint writes the state of the builder to w.
+func (b *Builder) Checkpoint(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.Write(checkpointMagic); err != nil {
+		return err
+	}
+
+	var buf []byte
+	buf = binary.AppendUvarint(buf, uint64(len(b.levels)))
+	for _, level := range b.levels {
+		buf = binary.AppendUvarint(buf, uint64(len(level)))
+		for _, hash := range level {
+			buf = binary.AppendUvarint(buf, uint64(len(hash)))
+			buf = append(buf, hash...)
+		}
+
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+		buf = buf[:0]
+	}
+
+	return bw.Flush()
+}
+
+// ResumeBuilder restores a builder from a checkpoint written by Checkpoint.
+// The same hash function and options have to be used as when
+// the checkpoint was written.
+func ResumeBuilder(r io.Reader, newHashFunc func() hash.Hash, opts ...Option) (*Builder, error) {
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, len(checkpointMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != string(checkpointMagic) {
+		return nil, fmt.Errorf("%w: bad header", ErrInvalidCheckpoint)
+	}
+
+	numLevels, err := binary.ReadUvarint(br)
+	if err != nil || numLevels == 0 || numLevels > 64 {
+		return nil, fmt.Errorf("%w: bad number of levels", ErrInvalidCheckpoint)
+	}
+
+	levels := make([][][]byte, numLevels)
+	for l := range levels {
+		count, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidCheckpoint, err)
+		}
+
+		// Every level holds the parents of the pairs of the level below.
+		if l > 0 && count != uint64(len(levels[l-1])/2) {
+			return nil, fmt.Errorf("%w: level %d has %d hashes, expected %d",
+				ErrInvalidCheckpoint, l, count, len(levels[l-1])/2)
+		}
+
+		levels[l] = make([][]byte, 0, min(count, 1<<20))
+		for i := uint64(0); i < count; i++ {
+			size, err := binary.ReadUvarint(br)
+			if err != nil || size > 1<<10 {
+				return nil, fmt.Errorf("%w: bad hash size", ErrInvalidCheckpoint)
+			}
+			hash := make([]byte, size)
+			if _, err := io.ReadFull(br, hash); err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrInvalidCheckpoint, err)
+			}
+			levels[l] = append(levels[l], hash)
+		}
+	}
+
+	// The top level can't hold a complete pair, or it would have been hashed.
+	if len(levels[numLevels-1]) > 1 {
+		return nil, fmt.Errorf("%w: unfinished top level", ErrInvalidCheckpoint)
+	}
+
+	b := NewBuilder(newHashFunc, opts...)
+	b.levels = levels
+	return b, nil
+}
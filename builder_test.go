@@ -0,0 +1,166 @@
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilder(t *testing.T) {
+	t.Parallel()
+
+	for _, size := range []int{1, 2, 3, 7, 8, 13, 64} {
+		t.Run(fmt.Sprintf("%d leaves", size), func(t *testing.T) {
+			t.Parallel()
+
+			data := generateDummyData(size)
+			b := NewBuilder(sha256.New)
+			require.NoError(t, b.Add(data...))
+			assert.Equal(t, size, b.Len())
+
+			tree, err := b.Tree()
+			require.NoError(t, err)
+
+			expTree, err := NewTree(data, sha256.New)
+			require.NoError(t, err)
+			assert.Equal(t, expTree.Root.Hash, tree.Root.Hash, "Tree root mismatch")
+
+			for i := range data {
+				proof, err := tree.GenerateProofByIndex(i)
+				require.NoError(t, err)
+
+				isValid, err := tree.VerifyProof(proof, data[i])
+				require.NoError(t, err)
+				assert.True(t, isValid)
+			}
+		})
+	}
+
+	_, err := NewBuilder(sha256.New).Tree()
+	require.ErrorIs(t, err, ErrNoLeaves)
+}
+
+func TestBuilderCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	data := generateDummyData(37)
+
+	// Build the first part, checkpoint and resume with the rest.
+	b := NewBuilder(sha256.New)
+	require.NoError(t, b.Add(data[:22]...))
+
+	var buf bytes.Buffer
+	require.NoError(t, b.Checkpoint(&buf))
+
+	resumed, err := ResumeBuilder(&buf, sha256.New)
+	require.NoError(t, err)
+	assert.Equal(t, 22, resumed.Len())
+
+	require.NoError(t, resumed.Add(data[22:]...))
+	tree, err := resumed.Tree()
+	require.NoError(t, err)
+
+	expTree, err := NewTree(data, sha256.New)
+	require.NoError(t, err)
+	assert.Equal(t, expTree.Root.Hash, tree.Root.Hash, "Tree root mismatch")
+}
+
+func TestBuilderWithOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{
+			name: "Domain prefixes",
+			opts: []Option{WithDomainPrefixes([]byte{0}, []byte{1})},
+		},
+		{
+			name: "Level tags",
+			opts: []Option{WithLevelTags(LevelIndexTag)},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := generateDummyData(13)
+			opts := append(tc.opts, WithRootHistory())
+			b := NewBuilder(sha256.New, opts...)
+			require.NoError(t, b.Add(data[:6]...))
+
+			var buf bytes.Buffer
+			require.NoError(t, b.Checkpoint(&buf))
+			resumed, err := ResumeBuilder(&buf, sha256.New, opts...)
+			require.NoError(t, err)
+			require.NoError(t, resumed.Add(data[6:]...))
+
+			tree, err := resumed.Tree()
+			require.NoError(t, err)
+			expTree, err := NewTree(data, sha256.New, tc.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, expTree.Root.Hash, tree.Root.Hash, "Tree root mismatch")
+			require.NoError(t, tree.Validate())
+
+			proof, err := tree.GenerateProofByIndex(12)
+			require.NoError(t, err)
+			isValid, err := tree.VerifyProof(proof, data[12])
+			require.NoError(t, err)
+			assert.True(t, isValid)
+
+			// Built trees record their root.
+			assert.Len(t, tree.RootHistory(), 1)
+		})
+	}
+
+	tree, err := NewBuilder(sha256.New, WithEmptyTree()).Tree()
+	require.NoError(t, err)
+	assert.Zero(t, tree.Len())
+
+	err = NewBuilder(sha256.New, WithFixedLeafSize(32)).Add([]byte("short"))
+	require.ErrorIs(t, err, ErrInvalidLeafSize)
+}
+
+func TestResumeBuilderInvalid(t *testing.T) {
+	t.Parallel()
+
+	b := NewBuilder(sha256.New)
+	require.NoError(t, b.Add(generateDummyData(5)...))
+
+	var buf bytes.Buffer
+	require.NoError(t, b.Checkpoint(&buf))
+	checkpoint := buf.Bytes()
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{
+			name: "Empty",
+			data: []byte{},
+		},
+		{
+			name: "Bad magic",
+			data: append([]byte("XXXX\x01"), checkpoint[len(checkpointMagic):]...),
+		},
+		{
+			name: "Truncated",
+			data: checkpoint[:len(checkpoint)-10],
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ResumeBuilder(bytes.NewReader(tc.data), sha256.New)
+			require.ErrorIs(t, err, ErrInvalidCheckpoint)
+		})
+	}
+}
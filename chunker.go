@@ -0,0 +1,203 @@
+package merkle
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/bits"
+)
+
+var ErrInvalidChunkSize = errors.New("invalid chunk size")
+
+// Chunker splits a stream of bytes into chunks.
+type Chunker interface {
+	// Next returns the next chunk of the stream.
+	// It returns io.EOF when there are no chunks left.
+	Next() ([]byte, error)
+}
+
+// fixedChunker splits a stream into chunks of the same size.
+// Only the last chunk can be smaller.
+type fixedChunker struct {
+	r    io.Reader
+	size int
+}
+
+// NewFixedChunker creates a Chunker that splits r into chunks of size bytes.
+func NewFixedChunker(r io.Reader, size int) (Chunker, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidChunkSize, size)
+	}
+	return &fixedChunker{r: r, size: size}, nil
+}
+
+func (c *fixedChunker) Next() ([]byte, error) {
+	chunk := make([]byte, c.size)
+	n, err := io.ReadFull(c.r, chunk)
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	return chunk[:n], nil
+}
+
+// gearTable holds the random values used by the rolling hash
+// of the content defined chunker.
+var gearTable = func() [256]uint64 {
+	var table [256]uint64
+
+	// Fill the table with splitmix64 so chunk boundaries
+	// are the same across processes and machines.
+	seed := uint64(0x9e3779b97f4a7c15)
+	for i := range table {
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[i] = z ^ (z >> 31)
+	}
+	return table
+}()
+
+// contentDefinedChunker splits a stream where a rolling hash
+// over the content matches a mask, so inserting or removing bytes
+// only changes the chunks around the edit.
+type contentDefinedChunker struct {
+	r       *bufio.Reader
+	minSize int
+	maxSize int
+	mask    uint64
+}
+
+// NewContentDefinedChunker creates a Chunker that splits r at content defined
+// boundaries. Chunks are between minSize and maxSize bytes,
+// and avgSize bytes on average. avgSize must be a power of two.
+func NewContentDefinedChunker(r io.Reader, minSize, avgSize, maxSize int) (Chunker, error) {
+	if minSize <= 0 || avgSize < minSize || maxSize < avgSize {
+		return nil, fmt.Errorf("%w: expected 0 < min <= avg <= max, got %d, %d, %d",
+			ErrInvalidChunkSize, minSize, avgSize, maxSize)
+	}
+	if avgSize&(avgSize-1) != 0 {
+		return nil, fmt.Errorf("%w: average size %d is not a power of two", ErrInvalidChunkSize, avgSize)
+	}
+
+	// The mask selects the high bits of the fingerprint, since the low
+	// bits only depend on the last few bytes, as in FastCDC.
+	maskBits := bits.TrailingZeros(uint(avgSize))
+	return &contentDefinedChunker{
+		r:       bufio.NewReader(r),
+		minSize: minSize,
+		maxSize: maxSize,
+		mask:    (uint64(1)<<maskBits - 1) << (64 - maskBits),
+	}, nil
+}
+
+func (c *contentDefinedChunker) Next() ([]byte, error) {
+	var chunk bytes.Buffer
+	var fingerprint uint64
+
+	for chunk.Len() < c.maxSize {
+		b, err := c.r.ReadByte()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		chunk.WriteByte(b)
+
+		fingerprint = (fingerprint << 1) + gearTable[b]
+		if chunk.Len() >= c.minSize && fingerprint&c.mask == 0 {
+			break
+		}
+	}
+
+	if chunk.Len() == 0 {
+		return nil, io.EOF
+	}
+	return chunk.Bytes(), nil
+}
+
+// ChunkedFile is a Merkle tree over the chunks of a file.
+// Only the chunk hashes are kept in memory.
+type ChunkedFile struct {
+	Tree    *Tree
+	Offsets []int64
+	Sizes   []int
+}
+
+// NewChunkedFile reads all chunks from c and builds a Merkle tree
+// over the chunk hashes.
+func NewChunkedFile(c Chunker, newHashFunc func() hash.Hash, opts ...Option) (*ChunkedFile, error) {
+	var (
+		hashes  [][]byte
+		offsets []int64
+		sizes   []int
+		offset  int64
+	)
+
+	hashFunc := newConfig(opts, newHashFunc).hasher.NewLeafHasher()
+	for {
+		chunk, err := c.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		hashFunc.Reset()
+		hashFunc.Write(chunk)
+		hashes = append(hashes, hashFunc.Sum(nil))
+		offsets = append(offsets, offset)
+		sizes = append(sizes, len(chunk))
+		offset += int64(len(chunk))
+	}
+
+	tree, err := NewTreeFromHashes(hashes, newHashFunc, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChunkedFile{
+		Tree:    tree,
+		Offsets: offsets,
+		Sizes:   sizes,
+	}, nil
+}
+
+// NumChunks returns the number of chunks in the file.
+func (f *ChunkedFile) NumChunks() int {
+	return len(f.Tree.Leaves)
+}
+
+// ProveChunk generates an inclusion proof for the chunk at the given index.
+func (f *ChunkedFile) ProveChunk(index int) (*Proof, error) {
+	return f.Tree.GenerateProofByIndex(index)
+}
+
+// VerifyChunk verifies that chunk is part of a file with the given root
+// and number of chunks. It is meant for receivers that only know the root
+// and not the full tree. The options have to match the options of the file.
+func VerifyChunk(root []byte, numChunks int, chunk []byte, proof *Proof, newHashFunc func() hash.Hash, opts ...Option) (bool, error) {
+	cfg := newConfig(opts, newHashFunc)
+	leafHashFunc := cfg.hasher.NewLeafHasher()
+	leafHashFunc.Write(chunk)
+	leafHash := leafHashFunc.Sum(nil)
+
+	computedRoot, ok := rootFromProofWithConfig(leafHash, proof, numChunks, cfg.hasher.NewNodeHasher(), &cfg)
+	if !ok {
+		return false, &ProofSizeError{Index: proof.Index, NumHashes: len(proof.Hashes), Size: numChunks}
+	}
+
+	if !bytes.Equal(computedRoot, root) {
+		return false, &RootMismatchError{Expected: root, Actual: computedRoot}
+	}
+
+	return true, nil
+}
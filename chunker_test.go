@@ -0,0 +1,184 @@
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readChunks(t *testing.T, c Chunker) [][]byte {
+	t.Helper()
+
+	var chunks [][]byte
+	for {
+		chunk, err := c.Next()
+		if errors.Is(err, io.EOF) {
+			return chunks
+		}
+		require.NoError(t, err)
+		chunks = append(chunks, chunk)
+	}
+}
+
+func TestFixedChunker(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		data      []byte
+		size      int
+		expChunks [][]byte
+		err       error
+	}{
+		{
+			name:      "Even split",
+			data:      []byte("abcdef"),
+			size:      2,
+			expChunks: [][]byte{[]byte("ab"), []byte("cd"), []byte("ef")},
+		},
+		{
+			name:      "Short last chunk",
+			data:      []byte("abcdefg"),
+			size:      3,
+			expChunks: [][]byte{[]byte("abc"), []byte("def"), []byte("g")},
+		},
+		{
+			name: "Empty input",
+			data: []byte{},
+			size: 3,
+		},
+		{
+			name: "Invalid size",
+			data: []byte("abc"),
+			size: 0,
+			err:  ErrInvalidChunkSize,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c, err := NewFixedChunker(bytes.NewReader(tc.data), tc.size)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expChunks, readChunks(t, c))
+		})
+	}
+}
+
+func TestContentDefinedChunker(t *testing.T) {
+	t.Parallel()
+
+	data := make([]byte, 1<<16)
+	rand.New(rand.NewSource(42)).Read(data)
+
+	c, err := NewContentDefinedChunker(bytes.NewReader(data), 256, 1024, 4096)
+	require.NoError(t, err)
+	chunks := readChunks(t, c)
+
+	require.Greater(t, len(chunks), 1)
+	assert.Equal(t, data, bytes.Join(chunks, nil), "Chunks should reassemble the input")
+	for i, chunk := range chunks {
+		assert.LessOrEqual(t, len(chunk), 4096)
+		if i < len(chunks)-1 {
+			assert.GreaterOrEqual(t, len(chunk), 256)
+		}
+	}
+
+	// Cuts are taken from the high bits of the fingerprint, which depend on
+	// the last 64 bytes, so chunks are about avgSize bytes past minSize.
+	assert.Equal(t, uint64(0x3ff)<<54, c.(*contentDefinedChunker).mask)
+	avgSize := len(data) / len(chunks)
+	assert.InDelta(t, 256+1024, avgSize, 512, "Average chunk size")
+
+	// Prepending data should only change the chunks at the start.
+	shifted := append([]byte("prefix"), data...)
+	c, err = NewContentDefinedChunker(bytes.NewReader(shifted), 256, 1024, 4096)
+	require.NoError(t, err)
+	shiftedChunks := readChunks(t, c)
+
+	assert.Equal(t, chunks[len(chunks)-1], shiftedChunks[len(shiftedChunks)-1])
+
+	_, err = NewContentDefinedChunker(bytes.NewReader(data), 256, 1000, 4096)
+	require.ErrorIs(t, err, ErrInvalidChunkSize)
+
+	_, err = NewContentDefinedChunker(bytes.NewReader(data), 2048, 1024, 4096)
+	require.ErrorIs(t, err, ErrInvalidChunkSize)
+}
+
+func TestChunkedFile(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("the quick brown fox jumps over the lazy dog")
+
+	c, err := NewFixedChunker(bytes.NewReader(data), 8)
+	require.NoError(t, err)
+
+	file, err := NewChunkedFile(c, sha256.New)
+	require.NoError(t, err)
+	require.Equal(t, 6, file.NumChunks())
+
+	for i := 0; i < file.NumChunks(); i++ {
+		chunk := data[file.Offsets[i] : file.Offsets[i]+int64(file.Sizes[i])]
+
+		proof, err := file.ProveChunk(i)
+		require.NoError(t, err)
+
+		isValid, err := VerifyChunk(file.Tree.Root.Hash, file.NumChunks(), chunk, proof, sha256.New)
+		require.NoError(t, err)
+		assert.True(t, isValid, "Chunk %d should be valid", i)
+
+		isValid, err = VerifyChunk(file.Tree.Root.Hash, file.NumChunks(), []byte("tampered"), proof, sha256.New)
+		require.ErrorIs(t, err, ErrProofVerificationFailed)
+		assert.False(t, isValid)
+	}
+
+	c, err = NewFixedChunker(bytes.NewReader(nil), 8)
+	require.NoError(t, err)
+	_, err = NewChunkedFile(c, sha256.New)
+	require.ErrorIs(t, err, ErrNoLeaves)
+}
+
+func TestChunkedFileWithOptions(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("the quick brown fox jumps over the lazy dog")
+	opts := []Option{WithLevelTags(LevelIndexTag), WithDomainPrefixes([]byte{0}, []byte{1})}
+
+	c, err := NewFixedChunker(bytes.NewReader(data), 8)
+	require.NoError(t, err)
+	file, err := NewChunkedFile(c, sha256.New, opts...)
+	require.NoError(t, err)
+
+	values, err := NewFixedChunker(bytes.NewReader(data), 8)
+	require.NoError(t, err)
+	tree, err := NewTree(readChunks(t, values), sha256.New, opts...)
+	require.NoError(t, err)
+	assert.Equal(t, tree.Root.Hash, file.Tree.Root.Hash)
+
+	for i := 0; i < file.NumChunks(); i++ {
+		chunk := data[file.Offsets[i] : file.Offsets[i]+int64(file.Sizes[i])]
+
+		proof, err := file.ProveChunk(i)
+		require.NoError(t, err)
+
+		isValid, err := VerifyChunk(file.Tree.Root.Hash, file.NumChunks(), chunk, proof, sha256.New, opts...)
+		require.NoError(t, err)
+		assert.True(t, isValid, "Chunk %d should be valid", i)
+
+		isValid, err = VerifyChunk(file.Tree.Root.Hash, file.NumChunks(), chunk, proof, sha256.New)
+		require.ErrorIs(t, err, ErrProofVerificationFailed)
+		assert.False(t, isValid)
+	}
+}
@@ -0,0 +1,203 @@
+package merkle
+
+import (
+	"bytes"
+	"fmt"
+	"hash"
+	"iter"
+)
+
+// ComputeRoot computes the root hash of a tree over values without
+// building the tree. Only the roots of the complete subtrees seen so far
+// are kept, so it needs O(log n) memory and few allocations.
+// It gives the same root as NewTree with the same options.
+func ComputeRoot(values [][]byte, newHashFunc func() hash.Hash, opts ...Option) ([]byte, error) {
+	b := NewRootBuilder(newHashFunc, opts...)
+	values, err := b.cfg.leafValues(values)
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range values {
+		b.addValue(value)
+	}
+	return b.Root()
+}
+
+// ComputeRootFromSeq computes the root hash of a tree over the values
+// yielded by seq like ComputeRoot, but consumes them one at a time,
+// so the values never have to be in memory together.
+func ComputeRootFromSeq(seq iter.Seq[[]byte], newHashFunc func() hash.Hash, opts ...Option) ([]byte, error) {
+	b := NewRootBuilder(newHashFunc, opts...)
+	for value := range seq {
+		if err := b.Add(value); err != nil {
+			return nil, err
+		}
+	}
+	return b.Root()
+}
+
+// RootBuilder computes the root hash of a tree over leaves that are added
+// one at a time, for datasets that are too large to hold in memory.
+// It only keeps the roots of the complete subtrees seen so far, which
+// are O(log n) hashes, and neither the leaves nor the tree.
+type RootBuilder struct {
+	cfg          config
+	leafHashFunc hash.Hash
+	nodeHashFunc hash.Hash
+	size         int
+
+	// hashes holds the roots of the complete subtrees and heights their
+	// heights, from the largest to the smallest. Buffers of merged roots
+	// are reused for later hashes.
+	hashes  [][]byte
+	heights []int
+	free    [][]byte
+}
+
+// NewRootBuilder creates a builder without leaves.
+func NewRootBuilder(newHashFunc func() hash.Hash, opts ...Option) *RootBuilder {
+	return newRootBuilder(newConfig(opts, newHashFunc))
+}
+
+// newRootBuilder creates a builder with the given config.
+func newRootBuilder(cfg config) *RootBuilder {
+	return &RootBuilder{
+		cfg:          cfg,
+		leafHashFunc: cfg.hasher.NewLeafHasher(),
+		nodeHashFunc: cfg.hasher.NewNodeHasher(),
+		hashes:       make([][]byte, 0, 64),
+		heights:      make([]int, 0, 64),
+	}
+}
+
+// Len returns the number of leaves added so far.
+func (b *RootBuilder) Len() int {
+	return b.size
+}
+
+// reset removes all leaves, keeping the buffers for reuse.
+func (b *RootBuilder) reset() {
+	b.size = 0
+	b.free = append(b.free, b.hashes...)
+	b.hashes, b.heights = b.hashes[:0], b.heights[:0]
+}
+
+// Add hashes value and adds it as the next leaf.
+// The builder doesn't keep a reference to value.
+func (b *RootBuilder) Add(value []byte) error {
+	value, err := b.cfg.leafValue(value)
+	if err != nil {
+		return fmt.Errorf("leaf %d: %w", b.size, err)
+	}
+	b.addValue(value)
+	return nil
+}
+
+// AddHash adds an already hashed leaf.
+func (b *RootBuilder) AddHash(hash []byte) error {
+	if err := b.cfg.checkLeafSize(hash); err != nil {
+		return fmt.Errorf("leaf %d: %w", b.size, err)
+	}
+	b.push(append(b.buffer(), hash...))
+	return nil
+}
+
+// addValue hashes a valid value and adds it as the next leaf.
+func (b *RootBuilder) addValue(value []byte) {
+	b.leafHashFunc.Reset()
+	b.leafHashFunc.Write(value)
+	b.push(b.leafHashFunc.Sum(b.buffer()))
+}
+
+// buffer returns an empty buffer for the next leaf hash.
+func (b *RootBuilder) buffer() []byte {
+	n := len(b.free)
+	if n == 0 {
+		return nil
+	}
+	buf := b.free[n-1]
+	b.free = b.free[:n-1]
+	return buf[:0]
+}
+
+// push adds a leaf hash and merges the subtrees
+// of equal height like a binary counter.
+func (b *RootBuilder) push(leafHash []byte) {
+	b.size++
+	b.hashes = append(b.hashes, leafHash)
+	b.heights = append(b.heights, 0)
+	for n := len(b.hashes); n > 1 && b.heights[n-2] == b.heights[n-1]; n-- {
+		b.hashes[n-2] = b.cfg.combineInto(b.hashes[n-2], b.heights[n-1]+1, b.hashes[n-2], b.hashes[n-1], b.nodeHashFunc)
+		b.heights[n-2]++
+		b.free = append(b.free, b.hashes[n-1])
+		b.hashes, b.heights = b.hashes[:n-1], b.heights[:n-1]
+	}
+}
+
+// Root returns the root hash of the tree over the leaves added so far.
+// More leaves can be added afterwards. It returns ErrNoLeaves if no leaf
+// has been added, unless the builder was created with WithEmptyTree.
+func (b *RootBuilder) Root() ([]byte, error) {
+	if b.size == 0 {
+		if !b.cfg.allowEmpty {
+			return nil, ErrNoLeaves
+		}
+		b.nodeHashFunc.Reset()
+		return b.nodeHashFunc.Sum(nil), nil
+	}
+
+	// Hash the subtrees together from right to left.
+	root := bytes.Clone(b.hashes[len(b.hashes)-1])
+	for i := len(b.hashes) - 2; i >= 0; i-- {
+		root = b.cfg.combineInto(root, b.heights[i]+1, b.hashes[i], root, b.nodeHashFunc)
+	}
+	return root, nil
+}
+
+// rootFromLeafHashes computes the root hash of a tree over n leaves,
+// where leaf returns the hash of the leaf at an index. The subtrees
+// of blocks of leaves are hashed in parallel, and then joined.
+func rootFromLeafHashes(n int, leaf func(i int) []byte, cfg *config) []byte {
+	level := min(cacheBlockLevels, treeLevels(n))
+	hashes := make([][]byte, (n+1<<level-1)>>level)
+	cfg.parallelism.batches(len(hashes), func(start, end int) {
+		b := newRootBuilder(*cfg)
+		for i := start; i < end; i++ {
+			b.reset()
+			for j := i << level; j < min(n, (i+1)<<level); j++ {
+				b.push(append(b.buffer(), leaf(j)...))
+			}
+			hashes[i], _ = b.Root()
+		}
+	})
+
+	hashFunc := cfg.hasher.NewNodeHasher()
+	for level++; len(hashes) > 1; level++ {
+		for i := 0; i < len(hashes); i += 2 {
+			if i+1 == len(hashes) {
+				// Carry the last node up if it doesn't have a sibling.
+				hashes[i/2] = hashes[i]
+				continue
+			}
+			hashes[i/2] = combineLevelHashes(level, hashes[i], hashes[i+1], hashFunc, cfg)
+		}
+		hashes = hashes[:(len(hashes)+1)/2]
+	}
+	return hashes[0]
+}
+
+// combineInto combines two hashes like combineLevelHashes,
+// reusing the buffer of dst for the result.
+func (cfg *config) combineInto(dst []byte, level int, leftHash, rightHash []byte, hashFunc hash.Hash) []byte {
+	if len(leftHash) == 0 || len(rightHash) == 0 || cfg.combine != nil {
+		return append(dst[:0], combineLevelHashes(level, leftHash, rightHash, hashFunc, cfg)...)
+	}
+
+	hashFunc.Reset()
+	if cfg.levelTag != nil {
+		hashFunc.Write(cfg.levelTag(level))
+	}
+	hashFunc.Write(leftHash)
+	hashFunc.Write(rightHash)
+	return hashFunc.Sum(dst[:0])
+}
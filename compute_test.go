@@ -0,0 +1,217 @@
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeRoot(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{
+			name: "Default",
+		},
+		{
+			name: "Level tags",
+			opts: []Option{WithLevelTags(LevelIndexTag)},
+		},
+		{
+			name: "Domain prefixes",
+			opts: []Option{WithDomainPrefixes([]byte{0}, []byte{1})},
+		},
+		{
+			name: "Length prefixed leaves",
+			opts: []Option{WithLengthPrefixedLeaves()},
+		},
+		{
+			name: "Custom combine",
+			opts: []Option{WithCombine(func(left, right []byte) []byte {
+				if bytes.Compare(left, right) > 0 {
+					left, right = right, left
+				}
+				hash := sha256.Sum256(append(bytes.Clone(left), right...))
+				return hash[:]
+			})},
+		},
+	}
+
+	for _, tc := range tests {
+		for size := 1; size <= 33; size++ {
+			t.Run(fmt.Sprintf("%s, %d leaves", tc.name, size), func(t *testing.T) {
+				t.Parallel()
+
+				data := generateDummyData(size)
+				tree, err := NewTree(data, sha256.New, tc.opts...)
+				require.NoError(t, err)
+
+				root, err := ComputeRoot(data, sha256.New, tc.opts...)
+				require.NoError(t, err)
+				assert.Equal(t, tree.Root.Hash, root)
+			})
+		}
+	}
+}
+
+func TestComputeRootEmpty(t *testing.T) {
+	t.Parallel()
+
+	_, err := ComputeRoot(nil, sha256.New)
+	require.ErrorIs(t, err, ErrNoLeaves)
+
+	tree, err := NewTree(nil, sha256.New, WithEmptyTree())
+	require.NoError(t, err)
+	root, err := ComputeRoot(nil, sha256.New, WithEmptyTree())
+	require.NoError(t, err)
+	assert.Equal(t, tree.Root.Hash, root)
+
+	_, err = ComputeRoot([][]byte{[]byte("short")}, sha256.New, WithFixedLeafSize(32))
+	require.ErrorIs(t, err, ErrInvalidLeafSize)
+}
+
+func TestComputeRootAllocations(t *testing.T) {
+	data := generateDummyData(10_000)
+	allocs := testing.AllocsPerRun(10, func() {
+		_, err := ComputeRoot(data, sha256.New)
+		require.NoError(t, err)
+	})
+	assert.Less(t, allocs, 100.0)
+}
+
+func TestRootBuilder(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{
+			name: "Default",
+		},
+		{
+			name: "Level tags",
+			opts: []Option{WithLevelTags(LevelIndexTag)},
+		},
+		{
+			name: "Domain prefixes",
+			opts: []Option{WithDomainPrefixes([]byte{0}, []byte{1})},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := generateDummyData(33)
+			b := NewRootBuilder(sha256.New, tc.opts...)
+			for i, value := range data {
+				require.NoError(t, b.Add(value))
+				assert.Equal(t, i+1, b.Len())
+
+				// The root can be read between leaves.
+				tree, err := NewTree(data[:i+1], sha256.New, tc.opts...)
+				require.NoError(t, err)
+				root, err := b.Root()
+				require.NoError(t, err)
+				assert.Equal(t, tree.Root.Hash, root)
+			}
+		})
+	}
+}
+
+func TestRootBuilderAddHash(t *testing.T) {
+	t.Parallel()
+
+	data := generateDummyData(13)
+	hashes := preHashLeaves(data, sha256.New)
+
+	b := NewRootBuilder(sha256.New, WithFixedLeafSize(0))
+	for _, hash := range hashes {
+		require.NoError(t, b.AddHash(hash))
+	}
+	require.ErrorIs(t, b.AddHash([]byte("short")), ErrInvalidLeafSize)
+
+	tree, err := NewTree(data, sha256.New)
+	require.NoError(t, err)
+	root, err := b.Root()
+	require.NoError(t, err)
+	assert.Equal(t, tree.Root.Hash, root)
+}
+
+func TestRootBuilderEmpty(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewRootBuilder(sha256.New).Root()
+	require.ErrorIs(t, err, ErrNoLeaves)
+
+	expRoot, err := ComputeRoot(nil, sha256.New, WithEmptyTree())
+	require.NoError(t, err)
+	root, err := NewRootBuilder(sha256.New, WithEmptyTree()).Root()
+	require.NoError(t, err)
+	assert.Equal(t, expRoot, root)
+}
+
+func TestRootBuilderMemory(t *testing.T) {
+	b := NewRootBuilder(sha256.New)
+	value := make([]byte, 32)
+	for i := 0; i < 1<<10; i++ {
+		require.NoError(t, b.Add(value))
+	}
+
+	// Hash buffers are reused, so adding leaves doesn't allocate.
+	allocs := testing.AllocsPerRun(1<<10, func() {
+		if err := b.Add(value); err != nil {
+			t.Fatal(err)
+		}
+	})
+	assert.Zero(t, allocs)
+	assert.LessOrEqual(t, len(b.hashes), 12)
+}
+
+func TestComputeRootFromSeq(t *testing.T) {
+	t.Parallel()
+
+	data := generateDummyData(21)
+	expRoot, err := ComputeRoot(data, sha256.New)
+	require.NoError(t, err)
+
+	root, err := ComputeRootFromSeq(slices.Values(data), sha256.New)
+	require.NoError(t, err)
+	assert.Equal(t, expRoot, root)
+
+	data[3] = []byte("short")
+	_, err = ComputeRootFromSeq(slices.Values(data), sha256.New, WithFixedLeafSize(32))
+	require.ErrorIs(t, err, ErrInvalidLeafSize)
+	assert.ErrorContains(t, err, "leaf 3")
+}
+
+func BenchmarkComputeRoot(b *testing.B) {
+	data := generateDummyData(10_000)
+
+	b.Run("NewTree", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := NewTree(data, sha256.New); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("ComputeRoot", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := ComputeRoot(data, sha256.New); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package merkle
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidCTField = errors.New("invalid certificate transparency field")
+
+// Certificate Transparency constants from RFC 6962, section 3.4.
+const (
+	ctVersionV1           = 0
+	ctLeafTypeTimestamped = 0
+	ctEntryTypeX509       = 0
+	ctEntryTypePrecert    = 1
+
+	// Maximum lengths of the variable-length TLS vectors.
+	ctMaxCertLen       = 1<<24 - 1
+	ctMaxExtensionsLen = 1<<16 - 1
+
+	// Sizes of the fixed fields: the header with the version, leaf type,
+	// timestamp and entry type, and the length prefixes of the vectors.
+	ctHeaderLen         = 1 + 1 + 8 + 2
+	ctCertLenSize       = 3
+	ctExtensionsLenSize = 2
+)
+
+// CTX509Leaf builds the TLS encoded RFC 6962 MerkleTreeLeaf for an X.509
+// certificate log entry. timestamp is in milliseconds since the Unix epoch
+// and cert is the DER encoded certificate.
+// The result can be passed to NewTree as a leaf value. RFC 6962 trees
+// use SHA-256 with WithDomainPrefixes([]byte{0}, []byte{1}); without
+// the prefixes the root won't match the log's signed tree head.
+func CTX509Leaf(timestamp uint64, cert, extensions []byte) ([]byte, error) {
+	if len(cert) == 0 || len(cert) > ctMaxCertLen {
+		return nil, fmt.Errorf("%w: certificate is %d bytes", ErrInvalidCTField, len(cert))
+	}
+	if len(extensions) > ctMaxExtensionsLen {
+		return nil, fmt.Errorf("%w: extensions are %d bytes", ErrInvalidCTField, len(extensions))
+	}
+
+	leaf := make([]byte, 0, ctHeaderLen+ctCertLenSize+len(cert)+ctExtensionsLenSize+len(extensions))
+	leaf = appendCTHeader(leaf, timestamp, ctEntryTypeX509)
+	leaf = appendUint24Vector(leaf, cert)
+	leaf = appendUint16Vector(leaf, extensions)
+	return leaf, nil
+}
+
+// CTPrecertLeaf builds the TLS encoded RFC 6962 MerkleTreeLeaf for a
+// precertificate log entry. issuerKeyHash is the SHA-256 hash of the
+// issuer's public key and tbsCertificate is the DER encoded TBSCertificate
+// with the poison extension removed. As with CTX509Leaf, build the tree
+// with WithDomainPrefixes([]byte{0}, []byte{1}).
+func CTPrecertLeaf(timestamp uint64, issuerKeyHash [32]byte, tbsCertificate, extensions []byte) ([]byte, error) {
+	if len(tbsCertificate) == 0 || len(tbsCertificate) > ctMaxCertLen {
+		return nil, fmt.Errorf("%w: TBSCertificate is %d bytes", ErrInvalidCTField, len(tbsCertificate))
+	}
+	if len(extensions) > ctMaxExtensionsLen {
+		return nil, fmt.Errorf("%w: extensions are %d bytes", ErrInvalidCTField, len(extensions))
+	}
+
+	leaf := make([]byte, 0, ctHeaderLen+len(issuerKeyHash)+ctCertLenSize+len(tbsCertificate)+ctExtensionsLenSize+len(extensions))
+	leaf = appendCTHeader(leaf, timestamp, ctEntryTypePrecert)
+	leaf = append(leaf, issuerKeyHash[:]...)
+	leaf = appendUint24Vector(leaf, tbsCertificate)
+	leaf = appendUint16Vector(leaf, extensions)
+	return leaf, nil
+}
+
+// appendCTHeader appends the fields shared by all leaf types:
+// version, leaf type, timestamp and entry type.
+func appendCTHeader(buf []byte, timestamp uint64, entryType uint16) []byte {
+	buf = append(buf, ctVersionV1, ctLeafTypeTimestamped)
+	buf = binary.BigEndian.AppendUint64(buf, timestamp)
+	return binary.BigEndian.AppendUint16(buf, entryType)
+}
+
+// appendUint24Vector appends data prefixed with its length as a 24-bit integer.
+func appendUint24Vector(buf, data []byte) []byte {
+	n := len(data)
+	buf = append(buf, byte(n>>16), byte(n>>8), byte(n))
+	return append(buf, data...)
+}
+
+// appendUint16Vector appends data prefixed with its length as a 16-bit integer.
+func appendUint16Vector(buf, data []byte) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(data)))
+	return append(buf, data...)
+}
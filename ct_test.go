@@ -0,0 +1,131 @@
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCTX509Leaf(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		timestamp  uint64
+		cert       []byte
+		extensions []byte
+		exp        string
+		err        error
+	}{
+		{
+			name:      "Certificate without extensions",
+			timestamp: 0x0102030405060708,
+			cert:      []byte{0xaa, 0xbb, 0xcc},
+			exp: "0000" + // version, leaf type
+				"0102030405060708" + // timestamp
+				"0000" + // x509_entry
+				"000003aabbcc" + // certificate
+				"0000", // extensions
+		},
+		{
+			name:       "Certificate with extensions",
+			timestamp:  1,
+			cert:       []byte{0x01},
+			extensions: []byte{0xff, 0xee},
+			exp:        "0000" + "0000000000000001" + "0000" + "00000101" + "0002ffee",
+		},
+		{
+			name: "Empty certificate",
+			cert: []byte{},
+			err:  ErrInvalidCTField,
+		},
+		{
+			name:       "Extensions too large",
+			cert:       []byte{0x01},
+			extensions: bytes.Repeat([]byte{0x01}, 1<<16),
+			err:        ErrInvalidCTField,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			leaf, err := CTX509Leaf(tc.timestamp, tc.cert, tc.extensions)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.exp, hex.EncodeToString(leaf))
+			assert.Equal(t, len(leaf), cap(leaf), "Leaf should be allocated at its exact size")
+		})
+	}
+}
+
+func TestCTPrecertLeaf(t *testing.T) {
+	t.Parallel()
+
+	var issuerKeyHash [32]byte
+	for i := range issuerKeyHash {
+		issuerKeyHash[i] = byte(i)
+	}
+
+	leaf, err := CTPrecertLeaf(2, issuerKeyHash, []byte{0x30, 0x00}, nil)
+	require.NoError(t, err)
+
+	exp := "0000" + "0000000000000002" + "0001" +
+		hex.EncodeToString(issuerKeyHash[:]) +
+		"0000023000" + "0000"
+	assert.Equal(t, exp, hex.EncodeToString(leaf))
+	assert.Equal(t, len(leaf), cap(leaf), "Leaf should be allocated at its exact size")
+
+	_, err = CTPrecertLeaf(2, issuerKeyHash, nil, nil)
+	require.ErrorIs(t, err, ErrInvalidCTField)
+}
+
+func TestCTTreeHashes(t *testing.T) {
+	t.Parallel()
+
+	// Test vectors from the RFC 6962 reference implementation's
+	// merkle_tree_test.cc. The first n leaves hash to roots[n-1].
+	leaves := []string{
+		"", "00", "10", "2021", "3031", "40414243",
+		"5051525354555657", "606162636465666768696a6b6c6d6e6f",
+	}
+	roots := []string{
+		"6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d",
+		"fac54203e7cc696cf0dfcb42c92a1d9dbaf70ad9e621f4bd8d98662f00e3c125",
+		"aeb6bcfe274b70a14fb067a5e5578264db0fa9b51af5e0ba159158f329e06e77",
+		"d37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7",
+		"4e3bbb1f7b478dcfe71fb631631519a3bca12c9aefca1612bfce4c13a86264d4",
+		"76e67dadbcdf1e10e1b74ddc608abd2f98dfb16fbce75277b5232a127f2087ef",
+		"ddb89be403809e325750d3d263cd78929c2942b7942a34b77e122c9594a74c8c",
+		"5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328",
+	}
+
+	values := make([][]byte, len(leaves))
+	for i, leaf := range leaves {
+		var err error
+		values[i], err = hex.DecodeString(leaf)
+		require.NoError(t, err)
+	}
+
+	for n := 1; n <= len(values); n++ {
+		tree, err := NewTree(values[:n], sha256.New, WithDomainPrefixes([]byte{0}, []byte{1}))
+		require.NoError(t, err)
+		assert.Equal(t, roots[n-1], hex.EncodeToString(tree.Root.Hash), "Root mismatch for %d leaves", n)
+	}
+
+	// A CT leaf is hashed as SHA-256(0x00 || MerkleTreeLeaf).
+	leaf, err := CTX509Leaf(0x0102030405060708, []byte{0xaa, 0xbb, 0xcc}, nil)
+	require.NoError(t, err)
+	tree, err := NewTree([][]byte{leaf}, sha256.New, WithDomainPrefixes([]byte{0}, []byte{1}))
+	require.NoError(t, err)
+	assert.Equal(t, "7ed1ae324fe7ab5dd37a209c999905491581d9be1cf9779e821baf994dcb394c",
+		hex.EncodeToString(tree.Root.Hash))
+}
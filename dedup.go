@@ -0,0 +1,40 @@
+package merkle
+
+import "bytes"
+
+// dedupe makes nodes with equal hashes share one hash, and leaves with
+// equal values share one value, so repeated leaves and the identical
+// subtrees above them only store their hashes once. Nodes aren't shared,
+// since every node links to its own parent.
+func (t *Tree) dedupe() {
+	hashes := make(map[string][]byte)
+	// values maps leaf hashes to leaf values, so large values
+	// aren't copied into keys.
+	values := make(map[string][]byte)
+
+	stack := []*Node{t.Root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if node == nil {
+			continue
+		}
+
+		if hash, ok := hashes[string(node.Hash)]; ok {
+			node.Hash = hash
+		} else {
+			hashes[string(node.Hash)] = node.Hash
+		}
+
+		if node.Left == nil && node.Right == nil && node.Value != nil {
+			value, ok := values[string(node.Hash)]
+			switch {
+			case !ok:
+				values[string(node.Hash)] = node.Value
+			case bytes.Equal(value, node.Value):
+				node.Value = value
+			}
+		}
+		stack = append(stack, node.Left, node.Right)
+	}
+}
@@ -0,0 +1,72 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithDeduplication(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		values [][]byte
+		// same holds pairs of leaves that share their hashes and values.
+		same [][2]int
+	}{
+		{
+			name:   "Repeated leaves",
+			values: [][]byte{[]byte("a"), []byte("b"), []byte("a"), []byte("c"), []byte("a")},
+			same:   [][2]int{{0, 2}, {0, 4}},
+		},
+		{
+			name: "Repeated subtrees",
+			values: [][]byte{
+				[]byte("a"), []byte("b"), []byte("c"), []byte("d"),
+				[]byte("a"), []byte("b"), []byte("c"), []byte("d"),
+			},
+			same: [][2]int{{0, 4}, {3, 7}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Copy the values, so equal values don't share memory up front.
+			values := make([][]byte, len(tc.values))
+			for i, value := range tc.values {
+				values[i] = append([]byte{}, value...)
+			}
+			tree, err := NewTree(values, sha256.New, WithDeduplication())
+			require.NoError(t, err)
+			expTree, err := NewTree(tc.values, sha256.New)
+			require.NoError(t, err)
+			assert.Equal(t, expTree.RootHash(), tree.RootHash())
+			require.NoError(t, tree.Validate())
+
+			for _, pair := range tc.same {
+				a, b := tree.Leaves[pair[0]], tree.Leaves[pair[1]]
+				assert.Same(t, &a.Hash[0], &b.Hash[0])
+				assert.Same(t, &a.Value[0], &b.Value[0])
+				assert.NotSame(t, a, b)
+			}
+
+			// Updating a leaf doesn't change the leaves it shared its hash with.
+			pair := tc.same[0]
+			require.NoError(t, tree.UpdateLeaf(pair[0], []byte("new")))
+			assert.Equal(t, expTree.Leaves[pair[1]].Hash, tree.Leaves[pair[1]].Hash)
+			assert.Equal(t, tc.values[pair[1]], tree.Leaves[pair[1]].Value)
+			require.NoError(t, tree.Validate())
+		})
+	}
+
+	// The roots of identical subtrees share their hash.
+	values := generateDummyData(4)
+	tree, err := NewTree(append(values, values...), sha256.New, WithDeduplication())
+	require.NoError(t, err)
+	assert.Same(t, &tree.Root.Left.Hash[0], &tree.Root.Right.Hash[0])
+}
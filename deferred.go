@@ -0,0 +1,62 @@
+package merkle
+
+// Commit rehashes the nodes above the leaves that have been updated
+// since the tree was last read. It is only needed for trees created
+// with WithDeferredHashing, and does nothing if no node is dirty.
+func (t *Tree) Commit() {
+	t.flush()
+}
+
+// flush rehashes the dirty nodes before the tree is read or restructured
+// and records the new root of the deferred updates.
+func (t *Tree) flush() {
+	if len(t.dirty) == 0 {
+		return
+	}
+	t.hashDirty()
+	t.rootChanged(MutationUpdate)
+}
+
+// markDirty marks the nodes above the leaf at index as dirty.
+func (t *Tree) markDirty(index int) {
+	if t.dirtySeen == nil {
+		t.dirtySeen = make(map[*Node]bool)
+	}
+	levels := t.pathLevels(index)
+	for i, parent := 0, t.Leaves[index].Parent; parent != nil && !t.dirtySeen[parent]; i, parent = i+1, parent.Parent {
+		t.dirtySeen[parent] = true
+		level := levels[i]
+		for len(t.dirty) <= level {
+			t.dirty = append(t.dirty, nil)
+		}
+		t.dirty[level] = append(t.dirty[level], parent)
+	}
+}
+
+// parallelRehashThreshold is the number of nodes on a level, or leaves in
+// a batch, from which they are hashed in parallel. The nodes on a level
+// don't depend on each other, but hashing a few of them isn't worth
+// starting goroutines.
+const parallelRehashThreshold = 1 << 10
+
+// hashDirty rehashes the dirty nodes by level, so children are
+// always hashed before their parents, and every node only once.
+// Levels with many dirty nodes are hashed in parallel.
+func (t *Tree) hashDirty() {
+	for level, nodes := range t.dirty {
+		if len(nodes) < parallelRehashThreshold {
+			for _, node := range nodes {
+				t.rehashNode(node, level)
+			}
+			continue
+		}
+		t.cfg.parallelism.batches(len(nodes), func(start, end int) {
+			hashFunc := t.cfg.hasher.NewNodeHasher()
+			for _, node := range nodes[start:end] {
+				node.Hash = combineLevelHashes(level, node.Left.Hash, node.Right.Hash, hashFunc, &t.cfg)
+			}
+		})
+	}
+	t.dirty = nil
+	t.dirtySeen = nil
+}
@@ -0,0 +1,127 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithDeferredHashing(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		mutate    func(tree *Tree) error
+		read      func(tree *Tree) error
+		expValues [][]byte
+	}{
+		{
+			name: "Commit",
+			mutate: func(tree *Tree) error {
+				if err := tree.UpdateLeaf(0, []byte("x")); err != nil {
+					return err
+				}
+				return tree.UpdateLeaves(map[int][]byte{1: []byte("y"), 4: []byte("z")})
+			},
+			read: func(tree *Tree) error {
+				tree.Commit()
+				return nil
+			},
+			expValues: [][]byte{[]byte("x"), []byte("y"), []byte("2"), []byte("3"), []byte("z")},
+		},
+		{
+			name: "Generate proof",
+			mutate: func(tree *Tree) error {
+				return tree.UpdateLeaf(2, []byte("x"))
+			},
+			read: func(tree *Tree) error {
+				_, err := tree.GenerateProofByIndex(0)
+				return err
+			},
+			expValues: [][]byte{[]byte("0"), []byte("1"), []byte("x"), []byte("3"), []byte("4")},
+		},
+		{
+			name: "Append after updates",
+			mutate: func(tree *Tree) error {
+				return tree.UpdateLeaf(4, []byte("x"))
+			},
+			read: func(tree *Tree) error {
+				return tree.AppendLeaf([]byte("y"))
+			},
+			expValues: [][]byte{[]byte("0"), []byte("1"), []byte("2"), []byte("3"), []byte("x"), []byte("y")},
+		},
+		{
+			name: "Remove after updates",
+			mutate: func(tree *Tree) error {
+				return tree.UpdateLeaf(1, []byte("x"))
+			},
+			read: func(tree *Tree) error {
+				return tree.RemoveLeaf(0)
+			},
+			expValues: [][]byte{[]byte("x"), []byte("2"), []byte("3"), []byte("4")},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			values := make([][]byte, 5)
+			for i := range values {
+				values[i] = []byte{byte('0' + i)}
+			}
+			tree, err := NewTree(values, sha256.New, WithDeferredHashing())
+			require.NoError(t, err)
+			root := tree.Root.Hash
+
+			require.NoError(t, tc.mutate(tree))
+			// The nodes are not rehashed until the tree is read.
+			assert.Equal(t, root, tree.Root.Hash)
+
+			require.NoError(t, tc.read(tree))
+			expTree, err := NewTree(tc.expValues, sha256.New)
+			require.NoError(t, err)
+			assert.Equal(t, expTree.Root.Hash, tree.Root.Hash)
+			require.NoError(t, tree.Validate())
+
+			for i, value := range tc.expValues {
+				proof, err := tree.GenerateProofByIndex(i)
+				require.NoError(t, err)
+				isValid, err := tree.VerifyProof(proof, value)
+				require.NoError(t, err)
+				assert.True(t, isValid)
+			}
+		})
+	}
+}
+
+func TestWithDeferredHashingCoalesces(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+	combine := func(left, right []byte) []byte {
+		calls++
+		return combineHashes(left, right, sha256.New())
+	}
+	tree, err := NewTree(generateDummyData(8), sha256.New, WithCombine(combine), WithDeferredHashing(), WithRootHistory())
+	require.NoError(t, err)
+
+	calls = 0
+	for i := range 4 {
+		require.NoError(t, tree.UpdateLeaf(i, []byte("x")))
+	}
+	assert.Zero(t, calls)
+
+	// The nodes above the first four leaves are hashed once.
+	tree.RootHash()
+	assert.Equal(t, 4, calls)
+	tree.Commit()
+	assert.Equal(t, 4, calls)
+
+	history := tree.RootHistory()
+	require.Len(t, history, 2)
+	assert.Equal(t, MutationUpdate, history[1].Cause)
+	assert.Equal(t, tree.RootHash(), history[1].Root)
+}
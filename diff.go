@@ -0,0 +1,45 @@
+package merkle
+
+import (
+	"bytes"
+	"math/bits"
+)
+
+// Diff returns the indices of the leaves that differ between t and other,
+// in increasing order. Both trees are descended together and subtrees with
+// the same hash are skipped, so only the paths to the differing leaves are
+// visited. Leaves that only one of the trees has are different, as are
+// leaves below pruned subtrees with different hashes.
+// Both trees must use the same hash function and options.
+func (t *Tree) Diff(other *Tree) []int {
+	t.flush()
+	other.flush()
+	common := min(len(t.Leaves), len(other.Leaves))
+
+	var diff []int
+	if common > 0 {
+		diff = diffRange(t, other, 0, common, diff)
+	}
+	for i := common; i < max(len(t.Leaves), len(other.Leaves)); i++ {
+		diff = append(diff, i)
+	}
+	return diff
+}
+
+// diffRange appends the indices of the leaves in [start, end)
+// that differ between a and b to diff.
+func diffRange(a, b *Tree, start, end int, diff []int) []int {
+	// Any node over the range has the same hash for the same leaves.
+	nodeA, nodeB := a.findNode(start, end), b.findNode(start, end)
+	if nodeA != nil && nodeB != nil && bytes.Equal(nodeA.Hash, nodeB.Hash) {
+		return diff
+	}
+
+	n := end - start
+	if n == 1 {
+		return append(diff, start)
+	}
+	k := 1 << (bits.Len(uint(n-1)) - 1)
+	diff = diffRange(a, b, start, start+k, diff)
+	return diffRange(a, b, start+k, end, diff)
+}
@@ -0,0 +1,95 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		size    int
+		change  func(tree *Tree) error
+		expDiff []int
+	}{
+		{
+			name:   "Equal trees",
+			size:   7,
+			change: func(*Tree) error { return nil },
+		},
+		{
+			name: "Updated leaves",
+			size: 13,
+			change: func(tree *Tree) error {
+				return tree.UpdateLeaves(map[int][]byte{2: []byte("a"), 9: []byte("b"), 12: []byte("c")})
+			},
+			expDiff: []int{2, 9, 12},
+		},
+		{
+			name: "Appended leaves",
+			size: 5,
+			change: func(tree *Tree) error {
+				return tree.AppendLeaves([][]byte{[]byte("a"), []byte("b")})
+			},
+			expDiff: []int{5, 6},
+		},
+		{
+			name: "Removed leaf",
+			size: 6,
+			change: func(tree *Tree) error {
+				return tree.RemoveLeaf(3)
+			},
+			expDiff: []int{3, 4, 5},
+		},
+		{
+			name: "Updated and appended leaves",
+			size: 8,
+			change: func(tree *Tree) error {
+				if err := tree.UpdateLeaf(7, []byte("a")); err != nil {
+					return err
+				}
+				return tree.AppendLeaf([]byte("b"))
+			},
+			expDiff: []int{7, 8},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := NewTree(generateDummyData(tc.size), sha256.New)
+			require.NoError(t, err)
+			other, err := NewTree(generateDummyData(tc.size), sha256.New)
+			require.NoError(t, err)
+			require.NoError(t, tc.change(other))
+
+			assert.Equal(t, tc.expDiff, tree.Diff(other))
+			assert.Equal(t, tc.expDiff, other.Diff(tree))
+		})
+	}
+}
+
+func TestDiffPruned(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewTree(generateDummyData(8), sha256.New)
+	require.NoError(t, err)
+	require.NoError(t, tree.Prune([]int{1}))
+
+	other, err := NewTree(generateDummyData(8), sha256.New)
+	require.NoError(t, err)
+	assert.Empty(t, tree.Diff(other))
+
+	// The changed leaf is below a pruned subtree, so all its leaves differ.
+	require.NoError(t, other.UpdateLeaf(5, []byte("a")))
+	assert.Equal(t, []int{4, 5, 6, 7}, tree.Diff(other))
+
+	require.NoError(t, other.UpdateLeaf(1, []byte("b")))
+	assert.Equal(t, []int{1, 4, 5, 6, 7}, tree.Diff(other))
+}
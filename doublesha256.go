@@ -0,0 +1,107 @@
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"hash"
+	"math/bits"
+)
+
+// doubleSHA256 computes SHA-256 of the SHA-256 digest of the input.
+type doubleSHA256 struct {
+	hash.Hash
+}
+
+// NewDoubleSHA256 returns a new hash computing SHA-256(SHA-256(data)),
+// as used for leaves and nodes of Bitcoin Merkle trees.
+//
+// Bitcoin duplicates the last node on levels with an odd number of nodes,
+// while Tree carries it up, so trees only match Bitcoin when no level has
+// an odd number of nodes. Use BitcoinMerkleRoot for Bitcoin blocks.
+func NewDoubleSHA256() hash.Hash {
+	return doubleSHA256{Hash: sha256.New()}
+}
+
+func (h doubleSHA256) Sum(b []byte) []byte {
+	digest := sha256.Sum256(h.Hash.Sum(nil))
+	return append(b, digest[:]...)
+}
+
+// BitcoinMerkleRoot returns the Merkle root of a Bitcoin block with the
+// given txids, in internal byte order. The last node on levels with an
+// odd number of nodes is hashed with itself.
+//
+// Duplicating the last node means a block with an odd number of nodes on
+// a level has the same root as the block with those nodes repeated,
+// so callers have to reject blocks with duplicate txids.
+func BitcoinMerkleRoot(txids [][]byte) ([]byte, error) {
+	if len(txids) == 0 {
+		return nil, ErrNoLeaves
+	}
+	levels := bitcoinLevels(txids)
+	return bytes.Clone(levels[len(levels)-1][0]), nil
+}
+
+// BitcoinMerkleProof returns the proof for the txid at index in a Bitcoin
+// block with the given txids. A node without a sibling is its own
+// sibling, so the proof has a hash for every level.
+func BitcoinMerkleProof(txids [][]byte, index int) (*Proof, error) {
+	if index < 0 || index >= len(txids) {
+		return nil, indexOutOfBounds(index, len(txids))
+	}
+
+	levels := bitcoinLevels(txids)
+	proof := &Proof{Index: index}
+	for _, level := range levels[:len(levels)-1] {
+		sibling := index ^ 1
+		if sibling == len(level) {
+			sibling = index
+		}
+		proof.Hashes = append(proof.Hashes, bytes.Clone(level[sibling]))
+		index /= 2
+	}
+	return proof, nil
+}
+
+// VerifyBitcoinMerkleProof verifies that txid is at proof.Index
+// of a Bitcoin block with numTxs transactions and the given root.
+func VerifyBitcoinMerkleProof(root, txid []byte, numTxs int, proof *Proof) (bool, error) {
+	if proof.Index < 0 || proof.Index >= numTxs || len(proof.Hashes) != bits.Len(uint(numTxs-1)) {
+		return false, &ProofSizeError{Index: proof.Index, NumHashes: len(proof.Hashes), Size: numTxs}
+	}
+
+	// Every level has a sibling, so the index bits pick the sides.
+	hashFunc := NewDoubleSHA256()
+	currentHash := txid
+	for i, siblingHash := range proof.Hashes {
+		if proof.Index>>i&1 == 0 {
+			currentHash = combineHashes(currentHash, siblingHash, hashFunc)
+		} else {
+			currentHash = combineHashes(siblingHash, currentHash, hashFunc)
+		}
+	}
+
+	if !bytes.Equal(currentHash, root) {
+		return false, &RootMismatchError{Expected: root, Actual: currentHash}
+	}
+	return true, nil
+}
+
+// bitcoinLevels returns the levels of the Bitcoin Merkle tree
+// over txids, from the txids up to the root.
+func bitcoinLevels(txids [][]byte) [][][]byte {
+	hashFunc := NewDoubleSHA256()
+	levels := [][][]byte{txids}
+	for level := txids; len(level) > 1; level = levels[len(levels)-1] {
+		parents := make([][]byte, (len(level)+1)/2)
+		for i := range parents {
+			left, right := level[2*i], level[2*i]
+			if 2*i+1 < len(level) {
+				right = level[2*i+1]
+			}
+			parents[i] = combineHashes(left, right, hashFunc)
+		}
+		levels = append(levels, parents)
+	}
+	return levels
+}
@@ -0,0 +1,191 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoubleSHA256(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewTree([][]byte{[]byte("abc")}, NewDoubleSHA256)
+	require.NoError(t, err)
+	assert.Equal(t, "4f8b42c22dd3729b519ba6f68d2da7cc5b2d606d05daed5ad5128cc03e6c6358",
+		hex.EncodeToString(tree.Root.Hash))
+}
+
+func TestDoubleSHA256Nodes(t *testing.T) {
+	t.Parallel()
+
+	doubleSum := func(data ...[]byte) []byte {
+		h := sha256.New()
+		for _, b := range data {
+			h.Write(b)
+		}
+		digest := sha256.Sum256(h.Sum(nil))
+		return digest[:]
+	}
+
+	tree, err := NewTree([][]byte{[]byte("a"), []byte("b")}, NewDoubleSHA256)
+	require.NoError(t, err)
+
+	left, right := doubleSum([]byte("a")), doubleSum([]byte("b"))
+	assert.Equal(t, left, tree.Leaves[0].Hash)
+	assert.Equal(t, right, tree.Leaves[1].Hash)
+	assert.Equal(t, doubleSum(left, right), tree.Root.Hash)
+}
+
+// bitcoinTxids decodes txids displayed in reverse byte order.
+func bitcoinTxids(t *testing.T, txids ...string) [][]byte {
+	t.Helper()
+
+	hashes := make([][]byte, len(txids))
+	for i, txid := range txids {
+		hash, err := hex.DecodeString(txid)
+		require.NoError(t, err)
+		slices.Reverse(hash)
+		hashes[i] = hash
+	}
+	return hashes
+}
+
+// syntheticTxids returns the double SHA-256 of "tx0", "tx1", and so on.
+func syntheticTxids(n int) [][]byte {
+	txids := make([][]byte, n)
+	for i := range txids {
+		h := NewDoubleSHA256()
+		fmt.Fprintf(h, "tx%d", i)
+		txids[i] = h.Sum(nil)
+	}
+	return txids
+}
+
+func TestBitcoinMerkleRoot(t *testing.T) {
+	t.Parallel()
+
+	block100000 := bitcoinTxids(t,
+		"8c14f0db3df150123e6f3dbbf30f8b955a8249b62ac1d1ff16284aefa3d06d87",
+		"fff2525b8931402dd09222c50775608f75787bd2b87e56995a7bdd30f79702c4",
+		"6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4",
+		"e9a66845e05d5abc0ad04ec80f774a7e585c6e8db975962d069a522137b80c1d",
+	)
+	decodeHex := func(s string) []byte {
+		b, err := hex.DecodeString(s)
+		require.NoError(t, err)
+		return b
+	}
+
+	tests := []struct {
+		name    string
+		txids   [][]byte
+		expRoot []byte
+	}{
+		{
+			// The genesis block only holds its coinbase transaction.
+			name:    "Genesis block",
+			txids:   bitcoinTxids(t, "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b"),
+			expRoot: bitcoinTxids(t, "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b")[0],
+		},
+		{
+			name:    "Block 100000",
+			txids:   block100000,
+			expRoot: bitcoinTxids(t, "f3e94742aca4b5ef85488dc37c06c3282295ffec960994b2c0d5ac2a25a95766")[0],
+		},
+		{
+			// The roots of blocks with odd levels are computed
+			// like ComputeMerkleRoot in Bitcoin Core.
+			name:    "3 transactions",
+			txids:   syntheticTxids(3),
+			expRoot: decodeHex("e723898188fe36f157a1a97e4a608823a4c64cc81ac658dba49c4b66eef59a1f"),
+		},
+		{
+			name:    "5 transactions",
+			txids:   syntheticTxids(5),
+			expRoot: decodeHex("dbbb0e2d40a03cef04f27cf8109829d6020eddec6f99f2b37c05fa560fe8a7ee"),
+		},
+		{
+			name:    "7 transactions",
+			txids:   syntheticTxids(7),
+			expRoot: decodeHex("6aef8908e00c9290392b2787452001183ca10d3556e865647071aa46075d9ccb"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			root, err := BitcoinMerkleRoot(tc.txids)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expRoot, root)
+
+			for i, txid := range tc.txids {
+				proof, err := BitcoinMerkleProof(tc.txids, i)
+				require.NoError(t, err)
+
+				isValid, err := VerifyBitcoinMerkleProof(root, txid, len(tc.txids), proof)
+				require.NoError(t, err)
+				assert.True(t, isValid, "Proof for transaction %d should be valid", i)
+			}
+		})
+	}
+
+	// Trees carry odd nodes up, so they only match
+	// Bitcoin when no level has an odd number of nodes.
+	tree, err := NewTreeFromHashes(block100000, NewDoubleSHA256)
+	require.NoError(t, err)
+	assert.Equal(t, tests[1].expRoot, tree.Root.Hash)
+
+	_, err = BitcoinMerkleRoot(nil)
+	require.ErrorIs(t, err, ErrNoLeaves)
+}
+
+func TestBitcoinMerkleRootDuplicatesOddNodes(t *testing.T) {
+	t.Parallel()
+
+	txids := syntheticTxids(3)
+	root, err := BitcoinMerkleRoot(txids)
+	require.NoError(t, err)
+
+	h := NewDoubleSHA256()
+	left := combineHashes(txids[0], txids[1], h)
+	right := combineHashes(txids[2], txids[2], h)
+	assert.Equal(t, combineHashes(left, right, h), root)
+
+	// Repeating the last transaction keeps the root.
+	mutated, err := BitcoinMerkleRoot(append(slices.Clone(txids), txids[2]))
+	require.NoError(t, err)
+	assert.Equal(t, root, mutated)
+
+	tree, err := NewTreeFromHashes(txids, NewDoubleSHA256)
+	require.NoError(t, err)
+	assert.NotEqual(t, root, tree.Root.Hash)
+}
+
+func TestBitcoinMerkleProof(t *testing.T) {
+	t.Parallel()
+
+	txids := syntheticTxids(5)
+	root, err := BitcoinMerkleRoot(txids)
+	require.NoError(t, err)
+
+	// The last transaction is its own sibling on the first level.
+	proof, err := BitcoinMerkleProof(txids, 4)
+	require.NoError(t, err)
+	require.Len(t, proof.Hashes, 3)
+	assert.Equal(t, txids[4], proof.Hashes[0])
+
+	_, err = VerifyBitcoinMerkleProof(root, txids[3], len(txids), proof)
+	require.ErrorIs(t, err, ErrProofVerificationFailed)
+
+	_, err = VerifyBitcoinMerkleProof(root, txids[4], len(txids)+4, proof)
+	require.ErrorIs(t, err, ErrProofVerificationFailed)
+
+	_, err = BitcoinMerkleProof(txids, 5)
+	require.ErrorIs(t, err, ErrIndexOutOfBounds)
+}
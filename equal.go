@@ -0,0 +1,41 @@
+package merkle
+
+import (
+	"bytes"
+	"crypto/subtle"
+)
+
+// Equal reports whether t and other have the same shape and the same
+// hash in every node. Leaf values are not compared.
+func (t *Tree) Equal(other *Tree) bool {
+	if t == nil || other == nil {
+		return t == other
+	}
+	t.flush()
+	other.flush()
+	if len(t.Leaves) != len(other.Leaves) {
+		return false
+	}
+	return equalNodes(t.Root, other.Root)
+}
+
+// equalNodes reports whether the subtrees below a and b are equal.
+func equalNodes(a, b *Node) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return bytes.Equal(a.Hash, b.Hash) &&
+		equalNodes(a.Left, b.Left) &&
+		equalNodes(a.Right, b.Right)
+}
+
+// RootEqual reports whether the root hash of the tree is root.
+// The comparison takes constant time, so it doesn't leak how much
+// of a guessed root matches.
+func (t *Tree) RootEqual(root []byte) bool {
+	t.flush()
+	if t.Root == nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(t.Root.Hash, root) == 1
+}
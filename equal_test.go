@@ -0,0 +1,105 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEqual(t *testing.T) {
+	t.Parallel()
+
+	newTree := func(t *testing.T, values [][]byte, opts ...Option) *Tree {
+		t.Helper()
+		tree, err := NewTree(values, sha256.New, opts...)
+		require.NoError(t, err)
+		return tree
+	}
+
+	data := generateDummyData(7)
+
+	tests := []struct {
+		name  string
+		a     func(t *testing.T) *Tree
+		b     func(t *testing.T) *Tree
+		equal bool
+	}{
+		{
+			name:  "Same values",
+			a:     func(t *testing.T) *Tree { return newTree(t, data) },
+			b:     func(t *testing.T) *Tree { return newTree(t, data) },
+			equal: true,
+		},
+		{
+			name: "Built from hashes",
+			a:    func(t *testing.T) *Tree { return newTree(t, data) },
+			b: func(t *testing.T) *Tree {
+				tree, err := NewTreeFromHashes(preHashLeaves(data, sha256.New), sha256.New)
+				require.NoError(t, err)
+				return tree
+			},
+			equal: true,
+		},
+		{
+			name:  "Different leaf",
+			a:     func(t *testing.T) *Tree { return newTree(t, data) },
+			b:     func(t *testing.T) *Tree { return newTree(t, generateDummyData(8)[1:]) },
+			equal: false,
+		},
+		{
+			name:  "Different size",
+			a:     func(t *testing.T) *Tree { return newTree(t, data) },
+			b:     func(t *testing.T) *Tree { return newTree(t, data[:6]) },
+			equal: false,
+		},
+		{
+			name:  "Different options",
+			a:     func(t *testing.T) *Tree { return newTree(t, data) },
+			b:     func(t *testing.T) *Tree { return newTree(t, data, WithLevelTags(LevelIndexTag)) },
+			equal: false,
+		},
+		{
+			name: "Pruned tree",
+			a:    func(t *testing.T) *Tree { return newTree(t, data) },
+			b: func(t *testing.T) *Tree {
+				tree := newTree(t, data)
+				require.NoError(t, tree.Prune([]int{0}))
+				return tree
+			},
+			equal: false,
+		},
+		{
+			name:  "Nil tree",
+			a:     func(t *testing.T) *Tree { return newTree(t, data) },
+			b:     func(t *testing.T) *Tree { return nil },
+			equal: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			a, b := tc.a(t), tc.b(t)
+			assert.Equal(t, tc.equal, a.Equal(b))
+			assert.Equal(t, tc.equal, b.Equal(a))
+		})
+	}
+}
+
+func TestRootEqual(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewTree(generateDummyData(5), sha256.New)
+	require.NoError(t, err)
+	other, err := NewTree(generateDummyData(5), sha512.New)
+	require.NoError(t, err)
+
+	assert.True(t, tree.RootEqual(tree.Root.Hash))
+	assert.False(t, tree.RootEqual(other.Root.Hash))
+	assert.False(t, tree.RootEqual(tree.Root.Hash[:16]))
+	assert.False(t, tree.RootEqual(nil))
+}
@@ -0,0 +1,56 @@
+package merkle
+
+import "fmt"
+
+// IndexError reports a leaf index that is out of bounds or has been pruned.
+// It matches ErrIndexOutOfBounds or ErrLeafPruned with errors.Is.
+type IndexError struct {
+	Index int
+	Size  int
+	Err   error
+}
+
+func indexOutOfBounds(index, size int) *IndexError {
+	return &IndexError{Index: index, Size: size, Err: ErrIndexOutOfBounds}
+}
+
+func (e *IndexError) Error() string {
+	return fmt.Sprintf("%v: leaf %d in a tree with %d leaves", e.Err, e.Index, e.Size)
+}
+
+func (e *IndexError) Unwrap() error {
+	return e.Err
+}
+
+// ProofSizeError reports a proof that doesn't fit the shape of a tree
+// with Size leaves. It matches ErrProofVerificationFailed with errors.Is.
+type ProofSizeError struct {
+	Index     int
+	NumHashes int
+	Size      int
+}
+
+func (e *ProofSizeError) Error() string {
+	return fmt.Sprintf("%v: proof with %d hashes for leaf %d does not match a tree with %d leaves",
+		ErrProofVerificationFailed, e.NumHashes, e.Index, e.Size)
+}
+
+func (e *ProofSizeError) Unwrap() error {
+	return ErrProofVerificationFailed
+}
+
+// RootMismatchError reports a proof that leads to another root than
+// the expected one. It matches ErrProofVerificationFailed with errors.Is.
+type RootMismatchError struct {
+	Expected []byte
+	Actual   []byte
+}
+
+func (e *RootMismatchError) Error() string {
+	return fmt.Sprintf("%v: expected root %x, but got %x",
+		ErrProofVerificationFailed, e.Expected, e.Actual)
+}
+
+func (e *RootMismatchError) Unwrap() error {
+	return ErrProofVerificationFailed
+}
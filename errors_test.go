@@ -0,0 +1,100 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		index    int
+		prune    bool
+		sentinel error
+	}{
+		{
+			name:     "Out of bounds",
+			index:    7,
+			sentinel: ErrIndexOutOfBounds,
+		},
+		{
+			name:     "Negative index",
+			index:    -1,
+			sentinel: ErrIndexOutOfBounds,
+		},
+		{
+			name:     "Pruned leaf",
+			index:    2,
+			prune:    true,
+			sentinel: ErrLeafPruned,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := NewTree(generateDummyData(5), sha256.New)
+			require.NoError(t, err)
+			if tc.prune {
+				require.NoError(t, tree.Prune([]int{0}))
+			}
+
+			_, err = tree.GenerateProofByIndex(tc.index)
+			require.ErrorIs(t, err, tc.sentinel)
+
+			var indexErr *IndexError
+			require.ErrorAs(t, err, &indexErr)
+			assert.Equal(t, tc.index, indexErr.Index)
+			assert.Equal(t, 5, indexErr.Size)
+		})
+	}
+}
+
+func TestProofErrors(t *testing.T) {
+	t.Parallel()
+
+	data := generateDummyData(5)
+	tree, err := NewTree(data, sha256.New)
+	require.NoError(t, err)
+
+	proof, err := tree.GenerateProofByIndex(1)
+	require.NoError(t, err)
+
+	t.Run("Root mismatch", func(t *testing.T) {
+		t.Parallel()
+
+		tree, err := NewTree(data, sha256.New)
+		require.NoError(t, err)
+
+		_, err = tree.VerifyProof(proof, []byte("tampered"))
+		require.ErrorIs(t, err, ErrProofVerificationFailed)
+
+		var rootErr *RootMismatchError
+		require.ErrorAs(t, err, &rootErr)
+		assert.Equal(t, tree.Root.Hash, rootErr.Expected)
+		assert.NotEqual(t, rootErr.Expected, rootErr.Actual)
+	})
+
+	t.Run("Proof size", func(t *testing.T) {
+		t.Parallel()
+
+		tree, err := NewTree(data, sha256.New)
+		require.NoError(t, err)
+
+		short := &Proof{Hashes: proof.Hashes[:1], Index: proof.Index}
+		_, err = tree.VerifyProof(short, data[1])
+		require.ErrorIs(t, err, ErrProofVerificationFailed)
+
+		var sizeErr *ProofSizeError
+		require.ErrorAs(t, err, &sizeErr)
+		assert.Equal(t, ProofSizeError{Index: 1, NumHashes: 1, Size: 5}, *sizeErr)
+		assert.False(t, errors.As(err, new(*RootMismatchError)))
+	})
+}
@@ -0,0 +1,339 @@
+package merkle
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+var (
+	ErrHashSizeMismatch = errors.New("leaf and node hashes have different sizes")
+	ErrLeafHashesOnly   = errors.New("tree only stores its leaf hashes")
+)
+
+// FlatTree is a Merkle tree that stores the hashes of all nodes in one
+// contiguous slice instead of linked Nodes, which saves an allocation
+// per node, keeps the hashes of a level next to each other in memory
+// and gives the garbage collector no pointers to scan.
+// The levels are stored one after another from the leaves up, rather than
+// in heap order, since trees with a size that isn't a power of two would
+// leave gaps in a heap. A FlatTree has the same root and proofs as a Tree
+// built with the same options, but it doesn't retain the leaf values.
+type FlatTree struct {
+	// hashes holds the hashes of the stored levels, each size bytes long.
+	hashes []byte
+	size   int
+	// offsets[l] is the index of the first node on level l, and the last
+	// offset is the number of nodes.
+	offsets []int
+
+	// stored is the number of levels whose hashes are stored, from the
+	// leaves up. The levels above are recomputed when they are needed,
+	// and root holds the root hash if it isn't stored.
+	stored int
+	root   []byte
+
+	emptyRoot    []byte
+	hashFunc     hash.Hash
+	leafHashFunc hash.Hash
+	cfg          config
+}
+
+// NewFlatTree creates a new Merkle tree with a flat layout
+// from the given values and hash function.
+func NewFlatTree(values [][]byte, newHashFunc func() hash.Hash, opts ...Option) (*FlatTree, error) {
+	cfg := newConfig(opts, newHashFunc)
+	if len(values) == 0 && !cfg.allowEmpty {
+		return nil, ErrNoLeaves
+	}
+	values, err := cfg.leafValues(values)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &FlatTree{
+		hashFunc:     cfg.hasher.NewNodeHasher(),
+		leafHashFunc: cfg.hasher.NewLeafHasher(),
+		cfg:          cfg,
+	}
+	t.size = t.leafHashFunc.Size()
+	if len(values) == 0 {
+		t.emptyRoot = t.hashFunc.Sum(nil)
+		return t, nil
+	}
+
+	t.offsets = flatOffsets(len(values))
+	t.stored = t.levels()
+	if cfg.leafHashesOnly {
+		t.stored = 1
+	}
+	t.hashes = make([]byte, t.offsets[t.stored]*t.size)
+
+	// Hash the leaves and the levels above them in blocks of adjacent
+	// leaves, so the hashes of a block are still in the cache when
+	// its subtree is built, and then the levels above the blocks.
+	blockLevels := min(cacheBlockLevels, t.stored-1)
+	numBlocks := (len(values) + cacheBlock - 1) / cacheBlock
+	err = cfg.parallelism.batchesContext(context.Background(), numBlocks, func(ctx context.Context, start, end int) error {
+		leafHashFunc := cfg.hasher.NewLeafHasher()
+		hashFunc := cfg.hasher.NewNodeHasher()
+		for b := start; b < end; b++ {
+			for i := b * cacheBlock; i < min(len(values), (b+1)*cacheBlock); i++ {
+				leafHashFunc.Reset()
+				leafHashFunc.Write(values[i])
+				if err := t.setHash(0, i, leafHashFunc.Sum(t.node(0, i)[:0])); err != nil {
+					return err
+				}
+			}
+			for level := 1; level <= blockLevels; level++ {
+				size := cacheBlock >> level
+				for i := b * size; i < min(t.count(level), (b+1)*size); i++ {
+					if err := t.hashNode(level, i, hashFunc); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for level := blockLevels + 1; level < t.stored; level++ {
+		err := cfg.parallelism.batchesContext(context.Background(), t.count(level), func(ctx context.Context, start, end int) error {
+			hashFunc := cfg.hasher.NewNodeHasher()
+			for i := start; i < end; i++ {
+				if err := t.hashNode(level, i, hashFunc); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if t.stored < t.levels() {
+		t.root = t.computeRoot()
+	}
+	return t, nil
+}
+
+// flatOffsets returns the index of the first node on every level of a tree
+// with size leaves in the flat layout, followed by the number of nodes.
+func flatOffsets(size int) []int {
+	var offsets []int
+	total := 0
+	for count := size; ; count = (count + 1) / 2 {
+		offsets = append(offsets, total)
+		total += count
+		if count == 1 {
+			break
+		}
+	}
+	return append(offsets, total)
+}
+
+// WriteTo writes the hashes of all nodes to w in the flat layout, one level
+// after another from the leaves up, e.g. to serve proofs from a file with
+// NewFileNodeStore or to load the tree again with ReadFlatTree. It fails with ErrLeafHashesOnly if the upper levels
+// aren't stored.
+func (t *FlatTree) WriteTo(w io.Writer) (int64, error) {
+	if t.stored < t.levels() {
+		return 0, ErrLeafHashesOnly
+	}
+	n, err := w.Write(t.hashes)
+	return int64(n), err
+}
+
+// computeRoot computes the root hash from the leaf hashes.
+func (t *FlatTree) computeRoot() []byte {
+	return rootFromLeafHashes(t.Len(), func(i int) []byte { return t.node(0, i) }, &t.cfg)
+}
+
+// nodeHash returns the hash of the node at the given level and index.
+// Nodes on levels that aren't stored are recomputed with b.
+func (t *FlatTree) nodeHash(b *RootBuilder, level, index int) []byte {
+	switch {
+	case level < t.stored:
+		return bytes.Clone(t.node(level, index))
+	case level <= cacheBlockLevels:
+		return t.rangeRoot(b, level, index)
+	}
+
+	left := t.nodeHash(b, level-1, 2*index)
+	if 2*index+1 == t.count(level-1) {
+		return left
+	}
+	right := t.nodeHash(b, level-1, 2*index+1)
+	return combineLevelHashes(level, left, right, t.hashFunc, &t.cfg)
+}
+
+// rangeRoot computes the hash of the node at the given level and index
+// from the leaves below it. The subtree of an aligned range of leaves
+// has the shape of a tree over just those leaves.
+func (t *FlatTree) rangeRoot(b *RootBuilder, level, index int) []byte {
+	b.reset()
+	for i := index << level; i < min(t.Len(), (index+1)<<level); i++ {
+		b.push(append(b.buffer(), t.node(0, i)...))
+	}
+	root, _ := b.Root()
+	return root
+}
+
+// levels returns the number of levels including the leaves.
+func (t *FlatTree) levels() int {
+	return len(t.offsets) - 1
+}
+
+// count returns the number of nodes on the given level.
+func (t *FlatTree) count(level int) int {
+	return t.offsets[level+1] - t.offsets[level]
+}
+
+// node returns the hash of the node at the given level and index.
+// Its capacity is limited to the hash, so appending to it never
+// overwrites the next node.
+func (t *FlatTree) node(level, index int) []byte {
+	start := (t.offsets[level] + index) * t.size
+	return t.hashes[start : start+t.size : start+t.size]
+}
+
+// setHash stores hash as the hash of the node at the given level
+// and index, unless it has already been computed in place.
+func (t *FlatTree) setHash(level, index int, hash []byte) error {
+	if len(hash) != t.size {
+		return fmt.Errorf("%w: expected %d bytes, but got %d", ErrHashSizeMismatch, t.size, len(hash))
+	}
+	copy(t.node(level, index), hash)
+	return nil
+}
+
+// hashNode computes the hash of the node at the given level and index
+// from its children. A node without a sibling is carried up.
+func (t *FlatTree) hashNode(level, index int, hashFunc hash.Hash) error {
+	left := t.node(level-1, 2*index)
+	if 2*index+1 == t.count(level-1) {
+		copy(t.node(level, index), left)
+		return nil
+	}
+	right := t.node(level-1, 2*index+1)
+	return t.setHash(level, index, t.cfg.combineInto(t.node(level, index), level, left, right, hashFunc))
+}
+
+// Len returns the number of leaves in the tree.
+func (t *FlatTree) Len() int {
+	if len(t.offsets) == 0 {
+		return 0
+	}
+	return t.count(0)
+}
+
+// RootHash returns a copy of the root hash of the tree.
+func (t *FlatTree) RootHash() []byte {
+	return bytes.Clone(t.rootHash())
+}
+
+// rootHash returns the root hash of the tree without copying it.
+func (t *FlatTree) rootHash() []byte {
+	switch {
+	case t.Len() == 0:
+		return t.emptyRoot
+	case t.stored < t.levels():
+		return t.root
+	}
+	return t.node(t.levels()-1, 0)
+}
+
+// LeafHash returns a copy of the hash of the leaf at index.
+func (t *FlatTree) LeafHash(index int) ([]byte, error) {
+	if index < 0 || index >= t.Len() {
+		return nil, indexOutOfBounds(index, t.Len())
+	}
+	return bytes.Clone(t.node(0, index)), nil
+}
+
+// GenerateProofByIndex generates an inclusion proof for the leaf at index.
+func (t *FlatTree) GenerateProofByIndex(index int) (*Proof, error) {
+	if index < 0 || index >= t.Len() {
+		return nil, indexOutOfBounds(index, t.Len())
+	}
+
+	var b *RootBuilder
+	if t.stored < t.levels() {
+		b = newRootBuilder(t.cfg)
+	}
+	proof := &Proof{Index: index}
+	for level := 0; level < t.levels()-1; level++ {
+		// The last node on a level without a sibling is carried up.
+		if sibling := index ^ 1; sibling < t.count(level) {
+			proof.Hashes = append(proof.Hashes, t.nodeHash(b, level, sibling))
+		}
+		index /= 2
+	}
+	return proof, nil
+}
+
+// VerifyProof verifies that value is part of the tree.
+func (t *FlatTree) VerifyProof(proof *Proof, value []byte) (bool, error) {
+	buf := getDigest()
+	defer putDigest(buf)
+	t.leafHashFunc.Reset()
+	t.leafHashFunc.Write(value)
+	*buf = t.leafHashFunc.Sum(*buf)
+
+	root, ok := rootFromProofInto(*buf, *buf, proof, t.Len(), t.hashFunc, &t.cfg)
+	if !ok {
+		return false, &ProofSizeError{Index: proof.Index, NumHashes: len(proof.Hashes), Size: t.Len()}
+	}
+	*buf = root
+
+	if expRoot := t.rootHash(); !bytes.Equal(root, expRoot) {
+		return false, &RootMismatchError{Expected: bytes.Clone(expRoot), Actual: bytes.Clone(root)}
+	}
+	return true, nil
+}
+
+// UpdateLeaf updates the value of the leaf at the given index
+// and rehashes the nodes above it in place. If only the leaf hashes
+// are stored, the new root is computed from the proof of the leaf.
+func (t *FlatTree) UpdateLeaf(index int, value []byte) error {
+	if index < 0 || index >= t.Len() {
+		return indexOutOfBounds(index, t.Len())
+	}
+	value, err := t.cfg.leafValue(value)
+	if err != nil {
+		return err
+	}
+
+	var proof *Proof
+	if t.stored < t.levels() {
+		// The siblings on the path of the leaf don't change.
+		if proof, err = t.GenerateProofByIndex(index); err != nil {
+			return err
+		}
+	}
+
+	t.leafHashFunc.Reset()
+	t.leafHashFunc.Write(value)
+	if err := t.setHash(0, index, t.leafHashFunc.Sum(t.node(0, index)[:0])); err != nil {
+		return err
+	}
+	if proof != nil {
+		// The root is only handed out as a copy, so its buffer is reused.
+		t.root, _ = rootFromProofInto(t.root, t.node(0, index), proof, t.Len(), t.hashFunc, &t.cfg)
+		return nil
+	}
+	for level := 1; level < t.levels(); level++ {
+		index /= 2
+		if err := t.hashNode(level, index, t.hashFunc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFlatTree(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{
+			name: "Default options",
+		},
+		{
+			name: "Level tags",
+			opts: []Option{WithLevelTags(LevelIndexTag)},
+		},
+		{
+			name: "Domain prefixes",
+			opts: []Option{WithDomainPrefixes([]byte{0}, []byte{1})},
+		},
+	}
+
+	for _, tc := range tests {
+		for _, size := range []int{1, 2, 3, 7, 8, 13, 2*cacheBlock + 3} {
+			t.Run(fmt.Sprintf("%s with %d leaves", tc.name, size), func(t *testing.T) {
+				t.Parallel()
+
+				data := generateDummyData(size)
+				tree, err := NewTree(data, sha256.New, tc.opts...)
+				require.NoError(t, err)
+				flat, err := NewFlatTree(data, sha256.New, tc.opts...)
+				require.NoError(t, err)
+
+				assert.Equal(t, size, flat.Len())
+				assert.Equal(t, tree.Root.Hash, flat.RootHash())
+
+				for i, value := range data {
+					leafHash, err := flat.LeafHash(i)
+					require.NoError(t, err)
+					assert.Equal(t, tree.Leaves[i].Hash, leafHash)
+
+					expProof, err := tree.GenerateProofByIndex(i)
+					require.NoError(t, err)
+					proof, err := flat.GenerateProofByIndex(i)
+					require.NoError(t, err)
+					assert.Equal(t, expProof, proof)
+
+					isValid, err := flat.VerifyProof(proof, value)
+					require.NoError(t, err)
+					assert.True(t, isValid)
+				}
+			})
+		}
+	}
+}
+
+func TestFlatTreeUpdateLeaf(t *testing.T) {
+	t.Parallel()
+
+	data := generateDummyData(11)
+	tree, err := NewTree(data, sha256.New)
+	require.NoError(t, err)
+	flat, err := NewFlatTree(data, sha256.New)
+	require.NoError(t, err)
+
+	for _, index := range []int{0, 5, 10} {
+		value := []byte(fmt.Sprintf("new-%d", index))
+		require.NoError(t, tree.UpdateLeaf(index, value))
+		require.NoError(t, flat.UpdateLeaf(index, value))
+		assert.Equal(t, tree.Root.Hash, flat.RootHash())
+	}
+
+	require.ErrorIs(t, flat.UpdateLeaf(11, nil), ErrIndexOutOfBounds)
+	_, err = flat.GenerateProofByIndex(-1)
+	require.ErrorIs(t, err, ErrIndexOutOfBounds)
+
+	proof, err := flat.GenerateProofByIndex(3)
+	require.NoError(t, err)
+	_, err = flat.VerifyProof(proof, []byte("wrong"))
+	require.ErrorIs(t, err, ErrProofVerificationFailed)
+}
+
+func TestNewFlatTreeErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewFlatTree(nil, sha256.New)
+	require.ErrorIs(t, err, ErrNoLeaves)
+
+	flat, err := NewFlatTree(nil, sha256.New, WithEmptyTree())
+	require.NoError(t, err)
+	tree, err := NewTree(nil, sha256.New, WithEmptyTree())
+	require.NoError(t, err)
+	assert.Equal(t, tree.Root.Hash, flat.RootHash())
+	assert.Zero(t, flat.Len())
+
+	// Nodes must have the size of the leaves.
+	_, err = NewFlatTree(generateDummyData(2), sha256.New, WithCombine(func(left, right []byte) []byte {
+		return left[:4]
+	}))
+	require.ErrorIs(t, err, ErrHashSizeMismatch)
+}
+
+func TestWithLeafHashesOnly(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{
+			name: "Default options",
+		},
+		{
+			name: "Level tags",
+			opts: []Option{WithLevelTags(LevelIndexTag)},
+		},
+	}
+
+	for _, tc := range tests {
+		for _, size := range []int{1, 2, 5, 13, 2*cacheBlock + 3} {
+			t.Run(fmt.Sprintf("%s with %d leaves", tc.name, size), func(t *testing.T) {
+				t.Parallel()
+
+				data := generateDummyData(size)
+				tree, err := NewTree(data, sha256.New, tc.opts...)
+				require.NoError(t, err)
+				flat, err := NewFlatTree(data, sha256.New, append(tc.opts, WithLeafHashesOnly())...)
+				require.NoError(t, err)
+
+				// Only the leaf hashes are stored.
+				assert.Len(t, flat.hashes, size*sha256.Size)
+				assert.Equal(t, tree.Root.Hash, flat.RootHash())
+
+				for _, index := range []int{0, size / 2, size - 1} {
+					expProof, err := tree.GenerateProofByIndex(index)
+					require.NoError(t, err)
+					proof, err := flat.GenerateProofByIndex(index)
+					require.NoError(t, err)
+					assert.Equal(t, expProof, proof)
+
+					value := []byte(fmt.Sprintf("new-%d", index))
+					require.NoError(t, tree.UpdateLeaf(index, value))
+					require.NoError(t, flat.UpdateLeaf(index, value))
+					assert.Equal(t, tree.Root.Hash, flat.RootHash())
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkFlatTreeConstruction(b *testing.B) {
+	for _, size := range []int{1024, 16384, 131072} {
+		b.Run(fmt.Sprintf("%d leaves", size), func(b *testing.B) {
+			data := generateDummyData(size)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := NewFlatTree(data, sha256.New); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkFlatTreeProofLeafHashesOnly(b *testing.B) {
+	for _, size := range []int{1024, 16384} {
+		b.Run(fmt.Sprintf("%d leaves", size), func(b *testing.B) {
+			flat, err := NewFlatTree(generateDummyData(size), sha256.New, WithLeafHashesOnly())
+			require.NoError(b, err)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := flat.GenerateProofByIndex(size / 2); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
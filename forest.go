@@ -0,0 +1,167 @@
+package merkle
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"slices"
+)
+
+var ErrNoTree = errors.New("tree not found in the forest")
+
+// Forest manages multiple named trees and commits to all of them
+// under a single super-root, so a system with many datasets only
+// has to publish one hash.
+//
+// The super-root is the root of a MapTree from each tree name to the
+// root and size of that tree. It is computed from the current trees,
+// so updates to a tree are reflected in the next super-root.
+type Forest struct {
+	trees       map[string]*Tree
+	newHashFunc func() hash.Hash
+	opts        []Option
+}
+
+// NewForest creates an empty forest. The super-root is computed
+// with the given hash function and options.
+func NewForest(newHashFunc func() hash.Hash, opts ...Option) *Forest {
+	return &Forest{
+		trees:       make(map[string]*Tree),
+		newHashFunc: newHashFunc,
+		opts:        opts,
+	}
+}
+
+// Set adds the tree under the given name, replacing any existing tree.
+func (f *Forest) Set(name string, tree *Tree) {
+	f.trees[name] = tree
+}
+
+// Remove removes the tree with the given name.
+func (f *Forest) Remove(name string) {
+	delete(f.trees, name)
+}
+
+// Tree returns the tree with the given name.
+func (f *Forest) Tree(name string) (*Tree, bool) {
+	tree, ok := f.trees[name]
+	return tree, ok
+}
+
+// Names returns the names of the trees in sorted order.
+func (f *Forest) Names() []string {
+	names := make([]string, 0, len(f.trees))
+	for name := range f.trees {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// superTree builds the tree over the roots of all trees.
+func (f *Forest) superTree() (*MapTree, error) {
+	entries := make(map[string][]byte, len(f.trees))
+	for name, tree := range f.trees {
+		entries[name] = encodeForestEntry(tree.RootHash(), len(tree.Leaves))
+	}
+	return NewTreeFromMap(entries, f.newHashFunc, f.opts...)
+}
+
+// encodeForestEntry encodes the root and size of a tree,
+// so proofs into the tree are bound to its size.
+func encodeForestEntry(root []byte, size int) []byte {
+	buf := make([]byte, 0, len(root)+8)
+	buf = append(buf, root...)
+	return binary.BigEndian.AppendUint64(buf, uint64(size))
+}
+
+// SuperRoot returns the root committing to all trees in the forest.
+func (f *Forest) SuperRoot() ([]byte, error) {
+	super, err := f.superTree()
+	if err != nil {
+		return nil, err
+	}
+	return super.RootHash(), nil
+}
+
+// ForestProof proves that a value is a leaf of a named tree
+// in a forest with a given super-root.
+type ForestProof struct {
+	// Name, TreeRoot and TreeSize identify the tree.
+	Name     string
+	TreeRoot []byte
+	TreeSize int
+
+	// Leaf proves the value against the tree root.
+	Leaf *Proof
+
+	// Tree proves the tree root against the super-root.
+	Tree     *Proof
+	NumTrees int
+}
+
+// GenerateProof generates a compound proof for the leaf at index
+// in the tree with the given name.
+func (f *Forest) GenerateProof(name string, index int) (*ForestProof, error) {
+	tree, ok := f.trees[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrNoTree, name)
+	}
+
+	leafProof, err := tree.GenerateProofByIndex(index)
+	if err != nil {
+		return nil, err
+	}
+
+	super, err := f.superTree()
+	if err != nil {
+		return nil, err
+	}
+	treeProof, err := super.ProveKey(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ForestProof{
+		Name:     name,
+		TreeRoot: tree.RootHash(),
+		TreeSize: len(tree.Leaves),
+		Leaf:     leafProof,
+		Tree:     treeProof,
+		NumTrees: len(super.Keys),
+	}, nil
+}
+
+// VerifyForestProof verifies that value is part of the named tree
+// in a forest with the given super-root. The options have to match
+// the options of both the tree and the forest.
+func VerifyForestProof(superRoot []byte, proof *ForestProof, value []byte, newHashFunc func() hash.Hash, opts ...Option) (bool, error) {
+	cfg := newConfig(opts, newHashFunc)
+	leafHashFunc := cfg.hasher.NewLeafHasher()
+	nodeHashFunc := cfg.hasher.NewNodeHasher()
+
+	leafHashFunc.Write(value)
+	treeRoot, ok := rootFromProofWithConfig(leafHashFunc.Sum(nil), proof.Leaf, proof.TreeSize, nodeHashFunc, &cfg)
+	if !ok {
+		return false, fmt.Errorf("tree %q: %w", proof.Name,
+			&ProofSizeError{Index: proof.Leaf.Index, NumHashes: len(proof.Leaf.Hashes), Size: proof.TreeSize})
+	}
+	if !bytes.Equal(treeRoot, proof.TreeRoot) {
+		return false, fmt.Errorf("tree %q: %w", proof.Name,
+			&RootMismatchError{Expected: proof.TreeRoot, Actual: treeRoot})
+	}
+
+	leafHashFunc.Reset()
+	leafHashFunc.Write(EncodeMapEntry(proof.Name, encodeForestEntry(proof.TreeRoot, proof.TreeSize)))
+	computedRoot, ok := rootFromProofWithConfig(leafHashFunc.Sum(nil), proof.Tree, proof.NumTrees, nodeHashFunc, &cfg)
+	if !ok {
+		return false, &ProofSizeError{Index: proof.Tree.Index, NumHashes: len(proof.Tree.Hashes), Size: proof.NumTrees}
+	}
+	if !bytes.Equal(computedRoot, superRoot) {
+		return false, &RootMismatchError{Expected: superRoot, Actual: computedRoot}
+	}
+
+	return true, nil
+}
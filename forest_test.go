@@ -0,0 +1,107 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForest(t *testing.T) {
+	t.Parallel()
+
+	forest := NewForest(sha256.New)
+	_, err := forest.SuperRoot()
+	require.ErrorIs(t, err, ErrNoLeaves)
+
+	data := map[string][][]byte{
+		"blocks":  generateDummyData(5),
+		"txs":     generateDummyData(12),
+		"uploads": generateDummyData(1),
+	}
+	for name, values := range data {
+		tree, err := NewTree(values, sha256.New)
+		require.NoError(t, err)
+		forest.Set(name, tree)
+	}
+	assert.Equal(t, []string{"blocks", "txs", "uploads"}, forest.Names())
+
+	superRoot, err := forest.SuperRoot()
+	require.NoError(t, err)
+
+	for name, values := range data {
+		for i, value := range values {
+			proof, err := forest.GenerateProof(name, i)
+			require.NoError(t, err)
+
+			isValid, err := VerifyForestProof(superRoot, proof, value, sha256.New)
+			require.NoError(t, err)
+			assert.True(t, isValid, "Leaf %d of %s should be valid", i, name)
+		}
+	}
+
+	proof, err := forest.GenerateProof("txs", 3)
+	require.NoError(t, err)
+
+	isValid, err := VerifyForestProof(superRoot, proof, []byte("tampered"), sha256.New)
+	require.ErrorIs(t, err, ErrProofVerificationFailed)
+	assert.False(t, isValid)
+
+	renamed := *proof
+	renamed.Name = "blocks"
+	isValid, err = VerifyForestProof(superRoot, &renamed, data["txs"][3], sha256.New)
+	require.ErrorIs(t, err, ErrProofVerificationFailed)
+	assert.False(t, isValid)
+
+	_, err = forest.GenerateProof("missing", 0)
+	require.ErrorIs(t, err, ErrNoTree)
+	_, err = forest.GenerateProof("txs", 12)
+	require.ErrorIs(t, err, ErrIndexOutOfBounds)
+
+	// Updating a tree changes the super-root.
+	tree, ok := forest.Tree("blocks")
+	require.True(t, ok)
+	require.NoError(t, tree.UpdateLeaf(0, []byte("new")))
+
+	newSuperRoot, err := forest.SuperRoot()
+	require.NoError(t, err)
+	assert.NotEqual(t, superRoot, newSuperRoot)
+
+	isValid, err = VerifyForestProof(superRoot, proof, data["txs"][3], sha256.New)
+	require.NoError(t, err)
+	assert.True(t, isValid, "Old proofs should still verify against the old super-root")
+
+	forest.Remove("blocks")
+	_, ok = forest.Tree("blocks")
+	assert.False(t, ok)
+}
+
+func TestForestWithOptions(t *testing.T) {
+	t.Parallel()
+
+	opts := []Option{WithLevelTags(LevelIndexTag), WithDomainPrefixes([]byte{0}, []byte{1})}
+	forest := NewForest(sha256.New, opts...)
+	values := generateDummyData(7)
+	for _, name := range []string{"blocks", "txs", "uploads"} {
+		tree, err := NewTree(values, sha256.New, opts...)
+		require.NoError(t, err)
+		forest.Set(name, tree)
+	}
+
+	superRoot, err := forest.SuperRoot()
+	require.NoError(t, err)
+
+	for i, value := range values {
+		proof, err := forest.GenerateProof("txs", i)
+		require.NoError(t, err)
+
+		isValid, err := VerifyForestProof(superRoot, proof, value, sha256.New, opts...)
+		require.NoError(t, err)
+		assert.True(t, isValid, "Leaf %d should be valid", i)
+
+		isValid, err = VerifyForestProof(superRoot, proof, value, sha256.New)
+		require.ErrorIs(t, err, ErrProofVerificationFailed)
+		assert.False(t, isValid)
+	}
+}
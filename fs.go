@@ -0,0 +1,55 @@
+package merkle
+
+import (
+	"hash"
+	"io"
+	"io/fs"
+)
+
+// NewTreeFromFS creates a new Merkle tree over the regular files in fsys.
+// Each leaf maps the slash-separated path of a file to the digest of its
+// contents, so the root commits to both file names and contents.
+// Directories, symlinks and other irregular files are skipped.
+// Proofs for a file are generated with ProveKey and verified with VerifyKey
+// using the digest returned by FileDigest. The options apply to the tree,
+// while file contents are digested with newHashFunc.
+func NewTreeFromFS(fsys fs.FS, newHashFunc func() hash.Hash, opts ...Option) (*MapTree, error) {
+	digests := make(map[string][]byte)
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		f, err := fsys.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		digest, err := FileDigest(f, newHashFunc)
+		if err != nil {
+			return err
+		}
+		digests[path] = digest
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTreeFromMap(digests, newHashFunc, opts...)
+}
+
+// FileDigest hashes the contents read from r without loading
+// them into memory at once.
+func FileDigest(r io.Reader, newHashFunc func() hash.Hash) ([]byte, error) {
+	hashFunc := newHashFunc()
+	if _, err := io.Copy(hashFunc, r); err != nil {
+		return nil, err
+	}
+	return hashFunc.Sum(nil), nil
+}
@@ -0,0 +1,91 @@
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTreeFromFS(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"b.txt":         {Data: []byte("bravo")},
+		"a.txt":         {Data: []byte("alpha")},
+		"dir/c.txt":     {Data: []byte("charlie")},
+		"dir/sub/d.txt": {Data: []byte("delta")},
+		"empty":         {Mode: fs.ModeDir | 0o755},
+	}
+
+	tree, err := NewTreeFromFS(fsys, sha256.New)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.txt", "b.txt", "dir/c.txt", "dir/sub/d.txt"}, tree.Keys)
+
+	for _, path := range tree.Keys {
+		digest, err := FileDigest(bytes.NewReader(fsys[path].Data), sha256.New)
+		require.NoError(t, err)
+
+		proof, err := tree.ProveKey(path)
+		require.NoError(t, err)
+
+		isValid, err := tree.VerifyKey(path, digest, proof)
+		require.NoError(t, err)
+		assert.True(t, isValid, "Proof for %s should be valid", path)
+	}
+
+	// Changing the contents of a file changes the root.
+	fsys["dir/c.txt"] = &fstest.MapFile{Data: []byte("changed")}
+	changed, err := NewTreeFromFS(fsys, sha256.New)
+	require.NoError(t, err)
+	assert.NotEqual(t, tree.RootHash(), changed.RootHash())
+
+	_, err = NewTreeFromFS(fstest.MapFS{}, sha256.New)
+	require.ErrorIs(t, err, ErrNoLeaves)
+}
+
+func TestNewTreeFromFSWithOptions(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"a.txt": {Data: []byte("alpha")},
+		"b.txt": {Data: []byte("bravo")},
+		"c.txt": {Data: []byte("charlie")},
+	}
+	opts := []Option{WithDomainPrefixes([]byte{0}, []byte{1})}
+
+	tree, err := NewTreeFromFS(fsys, sha256.New, opts...)
+	require.NoError(t, err)
+
+	digests := make(map[string][]byte)
+	for path, file := range fsys {
+		digest, err := FileDigest(bytes.NewReader(file.Data), sha256.New)
+		require.NoError(t, err)
+		digests[path] = digest
+	}
+	expTree, err := NewTreeFromMap(digests, sha256.New, opts...)
+	require.NoError(t, err)
+	assert.Equal(t, expTree.RootHash(), tree.RootHash())
+
+	plain, err := NewTreeFromFS(fsys, sha256.New)
+	require.NoError(t, err)
+	assert.NotEqual(t, plain.RootHash(), tree.RootHash())
+
+	empty, err := NewTreeFromFS(fstest.MapFS{}, sha256.New, WithEmptyTree())
+	require.NoError(t, err)
+	assert.Zero(t, empty.Len())
+}
+
+func TestFileDigest(t *testing.T) {
+	t.Parallel()
+
+	digest, err := FileDigest(bytes.NewReader([]byte("yolo")), sha256.New)
+	require.NoError(t, err)
+
+	exp := sha256.Sum256([]byte("yolo"))
+	assert.Equal(t, exp[:], digest)
+}
@@ -2,17 +2,28 @@ module github.com/estensen/merkle
 
 go 1.23.1
 
-require github.com/stretchr/testify v1.9.0
+require (
+	github.com/cespare/xxhash/v2 v2.3.0
+	github.com/consensys/gnark-crypto v0.17.0
+	github.com/stretchr/testify v1.10.0
+	golang.org/x/crypto v0.36.0
+	lukechampine.com/blake3 v1.4.1
+)
 
 require (
-	github.com/kr/pretty v0.3.1 // indirect
+	github.com/bits-and-blooms/bitset v1.20.0 // indirect
+	github.com/consensys/bavard v0.1.29 // indirect
+	github.com/klauspost/cpuid/v2 v2.0.9 // indirect
+	github.com/kr/text v0.2.0 // indirect
+	github.com/mmcloughlin/addchain v0.4.0 // indirect
 	github.com/rogpeppe/go-internal v1.12.0 // indirect
-	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
+	golang.org/x/sys v0.31.0 // indirect
+	rsc.io/tmplfunc v0.0.3 // indirect
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.2-0.20180830191138-d8f796af33cc // indirect
 	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
-	golang.org/x/sync v0.8.0
+	golang.org/x/sync v0.11.0
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
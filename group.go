@@ -0,0 +1,101 @@
+package merkle
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash"
+)
+
+var ErrInvalidGroupSize = errors.New("group size must be a positive power of two")
+
+// groupLevel returns the tree level holding the roots of groups
+// with groupSize leaves.
+func groupLevel(groupSize int) (int, error) {
+	if groupSize <= 0 || groupSize&(groupSize-1) != 0 {
+		return 0, fmt.Errorf("%w: %d", ErrInvalidGroupSize, groupSize)
+	}
+	return treeLevels(groupSize), nil
+}
+
+// GroupRoots returns the roots of the consecutive groups of groupSize
+// leaves, e.g. one root per 1024 leaves. The roots of full groups never
+// change when leaves are added after them. The last group can be partial.
+// It fails with ErrLeafPruned if a group is below a pruned node.
+func (t *Tree) GroupRoots(groupSize int) ([][]byte, error) {
+	level, err := groupLevel(groupSize)
+	if err != nil {
+		return nil, err
+	}
+
+	levels := t.levelNodes()
+	if level >= len(levels) {
+		// A single group covers the whole tree.
+		return [][]byte{t.Root.Hash}, nil
+	}
+
+	roots := make([][]byte, len(levels[level]))
+	for i, node := range levels[level] {
+		if node == nil {
+			return nil, fmt.Errorf("group %d: %w", i, ErrLeafPruned)
+		}
+		roots[i] = node.Hash
+	}
+	return roots, nil
+}
+
+// GenerateGroupProof generates a proof from the root of the given group
+// to the root of the tree. The groups form the bottom level of the tree
+// above them, so the proof index is the group index.
+func (t *Tree) GenerateGroupProof(groupSize, group int) (*Proof, error) {
+	level, err := groupLevel(groupSize)
+	if err != nil {
+		return nil, err
+	}
+
+	levels := t.levelNodes()
+	if level >= len(levels) {
+		if group != 0 {
+			return nil, indexOutOfBounds(group, 1)
+		}
+		return &Proof{Index: 0}, nil
+	}
+
+	if group < 0 || group >= len(levels[level]) {
+		return nil, indexOutOfBounds(group, len(levels[level]))
+	}
+	if levels[level][group] == nil {
+		return nil, fmt.Errorf("group %d: %w", group, ErrLeafPruned)
+	}
+
+	return &Proof{
+		Hashes: siblingHashes(levels[level][group]),
+		Index:  group,
+	}, nil
+}
+
+// VerifyGroupProof verifies that groupRoot is the root of a group
+// of groupSize leaves in a tree with numGroups groups and the given root.
+// The options have to match the options of the tree.
+func VerifyGroupProof(root, groupRoot []byte, groupSize, numGroups int, proof *Proof, newHashFunc func() hash.Hash, opts ...Option) (bool, error) {
+	level, err := groupLevel(groupSize)
+	if err != nil {
+		return false, err
+	}
+
+	// The proof starts at the level of the groups, not at the leaves.
+	cfg := newConfig(opts, newHashFunc)
+	if tag := cfg.levelTag; tag != nil {
+		cfg.levelTag = func(l int) []byte { return tag(level + l) }
+	}
+	computedRoot, ok := rootFromProofWithConfig(groupRoot, proof, numGroups, cfg.hasher.NewNodeHasher(), &cfg)
+	if !ok {
+		return false, &ProofSizeError{Index: proof.Index, NumHashes: len(proof.Hashes), Size: numGroups}
+	}
+
+	if !bytes.Equal(computedRoot, root) {
+		return false, &RootMismatchError{Expected: root, Actual: computedRoot}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,117 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupRoots(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		size      int
+		groupSize int
+		expGroups int
+	}{
+		{size: 16, groupSize: 4, expGroups: 4},
+		{size: 13, groupSize: 4, expGroups: 4},
+		{size: 9, groupSize: 8, expGroups: 2},
+		{size: 5, groupSize: 8, expGroups: 1},
+		{size: 7, groupSize: 1, expGroups: 7},
+	}
+
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%d leaves in groups of %d", tc.size, tc.groupSize), func(t *testing.T) {
+			t.Parallel()
+
+			data := generateDummyData(tc.size)
+			tree, err := NewTree(data, sha256.New)
+			require.NoError(t, err)
+
+			roots, err := tree.GroupRoots(tc.groupSize)
+			require.NoError(t, err)
+			require.Len(t, roots, tc.expGroups)
+
+			for i, groupRoot := range roots {
+				// Each group root is the root of a tree over the group's leaves.
+				end := min((i+1)*tc.groupSize, tc.size)
+				groupTree, err := NewTree(data[i*tc.groupSize:end], sha256.New)
+				require.NoError(t, err)
+				assert.Equal(t, groupTree.Root.Hash, groupRoot, "Group %d root mismatch", i)
+
+				proof, err := tree.GenerateGroupProof(tc.groupSize, i)
+				require.NoError(t, err)
+
+				isValid, err := VerifyGroupProof(tree.Root.Hash, groupRoot, tc.groupSize, len(roots), proof, sha256.New)
+				require.NoError(t, err)
+				assert.True(t, isValid)
+			}
+
+			_, err = tree.GenerateGroupProof(tc.groupSize, len(roots))
+			require.ErrorIs(t, err, ErrIndexOutOfBounds)
+		})
+	}
+}
+
+func TestGroupRootsStable(t *testing.T) {
+	t.Parallel()
+
+	data := generateDummyData(20)
+	small, err := NewTree(data[:9], sha256.New)
+	require.NoError(t, err)
+	large, err := NewTree(data, sha256.New)
+	require.NoError(t, err)
+
+	smallRoots, err := small.GroupRoots(4)
+	require.NoError(t, err)
+	largeRoots, err := large.GroupRoots(4)
+	require.NoError(t, err)
+
+	// Full groups keep their roots as the tree grows.
+	assert.Equal(t, smallRoots[:2], largeRoots[:2])
+}
+
+func TestGroupRootsInvalidSize(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewTree(generateDummyData(8), sha256.New)
+	require.NoError(t, err)
+
+	_, err = tree.GroupRoots(3)
+	require.ErrorIs(t, err, ErrInvalidGroupSize)
+
+	_, err = tree.GenerateGroupProof(0, 0)
+	require.ErrorIs(t, err, ErrInvalidGroupSize)
+}
+
+func TestVerifyGroupProofWithOptions(t *testing.T) {
+	t.Parallel()
+
+	opts := []Option{WithLevelTags(LevelIndexTag), WithDomainPrefixes([]byte{0}, []byte{1})}
+	tree, err := NewTree(generateDummyData(13), sha256.New, opts...)
+	require.NoError(t, err)
+
+	roots, err := tree.GroupRoots(4)
+	require.NoError(t, err)
+	for i, groupRoot := range roots {
+		proof, err := tree.GenerateGroupProof(4, i)
+		require.NoError(t, err)
+
+		isValid, err := VerifyGroupProof(tree.Root.Hash, groupRoot, 4, len(roots), proof, sha256.New, opts...)
+		require.NoError(t, err)
+		assert.True(t, isValid, "Group %d should be valid", i)
+
+		isValid, err = VerifyGroupProof(tree.Root.Hash, groupRoot, 4, len(roots), proof, sha256.New)
+		require.ErrorIs(t, err, ErrProofVerificationFailed)
+		assert.False(t, isValid)
+	}
+
+	proof, err := tree.GenerateGroupProof(4, 0)
+	require.NoError(t, err)
+	_, err = VerifyGroupProof(tree.Root.Hash, roots[0], 3, len(roots), proof, sha256.New, opts...)
+	require.ErrorIs(t, err, ErrInvalidGroupSize)
+}
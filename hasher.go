@@ -0,0 +1,157 @@
+package merkle
+
+import (
+	"crypto/hmac"
+	"encoding/binary"
+	"hash"
+)
+
+// Hasher creates the hash functions used for the leaves and
+// the internal nodes of a tree. Using separate hash functions allows
+// keyed, tweakable or domain separated hashing schemes.
+type Hasher interface {
+	// NewLeafHasher returns a hash function for leaf values.
+	NewLeafHasher() hash.Hash
+
+	// NewNodeHasher returns a hash function for the concatenated
+	// hashes of two child nodes.
+	NewNodeHasher() hash.Hash
+}
+
+// LeafHash hashes a leaf value exactly like a tree created with
+// the same hash function and options does, including HMAC keys
+// and domain or length prefixes. It lets verifiers compute leaf hashes
+// without building a tree.
+func LeafHash(value []byte, newHashFunc func() hash.Hash, opts ...Option) []byte {
+	cfg := newConfig(opts, newHashFunc)
+	return hashLeaf(cfg.hasher, value)
+}
+
+// LeafHash hashes a leaf value like the leaves of the tree.
+func (t *Tree) LeafHash(value []byte) []byte {
+	return hashLeaf(t.cfg.hasher, value)
+}
+
+// hashLeaf hashes a leaf value with a new leaf hash function of h.
+func hashLeaf(h Hasher, value []byte) []byte {
+	hashFunc := h.NewLeafHasher()
+	hashFunc.Write(value)
+	return hashFunc.Sum(nil)
+}
+
+// stdHasher uses the same hash function for leaves and nodes.
+type stdHasher struct {
+	newHashFunc func() hash.Hash
+}
+
+// StdHasher adapts a standard library style hash constructor,
+// such as sha256.New, to a Hasher that hashes leaves and nodes
+// the same way.
+func StdHasher(newHashFunc func() hash.Hash) Hasher {
+	return stdHasher{newHashFunc: newHashFunc}
+}
+
+func (h stdHasher) NewLeafHasher() hash.Hash {
+	return h.newHashFunc()
+}
+
+func (h stdHasher) NewNodeHasher() hash.Hash {
+	return h.newHashFunc()
+}
+
+// hmacLeafHasher keys the leaf hashes of a Hasher with HMAC.
+type hmacLeafHasher struct {
+	Hasher
+	key []byte
+}
+
+func (h hmacLeafHasher) NewLeafHasher() hash.Hash {
+	return hmac.New(h.Hasher.NewLeafHasher, h.key)
+}
+
+// splitHasher uses different hash functions for leaves and nodes.
+type splitHasher struct {
+	newLeafHash func() hash.Hash
+	newNodeHash func() hash.Hash
+}
+
+// SplitHasher returns a Hasher that hashes leaves with newLeafHash
+// and nodes with newNodeHash.
+func SplitHasher(newLeafHash, newNodeHash func() hash.Hash) Hasher {
+	return splitHasher{newLeafHash: newLeafHash, newNodeHash: newNodeHash}
+}
+
+func (h splitHasher) NewLeafHasher() hash.Hash {
+	return h.newLeafHash()
+}
+
+func (h splitHasher) NewNodeHasher() hash.Hash {
+	return h.newNodeHash()
+}
+
+// prefixHash writes a prefix before the hashed data,
+// also after it has been reset.
+type prefixHash struct {
+	hash.Hash
+	prefix []byte
+}
+
+func newPrefixHash(h hash.Hash, prefix []byte) hash.Hash {
+	p := &prefixHash{Hash: h, prefix: prefix}
+	p.Reset()
+	return p
+}
+
+func (h *prefixHash) Reset() {
+	h.Hash.Reset()
+	h.Hash.Write(h.prefix)
+}
+
+// prefixHasher prefixes the leaves and nodes of a Hasher.
+type prefixHasher struct {
+	Hasher
+	leafPrefix []byte
+	nodePrefix []byte
+}
+
+func (h prefixHasher) NewLeafHasher() hash.Hash {
+	return newPrefixHash(h.Hasher.NewLeafHasher(), h.leafPrefix)
+}
+
+func (h prefixHasher) NewNodeHasher() hash.Hash {
+	return newPrefixHash(h.Hasher.NewNodeHasher(), h.nodePrefix)
+}
+
+// lengthPrefixHash prefixes the hashed data with its length
+// as a big endian uint64. The data is buffered until Sum is called,
+// since the length isn't known before.
+type lengthPrefixHash struct {
+	hash.Hash
+	data []byte
+}
+
+func (h *lengthPrefixHash) Write(p []byte) (int, error) {
+	h.data = append(h.data, p...)
+	return len(p), nil
+}
+
+func (h *lengthPrefixHash) Sum(b []byte) []byte {
+	h.Hash.Reset()
+	h.Hash.Write(binary.BigEndian.AppendUint64(nil, uint64(len(h.data))))
+	h.Hash.Write(h.data)
+	return h.Hash.Sum(b)
+}
+
+func (h *lengthPrefixHash) Reset() {
+	h.Hash.Reset()
+	h.data = h.data[:0]
+}
+
+// lengthPrefixHasher length prefixes the leaves of a Hasher.
+type lengthPrefixHasher struct {
+	Hasher
+}
+
+func (h lengthPrefixHasher) NewLeafHasher() hash.Hash {
+	return &lengthPrefixHash{Hash: h.Hasher.NewLeafHasher()}
+}
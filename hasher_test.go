@@ -0,0 +1,120 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"hash"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfc6962Hasher separates leaves and nodes with the prefixes from RFC 6962.
+type rfc6962Hasher struct{}
+
+func (rfc6962Hasher) NewLeafHasher() hash.Hash { return newPrefixHash(sha256.New(), []byte{0x00}) }
+func (rfc6962Hasher) NewNodeHasher() hash.Hash { return newPrefixHash(sha256.New(), []byte{0x01}) }
+
+func TestWithHasher(t *testing.T) {
+	t.Parallel()
+
+	leafHash := func(value []byte) []byte {
+		sum := sha256.Sum256(append([]byte{0x00}, value...))
+		return sum[:]
+	}
+	nodeHash := func(left, right []byte) []byte {
+		sum := sha256.Sum256(append(append([]byte{0x01}, left...), right...))
+		return sum[:]
+	}
+
+	data := generateDummyData(3)
+	expRoot := nodeHash(nodeHash(leafHash(data[0]), leafHash(data[1])), leafHash(data[2]))
+
+	tree, err := NewTree(data, nil, WithHasher(rfc6962Hasher{}))
+	require.NoError(t, err)
+	assert.Equal(t, expRoot, tree.Root.Hash)
+
+	for i, value := range data {
+		proof, err := tree.GenerateProofByIndex(i)
+		require.NoError(t, err)
+
+		isValid, err := tree.VerifyProof(proof, value)
+		require.NoError(t, err)
+		assert.True(t, isValid)
+	}
+
+	require.NoError(t, tree.UpdateLeaf(2, []byte("new")))
+	expRoot = nodeHash(nodeHash(leafHash(data[0]), leafHash(data[1])), leafHash([]byte("new")))
+	assert.Equal(t, expRoot, tree.Root.Hash)
+
+	// The leaf hasher decides the digest size for fixed size leaves.
+	_, err = NewTree([][]byte{make([]byte, 32)}, nil, WithHasher(rfc6962Hasher{}), WithFixedLeafSize(0))
+	require.NoError(t, err)
+}
+
+func TestStdHasher(t *testing.T) {
+	t.Parallel()
+
+	data := generateDummyData(5)
+	tree, err := NewTree(data, sha256.New)
+	require.NoError(t, err)
+
+	hasherTree, err := NewTree(data, nil, WithHasher(StdHasher(sha256.New)))
+	require.NoError(t, err)
+	assert.Equal(t, tree.Root.Hash, hasherTree.Root.Hash)
+}
+
+func TestSplitHasher(t *testing.T) {
+	t.Parallel()
+
+	data := generateDummyData(2)
+	tree, err := NewTree(data, nil, WithHasher(SplitHasher(sha256.New, NewSHA3_256)))
+	require.NoError(t, err)
+
+	leaf0 := sha256.Sum256(data[0])
+	leaf1 := sha256.Sum256(data[1])
+	h := NewSHA3_256()
+	h.Write(leaf0[:])
+	h.Write(leaf1[:])
+	assert.Equal(t, h.Sum(nil), tree.Root.Hash)
+}
+
+func TestLeafHash(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{
+			name: "Default",
+		},
+		{
+			name: "HMAC leaves",
+			opts: []Option{WithHMACLeaves([]byte("secret"))},
+		},
+		{
+			name: "Domain prefixes",
+			opts: []Option{WithDomainPrefixes([]byte{0}, []byte{1})},
+		},
+		{
+			name: "Length prefixed leaves",
+			opts: []Option{WithLengthPrefixedLeaves()},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := generateDummyData(5)
+			tree, err := NewTree(data, sha256.New, tc.opts...)
+			require.NoError(t, err)
+
+			for i, value := range data {
+				assert.Equal(t, tree.Leaves[i].Hash, LeafHash(value, sha256.New, tc.opts...))
+				assert.Equal(t, tree.Leaves[i].Hash, tree.LeafHash(value))
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package merkle
+
+import (
+	"bytes"
+	"slices"
+	"time"
+)
+
+// Mutation is the kind of change that gave a tree a new root.
+type Mutation string
+
+const (
+	MutationBuild    Mutation = "build"
+	MutationUpdate   Mutation = "update"
+	MutationAppend   Mutation = "append"
+	MutationRemove   Mutation = "remove"
+	MutationCommit   Mutation = "commit"
+	MutationRehash   Mutation = "rehash"
+	MutationRollback Mutation = "rollback"
+)
+
+// RootRecord is a root the tree has had.
+type RootRecord struct {
+	Root  []byte
+	Time  time.Time
+	Cause Mutation
+}
+
+// RootHistory returns the roots the tree has had, from the oldest
+// to the current one. It returns nil unless the tree was created
+// with WithRootHistory.
+func (t *Tree) RootHistory() []RootRecord {
+	if t.history == nil {
+		return nil
+	}
+	history := make([]RootRecord, len(t.history))
+	for i, record := range t.history {
+		record.Root = bytes.Clone(record.Root)
+		history[i] = record
+	}
+	return history
+}
+
+// RootChange describes a change of the root of a tree.
+type RootChange struct {
+	Old   []byte
+	New   []byte
+	Cause Mutation
+}
+
+type rootSubscriber struct {
+	id int
+	fn func(RootChange)
+}
+
+// OnRootChange calls fn after every change that gives the tree
+// a different root, in the goroutine that changed the tree.
+// Subscribers are called in the order they subscribed. fn must not
+// change the tree, but it can e.g. send the change to a channel.
+// The returned function unsubscribes fn.
+func (t *Tree) OnRootChange(fn func(RootChange)) (unsubscribe func()) {
+	if len(t.subscribers) == 0 {
+		t.lastRoot = t.RootHash()
+	}
+	id := t.nextSubscriber
+	t.nextSubscriber++
+	t.subscribers = append(t.subscribers, rootSubscriber{id: id, fn: fn})
+
+	return func() {
+		t.subscribers = slices.DeleteFunc(t.subscribers, func(s rootSubscriber) bool {
+			return s.id == id
+		})
+	}
+}
+
+// rootChanged records the new root of the tree after a change
+// and notifies the subscribers if it differs from the last root.
+func (t *Tree) rootChanged(cause Mutation) {
+	t.generation++
+	if t.cfg.rootHistory {
+		t.history = append(t.history, RootRecord{
+			Root:  t.RootHash(),
+			Time:  time.Now(),
+			Cause: cause,
+		})
+	}
+
+	if len(t.subscribers) == 0 {
+		return
+	}
+	oldRoot := t.lastRoot
+	t.lastRoot = t.RootHash()
+	if bytes.Equal(oldRoot, t.lastRoot) {
+		return
+	}
+	for _, s := range slices.Clone(t.subscribers) {
+		s.fn(RootChange{
+			Old:   bytes.Clone(oldRoot),
+			New:   bytes.Clone(t.lastRoot),
+			Cause: cause,
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRootHistory(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewTree(generateDummyData(4), sha256.New, WithRootHistory())
+	require.NoError(t, err)
+
+	var expRoots [][]byte
+	expRoots = append(expRoots, tree.RootHash())
+	require.NoError(t, tree.UpdateLeaf(0, []byte("a")))
+	expRoots = append(expRoots, tree.RootHash())
+	require.NoError(t, tree.UpdateLeaves(map[int][]byte{1: []byte("b")}))
+	expRoots = append(expRoots, tree.RootHash())
+	require.NoError(t, tree.AppendLeaf([]byte("c")))
+	expRoots = append(expRoots, tree.RootHash())
+	require.NoError(t, tree.AppendLeaves([][]byte{[]byte("d")}))
+	expRoots = append(expRoots, tree.RootHash())
+	require.NoError(t, tree.RemoveLeaf(2))
+	expRoots = append(expRoots, tree.RootHash())
+	tx := tree.Begin()
+	require.NoError(t, tx.Append([]byte("e")))
+	require.NoError(t, tx.Commit())
+	expRoots = append(expRoots, tree.RootHash())
+	require.NoError(t, tree.ReHash(sha512.New))
+	expRoots = append(expRoots, tree.RootHash())
+
+	// Failed changes are not recorded.
+	require.ErrorIs(t, tree.UpdateLeaf(10, []byte("f")), ErrIndexOutOfBounds)
+
+	history := tree.RootHistory()
+	require.Len(t, history, len(expRoots))
+	expCauses := []Mutation{
+		MutationBuild, MutationUpdate, MutationUpdate, MutationAppend,
+		MutationAppend, MutationRemove, MutationCommit, MutationRehash,
+	}
+	for i, record := range history {
+		assert.Equal(t, expRoots[i], record.Root, "Root of record %d", i)
+		assert.Equal(t, expCauses[i], record.Cause, "Cause of record %d", i)
+		assert.False(t, record.Time.IsZero())
+		if i > 0 {
+			assert.False(t, record.Time.Before(history[i-1].Time), "Records should be in order")
+		}
+	}
+
+	// The history can't be changed through the returned records.
+	history[0].Root[0] ^= 0xff
+	assert.Equal(t, expRoots[0], tree.RootHistory()[0].Root)
+}
+
+func TestRootHistoryDisabled(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewTree(generateDummyData(4), sha256.New)
+	require.NoError(t, err)
+	require.NoError(t, tree.UpdateLeaf(0, []byte("a")))
+	assert.Nil(t, tree.RootHistory())
+}
+
+func TestOnRootChange(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewTree(generateDummyData(4), sha256.New)
+	require.NoError(t, err)
+
+	var changes []RootChange
+	unsubscribe := tree.OnRootChange(func(change RootChange) {
+		changes = append(changes, change)
+	})
+	ch := make(chan RootChange, 10)
+	tree.OnRootChange(func(change RootChange) {
+		ch <- change
+	})
+
+	root0 := tree.RootHash()
+	require.NoError(t, tree.AppendLeaf([]byte("a")))
+	root1 := tree.RootHash()
+	require.NoError(t, tree.UpdateLeaf(4, []byte("b")))
+	root2 := tree.RootHash()
+
+	// Changes that keep the root don't notify the subscribers.
+	require.NoError(t, tree.UpdateLeaf(4, []byte("b")))
+
+	unsubscribe()
+	require.NoError(t, tree.RemoveLeaf(0))
+	root3 := tree.RootHash()
+
+	expChanges := []RootChange{
+		{Old: root0, New: root1, Cause: MutationAppend},
+		{Old: root1, New: root2, Cause: MutationUpdate},
+	}
+	assert.Equal(t, expChanges, changes)
+
+	close(ch)
+	var chChanges []RootChange
+	for change := range ch {
+		chChanges = append(chChanges, change)
+	}
+	expChanges = append(expChanges, RootChange{Old: root2, New: root3, Cause: MutationRemove})
+	assert.Equal(t, expChanges, chChanges)
+}
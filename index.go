@@ -0,0 +1,107 @@
+package merkle
+
+import "bytes"
+
+// leafRef locates the leaves with a given hash.
+type leafRef struct {
+	// index is the index of the first leaf with the hash.
+	index int
+	// count is the number of leaves with the hash.
+	count int
+}
+
+// buildIndex indexes the leaves by their hash, so leaves can be found
+// by value without scanning the tree. Trees whose leaf values can't be
+// compared aren't indexed.
+func (t *Tree) buildIndex() {
+	if t.hashedLeaves && t.cfg.resolveValue == nil {
+		return
+	}
+	t.index = make(map[string]leafRef, max(len(t.Leaves), t.cfg.capacity))
+	for i, leaf := range t.Leaves {
+		if leaf != nil {
+			t.addToIndex(i, leaf.Hash)
+		}
+	}
+}
+
+// addToIndex records that the leaf at index i has the given hash.
+func (t *Tree) addToIndex(i int, hash []byte) {
+	if t.index == nil {
+		return
+	}
+
+	ref, ok := t.index[string(hash)]
+	if !ok || i < ref.index {
+		ref.index = i
+	}
+	ref.count++
+	t.index[string(hash)] = ref
+}
+
+// removeFromIndex records that the leaf at index i no longer has the given hash.
+func (t *Tree) removeFromIndex(i int, hash []byte) {
+	if t.index == nil {
+		return
+	}
+
+	ref, ok := t.index[string(hash)]
+	if !ok {
+		return
+	}
+	ref.count--
+	if ref.count == 0 {
+		delete(t.index, string(hash))
+		return
+	}
+
+	// Move on to the next leaf with the same hash.
+	if ref.index == i {
+		for j := i + 1; j < len(t.Leaves); j++ {
+			if t.Leaves[j] != nil && bytes.Equal(t.Leaves[j].Hash, hash) {
+				ref.index = j
+				break
+			}
+		}
+	}
+	t.index[string(hash)] = ref
+}
+
+// IndexOf returns the index of the first leaf with the given value.
+// It returns false if no leaf has the value, or if the tree doesn't
+// hold leaf values and they can't be resolved.
+func (t *Tree) IndexOf(value []byte) (int, bool) {
+	if t.index != nil {
+		buf := getDigest()
+		defer putDigest(buf)
+		leafHashFunc := t.cfg.hasher.NewLeafHasher()
+		leafHashFunc.Write(value)
+		*buf = leafHashFunc.Sum(*buf)
+
+		ref, ok := t.index[string(*buf)]
+		if !ok {
+			return 0, false
+		}
+		if leafValue, ok := t.leafValue(ref.index); ok && bytes.Equal(leafValue, value) {
+			return ref.index, true
+		}
+		// The hash collides with another value, which is only
+		// feasible with an insecure hash function.
+	}
+
+	for i, leaf := range t.Leaves {
+		if leaf == nil {
+			continue
+		}
+		if leafValue, ok := t.leafValue(i); ok && bytes.Equal(leafValue, value) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Contains reports whether a leaf has the given value.
+func (t *Tree) Contains(value []byte) bool {
+	_, found := t.IndexOf(value)
+	return found
+}
@@ -0,0 +1,246 @@
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scanLeaf finds the first leaf with the given value by scanning all leaves.
+func scanLeaf(tree *Tree, value []byte) (int, bool) {
+	for i, leaf := range tree.Leaves {
+		if leaf != nil && bytes.Equal(leaf.Value, value) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func TestValueIndex(t *testing.T) {
+	t.Parallel()
+
+	dup := []byte("dup")
+	values := [][]byte{[]byte("a"), dup, []byte("b"), dup, []byte("c"), dup}
+
+	tests := []struct {
+		name   string
+		mutate func(t *testing.T, tree *Tree)
+	}{
+		{
+			name:   "New tree",
+			mutate: func(t *testing.T, tree *Tree) {},
+		},
+		{
+			name: "Update first duplicate",
+			mutate: func(t *testing.T, tree *Tree) {
+				require.NoError(t, tree.UpdateLeaf(1, []byte("x")))
+			},
+		},
+		{
+			name: "Update to earlier duplicate",
+			mutate: func(t *testing.T, tree *Tree) {
+				require.NoError(t, tree.UpdateLeaf(0, dup))
+			},
+		},
+		{
+			name: "Update many",
+			mutate: func(t *testing.T, tree *Tree) {
+				require.NoError(t, tree.UpdateLeaves(map[int][]byte{
+					1: []byte("x"),
+					3: []byte("y"),
+					4: []byte("a"),
+				}))
+			},
+		},
+		{
+			name: "Remove first duplicate",
+			mutate: func(t *testing.T, tree *Tree) {
+				require.NoError(t, tree.RemoveLeaf(1))
+			},
+		},
+		{
+			name: "Append",
+			mutate: func(t *testing.T, tree *Tree) {
+				require.NoError(t, tree.AppendLeaf([]byte("d")))
+				require.NoError(t, tree.AppendLeaves([][]byte{dup, []byte("e")}))
+			},
+		},
+		{
+			name: "Prune",
+			mutate: func(t *testing.T, tree *Tree) {
+				require.NoError(t, tree.Prune([]int{0, 3}))
+			},
+		},
+		{
+			name: "ReHash",
+			mutate: func(t *testing.T, tree *Tree) {
+				require.NoError(t, tree.ReHash(sha256.New224))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := NewTree(values, sha256.New)
+			require.NoError(t, err)
+			tc.mutate(t, tree)
+
+			candidates := append(values, []byte("x"), []byte("y"), []byte("d"), []byte("e"), []byte("missing"))
+			for _, value := range candidates {
+				expIndex, expFound := scanLeaf(tree, value)
+				index, found := tree.IndexOf(value)
+				assert.Equal(t, expFound, found, "Found mismatch for %q", value)
+				assert.Equal(t, expIndex, index, "Index mismatch for %q", value)
+			}
+		})
+	}
+}
+
+func TestIndexOf(t *testing.T) {
+	t.Parallel()
+
+	values := [][]byte{[]byte("a"), []byte("b"), []byte("a")}
+
+	tests := []struct {
+		name     string
+		value    []byte
+		expIndex int
+		expFound bool
+	}{
+		{
+			name:     "Unique value",
+			value:    []byte("b"),
+			expIndex: 1,
+			expFound: true,
+		},
+		{
+			name:     "First of duplicates",
+			value:    []byte("a"),
+			expIndex: 0,
+			expFound: true,
+		},
+		{
+			name:     "Missing value",
+			value:    []byte("c"),
+			expFound: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := NewTree(values, sha256.New)
+			require.NoError(t, err)
+
+			index, found := tree.IndexOf(tc.value)
+			assert.Equal(t, tc.expFound, found)
+			assert.Equal(t, tc.expIndex, index)
+			assert.Equal(t, tc.expFound, tree.Contains(tc.value))
+		})
+	}
+}
+
+func TestWithoutLeafValues(t *testing.T) {
+	t.Parallel()
+
+	values := [][]byte{[]byte("a"), []byte("b"), []byte("a")}
+
+	tests := []struct {
+		name     string
+		resolve  func(values *[][]byte) func(index int) ([]byte, error)
+		expFound bool
+	}{
+		{
+			name: "Without resolver",
+		},
+		{
+			name: "With resolver",
+			resolve: func(values *[][]byte) func(index int) ([]byte, error) {
+				return func(index int) ([]byte, error) {
+					return (*values)[index], nil
+				}
+			},
+			expFound: true,
+		},
+		{
+			name: "Failing resolver",
+			resolve: func(*[][]byte) func(index int) ([]byte, error) {
+				return func(index int) ([]byte, error) {
+					return nil, errors.New("not found")
+				}
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			stored := slices.Clone(values)
+			var resolve func(index int) ([]byte, error)
+			if tc.resolve != nil {
+				resolve = tc.resolve(&stored)
+			}
+			tree, err := NewTree(values, sha256.New, WithoutLeafValues(resolve))
+			require.NoError(t, err)
+
+			expTree, err := NewTree(values, sha256.New)
+			require.NoError(t, err)
+			assert.Equal(t, expTree.RootHash(), tree.RootHash())
+
+			require.NoError(t, tree.AppendLeaf([]byte("c")))
+			stored = append(stored, []byte("c"))
+			require.NoError(t, tree.UpdateLeaf(1, []byte("d")))
+			stored[1] = []byte("d")
+			for _, leaf := range tree.Leaves {
+				assert.Nil(t, leaf.Value)
+			}
+			require.NoError(t, tree.Validate())
+
+			index, found := tree.IndexOf([]byte("d"))
+			assert.Equal(t, tc.expFound, found)
+			if found {
+				assert.Equal(t, 1, index)
+			}
+			assert.Equal(t, tc.expFound, tree.Contains([]byte("c")))
+			assert.False(t, tree.Contains([]byte("b")))
+
+			assert.ErrorIs(t, tree.ReHash(sha256.New), ErrNoLeafValues)
+		})
+	}
+}
+
+func TestValueIndexInsecureHash(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewTree(generateDummyData(10), nil, WithInsecureHash())
+	require.NoError(t, err)
+
+	// Simulate a collision by pointing the leaf hash at another leaf.
+	tree.index[string(tree.Leaves[3].Hash)] = leafRef{index: 5, count: 1}
+
+	index, found := tree.IndexOf(tree.Leaves[3].Value)
+	require.True(t, found)
+	assert.Equal(t, 3, index)
+}
+
+func BenchmarkGenerateProofByValue(b *testing.B) {
+	data := generateDummyData(100_000)
+	tree, err := NewTree(data, sha256.New)
+	require.NoError(b, err)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := tree.GenerateProof(data[len(data)-1]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package merkle
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"sort"
+)
+
+var (
+	ErrUnsortedEntries = errors.New("entries are not sorted by timestamp")
+	ErrEntryInRange    = errors.New("entry exists within range")
+)
+
+// Entry is a timestamped entry of an interval tree.
+type Entry struct {
+	Timestamp uint64
+	Data      []byte
+}
+
+// EncodeEntry encodes an entry into a leaf value:
+// the timestamp as a big endian uint64 followed by the data.
+func EncodeEntry(e Entry) []byte {
+	buf := make([]byte, 0, 8+len(e.Data))
+	buf = binary.BigEndian.AppendUint64(buf, e.Timestamp)
+	return append(buf, e.Data...)
+}
+
+// IntervalTree is a Merkle tree over entries ordered by timestamp.
+// Besides proving that an entry exists, it can prove that no entry
+// exists within a range of timestamps.
+type IntervalTree struct {
+	Tree    *Tree
+	Entries []Entry
+}
+
+// AbsenceProof proves that no entry has a timestamp in [Start, End).
+// It authenticates the adjacent leaves on either side of the range.
+// Left is nil if there is no entry before Start and Right is nil
+// if there is no entry at or after End.
+type AbsenceProof struct {
+	Start      uint64
+	End        uint64
+	Left       *Proof
+	LeftEntry  Entry
+	Right      *Proof
+	RightEntry Entry
+}
+
+// NewIntervalTree creates a new interval tree from entries
+// sorted by timestamp.
+func NewIntervalTree(entries []Entry, newHashFunc func() hash.Hash, opts ...Option) (*IntervalTree, error) {
+	values := make([][]byte, len(entries))
+	for i, e := range entries {
+		if i > 0 && e.Timestamp < entries[i-1].Timestamp {
+			return nil, fmt.Errorf("%w: entry %d", ErrUnsortedEntries, i)
+		}
+		values[i] = EncodeEntry(e)
+	}
+
+	tree, err := NewTree(values, newHashFunc, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IntervalTree{
+		Tree:    tree,
+		Entries: entries,
+	}, nil
+}
+
+// ProveAbsence proves that no entry has a timestamp in [start, end).
+// It returns ErrEntryInRange if there is such an entry.
+func (t *IntervalTree) ProveAbsence(start, end uint64) (*AbsenceProof, error) {
+	// The first entry at or after start.
+	right := sort.Search(len(t.Entries), func(i int) bool {
+		return t.Entries[i].Timestamp >= start
+	})
+	if right < len(t.Entries) && t.Entries[right].Timestamp < end {
+		return nil, fmt.Errorf("%w: entry %d has timestamp %d",
+			ErrEntryInRange, right, t.Entries[right].Timestamp)
+	}
+
+	proof := &AbsenceProof{
+		Start: start,
+		End:   end,
+	}
+
+	if left := right - 1; left >= 0 {
+		leftProof, err := t.Tree.GenerateProofByIndex(left)
+		if err != nil {
+			return nil, err
+		}
+		proof.Left = leftProof
+		proof.LeftEntry = t.Entries[left]
+	}
+
+	if right < len(t.Entries) {
+		rightProof, err := t.Tree.GenerateProofByIndex(right)
+		if err != nil {
+			return nil, err
+		}
+		proof.Right = rightProof
+		proof.RightEntry = t.Entries[right]
+	}
+
+	return proof, nil
+}
+
+// VerifyAbsence verifies an absence proof against the tree.
+func (t *IntervalTree) VerifyAbsence(proof *AbsenceProof) (bool, error) {
+	return verifyAbsenceProof(t.Tree.Root.Hash, len(t.Tree.Leaves), proof, &t.Tree.cfg)
+}
+
+// VerifyAbsenceProof verifies that no entry has a timestamp in
+// [proof.Start, proof.End) in an interval tree with size entries
+// and the given root. The options have to match the options of the tree.
+func VerifyAbsenceProof(root []byte, size int, proof *AbsenceProof, newHashFunc func() hash.Hash, opts ...Option) (bool, error) {
+	cfg := newConfig(opts, newHashFunc)
+	return verifyAbsenceProof(root, size, proof, &cfg)
+}
+
+// verifyAbsenceProof verifies an absence proof for a tree
+// hashed as configured by cfg.
+func verifyAbsenceProof(root []byte, size int, proof *AbsenceProof, cfg *config) (bool, error) {
+	leafHashFunc := cfg.hasher.NewLeafHasher()
+	nodeHashFunc := cfg.hasher.NewNodeHasher()
+
+	verifyEntry := func(e Entry, p *Proof) error {
+		leafHashFunc.Reset()
+		leafHashFunc.Write(EncodeEntry(e))
+		computedRoot, ok := rootFromProofWithConfig(leafHashFunc.Sum(nil), p, size, nodeHashFunc, cfg)
+		if !ok || !bytes.Equal(computedRoot, root) {
+			return fmt.Errorf("%w: entry at index %d is not in the tree",
+				ErrProofVerificationFailed, p.Index)
+		}
+		return nil
+	}
+
+	// The boundary entries must be adjacent leaves on either side of the range,
+	// or the first or last leaf if there is nothing on one side.
+	switch {
+	case proof.Left == nil && proof.Right == nil:
+		return false, fmt.Errorf("%w: no boundary entries", ErrProofVerificationFailed)
+	case proof.Left == nil && proof.Right.Index != 0:
+		return false, fmt.Errorf("%w: right boundary is not the first entry", ErrProofVerificationFailed)
+	case proof.Right == nil && proof.Left.Index != size-1:
+		return false, fmt.Errorf("%w: left boundary is not the last entry", ErrProofVerificationFailed)
+	case proof.Left != nil && proof.Right != nil && proof.Right.Index != proof.Left.Index+1:
+		return false, fmt.Errorf("%w: boundary entries are not adjacent", ErrProofVerificationFailed)
+	}
+
+	if proof.Left != nil {
+		if proof.LeftEntry.Timestamp >= proof.Start {
+			return false, fmt.Errorf("%w: left boundary has timestamp %d within range",
+				ErrProofVerificationFailed, proof.LeftEntry.Timestamp)
+		}
+		if err := verifyEntry(proof.LeftEntry, proof.Left); err != nil {
+			return false, err
+		}
+	}
+
+	if proof.Right != nil {
+		if proof.RightEntry.Timestamp < proof.End {
+			return false, fmt.Errorf("%w: right boundary has timestamp %d within range",
+				ErrProofVerificationFailed, proof.RightEntry.Timestamp)
+		}
+		if err := verifyEntry(proof.RightEntry, proof.Right); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,165 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntervalTreeProveAbsence(t *testing.T) {
+	t.Parallel()
+
+	entries := []Entry{
+		{Timestamp: 10, Data: []byte("a")},
+		{Timestamp: 20, Data: []byte("b")},
+		{Timestamp: 20, Data: []byte("c")},
+		{Timestamp: 35, Data: []byte("d")},
+		{Timestamp: 50, Data: []byte("e")},
+	}
+
+	tests := []struct {
+		name  string
+		start uint64
+		end   uint64
+		err   error
+	}{
+		{
+			name:  "Gap between entries",
+			start: 21,
+			end:   35,
+		},
+		{
+			name:  "Before first entry",
+			start: 0,
+			end:   10,
+		},
+		{
+			name:  "After last entry",
+			start: 51,
+			end:   100,
+		},
+		{
+			name:  "Empty range",
+			start: 20,
+			end:   20,
+		},
+		{
+			name:  "Range with entry",
+			start: 30,
+			end:   40,
+			err:   ErrEntryInRange,
+		},
+		{
+			name:  "Range starting at entry",
+			start: 35,
+			end:   36,
+			err:   ErrEntryInRange,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := NewIntervalTree(entries, sha256.New)
+			require.NoError(t, err)
+
+			proof, err := tree.ProveAbsence(tc.start, tc.end)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+
+			isValid, err := tree.VerifyAbsence(proof)
+			require.NoError(t, err)
+			assert.True(t, isValid)
+		})
+	}
+}
+
+func TestVerifyAbsenceProofRejectsForgery(t *testing.T) {
+	t.Parallel()
+
+	entries := []Entry{
+		{Timestamp: 10, Data: []byte("a")},
+		{Timestamp: 20, Data: []byte("b")},
+		{Timestamp: 30, Data: []byte("c")},
+	}
+	tree, err := NewIntervalTree(entries, sha256.New)
+	require.NoError(t, err)
+
+	// Claim the gap (10, 30) is empty by skipping the entry at 20.
+	left, err := tree.Tree.GenerateProofByIndex(0)
+	require.NoError(t, err)
+	right, err := tree.Tree.GenerateProofByIndex(2)
+	require.NoError(t, err)
+
+	forged := &AbsenceProof{
+		Start:      11,
+		End:        30,
+		Left:       left,
+		LeftEntry:  entries[0],
+		Right:      right,
+		RightEntry: entries[2],
+	}
+	isValid, err := tree.VerifyAbsence(forged)
+	require.ErrorIs(t, err, ErrProofVerificationFailed)
+	assert.False(t, isValid)
+
+	// Claim there is nothing after 25 by using a leaf that isn't the last.
+	proof, err := tree.ProveAbsence(11, 20)
+	require.NoError(t, err)
+	proof.Start, proof.End, proof.Right = 25, 100, nil
+	isValid, err = tree.VerifyAbsence(proof)
+	require.ErrorIs(t, err, ErrProofVerificationFailed)
+	assert.False(t, isValid)
+
+	// Tampered boundary entry.
+	proof, err = tree.ProveAbsence(21, 30)
+	require.NoError(t, err)
+	proof.LeftEntry.Data = []byte("x")
+	isValid, err = tree.VerifyAbsence(proof)
+	require.ErrorIs(t, err, ErrProofVerificationFailed)
+	assert.False(t, isValid)
+}
+
+func TestVerifyAbsenceProofWithOptions(t *testing.T) {
+	t.Parallel()
+
+	entries := []Entry{
+		{Timestamp: 10, Data: []byte("a")},
+		{Timestamp: 20, Data: []byte("b")},
+		{Timestamp: 30, Data: []byte("c")},
+	}
+	opts := []Option{WithLevelTags(LevelIndexTag), WithDomainPrefixes([]byte{0}, []byte{1})}
+	tree, err := NewIntervalTree(entries, sha256.New, opts...)
+	require.NoError(t, err)
+
+	proof, err := tree.ProveAbsence(21, 30)
+	require.NoError(t, err)
+
+	isValid, err := tree.VerifyAbsence(proof)
+	require.NoError(t, err)
+	assert.True(t, isValid)
+
+	isValid, err = VerifyAbsenceProof(tree.Tree.Root.Hash, len(entries), proof, sha256.New, opts...)
+	require.NoError(t, err)
+	assert.True(t, isValid)
+
+	isValid, err = VerifyAbsenceProof(tree.Tree.Root.Hash, len(entries), proof, sha256.New)
+	require.ErrorIs(t, err, ErrProofVerificationFailed)
+	assert.False(t, isValid)
+}
+
+func TestNewIntervalTreeUnsorted(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewIntervalTree([]Entry{{Timestamp: 2}, {Timestamp: 1}}, sha256.New)
+	require.ErrorIs(t, err, ErrUnsortedEntries)
+
+	_, err = NewIntervalTree(nil, sha256.New)
+	require.ErrorIs(t, err, ErrNoLeaves)
+}
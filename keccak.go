@@ -0,0 +1,14 @@
+package merkle
+
+import (
+	"hash"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// NewKeccak256 returns a new Keccak-256 hash as used by Ethereum.
+// It uses the original Keccak padding, so it differs from SHA3-256.
+// Pass it as the hash function of a tree to build keccak256 trees.
+func NewKeccak256() hash.Hash {
+	return sha3.NewLegacyKeccak256()
+}
@@ -0,0 +1,101 @@
+package merkle
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeccak256Root(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		values  [][]byte
+		expRoot string
+	}{
+		{
+			name:    "Single leaf",
+			values:  [][]byte{[]byte("a")},
+			expRoot: "3ac225168df54212a25c1c01fd35bebfea408fdac2e31ddd6f80a4bbf9a5f1cb",
+		},
+		{
+			name:    "Two leaves",
+			values:  [][]byte{[]byte("a"), []byte("b")},
+			expRoot: "805b21d846b189efaeb0377d6bb0d201b3872a363e607c25088f025b0c6ae1f8",
+		},
+		{
+			name:    "Three leaves",
+			values:  [][]byte{[]byte("a"), []byte("b"), []byte("c")},
+			expRoot: "aff1208e69c9e8be9b584b07ebac4e48a1ee9d15ce3afe20b77a4d29e4175aa3",
+		},
+		{
+			name:    "Five leaves",
+			values:  [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e")},
+			expRoot: "1dd0d2a6ae466d665cb26e1a31f07c57ae5df7d2bc559cd5826d417be9141a5d",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := NewTree(tc.values, NewKeccak256)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expRoot, hex.EncodeToString(tree.Root.Hash))
+		})
+	}
+}
+
+func TestKeccak256Proof(t *testing.T) {
+	t.Parallel()
+
+	values := [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e")}
+
+	tests := []struct {
+		name      string
+		index     int
+		expHashes []string
+	}{
+		{
+			name:  "Middle leaf",
+			index: 2,
+			expHashes: []string{
+				"f1918e8562236eb17adc8502332f4c9c82bc14e19bfc0aa10ab674ff75b3d2f3",
+				"805b21d846b189efaeb0377d6bb0d201b3872a363e607c25088f025b0c6ae1f8",
+				"a8982c89d80987fb9a510e25981ee9170206be21af3c8e0eb312ef1d3382e761",
+			},
+		},
+		{
+			name:  "Promoted leaf",
+			index: 4,
+			expHashes: []string{
+				"68203f90e9d07dc5859259d7536e87a6ba9d345f2552b5b9de2999ddce9ce1bf",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := NewTree(values, NewKeccak256)
+			require.NoError(t, err)
+
+			proof, err := tree.GenerateProofByIndex(tc.index)
+			require.NoError(t, err)
+
+			hashes := make([]string, len(proof.Hashes))
+			for i, hash := range proof.Hashes {
+				hashes[i] = hex.EncodeToString(hash)
+			}
+			assert.Equal(t, tc.expHashes, hashes)
+
+			isValid, err := tree.VerifyProof(proof, values[tc.index])
+			require.NoError(t, err)
+			assert.True(t, isValid)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package merkle
+
+import (
+	"errors"
+	"fmt"
+	"hash"
+)
+
+var ErrDuplicateKey = errors.New("duplicate key")
+
+// NewKeyedTree creates a new Merkle tree from the given values, where
+// the leaf with values[i] is identified by keys[i]. An empty key leaves
+// a leaf without a key. Keys are not hashed, so they don't change the
+// root, but leaves can be proven, updated and removed by key.
+func NewKeyedTree(keys []string, values [][]byte, newHashFunc func() hash.Hash, opts ...Option) (*Tree, error) {
+	if len(keys) != len(values) {
+		return nil, fmt.Errorf("got %d keys for %d values", len(keys), len(values))
+	}
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("%w: %q", ErrDuplicateKey, key)
+		}
+		seen[key] = true
+	}
+
+	tree, err := NewTree(values, newHashFunc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	for i, key := range keys {
+		tree.Leaves[i].Key = key
+	}
+	tree.buildKeys()
+	return tree, nil
+}
+
+// buildKeys indexes the leaves by their key.
+func (t *Tree) buildKeys() {
+	t.keys = make(map[string]int)
+	for i, leaf := range t.Leaves {
+		if leaf != nil && leaf.Key != "" {
+			t.keys[leaf.Key] = i
+		}
+	}
+}
+
+// IndexOfKey returns the index of the leaf with the given key.
+// It returns false if no leaf has the key.
+func (t *Tree) IndexOfKey(key string) (int, bool) {
+	index, ok := t.keys[key]
+	return index, ok
+}
+
+// GenerateProofByKey generates an inclusion proof for the leaf
+// with the given key. It returns ErrNoKey if no leaf has the key.
+func (t *Tree) GenerateProofByKey(key string) (*Proof, error) {
+	index, ok := t.keys[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrNoKey, key)
+	}
+	return t.GenerateProofByIndex(index)
+}
+
+// UpdateByKey updates the value of the leaf with the given key
+// and recalculates the tree.
+func (t *Tree) UpdateByKey(key string, value []byte) error {
+	index, ok := t.keys[key]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrNoKey, key)
+	}
+	return t.UpdateLeaf(index, value)
+}
+
+// RemoveByKey removes the leaf with the given key and recalculates the tree.
+func (t *Tree) RemoveByKey(key string) error {
+	index, ok := t.keys[key]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrNoKey, key)
+	}
+	return t.RemoveLeaf(index)
+}
@@ -0,0 +1,118 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewKeyedTree(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		keys []string
+		err  error
+	}{
+		{
+			name: "Unique keys",
+			keys: []string{"a", "b", "c"},
+		},
+		{
+			name: "Leaves without keys",
+			keys: []string{"a", "", ""},
+		},
+		{
+			name: "Duplicate keys",
+			keys: []string{"a", "b", "a"},
+			err:  ErrDuplicateKey,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := generateDummyData(len(tc.keys))
+			tree, err := NewKeyedTree(tc.keys, data, sha256.New)
+			require.ErrorIs(t, err, tc.err)
+			if tc.err != nil {
+				return
+			}
+
+			// Keys don't change the root.
+			expTree, err := NewTree(data, sha256.New)
+			require.NoError(t, err)
+			assert.Equal(t, expTree.Root.Hash, tree.Root.Hash)
+
+			for i, key := range tc.keys {
+				if key == "" {
+					continue
+				}
+				index, found := tree.IndexOfKey(key)
+				assert.True(t, found)
+				assert.Equal(t, i, index)
+			}
+			_, found := tree.IndexOfKey("")
+			assert.False(t, found)
+		})
+	}
+
+	_, err := NewKeyedTree([]string{"a"}, generateDummyData(2), sha256.New)
+	require.Error(t, err)
+}
+
+func TestKeyedTreeMutations(t *testing.T) {
+	t.Parallel()
+
+	data := generateDummyData(5)
+	tree, err := NewKeyedTree([]string{"a", "b", "c", "d", "e"}, data, sha256.New)
+	require.NoError(t, err)
+
+	proof, err := tree.GenerateProofByKey("c")
+	require.NoError(t, err)
+	isValid, err := tree.VerifyProof(proof, data[2])
+	require.NoError(t, err)
+	assert.True(t, isValid)
+
+	require.NoError(t, tree.UpdateByKey("d", []byte("new")))
+	proof, err = tree.GenerateProofByKey("d")
+	require.NoError(t, err)
+	isValid, err = tree.VerifyProof(proof, []byte("new"))
+	require.NoError(t, err)
+	assert.True(t, isValid)
+
+	// Removing a leaf moves the keys of the following leaves.
+	require.NoError(t, tree.RemoveByKey("b"))
+	index, found := tree.IndexOfKey("e")
+	assert.True(t, found)
+	assert.Equal(t, 3, index)
+	_, found = tree.IndexOfKey("b")
+	assert.False(t, found)
+
+	// Appended leaves have no keys and don't move other keys.
+	require.NoError(t, tree.AppendLeaf([]byte("f")))
+	index, found = tree.IndexOfKey("e")
+	assert.True(t, found)
+	assert.Equal(t, 3, index)
+
+	require.ErrorIs(t, tree.UpdateByKey("b", []byte("x")), ErrNoKey)
+	require.ErrorIs(t, tree.RemoveByKey("b"), ErrNoKey)
+	_, err = tree.GenerateProofByKey("b")
+	require.ErrorIs(t, err, ErrNoKey)
+}
+
+func TestKeyedTreePrune(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewKeyedTree([]string{"a", "b", "c", "d"}, generateDummyData(4), sha256.New)
+	require.NoError(t, err)
+	require.NoError(t, tree.Prune([]int{1}))
+
+	_, err = tree.GenerateProofByKey("b")
+	require.NoError(t, err)
+	_, err = tree.GenerateProofByKey("c")
+	require.ErrorIs(t, err, ErrNoKey)
+}
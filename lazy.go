@@ -0,0 +1,141 @@
+package merkle
+
+import (
+	"bytes"
+	"context"
+	"hash"
+	"slices"
+)
+
+// LazyTree is a Merkle tree whose root is known as soon as the leaves
+// have been hashed, while the nodes needed for proofs are built in a
+// background goroutine. Callers that publish the root first don't wait
+// for the whole tree to be allocated and linked.
+type LazyTree struct {
+	root []byte
+	done chan struct{}
+	tree *Tree
+}
+
+// NewLazyTree computes the root of a tree over values like NewTree and
+// returns once the root is known. The hashes of every level are kept,
+// and the nodes on top of them are linked in the background, so every
+// leaf and node is only hashed once.
+func NewLazyTree(values [][]byte, newHashFunc func() hash.Hash, opts ...Option) (*LazyTree, error) {
+	cfg := newConfig(opts, newHashFunc)
+	if len(values) == 0 && !cfg.allowEmpty {
+		return nil, ErrNoLeaves
+	}
+	values, err := cfg.leafValues(values)
+	if err != nil {
+		return nil, err
+	}
+
+	leafHashes, err := cfg.parallelism.preHashLeavesContext(context.Background(), values, cfg.hasher.NewLeafHasher)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &LazyTree{done: make(chan struct{})}
+	if len(leafHashes) == 0 {
+		t.root = cfg.hasher.NewNodeHasher().Sum(nil)
+		go func() {
+			defer close(t.done)
+			t.tree = newTree(leafHashes, values, newHashFunc, cfg)
+		}()
+		return t, nil
+	}
+
+	levels := levelHashes(leafHashes, &cfg)
+	t.root = bytes.Clone(levels[len(levels)-1][0])
+	go func() {
+		defer close(t.done)
+		t.tree = treeFromLevels(levels, values, newHashFunc, cfg)
+	}()
+	return t, nil
+}
+
+// levelHashes hashes the levels above leafHashes, from the leaves up to
+// the root. A node without a sibling is carried up without hashing,
+// so its hash is on every level it is carried through.
+func levelHashes(leafHashes [][]byte, cfg *config) [][][]byte {
+	levels := [][][]byte{leafHashes}
+	for level, hashes := 1, leafHashes; len(hashes) > 1; level++ {
+		parents := make([][]byte, (len(hashes)+1)/2)
+		cfg.parallelism.batches(len(hashes)/2, func(start, end int) {
+			hashFunc := cfg.hasher.NewNodeHasher()
+			for i := start; i < end; i++ {
+				parents[i] = combineLevelHashes(level, hashes[2*i], hashes[2*i+1], hashFunc, cfg)
+			}
+		})
+		if len(hashes)%2 == 1 {
+			// Carry the last node up if it doesn't have a sibling.
+			parents[len(parents)-1] = hashes[len(hashes)-1]
+		}
+		levels = append(levels, parents)
+		hashes = parents
+	}
+	return levels
+}
+
+// treeFromLevels links the nodes of a tree over values
+// with the hashes from levelHashes, without hashing.
+func treeFromLevels(levels [][][]byte, values [][]byte, newHashFunc func() hash.Hash, cfg config) *Tree {
+	leaves := make([]*Node, len(levels[0]))
+	for i, hash := range levels[0] {
+		leaves[i] = NewNode(hash, values[i])
+	}
+
+	nodes := slices.Clone(leaves)
+	for _, hashes := range levels[1:] {
+		numParents := len(nodes) / 2
+		for i := range numParents {
+			left, right := nodes[2*i], nodes[2*i+1]
+			parent := &Node{Hash: hashes[i], Left: left, Right: right}
+			left.Parent = parent
+			right.Parent = parent
+			nodes[i] = parent
+		}
+		if len(nodes)%2 == 1 {
+			nodes[numParents] = nodes[len(nodes)-1]
+		}
+		nodes = nodes[:len(hashes)]
+	}
+
+	tree := newTreeFromRoot(nodes[0], leaves, cfg.hasher.NewNodeHasher(), newHashFunc, cfg)
+	tree.buildIndex()
+	return tree
+}
+
+// RootHash returns a copy of the root hash of the tree.
+func (t *LazyTree) RootHash() []byte {
+	return bytes.Clone(t.root)
+}
+
+// Done returns a channel that is closed once the tree has been built.
+func (t *LazyTree) Done() <-chan struct{} {
+	return t.done
+}
+
+// Tree returns the built tree, waiting for it if needed.
+func (t *LazyTree) Tree() *Tree {
+	<-t.done
+	return t.tree
+}
+
+// TreeContext returns the built tree like Tree, but stops waiting
+// and returns the context error once ctx is done.
+func (t *LazyTree) TreeContext(ctx context.Context) (*Tree, error) {
+	select {
+	case <-t.done:
+		return t.tree, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// GenerateProofByIndex generates an inclusion proof for the leaf
+// at index, waiting for the tree to be built if needed.
+func (t *LazyTree) GenerateProofByIndex(index int) (*Proof, error) {
+	return t.Tree().GenerateProofByIndex(index)
+}
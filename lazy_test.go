@@ -0,0 +1,125 @@
+package merkle
+
+import (
+	"context"
+	"crypto/sha256"
+	"hash"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLazyTree(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		size int
+		opts []Option
+	}{
+		{
+			name: "Single leaf",
+			size: 1,
+		},
+		{
+			name: "Odd size",
+			size: 13,
+		},
+		{
+			name: "Several blocks",
+			size: 2*cacheBlock + 3,
+		},
+		{
+			name: "Domain prefixes",
+			size: 13,
+			opts: []Option{WithDomainPrefixes([]byte{0}, []byte{1})},
+		},
+		{
+			name: "Level tags",
+			size: 13,
+			opts: []Option{WithLevelTags(LevelIndexTag)},
+		},
+		{
+			name: "Empty tree",
+			size: 0,
+			opts: []Option{WithEmptyTree()},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := generateDummyData(tc.size)
+			expTree, err := NewTree(data, sha256.New, tc.opts...)
+			require.NoError(t, err)
+
+			lazy, err := NewLazyTree(data, sha256.New, tc.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, expTree.RootHash(), lazy.RootHash())
+
+			tree, err := lazy.TreeContext(context.Background())
+			require.NoError(t, err)
+			<-lazy.Done()
+			assert.Same(t, tree, lazy.Tree())
+			assert.Equal(t, expTree.RootHash(), tree.RootHash())
+			require.NoError(t, tree.Validate())
+
+			if tc.size > 0 {
+				proof, err := lazy.GenerateProofByIndex(tc.size / 2)
+				require.NoError(t, err)
+				ok, err := tree.VerifyProof(proof, data[tc.size/2])
+				require.NoError(t, err)
+				assert.True(t, ok)
+			}
+		})
+	}
+}
+
+// countingHash counts the sums of the hashes it creates.
+type countingHash struct {
+	hash.Hash
+	sums *atomic.Int64
+}
+
+func (h countingHash) Sum(b []byte) []byte {
+	h.sums.Add(1)
+	return h.Hash.Sum(b)
+}
+
+func TestNewLazyTreeHashesNodesOnce(t *testing.T) {
+	t.Parallel()
+
+	var sums atomic.Int64
+	newNodeHash := func() hash.Hash { return countingHash{Hash: sha256.New(), sums: &sums} }
+	opts := []Option{WithHasher(SplitHasher(sha256.New, newNodeHash))}
+
+	lazy, err := NewLazyTree(generateDummyData(13), nil, opts...)
+	require.NoError(t, err)
+	tree := lazy.Tree()
+	require.NotNil(t, tree)
+
+	// A tree with 13 leaves has 12 nodes above them.
+	assert.Equal(t, int64(12), sums.Load())
+}
+
+func TestNewLazyTreeErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewLazyTree(nil, sha256.New)
+	require.ErrorIs(t, err, ErrNoLeaves)
+
+	_, err = NewLazyTree([][]byte{[]byte("short")}, sha256.New, WithFixedLeafSize(32))
+	require.ErrorIs(t, err, ErrInvalidLeafSize)
+
+	lazy, err := NewLazyTree(generateDummyData(4), sha256.New)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := lazy.TreeContext(ctx); err != nil {
+		// The tree may already have been built.
+		assert.ErrorIs(t, err, context.Canceled)
+	}
+}
@@ -0,0 +1,136 @@
+package merkle
+
+import (
+	"iter"
+	"math/bits"
+)
+
+// Levels returns the node hashes of the tree grouped by level.
+// Levels()[0] holds the leaf hashes and the last level holds the root hash.
+// A node without a sibling is carried up without hashing, so it appears
+// on every level until it is paired. Nodes below pruned nodes are skipped.
+func (t *Tree) Levels() [][][]byte {
+	levels := t.levelNodes()
+	hashes := make([][][]byte, len(levels))
+	for i, level := range levels {
+		hashes[i] = make([][]byte, 0, len(level))
+		for _, node := range level {
+			if node != nil {
+				hashes[i] = append(hashes[i], node.Hash)
+			}
+		}
+	}
+	return hashes
+}
+
+// LevelOrder returns the node hashes of the tree in breadth-first order,
+// starting at the root and ending with the leaves.
+func (t *Tree) LevelOrder() [][]byte {
+	levels := t.Levels()
+
+	var size int
+	for _, level := range levels {
+		size += len(level)
+	}
+
+	hashes := make([][]byte, 0, size)
+	for i := len(levels) - 1; i >= 0; i-- {
+		hashes = append(hashes, levels[i]...)
+	}
+	return hashes
+}
+
+// levelNodes returns the nodes of the tree grouped by level, starting
+// with the leaves. Every node is at its index on the level, and the
+// nodes below pruned nodes are nil.
+func (t *Tree) levelNodes() [][]*Node {
+	t.flush()
+	n := len(t.Leaves)
+	if n == 0 {
+		return nil
+	}
+
+	levels := make([][]*Node, treeLevels(n)+1)
+	for l := range levels {
+		levels[l] = make([]*Node, (n-1)>>l+1)
+	}
+	// walk places the node over the size leaves from start on the levels
+	// from its own up to the level below its parent, since it is carried
+	// up until then.
+	var walk func(node *Node, start, size, parentLevel int)
+	walk = func(node *Node, start, size, parentLevel int) {
+		level := treeLevels(size)
+		for l := level; l < parentLevel; l++ {
+			levels[l][start>>l] = node
+		}
+		if size == 1 || node.Left == nil || node.Right == nil {
+			// The node is a leaf or the root of a pruned subtree.
+			return
+		}
+		split := 1 << (bits.Len(uint(size-1)) - 1)
+		walk(node.Left, start, split, level)
+		walk(node.Right, start+split, size-split, level)
+	}
+	walk(t.Root, 0, n, len(levels))
+	return levels
+}
+
+// AllLeaves returns an iterator over the indices and leaves of the tree.
+// Pruned leaves are skipped.
+func (t *Tree) AllLeaves() iter.Seq2[int, *Node] {
+	return func(yield func(int, *Node) bool) {
+		for i, leaf := range t.Leaves {
+			if leaf != nil && !yield(i, leaf) {
+				return
+			}
+		}
+	}
+}
+
+// Nodes returns an iterator over all nodes of the tree in depth-first
+// order, starting at the root. Every node is yielded once.
+func (t *Tree) Nodes() iter.Seq[*Node] {
+	t.flush()
+	return func(yield func(*Node) bool) {
+		if len(t.Leaves) == 0 {
+			return
+		}
+
+		var walk func(node *Node) bool
+		walk = func(node *Node) bool {
+			if node == nil {
+				return true
+			}
+			return yield(node) && walk(node.Left) && walk(node.Right)
+		}
+		walk(t.Root)
+	}
+}
+
+// LevelNodes returns an iterator over the nodes on the given level,
+// from left to right, like Levels does for hashes. Level 0 holds the
+// leaves. Nodes below pruned nodes are skipped.
+func (t *Tree) LevelNodes(level int) iter.Seq[*Node] {
+	t.flush()
+	return func(yield func(*Node) bool) {
+		if len(t.Leaves) == 0 || level < 0 || level > t.Depth() {
+			return
+		}
+
+		// A subtree with size leaves has its largest complete subtree
+		// on the left and the rest on the right. The nodes on the level
+		// are the highest nodes that are not above it.
+		var walk func(node *Node, size int) bool
+		walk = func(node *Node, size int) bool {
+			if node == nil {
+				return true
+			}
+			if treeLevels(size) <= level {
+				return yield(node)
+			}
+			split := 1 << (bits.Len(uint(size-1)) - 1)
+			return walk(node.Left, split) && walk(node.Right, size-split)
+		}
+		walk(t.Root, len(t.Leaves))
+	}
+}
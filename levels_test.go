@@ -0,0 +1,163 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevels(t *testing.T) {
+	t.Parallel()
+
+	hash := func(data ...[]byte) []byte {
+		hashFunc := sha256.New()
+		for _, d := range data {
+			hashFunc.Write(d)
+		}
+		return hashFunc.Sum(nil)
+	}
+	a, b, c := hash([]byte("a")), hash([]byte("b")), hash([]byte("c"))
+	ab := hash(a, b)
+	abc := hash(ab, c)
+
+	tests := []struct {
+		name      string
+		values    [][]byte
+		expLevels [][][]byte
+	}{
+		{
+			name:      "Single leaf",
+			values:    [][]byte{[]byte("a")},
+			expLevels: [][][]byte{{a}},
+		},
+		{
+			name:      "Two leaves",
+			values:    [][]byte{[]byte("a"), []byte("b")},
+			expLevels: [][][]byte{{a, b}, {ab}},
+		},
+		{
+			name:      "Three leaves carries the last leaf up",
+			values:    [][]byte{[]byte("a"), []byte("b"), []byte("c")},
+			expLevels: [][][]byte{{a, b, c}, {ab, c}, {abc}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := NewTree(tc.values, sha256.New)
+			require.NoError(t, err)
+
+			levels := tree.Levels()
+			assert.Equal(t, tc.expLevels, levels)
+			assert.Equal(t, tree.Root.Hash, levels[len(levels)-1][0])
+
+			var expOrder [][]byte
+			for i := len(tc.expLevels) - 1; i >= 0; i-- {
+				expOrder = append(expOrder, tc.expLevels[i]...)
+			}
+			assert.Equal(t, expOrder, tree.LevelOrder())
+		})
+	}
+}
+
+func TestIterators(t *testing.T) {
+	t.Parallel()
+
+	for size := 1; size <= 17; size++ {
+		t.Run(fmt.Sprintf("%d leaves", size), func(t *testing.T) {
+			t.Parallel()
+
+			data := generateDummyData(size)
+			tree, err := NewTree(data, sha256.New)
+			require.NoError(t, err)
+
+			var values [][]byte
+			for i, leaf := range tree.AllLeaves() {
+				assert.Len(t, values, i)
+				values = append(values, leaf.Value)
+			}
+			assert.Equal(t, data, values)
+
+			// A tree has n-1 internal nodes when odd nodes are carried up.
+			var numNodes int
+			for node := range tree.Nodes() {
+				if numNodes == 0 {
+					assert.Same(t, tree.Root, node)
+				}
+				numNodes++
+			}
+			assert.Equal(t, 2*size-1, numNodes)
+
+			levels := tree.Levels()
+			for level := range levels {
+				var hashes [][]byte
+				for node := range tree.LevelNodes(level) {
+					hashes = append(hashes, node.Hash)
+				}
+				assert.Equal(t, levels[level], hashes, "Level %d mismatch", level)
+			}
+			for _, level := range []int{-1, len(levels)} {
+				for range tree.LevelNodes(level) {
+					assert.Fail(t, "No nodes expected on level %d", level)
+				}
+			}
+		})
+	}
+}
+
+func TestIteratorsStopEarly(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewTree(generateDummyData(10), sha256.New)
+	require.NoError(t, err)
+
+	var count int
+	for range tree.Nodes() {
+		count++
+		if count == 3 {
+			break
+		}
+	}
+	assert.Equal(t, 3, count)
+
+	count = 0
+	for range tree.LevelNodes(0) {
+		count++
+		if count == 5 {
+			break
+		}
+	}
+	assert.Equal(t, 5, count)
+}
+
+func TestIteratorsPrunedTree(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewTree(generateDummyData(8), sha256.New)
+	require.NoError(t, err)
+	require.NoError(t, tree.Prune([]int{2}))
+
+	var indices []int
+	for i := range tree.AllLeaves() {
+		indices = append(indices, i)
+	}
+	assert.Equal(t, []int{2}, indices)
+
+	// Only the path to leaf 2 and the siblings on it are left.
+	var numNodes int
+	for range tree.Nodes() {
+		numNodes++
+	}
+	assert.Equal(t, 7, numNodes)
+
+	var numLeaves int
+	for range tree.LevelNodes(0) {
+		numLeaves++
+	}
+	assert.Equal(t, 2, numLeaves)
+}
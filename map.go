@@ -0,0 +1,88 @@
+package merkle
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash"
+	"slices"
+)
+
+var ErrNoKey = errors.New("key not found in the tree")
+
+// MapTree is a Merkle tree over the entries of a map.
+// The entries are ordered by key so the same map always
+// results in the same tree. The tree is only read through
+// MapTree, so the keys always match its leaves.
+type MapTree struct {
+	tree *Tree
+	Keys []string
+
+	index map[string]int
+}
+
+// NewTreeFromMap creates a new Merkle tree from the entries of m.
+// Each entry is encoded with EncodeMapEntry and the leaves are
+// sorted by key. An empty map is only allowed with WithEmptyTree.
+func NewTreeFromMap(m map[string][]byte, newHashFunc func() hash.Hash, opts ...Option) (*MapTree, error) {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	values := make([][]byte, len(keys))
+	index := make(map[string]int, len(keys))
+	for i, key := range keys {
+		values[i] = EncodeMapEntry(key, m[key])
+		index[key] = i
+	}
+
+	tree, err := NewTree(values, newHashFunc, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MapTree{
+		tree:  tree,
+		Keys:  keys,
+		index: index,
+	}, nil
+}
+
+// EncodeMapEntry encodes a key/value pair into a leaf value.
+// Both the key and the value are prefixed with their length
+// as a big endian uint64, so different entries never encode
+// to the same bytes.
+func EncodeMapEntry(key string, value []byte) []byte {
+	buf := make([]byte, 0, 16+len(key)+len(value))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(len(key)))
+	buf = append(buf, key...)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(len(value)))
+	buf = append(buf, value...)
+	return buf
+}
+
+// Len returns the number of entries in the tree.
+func (t *MapTree) Len() int {
+	return t.tree.Len()
+}
+
+// RootHash returns a copy of the root hash of the tree.
+func (t *MapTree) RootHash() []byte {
+	return t.tree.RootHash()
+}
+
+// ProveKey generates an inclusion proof for the entry with the given key.
+func (t *MapTree) ProveKey(key string) (*Proof, error) {
+	i, ok := t.index[key]
+	if !ok {
+		return nil, ErrNoKey
+	}
+	return t.tree.GenerateProofByIndex(i)
+}
+
+// VerifyKey verifies that the entry with the given key and value
+// is part of the tree.
+func (t *MapTree) VerifyKey(key string, value []byte, proof *Proof) (bool, error) {
+	return t.tree.VerifyProof(proof, EncodeMapEntry(key, value))
+}
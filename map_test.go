@@ -0,0 +1,117 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTreeFromMap(t *testing.T) {
+	t.Parallel()
+
+	m := map[string][]byte{
+		"charlie": []byte("3"),
+		"alpha":   []byte("1"),
+		"bravo":   []byte("2"),
+	}
+
+	tree, err := NewTreeFromMap(m, sha256.New)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alpha", "bravo", "charlie"}, tree.Keys)
+
+	// The root only depends on the map contents.
+	expTree, err := NewTree([][]byte{
+		EncodeMapEntry("alpha", []byte("1")),
+		EncodeMapEntry("bravo", []byte("2")),
+		EncodeMapEntry("charlie", []byte("3")),
+	}, sha256.New)
+	require.NoError(t, err)
+	assert.Equal(t, expTree.Root.Hash, tree.RootHash(), "Tree root mismatch")
+	assert.Equal(t, 3, tree.Len())
+
+	_, err = NewTreeFromMap(map[string][]byte{}, sha256.New)
+	require.ErrorIs(t, err, ErrNoLeaves)
+
+	empty, err := NewTreeFromMap(map[string][]byte{}, sha256.New, WithEmptyTree())
+	require.NoError(t, err)
+	assert.Zero(t, empty.Len())
+	assert.Empty(t, empty.Keys)
+	emptyRoot := sha256.Sum256(nil)
+	assert.Equal(t, emptyRoot[:], empty.RootHash())
+}
+
+func TestEncodeMapEntry(t *testing.T) {
+	t.Parallel()
+
+	// Moving bytes between key and value must change the encoding.
+	assert.NotEqual(t, EncodeMapEntry("ab", []byte("c")), EncodeMapEntry("a", []byte("bc")))
+	assert.Equal(t,
+		[]byte{0, 0, 0, 0, 0, 0, 0, 1, 'k', 0, 0, 0, 0, 0, 0, 0, 2, 'v', '1'},
+		EncodeMapEntry("k", []byte("v1")),
+	)
+}
+
+func TestProveKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		key     string
+		value   []byte
+		err     error
+		isValid bool
+	}{
+		{
+			name:    "Existing key",
+			key:     "bravo",
+			value:   []byte("2"),
+			isValid: true,
+		},
+		{
+			name:    "Last key",
+			key:     "charlie",
+			value:   []byte("3"),
+			isValid: true,
+		},
+		{
+			name:  "Wrong value",
+			key:   "bravo",
+			value: []byte("3"),
+			err:   ErrProofVerificationFailed,
+		},
+		{
+			name: "Missing key",
+			key:  "delta",
+			err:  ErrNoKey,
+		},
+	}
+
+	m := map[string][]byte{
+		"alpha":   []byte("1"),
+		"bravo":   []byte("2"),
+		"charlie": []byte("3"),
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := NewTreeFromMap(m, sha256.New)
+			require.NoError(t, err)
+
+			proof, err := tree.ProveKey(tc.key)
+			if errors.Is(tc.err, ErrNoKey) {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+
+			isValid, err := tree.VerifyKey(tc.key, tc.value, proof)
+			require.ErrorIs(t, err, tc.err)
+			assert.Equal(t, tc.isValid, isValid)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package merkle
+
+import "unsafe"
+
+// mapEntryOverhead approximates the memory a map uses per entry on top of
+// its keys and values, for its buckets and unused slots.
+const mapEntryOverhead = 16
+
+// MemoryEstimate is the approximate memory used by a tree in bytes.
+// It counts the data the tree holds, not the allocator's rounding.
+type MemoryEstimate struct {
+	// Nodes is the memory of the nodes and the lists of them.
+	Nodes int64
+	// Hashes is the memory of the node hashes. Hashes shared by
+	// WithDeduplication are counted once.
+	Hashes int64
+	// Values is the memory of the leaf values the tree retains,
+	// which may be shared with the caller.
+	Values int64
+	// Index is the memory of the maps that look up leaves
+	// by hash and key.
+	Index int64
+}
+
+// Total returns the memory of all parts of the tree.
+func (m MemoryEstimate) Total() int64 {
+	return m.Nodes + m.Hashes + m.Values + m.Index
+}
+
+// EstimateMemory returns the approximate memory used by the tree,
+// e.g. to plan capacity or to compare it with a FlatTree or a tree
+// without leaf values. It walks every node of the tree.
+func (t *Tree) EstimateMemory() MemoryEstimate {
+	var m MemoryEstimate
+	m.Nodes = int64(cap(t.Leaves))*int64(unsafe.Sizeof((*Node)(nil))) +
+		int64(cap(t.free))*int64(unsafe.Sizeof(Node{}))
+
+	// Shared slices are only counted once. Without deduplication,
+	// every node has its own hash and value.
+	var seen map[*byte]bool
+	if t.cfg.dedupe {
+		seen = make(map[*byte]bool)
+	}
+	size := func(b []byte) int64 {
+		if seen != nil && len(b) > 0 {
+			if seen[unsafe.SliceData(b)] {
+				return 0
+			}
+			seen[unsafe.SliceData(b)] = true
+		}
+		return int64(len(b))
+	}
+
+	stack := []*Node{t.Root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if node == nil {
+			continue
+		}
+		m.Nodes += int64(unsafe.Sizeof(*node)) + int64(len(node.Key))
+		m.Hashes += size(node.Hash)
+		m.Values += size(node.Value)
+		stack = append(stack, node.Left, node.Right)
+	}
+
+	for key := range t.index {
+		m.Index += int64(len(key)) + int64(unsafe.Sizeof(key)+unsafe.Sizeof(leafRef{})) + mapEntryOverhead
+	}
+	for key := range t.keys {
+		m.Index += int64(len(key)) + int64(unsafe.Sizeof(key)+unsafe.Sizeof(0)) + mapEntryOverhead
+	}
+	return m
+}
+
+// EstimateMemory returns the approximate memory used by the tree like
+// Tree.EstimateMemory. A FlatTree has no nodes, values or index.
+func (t *FlatTree) EstimateMemory() MemoryEstimate {
+	return MemoryEstimate{
+		Hashes: int64(len(t.hashes) + len(t.root) + len(t.emptyRoot)),
+	}
+}
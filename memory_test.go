@@ -0,0 +1,81 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateMemory(t *testing.T) {
+	t.Parallel()
+
+	const size = 13
+	data := generateDummyData(size)
+	valueBytes := int64(0)
+	for _, value := range data {
+		valueBytes += int64(len(value))
+	}
+	// A tree with n leaves has 2n-1 nodes.
+	numNodes := int64(2*size - 1)
+
+	tests := []struct {
+		name      string
+		opts      []Option
+		expValues int64
+		expIndex  bool
+	}{
+		{
+			name:      "Leaf values",
+			expValues: valueBytes,
+			expIndex:  true,
+		},
+		{
+			name: "Without leaf values",
+			opts: []Option{WithoutLeafValues(nil)},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := NewTree(data, sha256.New, tc.opts...)
+			require.NoError(t, err)
+
+			m := tree.EstimateMemory()
+			assert.GreaterOrEqual(t, m.Nodes, numNodes*int64(unsafe.Sizeof(Node{})))
+			assert.Equal(t, numNodes*sha256.Size, m.Hashes)
+			assert.Equal(t, tc.expValues, m.Values)
+			assert.Equal(t, tc.expIndex, m.Index > 0)
+			assert.Equal(t, m.Nodes+m.Hashes+m.Values+m.Index, m.Total())
+
+			flat, err := NewFlatTree(data, sha256.New)
+			require.NoError(t, err)
+			// The flat layout also stores the nodes that are carried up.
+			flatNodes := flatOffsets(size)[flat.levels()]
+			assert.Equal(t, MemoryEstimate{Hashes: int64(flatNodes) * sha256.Size}, flat.EstimateMemory())
+		})
+	}
+}
+
+func TestEstimateMemoryDeduplication(t *testing.T) {
+	t.Parallel()
+
+	data := make([][]byte, 8)
+	for i := range data {
+		data[i] = []byte{byte(i % 2)}
+	}
+	tree, err := NewTree(data, sha256.New)
+	require.NoError(t, err)
+	deduped, err := NewTree(data, sha256.New, WithDeduplication())
+	require.NoError(t, err)
+
+	// The leaves hold two distinct hashes, and every level above them one.
+	m := deduped.EstimateMemory()
+	assert.Equal(t, int64(2+1+1+1)*sha256.Size, m.Hashes)
+	assert.Equal(t, int64(2), m.Values)
+	assert.Equal(t, tree.EstimateMemory().Nodes, m.Nodes)
+}
@@ -2,13 +2,17 @@ package merkle
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"hash"
+	"maps"
+	"math/bits"
 	"runtime"
 	"slices"
 	"strings"
+	"sync/atomic"
 
 	"golang.org/x/sync/errgroup"
 )
@@ -27,207 +31,806 @@ type Node struct {
 	Parent *Node
 	Hash   []byte
 	Value  []byte
+
+	// Metadata is attached to leaves by SetMetadata.
+	// It is not hashed.
+	Metadata map[string]any
+
+	// Key identifies a leaf of a tree created with NewKeyedTree.
+	// It is not hashed.
+	Key string
 }
 
 func NewNode(hash, val []byte) *Node {
 	return &Node{Hash: hash, Value: val}
 }
 
-// Tree represents a Merkle tree
+// Tree represents a Merkle tree.
+// Prefer the accessor methods like Len, Leaf and RootNode over the
+// exported fields, which may change with the internal representation.
 type Tree struct {
 	Root     *Node
 	HashFunc hash.Hash
 	Leaves   []*Node
+
+	leafHashFunc hash.Hash
+	newHashFunc  func() hash.Hash
+	cfg          config
+
+	// hashedLeaves is set if the tree was built from leaf hashes
+	// or drops leaf values, so the leaves don't hold their values.
+	hashedLeaves bool
+
+	// index maps leaf hashes to leaves, if the leaves hold their values.
+	index map[string]leafRef
+
+	// keys maps leaf keys to leaf indices, if the tree is keyed.
+	keys map[string]int
+
+	// free holds preallocated nodes, if the tree has a capacity.
+	free []Node
+
+	// subtreeRoots caches the hashes of ranges of leaves that aren't
+	// nodes of the tree, if enabled with WithSubtreeCache.
+	subtreeRoots *subtreeCache
+
+	// dirty holds the nodes whose hashes are stale by level,
+	// if hashing is deferred.
+	dirty     [][]*Node
+	dirtySeen map[*Node]bool
+
+	// history holds the roots of the tree, if enabled with WithRootHistory.
+	history []RootRecord
+
+	// generation counts the changes of the root, so transactions can
+	// tell if the tree changed since they began.
+	generation uint64
+
+	// lastRoot is the root hash the subscribers were last notified of.
+	lastRoot       []byte
+	subscribers    []rootSubscriber
+	nextSubscriber int
 }
 
 // NewTree creates a new Merkle tree from the given values and hash function.
-func NewTree(values [][]byte, newHashFunc func() hash.Hash) (*Tree, error) {
-	if len(values) == 0 {
+func NewTree(values [][]byte, newHashFunc func() hash.Hash, opts ...Option) (*Tree, error) {
+	return NewTreeContext(context.Background(), values, newHashFunc, opts...)
+}
+
+// NewTreeContext creates a new Merkle tree like NewTree, but stops
+// hashing and returns the context error once ctx is done.
+func NewTreeContext(ctx context.Context, values [][]byte, newHashFunc func() hash.Hash, opts ...Option) (*Tree, error) {
+	cfg := newConfig(opts, newHashFunc)
+	if len(values) == 0 && !cfg.allowEmpty {
 		return nil, ErrNoLeaves
 	}
+	values, err := cfg.leafValues(values)
+	if err != nil {
+		return nil, err
+	}
 
-	preHashedLeaves := preHashLeaves(values, newHashFunc)
+	buildCfg := cfg
+	buildCfg.parallelism = cfg.parallelism.forValues(values)
+	chunkSize := buildCfg.parallelism.pipelineChunk(len(values))
+	if cfg.buildProfile != nil || cfg.buildProgress != nil {
+		numChunks := max(1, (len(values)+chunkSize-1)/chunkSize)
+		buildCfg.monitor = newBuildMonitor(len(values), buildCfg.parallelism.numWorkers(numChunks), cfg.buildProgress)
+	}
+	leaves, root, err := buildTreePipelined(ctx, values, chunkSize, &buildCfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.buildProfile != nil {
+		cfg.buildProfile(buildCfg.monitor.finish())
+	}
 
+	tree := newTreeFromRoot(root, leaves, cfg.hasher.NewNodeHasher(), newHashFunc, cfg)
+	tree.buildIndex()
+	return tree, nil
+}
+
+// NewTreeFromHashes creates a new Merkle tree from already hashed leaves.
+// The leaves are used as-is, so no leaf values are retained in the tree.
+func NewTreeFromHashes(hashes [][]byte, newHashFunc func() hash.Hash, opts ...Option) (*Tree, error) {
+	cfg := newConfig(opts, newHashFunc)
+	if len(hashes) == 0 && !cfg.allowEmpty {
+		return nil, ErrNoLeaves
+	}
+	if err := cfg.checkLeafSizes(hashes); err != nil {
+		return nil, err
+	}
+
+	leafHashes := make([][]byte, len(hashes))
+	for i, hash := range hashes {
+		leafHashes[i] = bytes.Clone(hash)
+	}
+
+	return newTree(leafHashes, nil, newHashFunc, cfg), nil
+}
+
+// newTree builds the tree on top of the given leaf hashes.
+// values is either nil or holds the value of each leaf.
+func newTree(leafHashes, values [][]byte, newHashFunc func() hash.Hash, cfg config) *Tree {
+	tree, _ := newTreeContext(context.Background(), leafHashes, values, newHashFunc, cfg)
+	return tree
+}
+
+// newTreeContext builds the tree like newTree until ctx is done.
+func newTreeContext(ctx context.Context, leafHashes, values [][]byte, newHashFunc func() hash.Hash, cfg config) (*Tree, error) {
 	// Convert leaves into Nodes
-	nodes := make([]*Node, len(preHashedLeaves))
-	for i, hash := range preHashedLeaves {
-		node := NewNode(hash, values[i])
-		nodes[i] = node
+	nodes := make([]*Node, len(leafHashes))
+	for i, hash := range leafHashes {
+		var val []byte
+		if values != nil {
+			val = values[i]
+		}
+		nodes[i] = NewNode(hash, val)
+	}
+
+	tree, err := newTreeFromNodesContext(ctx, nodes, newHashFunc, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if values == nil {
+		tree.hashedLeaves = true
 	}
+	tree.buildIndex()
+	return tree, nil
+}
 
-	hashFunc := newHashFunc()
+// newTreeFromNodes builds the tree on top of the given leaf nodes.
+func newTreeFromNodes(nodes []*Node, newHashFunc func() hash.Hash, cfg config) *Tree {
+	tree, _ := newTreeFromNodesContext(context.Background(), nodes, newHashFunc, cfg)
+	return tree
+}
+
+// newTreeFromNodesContext builds the tree like newTreeFromNodes until ctx is done.
+func newTreeFromNodesContext(ctx context.Context, nodes []*Node, newHashFunc func() hash.Hash, cfg config) (*Tree, error) {
+	hashFunc := cfg.hasher.NewNodeHasher()
+	root, err := buildTreeContext(ctx, nodes, hashFunc, &cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newTreeFromRoot(root, nodes, hashFunc, newHashFunc, cfg), nil
+}
 
+// newTreeFromRoot creates the tree from its built root and leaf nodes.
+// hashFunc is the node hasher the tree uses for its updates.
+func newTreeFromRoot(root *Node, nodes []*Node, hashFunc hash.Hash, newHashFunc func() hash.Hash, cfg config) *Tree {
 	tree := &Tree{
-		HashFunc: hashFunc,
+		Root:         root,
+		HashFunc:     hashFunc,
+		Leaves:       nodes,
+		leafHashFunc: cfg.hasher.NewLeafHasher(),
+		newHashFunc:  newHashFunc,
+		cfg:          cfg,
+		hashedLeaves: cfg.dropValues,
+		subtreeRoots: newSubtreeCache(cfg.subtreeCache),
+	}
+	if cfg.dropValues {
+		for _, leaf := range nodes {
+			leaf.Value = nil
+		}
+	}
+	if extra := cfg.capacity - len(nodes); extra > 0 {
+		tree.Leaves = append(make([]*Node, 0, cfg.capacity), nodes...)
+		// Every appended leaf adds a leaf and a parent node.
+		tree.free = make([]Node, 2*extra)
 	}
-	tree.Root = buildTree(nodes, hashFunc)
-	tree.Leaves = nodes
 
-	return tree, nil
+	if tree.Root == nil {
+		tree.Root = tree.emptyRoot()
+	}
+	if cfg.dedupe {
+		tree.dedupe()
+	}
+	tree.rootChanged(MutationBuild)
+
+	return tree
+}
+
+// storedValue returns the value to store in a leaf,
+// which is nil if the tree drops leaf values.
+func (t *Tree) storedValue(value []byte) []byte {
+	if t.cfg.dropValues {
+		return nil
+	}
+	return value
+}
+
+// leafValue returns the value of the leaf at index. Trees without leaf
+// values resolve it with the resolver from WithoutLeafValues, if any.
+func (t *Tree) leafValue(index int) ([]byte, bool) {
+	if !t.hashedLeaves {
+		return t.Leaves[index].Value, true
+	}
+	if t.cfg.resolveValue == nil {
+		return nil, false
+	}
+	value, err := t.cfg.resolveValue(index)
+	return value, err == nil
+}
+
+// emptyRoot returns the root of a tree without leaves,
+// which is the hash of the empty string.
+func (t *Tree) emptyRoot() *Node {
+	t.HashFunc.Reset()
+	return &Node{Hash: t.HashFunc.Sum(nil)}
+}
+
+// Len returns the number of leaves in the tree.
+func (t *Tree) Len() int {
+	return len(t.Leaves)
+}
+
+// Depth returns the number of levels above the leaves,
+// which is the length of the longest proof.
+func (t *Tree) Depth() int {
+	return treeLevels(len(t.Leaves))
+}
+
+// Leaf returns the leaf at the given index.
+func (t *Tree) Leaf(index int) (*Node, error) {
+	if err := t.checkLeaf(index); err != nil {
+		return nil, err
+	}
+	return t.Leaves[index], nil
+}
+
+// GetLeaf returns copies of the value and hash of the leaf at index.
+// The value is nil for trees built from leaf hashes.
+func (t *Tree) GetLeaf(index int) (value, hash []byte, err error) {
+	if err := t.checkLeaf(index); err != nil {
+		return nil, nil, err
+	}
+	leaf := t.Leaves[index]
+	return bytes.Clone(leaf.Value), bytes.Clone(leaf.Hash), nil
+}
+
+// LeafHashes returns copies of the hashes of all leaves, in order.
+// The hashes of pruned leaves are nil.
+func (t *Tree) LeafHashes() [][]byte {
+	hashes := make([][]byte, len(t.Leaves))
+	for i, leaf := range t.Leaves {
+		if leaf != nil {
+			hashes[i] = bytes.Clone(leaf.Hash)
+		}
+	}
+	return hashes
+}
+
+// LeafPage is a page of leaves returned by GetLeaves.
+type LeafPage struct {
+	// Values and Hashes hold copies of the values and hashes
+	// of the leaves on the page. Pruned leaves are nil.
+	Values [][]byte
+	Hashes [][]byte
+	// Offset is the index of the first leaf on the page.
+	Offset int
+	// Total is the number of leaves in the tree.
+	Total int
+}
+
+// GetLeaves returns up to limit leaves starting at offset, e.g. to list
+// the leaves of a large tree page by page. Only the leaves on the page
+// are copied. The page is empty if offset is the number of leaves.
+func (t *Tree) GetLeaves(offset, limit int) (*LeafPage, error) {
+	if offset < 0 || offset > len(t.Leaves) {
+		return nil, indexOutOfBounds(offset, len(t.Leaves))
+	}
+	if limit < 0 {
+		return nil, fmt.Errorf("negative limit %d", limit)
+	}
+
+	leaves := t.Leaves[offset : offset+min(limit, len(t.Leaves)-offset)]
+	page := &LeafPage{
+		Values: make([][]byte, len(leaves)),
+		Hashes: make([][]byte, len(leaves)),
+		Offset: offset,
+		Total:  len(t.Leaves),
+	}
+	for i, leaf := range leaves {
+		if leaf != nil {
+			page.Values[i] = bytes.Clone(leaf.Value)
+			page.Hashes[i] = bytes.Clone(leaf.Hash)
+		}
+	}
+	return page, nil
 }
 
+// RootNode returns the root node of the tree.
+func (t *Tree) RootNode() *Node {
+	t.flush()
+	return t.Root
+}
+
+// RootHash returns a copy of the root hash of the tree,
+// so callers can't change the hash stored in the tree.
+func (t *Tree) RootHash() []byte {
+	t.flush()
+	if t.Root == nil {
+		return nil
+	}
+	return bytes.Clone(t.Root.Hash)
+}
+
+// RootHex returns the root hash of the tree as a hex string.
+func (t *Tree) RootHex() string {
+	t.flush()
+	if t.Root == nil {
+		return ""
+	}
+	return hex.EncodeToString(t.Root.Hash)
+}
+
+// ctxCheckInterval is the number of hashes between checks
+// whether a context is done.
+const ctxCheckInterval = 1 << 12
+
 // preHashLeaves prehashes the values
 func preHashLeaves(values [][]byte, newHashFunc func() hash.Hash) [][]byte {
+	preHashedLeaves, _ := preHashLeavesContext(context.Background(), values, newHashFunc)
+	return preHashedLeaves
+}
+
+// preHashLeavesContext prehashes the values until ctx is done.
+func preHashLeavesContext(ctx context.Context, values [][]byte, newHashFunc func() hash.Hash) ([][]byte, error) {
+	return parallelism{}.preHashLeavesContext(ctx, values, newHashFunc)
+}
+
+// preHashLeavesContext prehashes the values with the given parallelism.
+// Without a batch size, the values are split into chunks of about the
+// same number of bytes that the workers claim one at a time, so leaves
+// of very different sizes don't leave most workers idle.
+func (p parallelism) preHashLeavesContext(ctx context.Context, values [][]byte, newHashFunc func() hash.Hash) ([][]byte, error) {
 	preHashedLeaves := make([][]byte, len(values))
+	if len(values) == 0 {
+		return preHashedLeaves, nil
+	}
+	p = p.forValues(values)
+
+	batches := p.batchesContext
+	if p.batchSize == 0 {
+		batches = func(ctx context.Context, n int, fn func(ctx context.Context, start, end int) error) error {
+			return p.claimContext(ctx, leafChunks(values, p.numWorkers(n)*chunksPerWorker), fn)
+		}
+	}
+	err := batches(ctx, len(values), func(ctx context.Context, start, end int) error {
+		hasher := newHashFunc()
+		for j := start; j < end; j++ {
+			if (j-start)%ctxCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+			hasher.Reset()
+			hasher.Write(values[j])
+			preHashedLeaves[j] = hasher.Sum(nil)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return preHashedLeaves, nil
+}
+
+// parallelism configures how many goroutines hash in parallel
+// and how many items each of them hashes at a time.
+type parallelism struct {
+	// workers is the number of goroutines, or the number of CPUs if 0.
+	workers int
+	// batchSize is the number of items per batch, or an equal share
+	// of the items for every worker if 0.
+	batchSize int
+}
 
-	numWorkers := runtime.NumCPU()
-	if len(values) < numWorkers {
-		numWorkers = len(values)
+// numWorkers returns the number of goroutines for n items.
+func (p parallelism) numWorkers(n int) int {
+	numWorkers := p.workers
+	if numWorkers == 0 {
+		numWorkers = runtime.NumCPU()
 	}
+	return min(numWorkers, n)
+}
 
-	var g errgroup.Group
-	g.SetLimit(numWorkers)
+// batches splits n items into batches and calls fn for each batch in parallel.
+func (p parallelism) batches(n int, fn func(start, end int)) {
+	_ = p.batchesContext(context.Background(), n, func(_ context.Context, start, end int) error {
+		fn(start, end)
+		return nil
+	})
+}
 
-	// Compute batch size using integer division
-	// and handle remaining values.
-	batchSize := len(values) / numWorkers
-	remainder := len(values) % numWorkers
+// batchesContext calls fn for each batch like batches. The context passed
+// to fn is canceled once a call fails, and the first error is returned.
+// With a single worker, the batches are run on the calling goroutine.
+func (p parallelism) batchesContext(ctx context.Context, n int, fn func(ctx context.Context, start, end int) error) error {
+	if n == 0 {
+		return nil
+	}
 
-	for i := 0; i < numWorkers; i++ {
+	numWorkers := p.numWorkers(n)
+	batchSize, numBatches := p.batchSize, 0
+	if batchSize > 0 {
+		numBatches = (n + batchSize - 1) / batchSize
+	} else {
+		// Compute batch size using integer division
+		// and add the remaining values to the last batch.
+		batchSize, numBatches = n/numWorkers, numWorkers
+	}
+	bounds := func(i int) (int, int) {
 		start := i * batchSize
-		end := start + batchSize
+		if i == numBatches-1 {
+			return start, n
+		}
+		return start, start + batchSize
+	}
 
-		// Add remaining values to the last batch
-		if i == numWorkers-1 {
-			end += remainder
+	if numWorkers == 1 {
+		for i := 0; i < numBatches; i++ {
+			start, end := bounds(i)
+			if err := fn(ctx, start, end); err != nil {
+				return err
+			}
 		}
+		return nil
+	}
 
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(numWorkers)
+	for i := 0; i < numBatches; i++ {
+		start, end := bounds(i)
 		g.Go(func() error {
-			hasher := newHashFunc()
-			for j := start; j < end; j++ {
-				hasher.Reset()
-				hasher.Write(values[j])
-				preHashedLeaves[j] = hasher.Sum(nil)
-			}
-			return nil
+			return fn(ctx, start, end)
 		})
 	}
+	return g.Wait()
+}
 
-	if err := g.Wait(); err != nil {
-		panic(err)
+// chunksPerWorker is the number of chunks per worker when leaves are
+// split by size, so workers with small leaves claim more chunks.
+const chunksPerWorker = 16
+
+// leafOverhead is the cost of hashing a leaf on top of its length in
+// bytes, so chunks of tiny leaves don't grow arbitrarily long.
+const leafOverhead = 64
+
+// minParallelWork is the amount of work below which values are hashed on
+// the calling goroutine by default, counted like leafOverhead. Starting
+// workers and their hashers costs more than hashing a few small leaves.
+const minParallelWork = 1 << 14
+
+// forValues returns the parallelism for hashing values. Unless the number
+// of workers is set, values with less than minParallelWork of work are
+// hashed by a single worker, so small trees don't fan out to every CPU.
+func (p parallelism) forValues(values [][]byte) parallelism {
+	if p.workers != 0 {
+		return p
+	}
+	work := 0
+	for _, value := range values {
+		work += len(value) + leafOverhead
+		if work >= minParallelWork {
+			return p
+		}
 	}
+	p.workers = 1
+	return p
+}
 
-	return preHashedLeaves
+// leafChunks splits values into about numChunks chunks of adjacent values
+// with the same number of bytes. It returns the start of every chunk,
+// followed by the number of values. A large value gets a chunk of its own.
+func leafChunks(values [][]byte, numChunks int) []int {
+	total := 0
+	for _, value := range values {
+		total += len(value) + leafOverhead
+	}
+	target := max(total/numChunks, 1)
+
+	bounds := []int{0}
+	size := 0
+	for i, value := range values[:len(values)-1] {
+		size += len(value) + leafOverhead
+		if size >= target || len(values[i+1])+leafOverhead >= target {
+			bounds = append(bounds, i+1)
+			size = 0
+		}
+	}
+	return append(bounds, len(values))
 }
 
-func buildTree(nodes []*Node, hashFunc hash.Hash) *Node {
-	if len(nodes) == 0 {
+// claimContext calls fn for each chunk [bounds[i], bounds[i+1]) like
+// batchesContext, but the workers claim the chunks one at a time,
+// so workers that finish early take over the remaining chunks
+// instead of waiting for the others.
+func (p parallelism) claimContext(ctx context.Context, bounds []int, fn func(ctx context.Context, start, end int) error) error {
+	numChunks := len(bounds) - 1
+	numWorkers := p.numWorkers(numChunks)
+	if numWorkers <= 1 {
+		for i := range numChunks {
+			if err := fn(ctx, bounds[i], bounds[i+1]); err != nil {
+				return err
+			}
+		}
 		return nil
 	}
-	for len(nodes) > 1 {
-		parents := make([]*Node, (len(nodes)+1)/2)
-		for i := 0; i < len(nodes); i += 2 {
-			left := nodes[i]
-			if i+1 < len(nodes) {
-				right := nodes[i+1]
-
-				// Hash the left and right node hashes
-				hashFunc.Reset()
-				hashFunc.Write(left.Hash)
-				hashFunc.Write(right.Hash)
-				parentHash := hashFunc.Sum(nil)
-
-				parentNode := &Node{
-					Hash:  parentHash,
-					Left:  left,
-					Right: right,
+
+	var next atomic.Int64
+	g, ctx := errgroup.WithContext(ctx)
+	for range numWorkers {
+		g.Go(func() error {
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= numChunks {
+					return nil
+				}
+				// Stop claiming chunks once another worker has failed.
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if err := fn(ctx, bounds[i], bounds[i+1]); err != nil {
+					return err
 				}
+			}
+		})
+	}
+	return g.Wait()
+}
 
-				left.Parent = parentNode
-				right.Parent = parentNode
+func buildTree(nodes []*Node, hashFunc hash.Hash, cfg *config) *Node {
+	root, _ := buildTreeContext(context.Background(), nodes, hashFunc, cfg)
+	return root
+}
 
-				parents[i/2] = parentNode
-			} else {
-				// If right is nil, carry the left node up without hashing
-				parents[i/2] = left
-			}
-		}
-		nodes = parents
+// buildTreeContext builds the tree like buildTree until ctx is done.
+func buildTreeContext(ctx context.Context, nodes []*Node, hashFunc hash.Hash, cfg *config) (*Node, error) {
+	if len(nodes) == 0 {
+		return nil, nil
 	}
-	return nodes[0]
+	return reduceBlocks(ctx, slices.Clone(nodes), 1, cfg.parallelism.workers, hashFunc, cfg)
 }
 
 // UpdateLeaf updates the value of the leaf at the given index
 // and recalculates the tree.
 func (t *Tree) UpdateLeaf(index int, newVal []byte) error {
-	if index < 0 || index >= len(t.Leaves) {
-		return ErrIndexOutOfBounds
+	if err := t.checkLeaf(index); err != nil {
+		return err
 	}
 
-	leaf := t.Leaves[index]
-	t.HashFunc.Reset()
-	t.HashFunc.Write(newVal)
-	leaf.Hash = t.HashFunc.Sum(nil)
-	leaf.Value = newVal
+	newVal, err := t.cfg.leafValue(newVal)
+	if err != nil {
+		return err
+	}
 
-	t.updateParentHashes(leaf)
+	t.setLeaf(index, newVal)
+	if t.cfg.deferHashing {
+		t.markDirty(index)
+		return nil
+	}
+	t.updateParentHashes(index)
+	t.rootChanged(MutationUpdate)
 	return nil
 }
 
-// updateParentHashes propagates changes upwards to the root
-// after a leaf has been updated.
-func (t *Tree) updateParentHashes(leaf *Node) {
-	current := leaf
-	for current.Parent != nil {
-		parent := current.Parent
-		t.HashFunc.Reset()
-		if parent.Left != nil {
-			t.HashFunc.Write(parent.Left.Hash)
+// UpdateLeaves updates the values of the leaves at the indices
+// in updates and recalculates the tree. Every node above the updated
+// leaves is only hashed once, which is faster than calling UpdateLeaf
+// for each leaf. No leaf is updated if any update is invalid.
+func (t *Tree) UpdateLeaves(updates map[int][]byte) error {
+	indices := slices.Sorted(maps.Keys(updates))
+	values := make(map[int][]byte, len(updates))
+	for _, index := range indices {
+		if err := t.checkLeaf(index); err != nil {
+			return fmt.Errorf("leaf %d: %w", index, err)
 		}
-		if parent.Right != nil {
-			t.HashFunc.Write(parent.Right.Hash)
+		value, err := t.cfg.leafValue(updates[index])
+		if err != nil {
+			return fmt.Errorf("leaf %d: %w", index, err)
 		}
-		parent.Hash = t.HashFunc.Sum(nil)
-		current = parent
+		values[index] = value
+	}
+
+	if t.cfg.deferHashing {
+		t.markLeaves(indices, values)
+		return nil
 	}
+	t.setLeaves(indices, values)
+	t.rootChanged(MutationUpdate)
+	return nil
+}
+
+// setLeaves sets the values of the leaves at the sorted indices,
+// which have already been checked, and rehashes the nodes above them.
+func (t *Tree) setLeaves(indices []int, values map[int][]byte) {
+	t.markLeaves(indices, values)
+	t.hashDirty()
+}
+
+// markLeaves updates the leaves at indices and marks the nodes
+// above them as dirty. Large batches of leaves are hashed in parallel.
+func (t *Tree) markLeaves(indices []int, values map[int][]byte) {
+	if len(indices) < parallelRehashThreshold {
+		for _, index := range indices {
+			t.setLeaf(index, values[index])
+			t.markDirty(index)
+		}
+		return
+	}
+
+	batch := make([][]byte, len(indices))
+	for i, index := range indices {
+		batch[i] = values[index]
+	}
+	// Hashing can't fail without a context that is done.
+	hashes, _ := t.cfg.parallelism.preHashLeavesContext(context.Background(), batch, t.cfg.hasher.NewLeafHasher)
+	for i, index := range indices {
+		t.setLeafHash(index, batch[i], hashes[i])
+		t.markDirty(index)
+	}
+}
+
+// setLeaf updates the value and hash of the leaf at index
+// and returns it. The nodes above it are not rehashed.
+func (t *Tree) setLeaf(index int, value []byte) *Node {
+	t.leafHashFunc.Reset()
+	t.leafHashFunc.Write(value)
+	return t.setLeafHash(index, value, t.leafHashFunc.Sum(nil))
+}
+
+// setLeafHash updates the leaf at index like setLeaf,
+// with a hash of value that has already been computed.
+func (t *Tree) setLeafHash(index int, value, hash []byte) *Node {
+	leaf := t.Leaves[index]
+	t.removeFromIndex(index, leaf.Hash)
+	t.subtreeRoots.invalidate(index)
+	leaf.Hash = hash
+	leaf.Value = t.storedValue(value)
+	t.addToIndex(index, leaf.Hash)
+	return leaf
+}
+
+// updateParentHashes propagates changes upwards to the root
+// after the leaf at index has been updated.
+func (t *Tree) updateParentHashes(index int) {
+	levels := t.pathLevels(index)
+	for i, parent := 0, t.Leaves[index].Parent; parent != nil; i, parent = i+1, parent.Parent {
+		t.rehashNode(parent, levels[i])
+	}
+}
+
+// rehashNode recomputes the hash of a node at the given level from its children.
+func (t *Tree) rehashNode(node *Node, level int) {
+	node.Hash = combineLevelHashes(level, node.Left.Hash, node.Right.Hash, t.HashFunc, &t.cfg)
+}
+
+// pathLevels returns the levels of the nodes above the leaf at index,
+// from its parent up to the root. The levels follow from the number of
+// leaves below the nodes, like in Validate, rather than from their
+// children, which pruned nodes don't have.
+func (t *Tree) pathLevels(index int) []int {
+	var levels []int
+	start, size := 0, len(t.Leaves)
+	for size > 1 {
+		// The left child is a complete subtree with 2^(level-1) leaves.
+		half := 1 << (bits.Len(uint(size-1)) - 1)
+		levels = append(levels, bits.Len(uint(half)))
+		if index < start+half {
+			size = half
+		} else {
+			start, size = start+half, size-half
+		}
+	}
+	slices.Reverse(levels)
+	return levels
+}
+
+// nodeLevel returns the level of a node above the leaves.
+// Left children are always complete subtrees, so the level
+// is the length of the leftmost path down to a leaf.
+func nodeLevel(node *Node) int {
+	level := 0
+	for node.Left != nil {
+		node = node.Left
+		level++
+	}
+	return level
 }
 
 // RemoveLeaf removes a leaf at a given index
-// and recalculates the tree.
+// and recalculates the tree. The complete subtrees before the leaf
+// are kept, while the leaves after it are moved one position to the left
+// and hashed into new subtrees, so the tree has the same shape as
+// a tree built from the remaining leaves.
 func (t *Tree) RemoveLeaf(index int) error {
-	if index < 0 || index >= len(t.Leaves) {
-		return ErrIndexOutOfBounds
+	t.flush()
+	if err := t.checkLeaf(index); err != nil {
+		return err
 	}
+	return t.removeLeaves([]int{index})
+}
 
-	leafToRemove := t.Leaves[index]
-	t.Leaves = slices.Delete(t.Leaves, index, index+1)
-	parent := leafToRemove.Parent
-
-	// If there are no leaves left, the tree is now empty
-	if len(t.Leaves) == 0 && parent == nil {
-		t.Root = nil
+// RemoveLeaves removes the leaves at the given indices and recalculates
+// the tree once, which is faster than calling RemoveLeaf for each leaf.
+// The indices refer to the leaves before any of them is removed, and
+// duplicate indices are ignored. No leaf is removed if any index is invalid.
+func (t *Tree) RemoveLeaves(indices []int) error {
+	t.flush()
+	indices = slices.Compact(slices.Sorted(slices.Values(indices)))
+	if len(indices) == 0 {
 		return nil
 	}
-
-	// Remove leaf ref from parent
-	if parent.Left == leafToRemove {
-		parent.Left = nil
-	} else if parent.Right == leafToRemove {
-		parent.Right = nil
+	for _, index := range indices {
+		if err := t.checkLeaf(index); err != nil {
+			return fmt.Errorf("leaf %d: %w", index, err)
+		}
 	}
+	return t.removeLeaves(indices)
+}
 
-	// Traverse tree upwards and update hashes
-	t.updateParentHashesAfterRemoval(parent)
+// removeLeaves removes the leaves at the sorted indices, which have
+// already been checked. The complete subtrees before the first removed
+// leaf are kept, and the leaves after it are hashed into new subtrees.
+func (t *Tree) removeLeaves(indices []int) error {
+	first := indices[0]
+	var following []*Node
+	for i, next := first+1, 1; i < len(t.Leaves); i++ {
+		if next < len(indices) && indices[next] == i {
+			next++
+			continue
+		}
+		if t.Leaves[i] == nil {
+			return &IndexError{Index: i, Size: len(t.Leaves), Err: ErrLeafPruned}
+		}
+		following = append(following, t.Leaves[i])
+	}
 
+	t.replaceLeaves(first, following)
+	t.rootChanged(MutationRemove)
 	return nil
 }
 
-// updateParentHashesAfterRemoval traverses up the tree to update
-// parent hashes after a leaf has been removed.
-func (t *Tree) updateParentHashesAfterRemoval(node *Node) {
-	current := node
-	for current != nil {
-		t.HashFunc.Reset()
-		if current.Left != nil {
-			// Only left child exists
-			t.HashFunc.Write(current.Left.Hash)
-		} else if current.Right != nil {
-			// Only right child exists
-			t.HashFunc.Write(current.Right.Hash)
+// replaceLeaves replaces the leaves from first on with nodes. The complete
+// subtrees before first are kept and only the nodes above the new leaves
+// are hashed.
+func (t *Tree) replaceLeaves(first int, nodes []*Node) {
+	peaks, heights := t.pushPeaks(t.prefixPeaks(first), peakHeights(first), nodes)
+	size := len(t.Leaves)
+	t.Leaves = append(t.Leaves[:first], nodes...)
+	if len(t.Leaves) < size {
+		clear(t.Leaves[len(t.Leaves):size])
+	}
+	t.subtreeRoots.clear()
+	// The indices of all following leaves have shifted.
+	if t.index != nil {
+		t.buildIndex()
+	}
+	if t.keys != nil {
+		t.buildKeys()
+	}
+
+	// If there are no leaves left, the tree is now empty
+	if len(t.Leaves) == 0 {
+		t.Root = nil
+		if t.cfg.allowEmpty {
+			t.Root = t.emptyRoot()
 		}
-		current.Hash = t.HashFunc.Sum(nil)
-		current = current.Parent
+		return
+	}
+	t.Root = t.joinPeaks(peaks, heights)
+}
+
+// RemoveLeafByValue removes the first leaf with the given value
+// and recalculates the tree. Other leaves with the same value are kept.
+// It returns ErrNoVal if no leaf has the value.
+func (t *Tree) RemoveLeafByValue(value []byte) error {
+	index, found := t.IndexOf(value)
+	if !found {
+		return ErrNoVal
 	}
+	return t.RemoveLeaf(index)
 }
 
 // Proof represents the hash chain from a leaf to the root
@@ -239,17 +842,7 @@ type Proof struct {
 
 // GenerateProof generates an inclucion proof for a given value.
 func (t *Tree) GenerateProof(value []byte) (*Proof, error) {
-	var leafIndex int
-	found := false
-
-	// Find the leaf node that contains the given value.
-	for i, leaf := range t.Leaves {
-		if bytes.Equal(leaf.Value, value) {
-			leafIndex = i
-			found = true
-			break
-		}
-	}
+	leafIndex, found := t.IndexOf(value)
 
 	// If the leaf is not found, return an error.
 	if !found {
@@ -262,15 +855,47 @@ func (t *Tree) GenerateProof(value []byte) (*Proof, error) {
 
 // GenerateProofByIndex generates a proof for a leaf at the given index.
 func (t *Tree) GenerateProofByIndex(index int) (*Proof, error) {
-	if index < 0 || index >= len(t.Leaves) {
-		return nil, ErrIndexOutOfBounds
+	t.flush()
+	if err := t.checkLeaf(index); err != nil {
+		return nil, err
 	}
 
-	leaf := t.Leaves[index]
 	var hashes [][]byte
+	if depth := t.Depth(); depth > 0 {
+		hashes = make([][]byte, 0, depth)
+	}
+	return &Proof{
+		Hashes: appendSiblingHashes(hashes, t.Leaves[index]),
+		Index:  index,
+	}, nil
+}
+
+// GenerateProofInto generates a proof for the leaf at the given index
+// like GenerateProofByIndex, but writes it into proof and reuses the
+// capacity of proof.Hashes, so serving proofs from a reused Proof
+// doesn't allocate. The hashes are shared with the tree and must not
+// be modified; they are only valid until the tree changes.
+func (t *Tree) GenerateProofInto(index int, proof *Proof) error {
+	t.flush()
+	if err := t.checkLeaf(index); err != nil {
+		return err
+	}
+
+	proof.Hashes = appendSiblingHashes(proof.Hashes[:0], t.Leaves[index])
+	proof.Index = index
+	return nil
+}
 
-	// Traverse from the leaf to the root and collect sibling hashes.
-	current := leaf
+// siblingHashes traverses from the node to the root
+// and collects the sibling hashes.
+func siblingHashes(node *Node) [][]byte {
+	return appendSiblingHashes(nil, node)
+}
+
+// appendSiblingHashes appends the sibling hashes
+// from the node to the root to hashes.
+func appendSiblingHashes(hashes [][]byte, node *Node) [][]byte {
+	current := node
 	for current.Parent != nil {
 		var siblingHash []byte
 		parent := current.Parent
@@ -292,41 +917,182 @@ func (t *Tree) GenerateProofByIndex(index int) (*Proof, error) {
 		current = parent
 	}
 
-	return &Proof{
-		Hashes: hashes,
-		Index:  index,
-	}, nil
+	return hashes
 }
 
 // VerifyProof returns true if the proof is verified, otherwise false.
 // It also returns an error if the verification process encounters an issue.
+// Proofs skip the levels where the path is carried up without a sibling,
+// so a proof must have exactly one hash for every other level of the tree.
 func (t *Tree) VerifyProof(proof *Proof, value []byte) (bool, error) {
-	// Hash the leaf value.
-	t.HashFunc.Reset()
-	t.HashFunc.Write(value)
-	currentHash := t.HashFunc.Sum(nil)
+	t.flush()
+	// Hash the leaf value and the path up to the root in a pooled buffer.
+	buf := getDigest()
+	defer putDigest(buf)
+	t.leafHashFunc.Reset()
+	t.leafHashFunc.Write(value)
+	*buf = t.leafHashFunc.Sum(*buf)
 
 	// Traverse through the proof and compute the root hash.
+	currentHash, ok := rootFromProofInto(*buf, *buf, proof, len(t.Leaves), t.HashFunc, &t.cfg)
+	if !ok {
+		return false, &ProofSizeError{Index: proof.Index, NumHashes: len(proof.Hashes), Size: len(t.Leaves)}
+	}
+	*buf = currentHash
+
+	// Compare the calculated root hash with the actual root hash.
+	if !bytes.Equal(currentHash, t.Root.Hash) {
+		return false, &RootMismatchError{Expected: t.Root.Hash, Actual: bytes.Clone(currentHash)}
+	}
+
+	return true, nil
+}
+
+// rootFromProofWithConfig computes the root hash from a leaf hash and
+// its proof in a tree with the given number of leaves, where nodes are
+// hashed as configured by cfg.
+// It returns false if the proof doesn't fit the shape of the tree.
+func rootFromProofWithConfig(leafHash []byte, proof *Proof, size int, hashFunc hash.Hash, cfg *config) ([]byte, bool) {
+	return rootFromProofInto(nil, leafHash, proof, size, hashFunc, cfg)
+}
+
+// rootFromProofInto computes the root hash like rootFromProofWithConfig,
+// hashing every node on the path into the buffer of dst.
+// dst may hold leafHash, which is left unchanged otherwise.
+func rootFromProofInto(dst, leafHash []byte, proof *Proof, size int, hashFunc hash.Hash, cfg *config) ([]byte, bool) {
 	index := proof.Index
-	for _, siblingHash := range proof.Hashes {
-		if index%2 == 0 {
-			// If the index is even, current node is on the left.
-			currentHash = combineHashes(currentHash, siblingHash, t.HashFunc)
-		} else {
-			// If the index is odd, current node is on the right.
-			currentHash = combineHashes(siblingHash, currentHash, t.HashFunc)
+	if index < 0 || index >= size {
+		return nil, false
+	}
+	if cfg == nil {
+		cfg = &config{}
+	}
+
+	currentHash := append(dst[:0], leafHash...)
+	if size&(size-1) == 0 {
+		// Every node of a complete tree has a sibling, so the proof
+		// holds one hash per level and the index bits pick the sides.
+		if len(proof.Hashes) != bits.Len(uint(size))-1 {
+			return nil, false
+		}
+		for i, siblingHash := range proof.Hashes {
+			if index>>i&1 == 0 {
+				currentHash = cfg.combineInto(currentHash, i+1, currentHash, siblingHash, hashFunc)
+			} else {
+				currentHash = cfg.combineInto(currentHash, i+1, siblingHash, currentHash, hashFunc)
+			}
+		}
+		return currentHash, true
+	}
+	hashes := proof.Hashes
+	for level := 1; size > 1; level++ {
+		// The last node on a level without a sibling
+		// is carried up without hashing.
+		if index%2 == 1 || index+1 < size {
+			if len(hashes) == 0 {
+				return nil, false
+			}
+			siblingHash := hashes[0]
+			hashes = hashes[1:]
+
+			if index%2 == 0 {
+				// If the index is even, current node is on the left.
+				currentHash = cfg.combineInto(currentHash, level, currentHash, siblingHash, hashFunc)
+			} else {
+				// If the index is odd, current node is on the right.
+				currentHash = cfg.combineInto(currentHash, level, siblingHash, currentHash, hashFunc)
+			}
 		}
 		// Move up the tree by dividing index by 2.
 		index /= 2
+		size = (size + 1) / 2
 	}
 
-	// Compare the calculated root hash with the actual root hash.
-	if !bytes.Equal(currentHash, t.Root.Hash) {
-		return false, fmt.Errorf("%w: expected root %x, but got %x",
-			ErrProofVerificationFailed, t.Root.Hash, currentHash)
+	// All hashes in the proof must be used.
+	if len(hashes) > 0 {
+		return nil, false
 	}
 
-	return true, nil
+	return currentHash, true
+}
+
+// completeNode returns the root of the complete subtree with
+// 2^level leaves at the given index on that level.
+func (t *Tree) completeNode(level, index int) *Node {
+	node := t.Leaves[index<<level]
+	for i := 0; i < level; i++ {
+		node = node.Parent
+	}
+	return node
+}
+
+// treeLevels returns the number of levels above the leaves
+// in a tree with size leaves.
+func treeLevels(size int) int {
+	if size <= 1 {
+		return 0
+	}
+	return bits.Len(uint(size - 1))
+}
+
+// subtreeHash computes the hash of the node at the given level and index
+// in a tree with size leaves. Hashes of complete subtrees are looked up
+// with completeHash, while nodes on the right edge of the tree are
+// computed from their children.
+func subtreeHash(level, index, size int, completeHash func(level, index int) ([]byte, error), hashFunc hash.Hash, cfg *config) ([]byte, error) {
+	start := index << level
+	if start+1<<level <= size {
+		return completeHash(level, index)
+	}
+
+	// Carry the left child up if there are no leaves on the right.
+	left, err := subtreeHash(level-1, 2*index, size, completeHash, hashFunc, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if start+1<<(level-1) >= size {
+		return left, nil
+	}
+
+	right, err := subtreeHash(level-1, 2*index+1, size, completeHash, hashFunc, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return combineLevelHashes(level, left, right, hashFunc, cfg), nil
+}
+
+// proofFromSubtrees builds the proof for the leaf at index in a tree
+// with size leaves, using nodeHash to look up the sibling hashes.
+func proofFromSubtrees(index, size int, nodeHash func(level, index int) ([]byte, error)) (*Proof, error) {
+	var hashes [][]byte
+	levelIndex := index
+	levelSize := size
+	for level := 0; levelSize > 1; level++ {
+		// Only nodes with a sibling contribute to the proof.
+		sibling := -1
+		switch {
+		case levelIndex%2 == 1:
+			sibling = levelIndex - 1
+		case levelIndex+1 < levelSize:
+			sibling = levelIndex + 1
+		}
+
+		if sibling >= 0 {
+			siblingHash, err := nodeHash(level, sibling)
+			if err != nil {
+				return nil, err
+			}
+			hashes = append(hashes, siblingHash)
+		}
+
+		levelIndex /= 2
+		levelSize = (levelSize + 1) / 2
+	}
+
+	return &Proof{
+		Hashes: hashes,
+		Index:  index,
+	}, nil
 }
 
 // combineHashes combines two hashes in the order they appear in the tree.
@@ -350,7 +1116,28 @@ func combineHashes(leftHash, rightHash []byte, hashFunc hash.Hash) []byte {
 	return hashFunc.Sum(nil)
 }
 
+// combineLevelHashes combines two hashes like combineHashes for the
+// parent at the given level, with the custom combine function or level
+// tags of cfg if set. cfg may be nil.
+func combineLevelHashes(level int, leftHash, rightHash []byte, hashFunc hash.Hash, cfg *config) []byte {
+	if cfg == nil || len(leftHash) == 0 || len(rightHash) == 0 {
+		return combineHashes(leftHash, rightHash, hashFunc)
+	}
+	if cfg.combine != nil {
+		return cfg.combine(leftHash, rightHash)
+	}
+
+	hashFunc.Reset()
+	if cfg.levelTag != nil {
+		hashFunc.Write(cfg.levelTag(level))
+	}
+	hashFunc.Write(leftHash)
+	hashFunc.Write(rightHash)
+	return hashFunc.Sum(nil)
+}
+
 func (t *Tree) PrintTree() {
+	t.flush()
 	if t.Root == nil {
 		fmt.Println("Empty tree")
 	} else {
@@ -365,38 +1152,91 @@ func (n *Node) StringifyTree(prefix string, isLeft bool) string {
 		return ""
 	}
 
-	var result strings.Builder
+	// Size the output up front, so large trees are written
+	// into one buffer without growing it.
+	var s treeStringer
+	s.b.Grow(stringifiedSize(n, len(prefix), isLeft))
+	s.prefix = append(s.prefix, prefix...)
+	s.writeNode(n, isLeft)
+	return s.b.String()
+}
+
+const (
+	leftBranch  = "├── "
+	rightBranch = "└── "
+	leftIndent  = "│   "
+	rightIndent = "    "
+	valueLabel  = "    (Leaf Value: "
+)
+
+// treeStringer writes the ASCII representation of a tree into one
+// builder, reusing its buffers for the prefixes and hex encoded hashes.
+type treeStringer struct {
+	b      strings.Builder
+	prefix []byte
+	hex    []byte
+}
 
-	// Add current node (branch or leaf)
-	if len(prefix) > 0 {
+// writeNode writes the line of n and the subtree below it.
+func (s *treeStringer) writeNode(n *Node, isLeft bool) {
+	if len(s.prefix) > 0 {
+		s.b.Write(s.prefix)
 		if isLeft {
-			result.WriteString(fmt.Sprintf("%s├── %s\n", prefix, hex.EncodeToString(n.Hash)))
+			s.b.WriteString(leftBranch)
 		} else {
-			result.WriteString(fmt.Sprintf("%s└── %s\n", prefix, hex.EncodeToString(n.Hash)))
+			s.b.WriteString(rightBranch)
 		}
-	} else {
-		result.WriteString(hex.EncodeToString(n.Hash) + "\n")
 	}
+	s.hex = hex.AppendEncode(s.hex[:0], n.Hash)
+	s.b.Write(s.hex)
+	s.b.WriteByte('\n')
 
 	// Recursively stringify left and right subtrees
-	newPrefix := prefix
+	depth := len(s.prefix)
 	if isLeft {
-		newPrefix += "│   "
+		s.prefix = append(s.prefix, leftIndent...)
 	} else {
-		newPrefix += "    "
+		s.prefix = append(s.prefix, rightIndent...)
 	}
 
 	if n.Left != nil || n.Right != nil {
 		if n.Left != nil {
-			result.WriteString(n.Left.StringifyTree(newPrefix, true))
+			s.writeNode(n.Left, true)
 		}
 		if n.Right != nil {
-			result.WriteString(n.Right.StringifyTree(newPrefix, false))
+			s.writeNode(n.Right, false)
 		}
 	} else if n.Value != nil {
 		// Add leaf value without extra indentation
-		result.WriteString(fmt.Sprintf("%s    (Leaf Value: %s)\n", prefix, string(n.Value)))
+		s.b.Write(s.prefix[:depth])
+		s.b.WriteString(valueLabel)
+		s.b.Write(n.Value)
+		s.b.WriteString(")\n")
+	}
+	s.prefix = s.prefix[:depth]
+}
+
+// stringifiedSize returns the length of the representation
+// of the subtree of n written by writeNode.
+func stringifiedSize(n *Node, prefixLen int, isLeft bool) int {
+	size := prefixLen + hex.EncodedLen(len(n.Hash)) + 1
+	if prefixLen > 0 {
+		size += len(leftBranch)
 	}
 
-	return result.String()
+	childPrefixLen := prefixLen + len(rightIndent)
+	if isLeft {
+		childPrefixLen = prefixLen + len(leftIndent)
+	}
+	if n.Left != nil || n.Right != nil {
+		if n.Left != nil {
+			size += stringifiedSize(n.Left, childPrefixLen, true)
+		}
+		if n.Right != nil {
+			size += stringifiedSize(n.Right, childPrefixLen, false)
+		}
+	} else if n.Value != nil {
+		size += prefixLen + len(valueLabel) + len(n.Value) + len(")\n")
+	}
+	return size
 }
@@ -2,10 +2,14 @@ package merkle
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
+	"slices"
+	"sync/atomic"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -74,6 +78,362 @@ func TestNewTree(t *testing.T) {
 	}
 }
 
+func TestNewTreeWithEmptyTree(t *testing.T) {
+	t.Parallel()
+
+	// The empty root is the hash of the empty string.
+	const emptyRoot = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+	tree, err := NewTree([][]byte{}, sha256.New, WithEmptyTree())
+	require.NoError(t, err)
+	assert.Equal(t, emptyRoot, hex.EncodeToString(tree.Root.Hash))
+	assert.Empty(t, tree.Leaves)
+
+	_, err = tree.GenerateProof([]byte("yolo"))
+	require.ErrorIs(t, err, ErrNoVal)
+
+	_, err = tree.GenerateProofByIndex(0)
+	require.ErrorIs(t, err, ErrIndexOutOfBounds)
+
+	isValid, err := tree.VerifyProof(&Proof{Index: 0}, []byte{})
+	require.ErrorIs(t, err, ErrProofVerificationFailed)
+	assert.False(t, isValid)
+
+	tree, err = NewTreeFromHashes(nil, sha256.New, WithEmptyTree())
+	require.NoError(t, err)
+	assert.Equal(t, emptyRoot, hex.EncodeToString(tree.Root.Hash))
+
+	// Removing the last leaf results in the empty root.
+	tree, err = NewTree([][]byte{[]byte("yolo")}, sha256.New, WithEmptyTree())
+	require.NoError(t, err)
+	require.NoError(t, tree.RemoveLeaf(0))
+	assert.Equal(t, emptyRoot, hex.EncodeToString(tree.Root.Hash))
+}
+
+// cancelingHash cancels a context after a number of hashes
+// have been computed with it.
+type cancelingHash struct {
+	hash.Hash
+	count  *atomic.Int64
+	after  int64
+	cancel context.CancelFunc
+}
+
+func (h *cancelingHash) Sum(b []byte) []byte {
+	if h.count.Add(1) == h.after {
+		h.cancel()
+	}
+	return h.Hash.Sum(b)
+}
+
+func TestNewTreeContext(t *testing.T) {
+	t.Parallel()
+
+	data := generateDummyData(100_000)
+
+	tests := []struct {
+		name        string
+		cancelAfter int64
+		err         error
+	}{
+		{
+			name:        "Canceled before start",
+			cancelAfter: 0,
+			err:         context.Canceled,
+		},
+		{
+			name:        "Canceled while hashing leaves",
+			cancelAfter: 100,
+			err:         context.Canceled,
+		},
+		{
+			name:        "Canceled while building nodes",
+			cancelAfter: int64(len(data)) + 10,
+			err:         context.Canceled,
+		},
+		{
+			name:        "Not canceled",
+			cancelAfter: -1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.cancelAfter == 0 {
+				cancel()
+			}
+
+			var count atomic.Int64
+			newHashFunc := func() hash.Hash {
+				return &cancelingHash{Hash: sha256.New(), count: &count, after: tc.cancelAfter, cancel: cancel}
+			}
+
+			tree, err := NewTreeContext(ctx, data, newHashFunc)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				assert.Nil(t, tree)
+				assert.Less(t, count.Load(), int64(2*len(data)-1), "Hashing should stop early")
+				return
+			}
+			require.NoError(t, err)
+
+			expTree, err := NewTree(data, sha256.New)
+			require.NoError(t, err)
+			assert.Equal(t, expTree.Root.Hash, tree.Root.Hash)
+		})
+	}
+}
+
+func TestNewTreeFromHashes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		values [][]byte
+		err    error
+	}{
+		{
+			name:   "No hashes should fail",
+			values: [][]byte{},
+			err:    ErrNoLeaves,
+		},
+		{
+			name:   "One hash should succeed",
+			values: [][]byte{[]byte("yolo")},
+		},
+		{
+			name:   "Three hashes should succeed",
+			values: [][]byte{[]byte("yolo"), []byte("diftp"), []byte("ngmi")},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			hashes := make([][]byte, len(tc.values))
+			for i, val := range tc.values {
+				hash := sha256.Sum256(val)
+				hashes[i] = hash[:]
+			}
+
+			tree, err := NewTreeFromHashes(hashes, sha256.New)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+
+			expTree, err := NewTree(tc.values, sha256.New)
+			require.NoError(t, err)
+
+			assert.Equal(t, expTree.Root.Hash, tree.Root.Hash, "Tree root mismatch")
+			require.Len(t, tree.Leaves, len(hashes))
+			for i, leaf := range tree.Leaves {
+				assert.Equal(t, hashes[i], leaf.Hash)
+				assert.Nil(t, leaf.Value)
+			}
+		})
+	}
+}
+
+func TestAccessors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		size     int
+		expDepth int
+	}{
+		{
+			name:     "Empty tree",
+			size:     0,
+			expDepth: 0,
+		},
+		{
+			name:     "Single leaf",
+			size:     1,
+			expDepth: 0,
+		},
+		{
+			name:     "Power of two",
+			size:     8,
+			expDepth: 3,
+		},
+		{
+			name:     "Odd number of leaves",
+			size:     9,
+			expDepth: 4,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := generateDummyData(tc.size)
+			tree, err := NewTree(data, sha256.New, WithEmptyTree())
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.size, tree.Len())
+			assert.Equal(t, tc.expDepth, tree.Depth())
+			assert.Same(t, tree.Root, tree.RootNode())
+
+			for i, value := range data {
+				leaf, err := tree.Leaf(i)
+				require.NoError(t, err)
+				assert.Equal(t, value, leaf.Value)
+
+				proof, err := tree.GenerateProofByIndex(i)
+				require.NoError(t, err)
+				assert.LessOrEqual(t, len(proof.Hashes), tree.Depth())
+			}
+
+			_, err = tree.Leaf(tc.size)
+			require.ErrorIs(t, err, ErrIndexOutOfBounds)
+			_, err = tree.Leaf(-1)
+			require.ErrorIs(t, err, ErrIndexOutOfBounds)
+		})
+	}
+}
+
+func TestGetLeaf(t *testing.T) {
+	t.Parallel()
+
+	data := generateDummyData(5)
+	tree, err := NewTree(data, sha256.New)
+	require.NoError(t, err)
+
+	hashes := tree.LeafHashes()
+	require.Len(t, hashes, len(data))
+	for i, value := range data {
+		leafValue, leafHash, err := tree.GetLeaf(i)
+		require.NoError(t, err)
+		assert.Equal(t, value, leafValue)
+		assert.Equal(t, LeafHash(value, sha256.New), leafHash)
+		assert.Equal(t, leafHash, hashes[i])
+
+		// The returned slices are copies.
+		leafValue[0] ^= 0xff
+		leafHash[0] ^= 0xff
+		hashes[i][0] ^= 0xff
+		assert.Equal(t, value, tree.Leaves[i].Value)
+		assert.Equal(t, LeafHash(value, sha256.New), tree.Leaves[i].Hash)
+	}
+
+	_, _, err = tree.GetLeaf(5)
+	require.ErrorIs(t, err, ErrIndexOutOfBounds)
+	_, _, err = tree.GetLeaf(-1)
+	require.ErrorIs(t, err, ErrIndexOutOfBounds)
+
+	require.NoError(t, tree.Prune([]int{0}))
+	_, _, err = tree.GetLeaf(1)
+	require.ErrorIs(t, err, ErrLeafPruned)
+	hashes = tree.LeafHashes()
+	assert.NotNil(t, hashes[0])
+	assert.Nil(t, hashes[1])
+}
+
+func TestGetLeaves(t *testing.T) {
+	t.Parallel()
+
+	data := generateDummyData(10)
+	tree, err := NewTree(data, sha256.New)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		offset int
+		limit  int
+		expLen int
+		err    error
+	}{
+		{
+			name:   "First page",
+			offset: 0,
+			limit:  4,
+			expLen: 4,
+		},
+		{
+			name:   "Last partial page",
+			offset: 8,
+			limit:  4,
+			expLen: 2,
+		},
+		{
+			name:   "Past the last leaf",
+			offset: 10,
+			limit:  4,
+			expLen: 0,
+		},
+		{
+			name:   "Zero limit",
+			offset: 3,
+			limit:  0,
+			expLen: 0,
+		},
+		{
+			name:   "Offset out of bounds",
+			offset: 11,
+			limit:  4,
+			err:    ErrIndexOutOfBounds,
+		},
+		{
+			name:   "Negative offset",
+			offset: -1,
+			limit:  4,
+			err:    ErrIndexOutOfBounds,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			page, err := tree.GetLeaves(tc.offset, tc.limit)
+			require.ErrorIs(t, err, tc.err)
+			if tc.err != nil {
+				return
+			}
+
+			assert.Equal(t, tc.offset, page.Offset)
+			assert.Equal(t, len(data), page.Total)
+			assert.Equal(t, data[tc.offset:tc.offset+tc.expLen], page.Values)
+			require.Len(t, page.Hashes, tc.expLen)
+			for i, hash := range page.Hashes {
+				assert.Equal(t, tree.Leaves[tc.offset+i].Hash, hash)
+			}
+		})
+	}
+
+	_, err = tree.GetLeaves(0, -1)
+	require.Error(t, err)
+}
+
+func TestRootHash(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewTree([][]byte{[]byte("a"), []byte("b")}, sha256.New)
+	require.NoError(t, err)
+
+	root := tree.RootHash()
+	assert.Equal(t, tree.Root.Hash, root)
+	assert.Equal(t, "e5a01fee14e0ed5c48714f22180f25ad8365b53f9779f79dc4a3d7e93963f94a", tree.RootHex())
+
+	// Changing the returned hash doesn't change the tree.
+	root[0] ^= 0xff
+	assert.NotEqual(t, tree.Root.Hash, root)
+	assert.Equal(t, "e5a01fee14e0ed5c48714f22180f25ad8365b53f9779f79dc4a3d7e93963f94a", tree.RootHex())
+
+	var empty Tree
+	assert.Nil(t, empty.RootHash())
+	assert.Empty(t, empty.RootHex())
+}
+
 func TestUpdateLeaf(t *testing.T) {
 	t.Parallel()
 
@@ -124,6 +484,115 @@ func TestUpdateLeaf(t *testing.T) {
 	}
 }
 
+func TestUpdateLeaves(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		size    int
+		updates map[int][]byte
+		opts    []Option
+		err     error
+	}{
+		{
+			name:    "Single update",
+			size:    5,
+			updates: map[int][]byte{2: []byte("updated")},
+		},
+		{
+			name: "Updates sharing ancestors",
+			size: 13,
+			updates: map[int][]byte{
+				0:  []byte("first"),
+				1:  []byte("second"),
+				7:  []byte("middle"),
+				12: []byte("last"),
+			},
+		},
+		{
+			name: "Level tags",
+			size: 7,
+			updates: map[int][]byte{
+				3: []byte("a"),
+				6: []byte("b"),
+			},
+			opts: []Option{WithLevelTags(LevelIndexTag)},
+		},
+		{
+			name:    "No updates",
+			size:    3,
+			updates: map[int][]byte{},
+		},
+		{
+			name:    "Large batch",
+			size:    3*parallelRehashThreshold + 5,
+			updates: dummyUpdates(0, 3*parallelRehashThreshold+5, 1),
+			opts:    []Option{WithWorkers(4)},
+		},
+		{
+			name:    "Large sparse batch with level tags",
+			size:    4 * parallelRehashThreshold,
+			updates: dummyUpdates(1, 4*parallelRehashThreshold, 3),
+			opts:    []Option{WithWorkers(4), WithLevelTags(LevelIndexTag)},
+		},
+		{
+			name: "Invalid index",
+			size: 4,
+			updates: map[int][]byte{
+				0: []byte("valid"),
+				4: []byte("invalid"),
+			},
+			err: ErrIndexOutOfBounds,
+		},
+		{
+			name: "Invalid leaf size",
+			size: 4,
+			updates: map[int][]byte{
+				0: make([]byte, 32),
+				1: []byte("short"),
+			},
+			opts: []Option{WithFixedLeafSize(32)},
+			err:  ErrInvalidLeafSize,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := generateDummyData(tc.size)
+			tree, err := NewTree(data, sha256.New, tc.opts...)
+			require.NoError(t, err)
+			root := tree.Root.Hash
+
+			err = tree.UpdateLeaves(tc.updates)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				assert.Equal(t, root, tree.Root.Hash, "No leaf should be updated")
+				return
+			}
+			require.NoError(t, err)
+
+			for i, value := range tc.updates {
+				data[i] = value
+			}
+			expTree, err := NewTree(data, sha256.New, tc.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, expTree.Levels(), tree.Levels())
+			assert.Equal(t, expTree.Root.Hash, tree.Root.Hash)
+		})
+	}
+}
+
+// dummyUpdates returns new values for every step-th leaf from start to end.
+func dummyUpdates(start, end, step int) map[int][]byte {
+	updates := make(map[int][]byte)
+	for i := start; i < end; i += step {
+		updates[i] = []byte(fmt.Sprintf("updated %d", i))
+	}
+	return updates
+}
+
 func TestRemoveLeaf(t *testing.T) {
 	t.Parallel()
 
@@ -176,7 +645,7 @@ func TestRemoveLeaf(t *testing.T) {
 
 			err = tree.RemoveLeaf(tc.removeIdx)
 			if tc.err != nil {
-				assert.ErrorIs(t, tc.err, err, "Expected error")
+				assert.ErrorIs(t, err, tc.err, "Expected error")
 			} else {
 				require.NoError(t, err, "No error expected for valid removal")
 				if tc.expLeafLen > 0 && tc.removeIdx < len(tree.Leaves)-1 {
@@ -188,6 +657,225 @@ func TestRemoveLeaf(t *testing.T) {
 	}
 }
 
+func TestRemoveLeafShape(t *testing.T) {
+	t.Parallel()
+
+	for size := 1; size <= 17; size++ {
+		for index := 0; index < size; index++ {
+			t.Run(fmt.Sprintf("Remove leaf %d of %d", index, size), func(t *testing.T) {
+				t.Parallel()
+
+				data := generateDummyData(size)
+				tree, err := NewTree(data, sha256.New, WithLevelTags(func(level int) []byte {
+					return []byte{byte(level)}
+				}))
+				require.NoError(t, err)
+				require.NoError(t, tree.RemoveLeaf(index))
+
+				remaining := slices.Delete(data, index, index+1)
+				if len(remaining) == 0 {
+					assert.Nil(t, tree.Root)
+					return
+				}
+				expTree, err := NewTree(remaining, sha256.New, WithLevelTags(func(level int) []byte {
+					return []byte{byte(level)}
+				}))
+				require.NoError(t, err)
+				assert.True(t, tree.Equal(expTree), "Tree mismatch")
+
+				for i, value := range remaining {
+					proof, err := tree.GenerateProofByIndex(i)
+					require.NoError(t, err)
+					isValid, err := tree.VerifyProof(proof, value)
+					require.NoError(t, err)
+					assert.True(t, isValid, "Proof of leaf %d should verify", i)
+				}
+
+				// The tree can still grow after the removal.
+				require.NoError(t, tree.AppendLeaf([]byte("new")))
+				require.NoError(t, expTree.AppendLeaf([]byte("new")))
+				assert.True(t, tree.Equal(expTree), "Tree mismatch after append")
+			})
+		}
+	}
+}
+
+func TestRemoveLeafPruned(t *testing.T) {
+	t.Parallel()
+
+	data := generateDummyData(8)
+	tree, err := NewTree(data, sha256.New)
+	require.NoError(t, err)
+	require.NoError(t, tree.Prune([]int{1, 6, 7}))
+
+	// The leaves after leaf 1 are pruned and can't be moved.
+	require.ErrorIs(t, tree.RemoveLeaf(1), ErrLeafPruned)
+	assert.Len(t, tree.Leaves, 8)
+
+	require.NoError(t, tree.RemoveLeaf(6))
+	expTree, err := NewTree(slices.Delete(slices.Clone(data), 6, 7), sha256.New)
+	require.NoError(t, err)
+	assert.Equal(t, expTree.Root.Hash, tree.Root.Hash)
+
+	proof, err := tree.GenerateProofByIndex(6)
+	require.NoError(t, err)
+	isValid, err := tree.VerifyProof(proof, data[7])
+	require.NoError(t, err)
+	assert.True(t, isValid)
+}
+
+func TestRemoveLeaves(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		size    int
+		indices []int
+		prune   []int
+		err     error
+	}{
+		{
+			name:    "Single leaf",
+			size:    5,
+			indices: []int{2},
+		},
+		{
+			name:    "Unsorted with duplicates",
+			size:    13,
+			indices: []int{12, 0, 7, 0, 3},
+		},
+		{
+			name:    "Adjacent leaves",
+			size:    9,
+			indices: []int{4, 5, 6},
+		},
+		{
+			name:    "All leaves",
+			size:    4,
+			indices: []int{0, 1, 2, 3},
+		},
+		{
+			name: "No indices",
+			size: 3,
+		},
+		{
+			name:    "Invalid index",
+			size:    4,
+			indices: []int{0, 4},
+			err:     ErrIndexOutOfBounds,
+		},
+		{
+			name:    "Removed leaves after pruned leaf",
+			size:    8,
+			indices: []int{5, 6, 7},
+			prune:   []int{5, 6, 7},
+		},
+		{
+			name:    "Pruned leaf after removed leaf",
+			size:    8,
+			indices: []int{1, 7},
+			prune:   []int{1, 7},
+			err:     ErrLeafPruned,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := generateDummyData(tc.size)
+			tree, err := NewTree(data, sha256.New)
+			require.NoError(t, err)
+			if tc.prune != nil {
+				require.NoError(t, tree.Prune(tc.prune))
+			}
+			root := tree.Root.Hash
+
+			err = tree.RemoveLeaves(tc.indices)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				assert.Len(t, tree.Leaves, tc.size, "No leaf should be removed")
+				assert.Equal(t, root, tree.Root.Hash)
+				return
+			}
+			require.NoError(t, err)
+
+			var remaining [][]byte
+			for i, value := range data {
+				if !slices.Contains(tc.indices, i) {
+					remaining = append(remaining, value)
+				}
+			}
+			if len(remaining) == 0 {
+				assert.Empty(t, tree.Leaves)
+				assert.Nil(t, tree.Root)
+				return
+			}
+			expTree, err := NewTree(remaining, sha256.New)
+			require.NoError(t, err)
+			assert.Equal(t, expTree.Root.Hash, tree.Root.Hash)
+			assert.Len(t, tree.Leaves, len(remaining))
+			if tc.prune == nil {
+				assert.True(t, tree.Equal(expTree), "Tree mismatch")
+			}
+		})
+	}
+}
+
+func TestRemoveLeafByValue(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		initial   [][]byte
+		value     []byte
+		expValues [][]byte
+		err       error
+	}{
+		{
+			name:      "Remove unique value",
+			initial:   [][]byte{[]byte("leaf1"), []byte("leaf2"), []byte("leaf3")},
+			value:     []byte("leaf2"),
+			expValues: [][]byte{[]byte("leaf1"), []byte("leaf3")},
+		},
+		{
+			name:      "Remove first of duplicates",
+			initial:   [][]byte{[]byte("dup"), []byte("leaf2"), []byte("dup")},
+			value:     []byte("dup"),
+			expValues: [][]byte{[]byte("leaf2"), []byte("dup")},
+		},
+		{
+			name:    "Value not found",
+			initial: [][]byte{[]byte("leaf1"), []byte("leaf2")},
+			value:   []byte("missing"),
+			err:     ErrNoVal,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := NewTree(tc.initial, sha256.New)
+			require.NoError(t, err)
+
+			err = tree.RemoveLeafByValue(tc.value)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				assert.Len(t, tree.Leaves, len(tc.initial))
+				return
+			}
+			require.NoError(t, err)
+
+			values := make([][]byte, len(tree.Leaves))
+			for i, leaf := range tree.Leaves {
+				values[i] = leaf.Value
+			}
+			assert.Equal(t, tc.expValues, values)
+		})
+	}
+}
+
 func TestGenerateProof(t *testing.T) {
 	t.Parallel()
 
@@ -397,6 +1085,88 @@ func TestGenerateProofByIndex(t *testing.T) {
 	}
 }
 
+func TestGenerateProofInto(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		size  int
+		index int
+		err   error
+	}{
+		{
+			name:  "First leaf",
+			size:  7,
+			index: 0,
+		},
+		{
+			name:  "Carried leaf",
+			size:  7,
+			index: 6,
+		},
+		{
+			name:  "Single leaf",
+			size:  1,
+			index: 0,
+		},
+		{
+			name:  "Index out of bounds",
+			size:  7,
+			index: 7,
+			err:   ErrIndexOutOfBounds,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := NewTree(generateDummyData(tc.size), sha256.New)
+			require.NoError(t, err)
+
+			// Start from a proof with stale hashes to check they are replaced.
+			proof := &Proof{Hashes: [][]byte{[]byte("stale"), []byte("stale"), []byte("stale"), []byte("stale")}}
+			err = tree.GenerateProofInto(tc.index, proof)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+
+			expProof, err := tree.GenerateProofByIndex(tc.index)
+			require.NoError(t, err)
+			assert.Equal(t, expProof.Index, proof.Index)
+			require.Len(t, proof.Hashes, len(expProof.Hashes))
+			for i, hash := range expProof.Hashes {
+				assert.Equal(t, hash, proof.Hashes[i])
+			}
+		})
+	}
+}
+
+func TestGenerateProofIntoAllocations(t *testing.T) {
+	data := generateDummyData(1000)
+	tree, err := NewTree(data, sha256.New)
+	require.NoError(t, err)
+
+	var proof Proof
+	require.NoError(t, tree.GenerateProofInto(0, &proof))
+	allocs := testing.AllocsPerRun(100, func() {
+		if err := tree.GenerateProofInto(len(data)/2, &proof); err != nil {
+			t.Fatal(err)
+		}
+	})
+	assert.Zero(t, allocs)
+
+	// GenerateProofByIndex allocates the proof and its hashes once.
+	allocs = testing.AllocsPerRun(100, func() {
+		if _, err := tree.GenerateProofByIndex(len(data) / 2); err != nil {
+			t.Fatal(err)
+		}
+	})
+	assert.Equal(t, 2.0, allocs)
+}
+
 func TestVerifyProof(t *testing.T) {
 	t.Parallel()
 
@@ -574,11 +1344,21 @@ func TestGenerateVerifyProof(t *testing.T) {
 			proofValue: []byte("nonexistent"),
 			err:        ErrNoVal,
 		},
+		{
+			name:       "Three leaves, valid proof for last leaf",
+			values:     [][]byte{[]byte("yolo"), []byte("diftp"), []byte("ngmi")},
+			proofValue: []byte("ngmi"),
+		},
 		{
 			name:       "Five leaves, valid proof for third leaf",
 			values:     [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e")},
 			proofValue: []byte("c"),
 		},
+		{
+			name:       "Five leaves, valid proof for last leaf",
+			values:     [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e")},
+			proofValue: []byte("e"),
+		},
 		{
 			name:       "Five leaves, invalid proof for non-existent leaf",
 			values:     [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e")},
@@ -609,6 +1389,50 @@ func TestGenerateVerifyProof(t *testing.T) {
 	}
 }
 
+func TestVerifyProofOddSizes(t *testing.T) {
+	t.Parallel()
+
+	// Nodes without a sibling are carried up, so proofs of leaves on the
+	// right edge skip the levels where the path has no sibling.
+	for _, size := range []int{3, 5, 6, 7, 9, 11, 13, 17} {
+		t.Run(fmt.Sprintf("%d leaves", size), func(t *testing.T) {
+			t.Parallel()
+
+			data := generateDummyData(size)
+			tree, err := NewTree(data, sha256.New)
+			require.NoError(t, err)
+
+			for i, value := range data {
+				proof, err := tree.GenerateProofByIndex(i)
+				require.NoError(t, err)
+
+				isValid, err := tree.VerifyProof(proof, value)
+				require.NoError(t, err)
+				assert.True(t, isValid, "Proof for leaf %d should be valid", i)
+
+				var sizeErr *ProofSizeError
+				long := &Proof{Index: i, Hashes: append(slices.Clone(proof.Hashes), proof.Hashes[0])}
+				isValid, err = tree.VerifyProof(long, value)
+				require.ErrorAs(t, err, &sizeErr, "Proof for leaf %d with an extra hash", i)
+				assert.False(t, isValid)
+
+				short := &Proof{Index: i, Hashes: proof.Hashes[:len(proof.Hashes)-1]}
+				isValid, err = tree.VerifyProof(short, value)
+				require.ErrorAs(t, err, &sizeErr, "Proof for leaf %d without its last hash", i)
+				assert.False(t, isValid)
+			}
+
+			// The proof of the last leaf doesn't verify for another leaf.
+			proof, err := tree.GenerateProofByIndex(size - 1)
+			require.NoError(t, err)
+			proof.Index = size - 2
+			isValid, err := tree.VerifyProof(proof, data[size-1])
+			require.ErrorIs(t, err, ErrProofVerificationFailed)
+			assert.False(t, isValid)
+		})
+	}
+}
+
 func TestCombineHashes(t *testing.T) {
 	t.Parallel()
 
@@ -748,6 +1572,20 @@ func TestStringifyTree(t *testing.T) {
 
 			treeStr := tree.Root.StringifyTree("", false)
 			assert.Equal(t, tc.exp, treeStr)
+			assert.Equal(t, len(treeStr), stringifiedSize(tree.Root, 0, false))
+		})
+	}
+}
+
+func BenchmarkStringifyTree(b *testing.B) {
+	for _, size := range []int{1000, 100000} {
+		b.Run(fmt.Sprintf("%d leaves", size), func(b *testing.B) {
+			tree, _ := NewTree(generateDummyData(size), sha256.New)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_ = tree.Root.StringifyTree("", false)
+			}
 		})
 	}
 }
@@ -774,6 +1612,7 @@ func BenchmarkProofGeneration(b *testing.B) {
 			data := generateDummyData(size)
 			hashFunc := sha256.New
 			tree, _ := NewTree(data, hashFunc)
+			b.ReportAllocs()
 			b.ResetTimer()
 			for i := 0; i < b.N; i++ {
 				_, _ = tree.GenerateProof(data[size/2])
@@ -782,6 +1621,47 @@ func BenchmarkProofGeneration(b *testing.B) {
 	}
 }
 
+func TestVerifyProofAllocations(t *testing.T) {
+	data := generateDummyData(1000)
+	tree, err := NewTree(data, sha256.New)
+	require.NoError(t, err)
+	flatTree, err := NewFlatTree(data, sha256.New)
+	require.NoError(t, err)
+	proof, err := tree.GenerateProofByIndex(len(data) / 2)
+	require.NoError(t, err)
+
+	// The leaf and node hashes are computed in pooled buffers.
+	allocs := testing.AllocsPerRun(100, func() {
+		if ok, err := tree.VerifyProof(proof, data[len(data)/2]); !ok {
+			t.Fatal(err)
+		}
+	})
+	assert.Zero(t, allocs)
+	allocs = testing.AllocsPerRun(100, func() {
+		if ok, err := flatTree.VerifyProof(proof, data[len(data)/2]); !ok {
+			t.Fatal(err)
+		}
+	})
+	assert.Zero(t, allocs)
+}
+
+func BenchmarkGenerateProofInto(b *testing.B) {
+	for _, size := range []int{1000, 10000, 100000} {
+		b.Run(fmt.Sprintf("%d leaves", size), func(b *testing.B) {
+			tree, _ := NewTree(generateDummyData(size), sha256.New)
+			var proof Proof
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_ = tree.GenerateProofInto(size/2, &proof)
+			}
+			if allocs := testing.AllocsPerRun(10, func() { _ = tree.GenerateProofInto(size/2, &proof) }); allocs != 0 {
+				b.Fatalf("GenerateProofInto allocated %v times per call", allocs)
+			}
+		})
+	}
+}
+
 func BenchmarkProofVerification(b *testing.B) {
 	for _, size := range []int{1000, 10000, 100000} {
 		b.Run(fmt.Sprintf("%d leaves", size), func(b *testing.B) {
@@ -789,6 +1669,7 @@ func BenchmarkProofVerification(b *testing.B) {
 			hashFunc := sha256.New
 			tree, _ := NewTree(data, hashFunc)
 			proof, _ := tree.GenerateProof(data[size/2])
+			b.ReportAllocs()
 			b.ResetTimer()
 			for i := 0; i < b.N; i++ {
 				_, _ = tree.VerifyProof(proof, data[size/2])
@@ -0,0 +1,45 @@
+package merkle
+
+// SetMetadata attaches metadata to the leaf at index, replacing
+// any metadata it had. Metadata is not hashed, so it doesn't change
+// the root. It stays with the leaf when the leaf is updated or moved
+// by changes to other leaves.
+func (t *Tree) SetMetadata(index int, metadata map[string]any) error {
+	if err := t.checkLeaf(index); err != nil {
+		return err
+	}
+	t.Leaves[index].Metadata = metadata
+	return nil
+}
+
+// Metadata returns the metadata attached to the leaf at index.
+func (t *Tree) Metadata(index int) (map[string]any, error) {
+	if err := t.checkLeaf(index); err != nil {
+		return nil, err
+	}
+	return t.Leaves[index].Metadata, nil
+}
+
+// LeafProof is an inclusion proof together with the value
+// and metadata of the leaf, e.g. for exporting leaves with their proofs.
+type LeafProof struct {
+	Proof
+	Value    []byte
+	Metadata map[string]any
+}
+
+// GenerateLeafProof generates an inclusion proof for the leaf at index
+// that includes the value and metadata of the leaf.
+func (t *Tree) GenerateLeafProof(index int) (*LeafProof, error) {
+	proof, err := t.GenerateProofByIndex(index)
+	if err != nil {
+		return nil, err
+	}
+
+	leaf := t.Leaves[index]
+	return &LeafProof{
+		Proof:    *proof,
+		Value:    leaf.Value,
+		Metadata: leaf.Metadata,
+	}, nil
+}
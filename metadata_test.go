@@ -0,0 +1,77 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadata(t *testing.T) {
+	t.Parallel()
+
+	data := generateDummyData(5)
+	tree, err := NewTree(data, sha256.New)
+	require.NoError(t, err)
+	root := tree.RootHash()
+
+	metadata := map[string]any{"source": "ingest", "line": 3}
+	require.NoError(t, tree.SetMetadata(3, metadata))
+	assert.Equal(t, root, tree.RootHash(), "Metadata should not change the root")
+
+	md, err := tree.Metadata(3)
+	require.NoError(t, err)
+	assert.Equal(t, metadata, md)
+
+	md, err = tree.Metadata(0)
+	require.NoError(t, err)
+	assert.Nil(t, md)
+
+	// The metadata stays with the leaf when it's updated or moved.
+	require.NoError(t, tree.UpdateLeaf(3, []byte("new")))
+	require.NoError(t, tree.RemoveLeaf(0))
+	md, err = tree.Metadata(2)
+	require.NoError(t, err)
+	assert.Equal(t, metadata, md)
+
+	tests := []struct {
+		name  string
+		index int
+		err   error
+	}{
+		{name: "Negative index", index: -1, err: ErrIndexOutOfBounds},
+		{name: "Index too large", index: 4, err: ErrIndexOutOfBounds},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.ErrorIs(t, tree.SetMetadata(tc.index, metadata), tc.err)
+			_, err := tree.Metadata(tc.index)
+			require.ErrorIs(t, err, tc.err)
+			_, err = tree.GenerateLeafProof(tc.index)
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestGenerateLeafProof(t *testing.T) {
+	t.Parallel()
+
+	data := generateDummyData(5)
+	tree, err := NewTree(data, sha256.New)
+	require.NoError(t, err)
+
+	metadata := map[string]any{"owner": "alice"}
+	require.NoError(t, tree.SetMetadata(2, metadata))
+
+	proof, err := tree.GenerateLeafProof(2)
+	require.NoError(t, err)
+	assert.Equal(t, data[2], proof.Value)
+	assert.Equal(t, metadata, proof.Metadata)
+
+	isValid, err := tree.VerifyProof(&proof.Proof, proof.Value)
+	require.NoError(t, err)
+	assert.True(t, isValid)
+}
@@ -0,0 +1,106 @@
+package merkle
+
+import (
+	"fmt"
+	"hash"
+
+	blsfr "github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	blsmimc "github.com/consensys/gnark-crypto/ecc/bls12-381/fr/mimc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/mimc"
+)
+
+// mimcHash splits its input into field elements before passing them
+// to a MiMC hash, which only accepts canonical field elements.
+// The MiMC hash buffers the elements until Sum anyway, so the input
+// is buffered and only split in Sum, which keeps elements that span
+// writes together. Write checks elements as they are completed.
+type mimcHash struct {
+	hash.Hash
+	setCanonical func(element []byte) error
+	buf          []byte
+	// err is set by the first element that is not canonical.
+	err error
+}
+
+// NewMiMCBN254 returns a new MiMC hash over the scalar field of BN254,
+// as implemented by gnark-crypto, so roots and proofs can be verified
+// in gnark circuits.
+//
+// The input is split into 32 byte big endian field elements, and a short
+// last element is padded with leading zeros. Elements that are not smaller
+// than the modulus are rejected like by NewPoseidon2, so use
+// WithLeafValidator with ValidateFieldElements, and encode values
+// with FieldElement.
+func NewMiMCBN254() hash.Hash {
+	return &mimcHash{
+		Hash: mimc.NewMiMC(),
+		setCanonical: func(element []byte) error {
+			var e fr.Element
+			return e.SetBytesCanonical(element)
+		},
+	}
+}
+
+// NewMiMCBLS12381 returns a new MiMC hash over the scalar field
+// of BLS12-381. Its input is split into field elements like the input
+// of NewMiMCBN254, and ValidateBLS12381FieldElements checks values.
+func NewMiMCBLS12381() hash.Hash {
+	return &mimcHash{
+		Hash: blsmimc.NewMiMC(),
+		setCanonical: func(element []byte) error {
+			var e blsfr.Element
+			return e.SetBytesCanonical(element)
+		},
+	}
+}
+
+// ValidateBLS12381FieldElements checks that value is a sequence of 32 byte
+// big endian elements of the scalar field of BLS12-381. Use it with
+// WithLeafValidator in trees that hash with NewMiMCBLS12381.
+func ValidateBLS12381FieldElements(value []byte) ([]byte, error) {
+	return validateFieldElements(value, func(element []byte) error {
+		var e blsfr.Element
+		return e.SetBytesCanonical(element)
+	})
+}
+
+func (h *mimcHash) Write(p []byte) (int, error) {
+	if h.err != nil {
+		return 0, h.err
+	}
+
+	size := h.BlockSize()
+	n := len(h.buf)
+	h.buf = append(h.buf, p...)
+	for i := n - n%size; i+size <= len(h.buf); i += size {
+		element := h.buf[i : i+size]
+		if err := h.setCanonical(element); err != nil {
+			h.err = fmt.Errorf("%w: %x is not smaller than the modulus", ErrInvalidFieldElement, element)
+			h.buf = h.buf[:n]
+			return 0, h.err
+		}
+	}
+	return len(p), nil
+}
+
+func (h *mimcHash) Sum(b []byte) []byte {
+	if h.err != nil {
+		return append(b, failedDigest...)
+	}
+
+	h.Hash.Reset()
+	size := h.BlockSize()
+	for p := h.buf; len(p) > 0; p = p[min(len(p), size):] {
+		// Full elements were checked by Write, and the MiMC hash
+		// pads a short last element with leading zeros.
+		h.Hash.Write(p[:min(len(p), size)])
+	}
+	return h.Hash.Sum(b)
+}
+
+func (h *mimcHash) Reset() {
+	h.Hash.Reset()
+	h.buf = h.buf[:0]
+	h.err = nil
+}
@@ -0,0 +1,186 @@
+package merkle
+
+import (
+	"bytes"
+	"encoding/hex"
+	"hash"
+	"math/big"
+	"testing"
+
+	blsfr "github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr/mimc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiMCBN254(t *testing.T) {
+	t.Parallel()
+
+	// Test vectors from gnark-crypto.
+	tests := []struct {
+		name    string
+		in      []string
+		expHash string
+	}{
+		{
+			name:    "One element",
+			in:      []string{"105afe02a0f7648bee1669b05bf7ae69a37dbb6c86ebbee325dffe97ac1f8e64"},
+			expHash: "263b9e754e6c611d646e65b16c48f51ab7bc0abedfae9c6ea04e2814ed28daf4",
+		},
+		{
+			name: "Two elements",
+			in: []string{
+				"208f0b283064057cf912b65eaa51e2cb2b85fdbe2fd0b2841f4bca59321ef1bf",
+				"226bee7671296d05c998a5b5b4b1d25f478696d5997ba4f4be1a682c56a69e11",
+			},
+			expHash: "1476ada1433d73817a69e45c84c5d452ad858f2dfdb1f7e4da203d3c4fd42222",
+		},
+		{
+			name: "Short element",
+			in: []string{
+				"995d448ab1fc86dd4874ebcbc0a7eea41acbe2c76e300aa73a1a0e63d5bc1b",
+				"2190a93f59d9f8cbb4f6236c5b7bf511aec80e88bec71dad4f5bbba9346ff5e4",
+			},
+			expHash: "0cd4ef5556a9413b6bb98d12aba6ed9b937f0adce41ba618a212fdcb1629737a",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var value []byte
+			for _, in := range tc.in {
+				x, ok := new(big.Int).SetString(in, 16)
+				require.True(t, ok)
+				element, err := FieldElement(x)
+				require.NoError(t, err)
+				value = append(value, element...)
+			}
+
+			tree, err := NewTree([][]byte{value}, NewMiMCBN254)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expHash, hex.EncodeToString(tree.Root.Hash))
+		})
+	}
+}
+
+func TestMiMCBLS12381(t *testing.T) {
+	t.Parallel()
+
+	values := fieldElements(t, 1, 2, 3)
+	tree, err := NewTree(values, NewMiMCBLS12381)
+	require.NoError(t, err)
+
+	hashElements := func(elements ...[]byte) []byte {
+		h := mimc.NewMiMC()
+		for _, element := range elements {
+			_, err := h.Write(element)
+			require.NoError(t, err)
+		}
+		return h.Sum(nil)
+	}
+	leaves := [][]byte{hashElements(values[0]), hashElements(values[1]), hashElements(values[2])}
+	expRoot := hashElements(hashElements(leaves[0], leaves[1]), leaves[2])
+	assert.Equal(t, expRoot, tree.Root.Hash)
+
+	bn254Tree, err := NewTree(values, NewMiMCBN254)
+	require.NoError(t, err)
+	assert.NotEqual(t, bn254Tree.Root.Hash, tree.Root.Hash)
+
+	for i, value := range values {
+		proof, err := tree.GenerateProofByIndex(i)
+		require.NoError(t, err)
+
+		isValid, err := tree.VerifyProof(proof, value)
+		require.NoError(t, err)
+		assert.True(t, isValid)
+	}
+}
+
+func TestMiMCSplitWrites(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		newHashFunc func() hash.Hash
+	}{
+		{
+			name:        "BN254",
+			newHashFunc: NewMiMCBN254,
+		},
+		{
+			name:        "BLS12-381",
+			newHashFunc: NewMiMCBLS12381,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assertSplitWrites(t, tc.newHashFunc)
+		})
+	}
+}
+
+func TestMiMCNonCanonical(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		newHashFunc func() hash.Hash
+		validate    func(value []byte) ([]byte, error)
+		modulus     *big.Int
+	}{
+		{
+			name:        "BN254",
+			newHashFunc: NewMiMCBN254,
+			validate:    ValidateFieldElements,
+			modulus:     fr.Modulus(),
+		},
+		{
+			name:        "BLS12-381",
+			newHashFunc: NewMiMCBLS12381,
+			validate:    ValidateBLS12381FieldElements,
+			modulus:     blsfr.Modulus(),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			one := fieldElements(t, 1)[0]
+			modulus := tc.modulus.FillBytes(make([]byte, 32))
+			largest := new(big.Int).Sub(tc.modulus, big.NewInt(1)).FillBytes(make([]byte, 32))
+
+			h := tc.newHashFunc()
+			h.Write(one)
+			expDigest := h.Sum(nil)
+
+			// Elements that span writes are checked when they are complete.
+			h.Reset()
+			_, err := h.Write(append(bytes.Clone(one), modulus[:10]...))
+			require.NoError(t, err)
+			_, err = h.Write(modulus[10:])
+			require.ErrorIs(t, err, ErrInvalidFieldElement)
+
+			// The hash stays failed until it is reset.
+			_, err = h.Write(one)
+			require.ErrorIs(t, err, ErrInvalidFieldElement)
+			assert.Equal(t, bytes.Repeat([]byte{0xff}, 32), h.Sum(nil))
+			h.Reset()
+			h.Write(one)
+			assert.Equal(t, expDigest, h.Sum(nil))
+
+			_, err = tc.validate(largest)
+			require.NoError(t, err)
+			_, err = tc.validate(modulus)
+			require.ErrorIs(t, err, ErrInvalidFieldElement)
+			_, err = NewTree([][]byte{modulus}, tc.newHashFunc, WithLeafValidator(tc.validate))
+			require.ErrorIs(t, err, ErrInvalidFieldElement)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package merkle
+
+import (
+	"fmt"
+	"os"
+)
+
+// MappedFile is a file that is mapped into memory read-only, so trees
+// can be built over files larger than memory. The leaves returned by
+// Leaves alias the mapping instead of copying it, and the pages of the
+// file are only read as they are hashed and can be evicted again by
+// the operating system. On systems without mmap, the file is read
+// into memory instead.
+type MappedFile struct {
+	data  []byte
+	unmap func() error
+}
+
+// MapFile maps the file at path into memory.
+func MapFile(path string) (*MappedFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() == 0 {
+		// Empty files can't be mapped.
+		return &MappedFile{unmap: func() error { return nil }}, nil
+	}
+	if int64(int(info.Size())) != info.Size() {
+		return nil, fmt.Errorf("file %s is too large to map: %d bytes", path, info.Size())
+	}
+
+	data, unmap, err := mapFile(f, int(info.Size()))
+	if err != nil {
+		return nil, fmt.Errorf("map %s: %w", path, err)
+	}
+	return &MappedFile{data: data, unmap: unmap}, nil
+}
+
+// Bytes returns the contents of the file.
+// They must not be used after the file is closed.
+func (f *MappedFile) Bytes() []byte {
+	return f.data
+}
+
+// Leaves splits the file into leaves of size bytes, which alias the
+// mapping. Only the last leaf can be smaller. A tree built from them
+// holds the mapping as its leaf values, so the file must stay open
+// while the tree is used, unless it is built with WithoutLeafValues.
+func (f *MappedFile) Leaves(size int) ([][]byte, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidChunkSize, size)
+	}
+
+	leaves := make([][]byte, 0, (len(f.data)+size-1)/size)
+	for start := 0; start < len(f.data); start += size {
+		end := min(start+size, len(f.data))
+		leaves = append(leaves, f.data[start:end:end])
+	}
+	return leaves, nil
+}
+
+// Close unmaps the file. Slices of the mapping must not be used afterwards.
+func (f *MappedFile) Close() error {
+	if f.unmap == nil {
+		return nil
+	}
+	unmap := f.unmap
+	f.data, f.unmap = nil, nil
+	return unmap()
+}
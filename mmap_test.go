@@ -0,0 +1,95 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMapFile(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		size      int
+		leafSize  int
+		expLeaves int
+		expErr    error
+	}{
+		{
+			name:      "Complete leaves",
+			size:      4096,
+			leafSize:  1024,
+			expLeaves: 4,
+		},
+		{
+			name:      "Partial last leaf",
+			size:      5000,
+			leafSize:  1024,
+			expLeaves: 5,
+		},
+		{
+			name:      "Empty file",
+			size:      0,
+			leafSize:  1024,
+			expLeaves: 0,
+		},
+		{
+			name:     "Invalid leaf size",
+			size:     100,
+			leafSize: 0,
+			expErr:   ErrInvalidChunkSize,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			contents := make([]byte, tc.size)
+			for i := range contents {
+				contents[i] = byte(i * 7)
+			}
+			path := filepath.Join(t.TempDir(), "data")
+			require.NoError(t, os.WriteFile(path, contents, 0o600))
+
+			f, err := MapFile(path)
+			require.NoError(t, err)
+			defer f.Close()
+			assert.Equal(t, contents, append([]byte{}, f.Bytes()...))
+
+			leaves, err := f.Leaves(tc.leafSize)
+			if tc.expErr != nil {
+				require.ErrorIs(t, err, tc.expErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, leaves, tc.expLeaves)
+			if tc.expLeaves == 0 {
+				return
+			}
+
+			tree, err := NewTree(leaves, sha256.New)
+			require.NoError(t, err)
+			expTree, err := NewTree(generateChunks(contents, tc.leafSize), sha256.New)
+			require.NoError(t, err)
+			assert.Equal(t, expTree.RootHash(), tree.RootHash())
+
+			// The leaf values alias the mapping.
+			assert.Same(t, &f.Bytes()[0], &tree.Leaves[0].Value[0])
+		})
+	}
+}
+
+// generateChunks copies data into chunks of size bytes.
+func generateChunks(data []byte, size int) [][]byte {
+	var chunks [][]byte
+	for start := 0; start < len(data); start += size {
+		chunks = append(chunks, append([]byte{}, data[start:min(start+size, len(data))]...))
+	}
+	return chunks
+}
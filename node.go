@@ -0,0 +1,37 @@
+package merkle
+
+// IsLeaf reports whether the node has no children. The roots of
+// pruned subtrees have no children either.
+func (n *Node) IsLeaf() bool {
+	return n.Left == nil && n.Right == nil
+}
+
+// Sibling returns the other child of the parent of the node,
+// or nil for the root.
+func (n *Node) Sibling() *Node {
+	if n.Parent == nil {
+		return nil
+	}
+	if n.Parent.Left == n {
+		return n.Parent.Right
+	}
+	return n.Parent.Left
+}
+
+// Level returns the level of the node above the leaves, which are
+// at level 0. Nodes without a sibling are carried up, so they keep
+// the level they were created at. The level is counted down the
+// left children, so pruned subtrees report 0, and the nodes above
+// a pruned left child report a level that is too low.
+func (n *Node) Level() int {
+	return nodeLevel(n)
+}
+
+// PathToRoot returns the node and its ancestors, from the node to the root.
+func (n *Node) PathToRoot() []*Node {
+	var path []*Node
+	for node := n; node != nil; node = node.Parent {
+		path = append(path, node)
+	}
+	return path
+}
@@ -0,0 +1,86 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodeNavigation(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewTree(generateDummyData(5), sha256.New)
+	require.NoError(t, err)
+	root := tree.Root
+
+	tests := []struct {
+		name       string
+		node       *Node
+		expLeaf    bool
+		expSibling *Node
+		expLevel   int
+		expPath    []*Node
+	}{
+		{
+			name:     "Root",
+			node:     root,
+			expLevel: 3,
+			expPath:  []*Node{root},
+		},
+		{
+			name:       "Left leaf",
+			node:       tree.Leaves[2],
+			expLeaf:    true,
+			expSibling: tree.Leaves[3],
+			expLevel:   0,
+			expPath:    []*Node{tree.Leaves[2], root.Left.Right, root.Left, root},
+		},
+		{
+			name:       "Internal node",
+			node:       root.Left.Right,
+			expSibling: root.Left.Left,
+			expLevel:   1,
+			expPath:    []*Node{root.Left.Right, root.Left, root},
+		},
+		{
+			name:       "Promoted leaf",
+			node:       tree.Leaves[4],
+			expLeaf:    true,
+			expSibling: root.Left,
+			expLevel:   0,
+			expPath:    []*Node{tree.Leaves[4], root},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expLeaf, tc.node.IsLeaf())
+			assert.Same(t, tc.expSibling, tc.node.Sibling())
+			assert.Equal(t, tc.expLevel, tc.node.Level())
+			assert.Equal(t, tc.expPath, tc.node.PathToRoot())
+		})
+	}
+}
+
+func TestNodePathMatchesProof(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewTree(generateDummyData(11), sha256.New)
+	require.NoError(t, err)
+
+	for i, leaf := range tree.Leaves {
+		proof, err := tree.GenerateProofByIndex(i)
+		require.NoError(t, err)
+
+		path := leaf.PathToRoot()
+		require.Len(t, path, len(proof.Hashes)+1)
+		assert.Same(t, tree.Root, path[len(path)-1])
+		for j, node := range path[:len(path)-1] {
+			assert.Equal(t, proof.Hashes[j], node.Sibling().Hash)
+		}
+	}
+}
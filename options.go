@@ -0,0 +1,426 @@
+package merkle
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+var ErrInvalidLeafSize = errors.New("invalid leaf size")
+
+// Option configures how a tree is built.
+type Option func(*config)
+
+// config holds the settings applied by options.
+type config struct {
+	allowEmpty bool
+	hasher     Hasher
+	leafKey    []byte
+
+	lengthPrefix bool
+
+	// leafPrefix and nodePrefix are hashed before leaves and nodes.
+	prefixes   bool
+	leafPrefix []byte
+	nodePrefix []byte
+
+	// levelTag returns the tag that is hashed before the children
+	// of a node at the given level, if set.
+	levelTag func(level int) []byte
+
+	// combine replaces the hashing of nodes, if set.
+	combine func(left, right []byte) []byte
+
+	// fixedLeafSize requires all leaves to be leafSize bytes.
+	// A leafSize of 0 means the digest size of the hash function.
+	fixedLeafSize bool
+	leafSize      int
+
+	// validate checks every leaf value and returns the value
+	// to store in the tree, if set.
+	validate func(value []byte) ([]byte, error)
+
+	// rootHistory records every root of the tree.
+	rootHistory bool
+
+	// capacity is the expected number of leaves, if set.
+	capacity int
+
+	// deferHashing postpones rehashing nodes after updates
+	// until the tree is read.
+	deferHashing bool
+
+	// parallelism bounds the goroutines that hash in parallel.
+	parallelism parallelism
+
+	// leafHashesOnly makes flat trees store only their leaf hashes.
+	leafHashesOnly bool
+
+	// subtreeCache is the number of subtree roots to cache, if set.
+	subtreeCache int
+
+	// dropValues makes trees keep only the hashes of their leaves.
+	// resolveValue returns the value of a leaf by index, if set.
+	dropValues   bool
+	resolveValue func(index int) ([]byte, error)
+
+	// dedupe shares the hashes of equal nodes and the values
+	// of equal leaves when a tree is built.
+	dedupe bool
+
+	// buildProfile and buildProgress receive the profile and the progress
+	// of every build, if set, and monitor collects them while the tree
+	// is built.
+	buildProfile  func(BuildProfile)
+	buildProgress func(BuildProgress)
+	monitor       *buildMonitor
+}
+
+func newConfig(opts []Option, newHashFunc func() hash.Hash) config {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.hasher == nil {
+		cfg.hasher = StdHasher(newHashFunc)
+	}
+	cfg.setHasher(cfg.hasher)
+
+	if cfg.fixedLeafSize && cfg.leafSize <= 0 {
+		cfg.leafSize = cfg.hasher.NewLeafHasher().Size()
+	}
+
+	return cfg
+}
+
+// setHasher sets the hasher of the tree, wrapped by the hashers
+// of the configured leaf keys and prefixes.
+func (cfg *config) setHasher(h Hasher) {
+	if cfg.leafKey != nil {
+		h = hmacLeafHasher{Hasher: h, key: cfg.leafKey}
+	}
+	if cfg.prefixes {
+		h = prefixHasher{Hasher: h, leafPrefix: cfg.leafPrefix, nodePrefix: cfg.nodePrefix}
+	}
+	if cfg.lengthPrefix {
+		h = lengthPrefixHasher{Hasher: h}
+	}
+	cfg.hasher = h
+}
+
+// checkLeafSize returns an error if leaves must have a fixed size
+// and value doesn't have it.
+func (cfg *config) checkLeafSize(value []byte) error {
+	if cfg.fixedLeafSize && len(value) != cfg.leafSize {
+		return fmt.Errorf("%w: expected %d bytes, but got %d",
+			ErrInvalidLeafSize, cfg.leafSize, len(value))
+	}
+	return nil
+}
+
+// checkLeafSizes checks the size of every leaf.
+func (cfg *config) checkLeafSizes(values [][]byte) error {
+	if !cfg.fixedLeafSize {
+		return nil
+	}
+	for i, value := range values {
+		if err := cfg.checkLeafSize(value); err != nil {
+			return fmt.Errorf("leaf %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// leafValue checks value with the configured validator and leaf size
+// and returns the value to store in the tree.
+func (cfg *config) leafValue(value []byte) ([]byte, error) {
+	if cfg.validate != nil {
+		var err error
+		value, err = cfg.validate(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.checkLeafSize(value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// leafValues checks every value like leafValue. The values are only
+// copied into a new slice if there is a validator.
+func (cfg *config) leafValues(values [][]byte) ([][]byte, error) {
+	if cfg.validate == nil {
+		return values, cfg.checkLeafSizes(values)
+	}
+	out := make([][]byte, len(values))
+	for i, value := range values {
+		var err error
+		out[i], err = cfg.leafValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("leaf %d: %w", i, err)
+		}
+	}
+	return out, nil
+}
+
+// WithEmptyTree allows creating a tree without leaves.
+// The root of an empty tree is the hash of the empty string,
+// as defined in RFC 6962, instead of failing with ErrNoLeaves.
+func WithEmptyTree() Option {
+	return func(cfg *config) {
+		cfg.allowEmpty = true
+	}
+}
+
+// WithHasher hashes leaves and nodes with the hash functions
+// created by h instead of the hash function passed to the constructor.
+// The hash function passed to the constructor may be nil in that case.
+func WithHasher(h Hasher) Option {
+	return func(cfg *config) {
+		cfg.hasher = h
+	}
+}
+
+// WithInsecureHash hashes leaves and nodes with the 64-bit xxHash
+// instead of the hash function passed to the constructor.
+// xxHash is much faster than cryptographic hash functions, but collisions
+// are easy to find, so it must only be used for non-adversarial integrity
+// checks like cache invalidation and deduplication.
+// The hash function passed to the constructor may be nil.
+func WithInsecureHash() Option {
+	return func(cfg *config) {
+		cfg.hasher = StdHasher(func() hash.Hash { return xxhash.New() })
+	}
+}
+
+// WithBLAKE2b hashes leaves with BLAKE2b-256 keyed with leafKey and nodes
+// with BLAKE2b-256 keyed with nodeKey, instead of the hash function passed
+// to the constructor, which may be nil. Different keys separate leaves from
+// nodes, and trees from each other. An empty key is unkeyed BLAKE2b-256,
+// and keys longer than 64 bytes are hashed first. Use NewBLAKE2bFunc
+// for other digest sizes, salts or personalization strings.
+func WithBLAKE2b(leafKey, nodeKey []byte) Option {
+	return func(cfg *config) {
+		cfg.hasher = SplitHasher(newKeyedBLAKE2b(leafKey), newKeyedBLAKE2b(nodeKey))
+	}
+}
+
+// WithHMACLeaves keys the leaf hashes with an HMAC over the leaf hash
+// function, so only holders of the key can compute valid leaves.
+// This keeps leaves of a public root from being guessed or forged,
+// e.g. for private allowlists. Nodes are hashed without the key.
+func WithHMACLeaves(key []byte) Option {
+	return func(cfg *config) {
+		cfg.leafKey = bytes.Clone(key)
+	}
+}
+
+// WithLengthPrefixedLeaves frames every leaf value with its length before
+// it is hashed, so leaves built from variable length inputs can't collide
+// through ambiguous concatenations. A leaf is hashed as
+//
+//	H(uint64be(len(value)) || value)
+//
+// where uint64be is the length in bytes as a big endian 8 byte integer.
+// The length is written after any domain prefix or HMAC key,
+// e.g. H(leafPrefix || uint64be(len(value)) || value).
+func WithLengthPrefixedLeaves() Option {
+	return func(cfg *config) {
+		cfg.lengthPrefix = true
+	}
+}
+
+// WithDomainPrefixes hashes leaves as H(leafPrefix || value) and nodes
+// as H(nodePrefix || left || right), so a node can't be passed off as
+// a leaf. Prefixes of 0x00 and 0x01 result in RFC 6962 trees.
+// Use WithHasher and SplitHasher to use different hash functions
+// for leaves and nodes.
+func WithDomainPrefixes(leafPrefix, nodePrefix []byte) Option {
+	return func(cfg *config) {
+		cfg.prefixes = true
+		cfg.leafPrefix = bytes.Clone(leafPrefix)
+		cfg.nodePrefix = bytes.Clone(nodePrefix)
+	}
+}
+
+// WithLevelTags hashes every internal node as H(tag(level) || left || right),
+// so proofs can't be replayed at a different depth. Leaves are at level 0
+// and a node is one level above its left child. Nodes without a sibling
+// are carried up unchanged, so they keep the level they were created at.
+func WithLevelTags(tag func(level int) []byte) Option {
+	return func(cfg *config) {
+		cfg.levelTag = tag
+	}
+}
+
+// WithCombine replaces the hashing of nodes with combine, which returns
+// the hash of a node from the hashes of its children. It allows schemes
+// like sorted pairs or mixing extra data into nodes. combine must be
+// deterministic and safe for concurrent use, since nodes are hashed
+// in parallel, and it replaces level tags and node prefixes.
+// Nodes without a sibling are still carried up without calling combine.
+func WithCombine(combine func(left, right []byte) []byte) Option {
+	return func(cfg *config) {
+		cfg.combine = combine
+	}
+}
+
+// LevelIndexTag is a level tag for WithLevelTags that encodes
+// the level as a big endian uint32.
+func LevelIndexTag(level int) []byte {
+	return binary.BigEndian.AppendUint32(nil, uint32(level))
+}
+
+// WithFixedLeafSize requires every leaf to be exactly size bytes,
+// both when the tree is built and when leaves are updated.
+// A size of 0 requires leaves to be the digest size of the hash function,
+// for protocols where the leaves are themselves hashes.
+func WithFixedLeafSize(size int) Option {
+	return func(cfg *config) {
+		cfg.fixedLeafSize = true
+		cfg.leafSize = size
+	}
+}
+
+// WithLeafValidator calls validate on every value that enters the tree,
+// when it is built and when leaves are appended or updated.
+// validate returns the value to store in the tree, which allows
+// canonicalizing values, or an error to reject the value.
+// Leaves that are added as hashes are not validated.
+func WithLeafValidator(validate func(value []byte) ([]byte, error)) Option {
+	return func(cfg *config) {
+		cfg.validate = validate
+	}
+}
+
+// WithRootHistory records every root the tree has had, together with
+// the time and the kind of change, so it can be read with RootHistory.
+// The history grows by one record for every change of the tree.
+func WithRootHistory() Option {
+	return func(cfg *config) {
+		cfg.rootHistory = true
+	}
+}
+
+// WithDeferredHashing makes UpdateLeaf and UpdateLeaves only hash
+// the leaves and mark the nodes above them as dirty. The dirty nodes
+// are rehashed by Commit or the next method that reads node hashes,
+// like RootHash or GenerateProof, so every node is hashed once however
+// many leaves below it changed in between. The Root field and the hashes
+// of nodes read through the fields are stale until then.
+func WithDeferredHashing() Option {
+	return func(cfg *config) {
+		cfg.deferHashing = true
+	}
+}
+
+// WithWorkers limits the number of goroutines that hash leaves and nodes
+// in parallel to n, e.g. to leave CPUs to latency-sensitive work.
+// The default is the number of CPUs, except for inputs that are too small
+// to be worth it. With 1 worker, everything is hashed on the calling goroutine.
+func WithWorkers(n int) Option {
+	return func(cfg *config) {
+		cfg.parallelism.workers = max(n, 0)
+	}
+}
+
+// WithBatchSize makes every worker hash n leaves at a time. Smaller batches
+// hand out work in smaller pieces, so busy workers hold up the build less.
+// By default, the leaves are split into one batch per worker, or into
+// many batches of about the same number of bytes for leaves that are
+// hashed up front, like appended leaves.
+func WithBatchSize(n int) Option {
+	return func(cfg *config) {
+		cfg.parallelism.batchSize = max(n, 0)
+	}
+}
+
+// WithLeafHashesOnly makes a FlatTree store only the hashes of its leaves
+// and its root, which halves its memory. The nodes of a proof are
+// recomputed from the leaf hashes instead, so generating a proof
+// or updating a leaf takes O(n) hashes instead of O(log n).
+func WithLeafHashesOnly() Option {
+	return func(cfg *config) {
+		cfg.leafHashesOnly = true
+	}
+}
+
+// WithSubtreeCache caches the roots of up to n subtrees that aren't nodes
+// of the tree, like the right edges of the RFC 6962 decomposition of a
+// prefix of the leaves. Repeated SubtreeRoot and TreeAtSize requests,
+// as for consistency and range proofs against a growing log, then reuse
+// them instead of rehashing the same prefixes. Appending keeps the cache,
+// updates drop the entries that hold the updated leaves, and removals
+// drop all entries. The cache is cleared once it holds n roots.
+// It is guarded by a mutex, so concurrent reads can fill it.
+func WithSubtreeCache(n int) Option {
+	return func(cfg *config) {
+		cfg.subtreeCache = max(n, 0)
+	}
+}
+
+// WithoutLeafValues makes a Tree keep only the hashes of its leaves
+// and drop their values once they have been hashed, so trees over large
+// payloads don't hold every input byte. Value lookups like IndexOf and
+// Contains ask resolve for the value of a leaf at its current index,
+// and find nothing if resolve is nil or fails. Trees without leaf
+// values can't be rehashed and return ErrNoLeafValues.
+func WithoutLeafValues(resolve func(index int) ([]byte, error)) Option {
+	return func(cfg *config) {
+		cfg.dropValues = true
+		cfg.resolveValue = resolve
+	}
+}
+
+// WithBuildProfile calls report with a profile of every tree built by
+// NewTree and NewTreeContext, with the time spent on each level, the bytes
+// hashed and how busy the workers were, to see where the time of large
+// builds goes without attaching a profiler. Profiling reads the clock
+// a few times per level of every chunk of leaves, so it barely slows
+// down the build.
+func WithBuildProfile(report func(BuildProfile)) Option {
+	return func(cfg *config) {
+		cfg.buildProfile = report
+	}
+}
+
+// WithBuildProgress calls report while NewTree and NewTreeContext build
+// a tree, whenever a chunk of leaves or the nodes above it have been
+// hashed, so long builds can show their progress. The calls don't
+// overlap, but they are made from the hashing goroutines, so report
+// should return quickly.
+func WithBuildProgress(report func(BuildProgress)) Option {
+	return func(cfg *config) {
+		cfg.buildProgress = report
+	}
+}
+
+// WithDeduplication makes the nodes of a tree with equal hashes share one
+// hash, and leaves with equal values share one value, when the tree is
+// built. Only the hash and value bytes are shared: every node links to
+// its own parent, so repeated leaves and identical subtrees still have
+// a node each, and every leaf keeps its own index. The savings are the
+// hash bytes of the repeated nodes and the values of repeated leaves.
+// Building takes an extra pass over the tree, and hashes computed
+// by later changes aren't shared.
+func WithDeduplication() Option {
+	return func(cfg *config) {
+		cfg.dedupe = true
+	}
+}
+
+// WithCapacity preallocates storage for n leaves and the nodes above them,
+// for trees that are expected to grow to about n leaves by appending.
+// Once the tree outgrows its storage, it grows in proportion to the tree,
+// so appending stays amortized free of most allocations.
+func WithCapacity(n int) Option {
+	return func(cfg *config) {
+		cfg.capacity = max(n, 0)
+	}
+}
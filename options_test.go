@@ -0,0 +1,796 @@
+package merkle
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithFixedLeafSize(t *testing.T) {
+	t.Parallel()
+
+	digest := sha256.Sum256([]byte("yolo"))
+
+	tests := []struct {
+		name   string
+		values [][]byte
+		size   int
+		err    error
+	}{
+		{
+			name:   "Digest sized leaves",
+			values: [][]byte{digest[:], digest[:]},
+		},
+		{
+			name:   "Short leaf",
+			values: [][]byte{digest[:], digest[:31]},
+			err:    ErrInvalidLeafSize,
+		},
+		{
+			name:   "Configured size",
+			values: [][]byte{[]byte("abcd"), []byte("efgh")},
+			size:   4,
+		},
+		{
+			name:   "Configured size with long leaf",
+			values: [][]byte{[]byte("abcd"), []byte("efghi")},
+			size:   4,
+			err:    ErrInvalidLeafSize,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewTree(tc.values, sha256.New, WithFixedLeafSize(tc.size))
+			require.ErrorIs(t, err, tc.err)
+
+			_, err = NewTreeFromHashes(tc.values, sha256.New, WithFixedLeafSize(tc.size))
+			require.ErrorIs(t, err, tc.err)
+
+			_, err = NewTreeFromSeq(slices.Values(tc.values), sha256.New, WithFixedLeafSize(tc.size))
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestWithFixedLeafSizeUpdateLeaf(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewTree([][]byte{[]byte("abcd"), []byte("efgh")}, sha256.New, WithFixedLeafSize(4))
+	require.NoError(t, err)
+
+	require.NoError(t, tree.UpdateLeaf(0, []byte("wxyz")))
+
+	err = tree.UpdateLeaf(1, []byte("xyz"))
+	require.ErrorIs(t, err, ErrInvalidLeafSize)
+	assert.Equal(t, []byte("efgh"), tree.Leaves[1].Value, "Rejected update should not change the leaf")
+}
+
+func TestWithInsecureHash(t *testing.T) {
+	t.Parallel()
+
+	leafHash := func(value []byte) []byte {
+		return binary.BigEndian.AppendUint64(nil, xxhash.Sum64(value))
+	}
+	nodeHash := func(left, right []byte) []byte {
+		return leafHash(append(slices.Clone(left), right...))
+	}
+
+	data := generateDummyData(3)
+	tree, err := NewTree(data, nil, WithInsecureHash())
+	require.NoError(t, err)
+
+	expRoot := nodeHash(nodeHash(leafHash(data[0]), leafHash(data[1])), leafHash(data[2]))
+	assert.Equal(t, expRoot, tree.Root.Hash)
+
+	for i, value := range data {
+		proof, err := tree.GenerateProofByIndex(i)
+		require.NoError(t, err)
+
+		isValid, err := tree.VerifyProof(proof, value)
+		require.NoError(t, err)
+		assert.True(t, isValid)
+	}
+}
+
+func TestWithHMACLeaves(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("secret")
+	leafHash := func(value []byte) []byte {
+		mac := hmac.New(sha256.New, key)
+		mac.Write(value)
+		return mac.Sum(nil)
+	}
+	nodeHash := func(left, right []byte) []byte {
+		sum := sha256.Sum256(append(slices.Clone(left), right...))
+		return sum[:]
+	}
+
+	data := generateDummyData(3)
+	tree, err := NewTree(data, sha256.New, WithHMACLeaves(key))
+	require.NoError(t, err)
+
+	expRoot := nodeHash(nodeHash(leafHash(data[0]), leafHash(data[1])), leafHash(data[2]))
+	assert.Equal(t, expRoot, tree.Root.Hash)
+
+	proof, err := tree.GenerateProofByIndex(1)
+	require.NoError(t, err)
+	isValid, err := tree.VerifyProof(proof, data[1])
+	require.NoError(t, err)
+	assert.True(t, isValid)
+
+	// Leaves can't be verified without the key.
+	unkeyedTree, err := NewTree(data, sha256.New)
+	require.NoError(t, err)
+	assert.NotEqual(t, unkeyedTree.Root.Hash, tree.Root.Hash)
+
+	isValid, err = VerifyChunk(tree.Root.Hash, len(data), data[1], proof, sha256.New)
+	require.ErrorIs(t, err, ErrProofVerificationFailed)
+	assert.False(t, isValid)
+
+	require.NoError(t, tree.UpdateLeaf(2, []byte("new")))
+	expRoot = nodeHash(nodeHash(leafHash(data[0]), leafHash(data[1])), leafHash([]byte("new")))
+	assert.Equal(t, expRoot, tree.Root.Hash)
+}
+
+func TestWithLevelTags(t *testing.T) {
+	t.Parallel()
+
+	values := [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e")}
+	tests := []struct {
+		name    string
+		tag     func(level int) []byte
+		expRoot string
+	}{
+		{
+			name:    "Level index",
+			tag:     LevelIndexTag,
+			expRoot: "5b500bd122cf72541dec4bdc87e0f20ca1ba2a6927498544b92066d3efd82c4a",
+		},
+		{
+			name: "Label",
+			tag: func(level int) []byte {
+				return append([]byte("my-app/level/"), byte(level))
+			},
+			expRoot: "051659d6d07fedd9a4aba188b2a84302b80599c7e5c429da85a17f395ae9619e",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := NewTree(values, sha256.New, WithLevelTags(tc.tag))
+			require.NoError(t, err)
+			assert.Equal(t, tc.expRoot, hex.EncodeToString(tree.Root.Hash))
+
+			for i, value := range values {
+				proof, err := tree.GenerateProofByIndex(i)
+				require.NoError(t, err)
+
+				isValid, err := tree.VerifyProof(proof, value)
+				require.NoError(t, err)
+				assert.True(t, isValid)
+
+				// Proofs don't verify against an untagged tree.
+				isValid, err = VerifyChunk(tree.Root.Hash, len(values), value, proof, sha256.New)
+				require.ErrorIs(t, err, ErrProofVerificationFailed)
+				assert.False(t, isValid)
+			}
+
+			// Updates rehash the path with the same tags.
+			require.NoError(t, tree.UpdateLeaf(4, []byte("f")))
+			expTree, err := NewTree([][]byte{values[0], values[1], values[2], values[3], []byte("f")},
+				sha256.New, WithLevelTags(tc.tag))
+			require.NoError(t, err)
+			assert.Equal(t, expTree.Root.Hash, tree.Root.Hash)
+
+			snapshot, err := tree.TreeAtSize(3)
+			require.NoError(t, err)
+			expTree, err = NewTree(values[:3], sha256.New, WithLevelTags(tc.tag))
+			require.NoError(t, err)
+			assert.Equal(t, expTree.Root.Hash, snapshot.Root)
+		})
+	}
+}
+
+func TestWithDomainPrefixes(t *testing.T) {
+	t.Parallel()
+
+	// Test vectors from RFC 6962 implementations.
+	values := [][]byte{
+		{},
+		{0x00},
+		{0x10},
+		{0x20, 0x21},
+		{0x30, 0x31},
+		{0x40, 0x41, 0x42, 0x43},
+		{0x50, 0x51, 0x52, 0x53, 0x54, 0x55, 0x56, 0x57},
+		{0x60, 0x61, 0x62, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68, 0x69, 0x6a, 0x6b, 0x6c, 0x6d, 0x6e, 0x6f},
+	}
+	tests := []struct {
+		name    string
+		size    int
+		expRoot string
+	}{
+		{
+			name:    "One leaf",
+			size:    1,
+			expRoot: "6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d",
+		},
+		{
+			name:    "Two leaves",
+			size:    2,
+			expRoot: "fac54203e7cc696cf0dfcb42c92a1d9dbaf70ad9e621f4bd8d98662f00e3c125",
+		},
+		{
+			name:    "Three leaves",
+			size:    3,
+			expRoot: "aeb6bcfe274b70a14fb067a5e5578264db0fa9b51af5e0ba159158f329e06e77",
+		},
+		{
+			name:    "Five leaves",
+			size:    5,
+			expRoot: "4e3bbb1f7b478dcfe71fb631631519a3bca12c9aefca1612bfce4c13a86264d4",
+		},
+		{
+			name:    "Eight leaves",
+			size:    8,
+			expRoot: "5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := NewTree(values[:tc.size], sha256.New, WithDomainPrefixes([]byte{0x00}, []byte{0x01}))
+			require.NoError(t, err)
+			assert.Equal(t, tc.expRoot, hex.EncodeToString(tree.Root.Hash))
+
+			for i, value := range values[:tc.size] {
+				proof, err := tree.GenerateProofByIndex(i)
+				require.NoError(t, err)
+
+				isValid, err := tree.VerifyProof(proof, value)
+				require.NoError(t, err)
+				assert.True(t, isValid)
+			}
+		})
+	}
+}
+
+func TestWithCombine(t *testing.T) {
+	t.Parallel()
+
+	// Sorted pairs make proofs independent of the position of the nodes.
+	sortedPair := func(left, right []byte) []byte {
+		if bytes.Compare(left, right) > 0 {
+			left, right = right, left
+		}
+		sum := sha256.Sum256(append(slices.Clone(left), right...))
+		return sum[:]
+	}
+	leafHash := func(value []byte) []byte {
+		sum := sha256.Sum256(value)
+		return sum[:]
+	}
+
+	data := generateDummyData(5)
+	tree, err := NewTree(data, sha256.New, WithCombine(sortedPair))
+	require.NoError(t, err)
+
+	expRoot := sortedPair(
+		sortedPair(
+			sortedPair(leafHash(data[0]), leafHash(data[1])),
+			sortedPair(leafHash(data[2]), leafHash(data[3])),
+		),
+		leafHash(data[4]),
+	)
+	assert.Equal(t, expRoot, tree.Root.Hash)
+
+	for i, value := range data {
+		proof, err := tree.GenerateProofByIndex(i)
+		require.NoError(t, err)
+
+		isValid, err := tree.VerifyProof(proof, value)
+		require.NoError(t, err)
+		assert.True(t, isValid)
+	}
+
+	require.NoError(t, tree.UpdateLeaf(1, []byte("new")))
+	expTree, err := NewTree([][]byte{data[0], []byte("new"), data[2], data[3], data[4]},
+		sha256.New, WithCombine(sortedPair))
+	require.NoError(t, err)
+	assert.Equal(t, expTree.Root.Hash, tree.Root.Hash)
+
+	snapshot, err := tree.TreeAtSize(3)
+	require.NoError(t, err)
+	assert.Equal(t, sortedPair(tree.Root.Left.Left.Hash, leafHash(data[2])), snapshot.Root)
+}
+
+func TestWithLengthPrefixedLeaves(t *testing.T) {
+	t.Parallel()
+
+	leafHash := func(prefix, value []byte) []byte {
+		data := slices.Clone(prefix)
+		data = binary.BigEndian.AppendUint64(data, uint64(len(value)))
+		sum := sha256.Sum256(append(data, value...))
+		return sum[:]
+	}
+
+	tests := []struct {
+		name        string
+		opts        []Option
+		leafPrefix  []byte
+		expLeafHash string
+	}{
+		{
+			name:        "Length prefix",
+			opts:        []Option{WithLengthPrefixedLeaves()},
+			expLeafHash: "9caccc24b5d7e06c21c0277b6530d66664273e11f326515c58eaa3691afccfff",
+		},
+		{
+			name:       "Length and domain prefix",
+			opts:       []Option{WithLengthPrefixedLeaves(), WithDomainPrefixes([]byte{0x00}, []byte{0x01})},
+			leafPrefix: []byte{0x00},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			values := [][]byte{[]byte("ab"), []byte("c")}
+			tree, err := NewTree(values, sha256.New, tc.opts...)
+			require.NoError(t, err)
+
+			assert.Equal(t, leafHash(tc.leafPrefix, values[0]), tree.Leaves[0].Hash)
+			assert.Equal(t, leafHash(tc.leafPrefix, values[1]), tree.Leaves[1].Hash)
+			if tc.expLeafHash != "" {
+				assert.Equal(t, tc.expLeafHash, hex.EncodeToString(tree.Leaves[0].Hash))
+			}
+
+			proof, err := tree.GenerateProofByIndex(1)
+			require.NoError(t, err)
+			isValid, err := tree.VerifyProof(proof, values[1])
+			require.NoError(t, err)
+			assert.True(t, isValid)
+		})
+	}
+}
+
+func BenchmarkWithInsecureHash(b *testing.B) {
+	data := generateDummyData(16384)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := NewTree(data, nil, WithInsecureHash())
+		if err != nil {
+			b.Errorf("Error creating Merkle tree: %v", err)
+		}
+	}
+}
+
+func TestWithLeafValidator(t *testing.T) {
+	t.Parallel()
+
+	errEmpty := errors.New("empty leaf")
+	validator := WithLeafValidator(func(value []byte) ([]byte, error) {
+		if len(value) == 0 {
+			return nil, errEmpty
+		}
+		return bytes.ToLower(value), nil
+	})
+
+	tests := []struct {
+		name      string
+		values    [][]byte
+		expValues [][]byte
+		err       error
+	}{
+		{
+			name:      "Canonicalize leaves",
+			values:    [][]byte{[]byte("A"), []byte("b"), []byte("C")},
+			expValues: [][]byte{[]byte("a"), []byte("b"), []byte("c")},
+		},
+		{
+			name:   "Reject leaf",
+			values: [][]byte{[]byte("a"), {}},
+			err:    errEmpty,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := NewTree(tc.values, sha256.New, validator)
+			require.ErrorIs(t, err, tc.err)
+			_, err = NewTreeFromSeq(slices.Values(tc.values), sha256.New, validator)
+			require.ErrorIs(t, err, tc.err)
+			root, err := ComputeRoot(tc.values, sha256.New, validator)
+			require.ErrorIs(t, err, tc.err)
+			if tc.err != nil {
+				return
+			}
+
+			expTree, err := NewTree(tc.expValues, sha256.New)
+			require.NoError(t, err)
+			assert.Equal(t, expTree.Root.Hash, tree.Root.Hash)
+			assert.Equal(t, expTree.Root.Hash, root)
+			for i, value := range tc.expValues {
+				assert.Equal(t, value, tree.Leaves[i].Value)
+			}
+		})
+	}
+}
+
+func TestWithLeafValidatorMutations(t *testing.T) {
+	t.Parallel()
+
+	errEmpty := errors.New("empty leaf")
+	validator := WithLeafValidator(func(value []byte) ([]byte, error) {
+		if len(value) == 0 {
+			return nil, errEmpty
+		}
+		return bytes.ToLower(value), nil
+	})
+
+	tree, err := NewTree([][]byte{[]byte("a"), []byte("b")}, sha256.New, validator)
+	require.NoError(t, err)
+
+	require.NoError(t, tree.UpdateLeaf(0, []byte("X")))
+	require.NoError(t, tree.UpdateLeaves(map[int][]byte{1: []byte("Y")}))
+	require.NoError(t, tree.AppendLeaf([]byte("Z")))
+	require.NoError(t, tree.AppendLeaves([][]byte{[]byte("W")}))
+
+	tx := tree.Begin()
+	require.NoError(t, tx.Append([]byte("V")))
+	require.NoError(t, tx.Commit())
+
+	expValues := [][]byte{[]byte("x"), []byte("y"), []byte("z"), []byte("w"), []byte("v")}
+	expTree, err := NewTree(expValues, sha256.New)
+	require.NoError(t, err)
+	assert.True(t, tree.Equal(expTree), "Tree mismatch")
+
+	// Rejected values don't change the tree.
+	require.ErrorIs(t, tree.UpdateLeaf(0, nil), errEmpty)
+	require.ErrorIs(t, tree.UpdateLeaves(map[int][]byte{0: []byte("a"), 1: nil}), errEmpty)
+	require.ErrorIs(t, tree.AppendLeaf(nil), errEmpty)
+	require.ErrorIs(t, tree.AppendLeaves([][]byte{[]byte("a"), nil}), errEmpty)
+	tx = tree.Begin()
+	require.NoError(t, tx.Update(0, nil))
+	require.ErrorIs(t, tx.Commit(), errEmpty)
+	assert.True(t, tree.Equal(expTree), "Rejected values should not change the tree")
+}
+
+func TestWithCapacity(t *testing.T) {
+	t.Parallel()
+
+	data := generateDummyData(100)
+	tree, err := NewTree(data[:1], sha256.New, WithCapacity(64))
+	require.NoError(t, err)
+	assert.Equal(t, 64, cap(tree.Leaves))
+
+	// Appending past the capacity grows the tree.
+	require.NoError(t, tree.AppendLeaves(data[1:10]))
+	for _, value := range data[10:] {
+		require.NoError(t, tree.AppendLeaf(value))
+	}
+
+	expTree, err := NewTree(data, sha256.New)
+	require.NoError(t, err)
+	assert.True(t, tree.Equal(expTree), "Tree mismatch")
+	require.NoError(t, tree.Validate())
+}
+
+func TestWithCapacityAllocs(t *testing.T) {
+	data := generateDummyData(1000)
+	appendAll := func(opts ...Option) func() {
+		return func() {
+			tree, err := NewTree(data[:1], sha256.New, opts...)
+			require.NoError(t, err)
+			for _, value := range data[1:] {
+				require.NoError(t, tree.AppendLeaf(value))
+			}
+		}
+	}
+
+	allocs := testing.AllocsPerRun(10, appendAll())
+	allocsWithCapacity := testing.AllocsPerRun(10, appendAll(WithCapacity(len(data))))
+	assert.Less(t, allocsWithCapacity, allocs)
+}
+
+func TestWithWorkers(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{
+			name: "Single worker",
+			opts: []Option{WithWorkers(1)},
+		},
+		{
+			name: "Two workers",
+			opts: []Option{WithWorkers(2)},
+		},
+		{
+			name: "Batch size",
+			opts: []Option{WithBatchSize(3)},
+		},
+		{
+			name: "Single worker and batch size",
+			opts: []Option{WithWorkers(1), WithBatchSize(5)},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := generateDummyData(37)
+			expTree, err := NewTree(data, sha256.New)
+			require.NoError(t, err)
+
+			tree, err := NewTree(data, sha256.New, tc.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, expTree.Root.Hash, tree.Root.Hash)
+
+			tree, err = NewTree(data[:10], sha256.New, tc.opts...)
+			require.NoError(t, err)
+			require.NoError(t, tree.AppendLeaves(data[10:]))
+			assert.Equal(t, expTree.Root.Hash, tree.Root.Hash)
+
+			flat, err := NewFlatTree(data, sha256.New, tc.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, expTree.Root.Hash, flat.RootHash())
+		})
+	}
+}
+
+func TestParallelismBatches(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		p          parallelism
+		n          int
+		expBatches int
+	}{
+		{
+			name:       "One batch per worker",
+			p:          parallelism{workers: 3},
+			n:          10,
+			expBatches: 3,
+		},
+		{
+			name:       "Fewer items than workers",
+			p:          parallelism{workers: 8},
+			n:          5,
+			expBatches: 5,
+		},
+		{
+			name:       "Batch size",
+			p:          parallelism{workers: 2, batchSize: 4},
+			n:          10,
+			expBatches: 3,
+		},
+		{
+			name:       "Single worker",
+			p:          parallelism{workers: 1, batchSize: 3},
+			n:          7,
+			expBatches: 3,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				mu              sync.Mutex
+				covered         = make([]int, tc.n)
+				batches, active int
+				maxActive       int
+			)
+			tc.p.batches(tc.n, func(start, end int) {
+				mu.Lock()
+				batches++
+				active++
+				maxActive = max(maxActive, active)
+				for i := start; i < end; i++ {
+					covered[i]++
+				}
+				mu.Unlock()
+
+				time.Sleep(time.Millisecond)
+
+				mu.Lock()
+				active--
+				mu.Unlock()
+			})
+
+			assert.Equal(t, tc.expBatches, batches)
+			assert.LessOrEqual(t, maxActive, tc.p.workers)
+			for i, count := range covered {
+				assert.Equal(t, 1, count, "Item %d", i)
+			}
+		})
+	}
+}
+
+func TestLeafChunks(t *testing.T) {
+	t.Parallel()
+
+	small := make([]byte, 8)
+	large := make([]byte, 1<<16)
+
+	tests := []struct {
+		name      string
+		values    [][]byte
+		numChunks int
+		expBounds []int
+	}{
+		{
+			name:      "Single value",
+			values:    [][]byte{small},
+			numChunks: 4,
+			expBounds: []int{0, 1},
+		},
+		{
+			name:      "Equal sizes",
+			values:    [][]byte{small, small, small, small, small, small, small, small},
+			numChunks: 4,
+			expBounds: []int{0, 2, 4, 6, 8},
+		},
+		{
+			name:      "Large values get their own chunks",
+			values:    [][]byte{small, small, large, small, small, large, small},
+			numChunks: 4,
+			expBounds: []int{0, 2, 3, 5, 6, 7},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expBounds, leafChunks(tc.values, tc.numChunks))
+		})
+	}
+}
+
+func TestForValues(t *testing.T) {
+	t.Parallel()
+
+	small := generateDummyData(10)
+	large := [][]byte{make([]byte, minParallelWork)}
+
+	tests := []struct {
+		name       string
+		p          parallelism
+		values     [][]byte
+		expWorkers int
+	}{
+		{
+			name:       "Small input",
+			values:     small,
+			expWorkers: 1,
+		},
+		{
+			name:       "Large input",
+			values:     large,
+			expWorkers: 0,
+		},
+		{
+			name:       "Many small values",
+			values:     generateDummyData(minParallelWork / leafOverhead),
+			expWorkers: 0,
+		},
+		{
+			name:       "Workers set",
+			p:          parallelism{workers: 4},
+			values:     small,
+			expWorkers: 4,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expWorkers, tc.p.forValues(tc.values).workers)
+		})
+	}
+}
+
+func TestClaimContext(t *testing.T) {
+	t.Parallel()
+
+	bounds := []int{0, 1, 5, 6, 10, 12}
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name   string
+		p      parallelism
+		failAt int
+		expErr error
+	}{
+		{
+			name:   "Single worker",
+			p:      parallelism{workers: 1},
+			failAt: -1,
+		},
+		{
+			name:   "Several workers",
+			p:      parallelism{workers: 3},
+			failAt: -1,
+		},
+		{
+			name:   "Failing chunk",
+			p:      parallelism{workers: 3},
+			failAt: 5,
+			expErr: errFailed,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			covered := make([]int, bounds[len(bounds)-1])
+			err := tc.p.claimContext(context.Background(), bounds, func(ctx context.Context, start, end int) error {
+				if start == tc.failAt {
+					return errFailed
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				for i := start; i < end; i++ {
+					covered[i]++
+				}
+				return nil
+			})
+			if tc.expErr != nil {
+				require.ErrorIs(t, err, tc.expErr)
+				return
+			}
+			require.NoError(t, err)
+			for i, count := range covered {
+				assert.Equal(t, 1, count, "Item %d", i)
+			}
+		})
+	}
+}
+
+func TestPreHashLeavesVariableSizes(t *testing.T) {
+	t.Parallel()
+
+	data := generateDummyData(100)
+	data[10] = bytes.Repeat([]byte("large"), 1<<14)
+	data[90] = bytes.Repeat([]byte("large"), 1<<15)
+
+	hashes, err := parallelism{workers: 4}.preHashLeavesContext(context.Background(), data, sha256.New)
+	require.NoError(t, err)
+	for i, value := range data {
+		sum := sha256.Sum256(value)
+		assert.Equal(t, sum[:], hashes[i], "Leaf %d", i)
+	}
+}
@@ -0,0 +1,171 @@
+package merkle
+
+import (
+	"bytes"
+	"fmt"
+	"hash"
+)
+
+// ProofWithValue is an inclusion proof bundled with the proven value
+// and the size of the tree it was generated for, so it can be verified
+// without access to the tree.
+type ProofWithValue struct {
+	Proof
+	Value []byte
+	Size  int
+}
+
+// GenerateProofWithValue generates a self-contained proof
+// for the leaf at the given index.
+func (t *Tree) GenerateProofWithValue(index int) (*ProofWithValue, error) {
+	proof, err := t.GenerateProofByIndex(index)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProofWithValue{
+		Proof: *proof,
+		Value: t.Leaves[index].Value,
+		Size:  len(t.Leaves),
+	}, nil
+}
+
+// nodeKey identifies a node by its level and index on that level.
+type nodeKey struct {
+	level int
+	index int
+}
+
+// PartialTree is a verifier-side view of a tree that only contains
+// the leaves that have been proven against a trusted root, along with
+// the internal nodes on their paths. It answers membership queries
+// locally and can serve proofs for the leaves it knows about.
+type PartialTree struct {
+	Root     []byte
+	Size     int
+	HashFunc hash.Hash
+
+	cfg          config
+	leafHashFunc hash.Hash
+	nodes        map[nodeKey][]byte
+	values       map[int][]byte
+	indexes      map[string]int
+}
+
+// NewPartialTree creates a partial tree for the given trusted root
+// and absorbs the given proofs. The options must match the ones
+// the tree was built with.
+func NewPartialTree(root []byte, proofs []*ProofWithValue, newHashFunc func() hash.Hash, opts ...Option) (*PartialTree, error) {
+	cfg := newConfig(opts, newHashFunc)
+	t := &PartialTree{
+		Root:         root,
+		HashFunc:     cfg.hasher.NewNodeHasher(),
+		cfg:          cfg,
+		leafHashFunc: cfg.hasher.NewLeafHasher(),
+		nodes:        make(map[nodeKey][]byte),
+		values:       make(map[int][]byte),
+		indexes:      make(map[string]int),
+	}
+
+	for _, proof := range proofs {
+		if err := t.Absorb(proof); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// Absorb verifies the proof against the root and adds the proven leaf
+// and the nodes on its path to the partial tree. The size of the tree
+// is taken from the first proof and all later proofs must match it.
+func (t *PartialTree) Absorb(proof *ProofWithValue) error {
+	if t.Size != 0 && proof.Size != t.Size {
+		return fmt.Errorf("%w: proof is for a tree with %d leaves, expected %d",
+			ErrProofVerificationFailed, proof.Size, t.Size)
+	}
+	if proof.Index < 0 || proof.Index >= proof.Size {
+		return fmt.Errorf("%w: index %d in a tree with %d leaves",
+			ErrProofVerificationFailed, proof.Index, proof.Size)
+	}
+
+	t.leafHashFunc.Reset()
+	t.leafHashFunc.Write(proof.Value)
+	currentHash := t.leafHashFunc.Sum(nil)
+
+	// Collect the nodes on the path and only keep them if
+	// the proof leads to the trusted root.
+	nodes := map[nodeKey][]byte{{level: 0, index: proof.Index}: currentHash}
+	hashes := proof.Hashes
+	index, levelSize := proof.Index, proof.Size
+	for level := 0; levelSize > 1; level++ {
+		// The last node on a level without a sibling
+		// is carried up without hashing.
+		if index%2 == 1 || index+1 < levelSize {
+			if len(hashes) == 0 {
+				return fmt.Errorf("%w: proof is too short", ErrProofVerificationFailed)
+			}
+			siblingHash := hashes[0]
+			hashes = hashes[1:]
+			nodes[nodeKey{level: level, index: index ^ 1}] = siblingHash
+
+			if index%2 == 0 {
+				currentHash = combineLevelHashes(level+1, currentHash, siblingHash, t.HashFunc, &t.cfg)
+			} else {
+				currentHash = combineLevelHashes(level+1, siblingHash, currentHash, t.HashFunc, &t.cfg)
+			}
+		}
+		index /= 2
+		levelSize = (levelSize + 1) / 2
+		nodes[nodeKey{level: level + 1, index: index}] = currentHash
+	}
+
+	if len(hashes) > 0 {
+		return fmt.Errorf("%w: proof is too long", ErrProofVerificationFailed)
+	}
+	if !bytes.Equal(currentHash, t.Root) {
+		return &RootMismatchError{Expected: t.Root, Actual: currentHash}
+	}
+
+	t.Size = proof.Size
+	for key, hash := range nodes {
+		t.nodes[key] = hash
+	}
+	t.values[proof.Index] = proof.Value
+	t.indexes[string(proof.Value)] = proof.Index
+
+	return nil
+}
+
+// Len returns the number of proven leaves.
+func (t *PartialTree) Len() int {
+	return len(t.values)
+}
+
+// Value returns the value of the leaf at the given index
+// if it has been proven.
+func (t *PartialTree) Value(index int) ([]byte, bool) {
+	value, ok := t.values[index]
+	return value, ok
+}
+
+// Contains reports whether value has been proven to be in the tree.
+func (t *PartialTree) Contains(value []byte) bool {
+	_, ok := t.indexes[string(value)]
+	return ok
+}
+
+// GenerateProofByIndex generates a proof for a proven leaf
+// from the nodes in the partial tree.
+func (t *PartialTree) GenerateProofByIndex(index int) (*Proof, error) {
+	if index < 0 || index >= t.Size {
+		return nil, indexOutOfBounds(index, t.Size)
+	}
+	if _, ok := t.values[index]; !ok {
+		return nil, fmt.Errorf("%w: leaf %d has not been proven", ErrNoVal, index)
+	}
+
+	return proofFromSubtrees(index, t.Size, func(level, index int) ([]byte, error) {
+		return t.nodes[nodeKey{level: level, index: index}], nil
+	})
+}
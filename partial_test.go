@@ -0,0 +1,136 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPartialTree(t *testing.T) {
+	t.Parallel()
+
+	data := generateDummyData(11)
+	tree, err := NewTree(data, sha256.New)
+	require.NoError(t, err)
+
+	var proofs []*ProofWithValue
+	for _, i := range []int{2, 7, 10} {
+		proof, err := tree.GenerateProofWithValue(i)
+		require.NoError(t, err)
+		proofs = append(proofs, proof)
+	}
+
+	partial, err := NewPartialTree(tree.Root.Hash, proofs, sha256.New)
+	require.NoError(t, err)
+	assert.Equal(t, 3, partial.Len())
+	assert.Equal(t, 11, partial.Size)
+
+	assert.True(t, partial.Contains(data[7]))
+	assert.False(t, partial.Contains(data[3]))
+
+	value, ok := partial.Value(10)
+	assert.True(t, ok)
+	assert.Equal(t, data[10], value)
+	_, ok = partial.Value(3)
+	assert.False(t, ok)
+
+	// Proofs served by the partial tree match the full tree.
+	for _, i := range []int{2, 7, 10} {
+		proof, err := partial.GenerateProofByIndex(i)
+		require.NoError(t, err)
+
+		expProof, err := tree.GenerateProofByIndex(i)
+		require.NoError(t, err)
+		assert.Equal(t, expProof.Hashes, proof.Hashes)
+	}
+
+	_, err = partial.GenerateProofByIndex(3)
+	require.ErrorIs(t, err, ErrNoVal)
+	_, err = partial.GenerateProofByIndex(11)
+	require.ErrorIs(t, err, ErrIndexOutOfBounds)
+
+	// More proofs can be absorbed later.
+	proof, err := tree.GenerateProofWithValue(3)
+	require.NoError(t, err)
+	require.NoError(t, partial.Absorb(proof))
+	assert.True(t, partial.Contains(data[3]))
+}
+
+func TestPartialTreeAbsorbInvalid(t *testing.T) {
+	t.Parallel()
+
+	data := generateDummyData(6)
+	tree, err := NewTree(data, sha256.New)
+	require.NoError(t, err)
+
+	partial, err := NewPartialTree(tree.Root.Hash, nil, sha256.New)
+	require.NoError(t, err)
+
+	proof, err := tree.GenerateProofWithValue(1)
+	require.NoError(t, err)
+
+	tampered := *proof
+	tampered.Value = []byte("tampered")
+	require.ErrorIs(t, partial.Absorb(&tampered), ErrProofVerificationFailed)
+	assert.Equal(t, 0, partial.Len(), "Rejected proof should not be absorbed")
+
+	require.NoError(t, partial.Absorb(proof))
+
+	wrongSize := *proof
+	wrongSize.Size = 7
+	require.ErrorIs(t, partial.Absorb(&wrongSize), ErrProofVerificationFailed)
+
+	otherTree, err := NewTree(generateDummyData(7), sha256.New)
+	require.NoError(t, err)
+	_, err = NewPartialTree(otherTree.Root.Hash, []*ProofWithValue{proof}, sha256.New)
+	require.ErrorIs(t, err, ErrProofVerificationFailed)
+}
+
+func TestPartialTreeWithOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{
+			name: "Domain prefixes",
+			opts: []Option{WithDomainPrefixes([]byte{0}, []byte{1})},
+		},
+		{
+			name: "Level tags",
+			opts: []Option{WithLevelTags(LevelIndexTag)},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := generateDummyData(11)
+			tree, err := NewTree(data, sha256.New, tc.opts...)
+			require.NoError(t, err)
+
+			var proofs []*ProofWithValue
+			for _, i := range []int{0, 5, 10} {
+				proof, err := tree.GenerateProofWithValue(i)
+				require.NoError(t, err)
+				proofs = append(proofs, proof)
+			}
+
+			partial, err := NewPartialTree(tree.Root.Hash, proofs, sha256.New, tc.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, 3, partial.Len())
+
+			proof, err := tree.GenerateProofWithValue(7)
+			require.NoError(t, err)
+			require.NoError(t, partial.Absorb(proof))
+			assert.True(t, partial.Contains(data[7]))
+
+			_, err = NewPartialTree(tree.Root.Hash, proofs, sha256.New)
+			require.ErrorIs(t, err, ErrProofVerificationFailed)
+		})
+	}
+}
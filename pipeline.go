@@ -0,0 +1,189 @@
+package merkle
+
+import (
+	"context"
+	"hash"
+	"math/bits"
+)
+
+// cacheBlock is the number of adjacent nodes whose subtree is hashed
+// to completion before moving on to the next block, rather than hashing
+// the tree level by level. The nodes of a block stay in the L1 and L2
+// caches while its subtree is built, which matters for large trees
+// whose levels don't fit in the cache.
+const (
+	cacheBlockLevels = 10
+	cacheBlock       = 1 << cacheBlockLevels
+)
+
+// pipelineChunk returns the number of leaves per chunk for a tree with
+// n leaves, which is a power of two so that every chunk but the last
+// is a complete subtree. Unless the batch size is set, there are a few
+// chunks per worker to balance work.
+func (p parallelism) pipelineChunk(n int) int {
+	perChunk := p.batchSize
+	if perChunk <= 0 {
+		perChunk = min(cacheBlock, max(1, n/(4*p.numWorkers(max(n, 1)))))
+	}
+	return 1 << (bits.Len(uint(perChunk)) - 1)
+}
+
+// buildTreePipelined hashes the values and builds the tree on top of them
+// in one pass. Each worker builds the subtrees of chunks of chunkSize leaves
+// right after hashing them, instead of waiting for all leaves to be hashed,
+// and the roots of the chunks are then joined. It returns the leaves
+// and the root, which is nil if there are no values.
+func buildTreePipelined(ctx context.Context, values [][]byte, chunkSize int, cfg *config) ([]*Node, *Node, error) {
+	n := len(values)
+	if n == 0 {
+		return []*Node{}, nil, nil
+	}
+
+	leaves := make([]*Node, n)
+	numChunks := (n + chunkSize - 1) / chunkSize
+	roots := make([]*Node, numChunks)
+	// Every worker builds a run of chunks, or one chunk
+	// at a time if the batch size is set.
+	chunks := parallelism{workers: cfg.parallelism.workers}
+	if cfg.parallelism.batchSize > 0 {
+		chunks.batchSize = 1
+	}
+	err := chunks.batchesContext(ctx, numChunks, func(ctx context.Context, start, end int) error {
+		defer cfg.monitor.work(cfg.monitor.now())
+		leafHashFunc := cfg.hasher.NewLeafHasher()
+		hashFunc := cfg.hasher.NewNodeHasher()
+		scratch := make([]*Node, 0, chunkSize)
+		for c := start; c < end; c++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			chunk := leaves[c*chunkSize : min(n, (c+1)*chunkSize)]
+			chunkValues := values[c*chunkSize : c*chunkSize+len(chunk)]
+			hashStart := cfg.monitor.now()
+			for i, value := range chunkValues {
+				leafHashFunc.Reset()
+				leafHashFunc.Write(value)
+				chunk[i] = NewNode(leafHashFunc.Sum(nil), value)
+			}
+			if cfg.monitor != nil {
+				size := 0
+				for _, value := range chunkValues {
+					size += len(value)
+				}
+				cfg.monitor.leaves(len(chunk), size, hashStart)
+			}
+
+			root, err := reduceLevels(ctx, append(scratch[:0], chunk...), 1, hashFunc, cfg)
+			if err != nil {
+				return err
+			}
+			roots[c] = root
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The roots of the chunks are on the level above the chunk size.
+	level := bits.Len(uint(chunkSize))
+	root, err := reduceBlocks(ctx, roots, level, cfg.parallelism.workers, cfg.hasher.NewNodeHasher(), cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return leaves, root, nil
+}
+
+// reduceBlocks hashes the nodes together like reduceLevels, but builds the
+// subtrees of blocks of cacheBlock adjacent nodes to completion, in parallel,
+// before joining their roots. The nodes are overwritten.
+func reduceBlocks(ctx context.Context, nodes []*Node, level, workers int, hashFunc hash.Hash, cfg *config) (*Node, error) {
+	for len(nodes) > cacheBlock {
+		roots := make([]*Node, (len(nodes)+cacheBlock-1)/cacheBlock)
+		err := parallelism{workers: workers}.batchesContext(ctx, len(roots), func(ctx context.Context, start, end int) error {
+			defer cfg.monitor.work(cfg.monitor.now())
+			hashFunc := cfg.hasher.NewNodeHasher()
+			for b := start; b < end; b++ {
+				root, err := reduceLevels(ctx, nodes[b*cacheBlock:min(len(nodes), (b+1)*cacheBlock)], level, hashFunc, cfg)
+				if err != nil {
+					return err
+				}
+				roots[b] = root
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		nodes = roots
+		level += cacheBlockLevels
+	}
+	defer cfg.monitor.work(cfg.monitor.now())
+	return reduceLevels(ctx, nodes, level, hashFunc, cfg)
+}
+
+// reduceLevels hashes the nodes together pairwise, starting at the given
+// level, until one node is left and returns it. Nodes without a sibling are
+// carried up without hashing. The parents overwrite the nodes in place.
+func reduceLevels(ctx context.Context, nodes []*Node, level int, hashFunc hash.Hash, cfg *config) (*Node, error) {
+	if n := len(nodes); n&(n-1) == 0 {
+		return reduceComplete(ctx, nodes, level, hashFunc, cfg)
+	}
+	for ; len(nodes) > 1; level++ {
+		start := cfg.monitor.now()
+		for i := 0; i < len(nodes); i += 2 {
+			if (i/2)%ctxCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+			}
+			left := nodes[i]
+			if i+1 == len(nodes) {
+				// If right is nil, carry the left node up without hashing
+				nodes[i/2] = left
+				continue
+			}
+			right := nodes[i+1]
+			parent := &Node{
+				Hash:  combineLevelHashes(level, left.Hash, right.Hash, hashFunc, cfg),
+				Left:  left,
+				Right: right,
+			}
+			left.Parent = parent
+			right.Parent = parent
+			nodes[i/2] = parent
+		}
+		cfg.monitor.level(level, len(nodes)/2, start)
+		nodes = nodes[:(len(nodes)+1)/2]
+	}
+	return nodes[0], nil
+}
+
+// reduceComplete hashes the nodes together like reduceLevels, for a number
+// of nodes that is a power of two. Every node has a sibling on every level,
+// so the pairs are hashed without checking for a node to carry up.
+func reduceComplete(ctx context.Context, nodes []*Node, level int, hashFunc hash.Hash, cfg *config) (*Node, error) {
+	for ; len(nodes) > 1; level++ {
+		start := cfg.monitor.now()
+		half := len(nodes) / 2
+		for i := range half {
+			if i%ctxCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+			}
+			left, right := nodes[2*i], nodes[2*i+1]
+			parent := &Node{
+				Hash:  combineLevelHashes(level, left.Hash, right.Hash, hashFunc, cfg),
+				Left:  left,
+				Right: right,
+			}
+			left.Parent = parent
+			right.Parent = parent
+			nodes[i] = parent
+		}
+		cfg.monitor.level(level, half, start)
+		nodes = nodes[:half]
+	}
+	return nodes[0], nil
+}
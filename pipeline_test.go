@@ -0,0 +1,202 @@
+package merkle
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildTreePipelined(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		size      int
+		chunkSize int
+		opts      []Option
+	}{
+		{
+			name:      "Single chunk",
+			size:      5,
+			chunkSize: 8,
+		},
+		{
+			name:      "Leaf chunks",
+			size:      7,
+			chunkSize: 1,
+		},
+		{
+			name:      "Complete chunks",
+			size:      16,
+			chunkSize: 4,
+		},
+		{
+			name:      "Partial last chunk",
+			size:      13,
+			chunkSize: 4,
+		},
+		{
+			name:      "Single leaf in last chunk",
+			size:      17,
+			chunkSize: 2,
+		},
+		{
+			name:      "Level tags",
+			size:      11,
+			chunkSize: 2,
+			opts:      []Option{WithLevelTags(LevelIndexTag)},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := generateDummyData(tc.size)
+			cfg := newConfig(tc.opts, sha256.New)
+			leaves, root, err := buildTreePipelined(context.Background(), data, tc.chunkSize, &cfg)
+			require.NoError(t, err)
+
+			nodes := make([]*Node, tc.size)
+			for i, hash := range preHashLeaves(data, cfg.hasher.NewLeafHasher) {
+				nodes[i] = NewNode(hash, data[i])
+			}
+			expRoot := buildTree(nodes, cfg.hasher.NewNodeHasher(), &cfg)
+
+			assert.Equal(t, expRoot.Hash, root.Hash)
+			assert.Nil(t, root.Parent)
+			require.Len(t, leaves, tc.size)
+			for i, leaf := range leaves {
+				assert.Equal(t, data[i], leaf.Value)
+				assert.Equal(t, nodes[i].Hash, leaf.Hash)
+
+				// The leaves are linked to the root.
+				node := leaf
+				for node.Parent != nil {
+					assert.True(t, node.Parent.Left == node || node.Parent.Right == node)
+					node = node.Parent
+				}
+				assert.Same(t, root, node)
+			}
+		})
+	}
+}
+
+func TestBuildTreePipelinedCanceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cfg := newConfig(nil, sha256.New)
+	_, _, err := buildTreePipelined(ctx, generateDummyData(64), 4, &cfg)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestPipelineChunk(t *testing.T) {
+	t.Parallel()
+
+	for _, size := range []int{1, 3, 100, 1 << 20} {
+		t.Run(fmt.Sprintf("%d leaves", size), func(t *testing.T) {
+			t.Parallel()
+
+			chunk := parallelism{}.pipelineChunk(size)
+			assert.Positive(t, chunk)
+			assert.LessOrEqual(t, chunk, cacheBlock)
+			assert.Zero(t, chunk&(chunk-1), "Chunk size %d is not a power of two", chunk)
+
+			// The batch size is rounded down to a power of two.
+			assert.Equal(t, 64, parallelism{batchSize: 100}.pipelineChunk(size))
+		})
+	}
+}
+
+func TestReduceBlocks(t *testing.T) {
+	t.Parallel()
+
+	for _, size := range []int{cacheBlock, cacheBlock + 1, 3*cacheBlock + 5} {
+		t.Run(fmt.Sprintf("%d leaves", size), func(t *testing.T) {
+			t.Parallel()
+
+			cfg := newConfig([]Option{WithLevelTags(LevelIndexTag)}, sha256.New)
+			hashes := preHashLeaves(generateDummyData(size), cfg.hasher.NewLeafHasher)
+			nodes := make([]*Node, size)
+			expNodes := make([]*Node, size)
+			for i, hash := range hashes {
+				nodes[i] = NewNode(hash, nil)
+				expNodes[i] = NewNode(hash, nil)
+			}
+
+			root, err := reduceBlocks(context.Background(), nodes, 1, 0, cfg.hasher.NewNodeHasher(), &cfg)
+			require.NoError(t, err)
+			expRoot, err := reduceLevels(context.Background(), expNodes, 1, cfg.hasher.NewNodeHasher(), &cfg)
+			require.NoError(t, err)
+			assert.Equal(t, expRoot.Hash, root.Hash)
+		})
+	}
+}
+
+func TestReduceComplete(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		size int
+		opts []Option
+	}{
+		{
+			name: "Single node",
+			size: 1,
+		},
+		{
+			name: "Two nodes",
+			size: 2,
+		},
+		{
+			name: "Complete block",
+			size: cacheBlock,
+		},
+		{
+			name: "Level tags",
+			size: 64,
+			opts: []Option{WithLevelTags(func(level int) []byte { return []byte{byte(level)} })},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := generateDummyData(tc.size)
+			cfg := newConfig(tc.opts, sha256.New)
+			nodes := make([]*Node, tc.size)
+			for i, hash := range preHashLeaves(data, cfg.hasher.NewLeafHasher) {
+				nodes[i] = NewNode(hash, data[i])
+			}
+			root, err := reduceComplete(context.Background(), nodes, 1, cfg.hasher.NewNodeHasher(), &cfg)
+			require.NoError(t, err)
+
+			expRoot, err := ComputeRoot(data, sha256.New, tc.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, expRoot, root.Hash)
+
+			// Proofs of complete trees have one hash per level.
+			tree, err := NewTree(data, sha256.New, tc.opts...)
+			require.NoError(t, err)
+			proof, err := tree.GenerateProofByIndex(tc.size - 1)
+			require.NoError(t, err)
+			ok, err := tree.VerifyProof(proof, data[tc.size-1])
+			require.NoError(t, err)
+			assert.True(t, ok)
+
+			proof.Hashes = append(proof.Hashes, expRoot)
+			_, err = tree.VerifyProof(proof, data[tc.size-1])
+			var sizeErr *ProofSizeError
+			assert.ErrorAs(t, err, &sizeErr)
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package merkle
+
+import "sync"
+
+// digestPool recycles buffers for digests that are only needed while
+// another hash is computed, like the nodes on the path of a proof
+// or the leaf hash of a value that is looked up.
+var digestPool = sync.Pool{
+	New: func() any { return new([]byte) },
+}
+
+// getDigest returns an empty buffer from the pool.
+func getDigest() *[]byte {
+	buf := digestPool.Get().(*[]byte)
+	*buf = (*buf)[:0]
+	return buf
+}
+
+// putDigest returns buf to the pool. The digest in it
+// must not be used afterwards.
+func putDigest(buf *[]byte) {
+	digestPool.Put(buf)
+}
@@ -0,0 +1,157 @@
+package merkle
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
+)
+
+var ErrInvalidFieldElement = errors.New("invalid field element")
+
+// poseidon2Hash absorbs field elements into its state with the
+// Poseidon2 compression function, like the Merkle-Damgard hasher
+// of gnark-crypto. The input is split into elements across writes,
+// so a short last element is only absorbed by Sum.
+type poseidon2Hash struct {
+	perm  *poseidon2.Permutation
+	state []byte
+	// tail holds the bytes of the element that is not complete yet.
+	tail []byte
+	// err is set by the first element that is not canonical.
+	err error
+}
+
+// failedDigest is the digest of a hash that was given an element that
+// is not canonical. It isn't a field element, so it never matches the
+// digest of valid input.
+var failedDigest = bytes.Repeat([]byte{0xff}, fr.Bytes)
+
+// NewPoseidon2 returns a new Poseidon2 hash over the scalar field of BN254
+// with the default parameters of gnark-crypto, 6 full and 50 partial rounds,
+// so roots and proofs can be verified in gnark circuits.
+//
+// The input is split into 32 byte big endian field elements, and a short
+// last element is padded with leading zeros. Elements that are not smaller
+// than the modulus are rejected: Write returns ErrInvalidFieldElement and
+// the digest is 32 bytes of 0xff, which no valid input hashes to.
+// Trees don't see write errors, so use WithLeafValidator with
+// ValidateFieldElements to reject such values, and encode values
+// with FieldElement.
+func NewPoseidon2() hash.Hash {
+	return NewPoseidon2Func(6, 50)()
+}
+
+// NewPoseidon2Func returns a constructor for Poseidon2 hashes with the
+// given number of full and partial rounds. The round keys are derived
+// once and shared by all hashes.
+func NewPoseidon2Func(fullRounds, partialRounds int) func() hash.Hash {
+	perm := poseidon2.NewPermutation(2, fullRounds, partialRounds)
+	return func() hash.Hash {
+		return &poseidon2Hash{perm: perm, state: make([]byte, fr.Bytes)}
+	}
+}
+
+func (h *poseidon2Hash) Write(p []byte) (int, error) {
+	if h.err != nil {
+		return 0, h.err
+	}
+
+	n := len(p)
+	if len(h.tail) > 0 {
+		size := min(len(p), fr.Bytes-len(h.tail))
+		h.tail = append(h.tail, p[:size]...)
+		p = p[size:]
+		if len(h.tail) < fr.Bytes {
+			return n, nil
+		}
+		if h.state, h.err = h.compress(h.state, h.tail); h.err != nil {
+			return 0, h.err
+		}
+		h.tail = h.tail[:0]
+	}
+	for ; len(p) >= fr.Bytes; p = p[fr.Bytes:] {
+		if h.state, h.err = h.compress(h.state, p[:fr.Bytes]); h.err != nil {
+			return 0, h.err
+		}
+	}
+	h.tail = append(h.tail, p...)
+	return n, nil
+}
+
+// compress absorbs the element into state. Short elements are padded
+// with leading zeros, and elements that are not smaller than the modulus
+// are rejected.
+func (h *poseidon2Hash) compress(state, element []byte) ([]byte, error) {
+	block := make([]byte, fr.Bytes)
+	copy(block[fr.Bytes-len(element):], element)
+	next, err := h.perm.Compress(state, block)
+	if err != nil {
+		return state, fmt.Errorf("%w: %x is not smaller than the modulus", ErrInvalidFieldElement, element)
+	}
+	return next, nil
+}
+
+func (h *poseidon2Hash) Sum(b []byte) []byte {
+	if h.err != nil {
+		return append(b, failedDigest...)
+	}
+	if len(h.tail) == 0 {
+		return append(b, h.state...)
+	}
+	// A short element is always smaller than the modulus.
+	state, _ := h.compress(h.state, h.tail)
+	return append(b, state...)
+}
+
+func (h *poseidon2Hash) Reset() {
+	h.state = make([]byte, fr.Bytes)
+	h.tail = h.tail[:0]
+	h.err = nil
+}
+
+func (h *poseidon2Hash) Size() int {
+	return fr.Bytes
+}
+
+func (h *poseidon2Hash) BlockSize() int {
+	return fr.Bytes
+}
+
+// FieldElement encodes x as a 32 byte big endian element
+// of the scalar field of BN254.
+func FieldElement(x *big.Int) ([]byte, error) {
+	if x.Sign() < 0 || x.Cmp(fr.Modulus()) >= 0 {
+		return nil, fmt.Errorf("%w: %s is not in [0, %s)", ErrInvalidFieldElement, x, fr.Modulus())
+	}
+	return x.FillBytes(make([]byte, fr.Bytes)), nil
+}
+
+// ValidateFieldElements checks that value is a sequence of 32 byte big
+// endian elements of the scalar field of BN254, as encoded by FieldElement.
+// Use it with WithLeafValidator in trees that hash with NewPoseidon2 or
+// NewMiMCBN254, so padded and out of range values are rejected.
+func ValidateFieldElements(value []byte) ([]byte, error) {
+	return validateFieldElements(value, func(element []byte) error {
+		var e fr.Element
+		return e.SetBytesCanonical(element)
+	})
+}
+
+// validateFieldElements splits value into 32 byte elements and checks
+// each of them with setCanonical.
+func validateFieldElements(value []byte, setCanonical func(element []byte) error) ([]byte, error) {
+	if len(value) == 0 || len(value)%fr.Bytes != 0 {
+		return nil, fmt.Errorf("%w: %d bytes is not a multiple of %d", ErrInvalidFieldElement, len(value), fr.Bytes)
+	}
+	for i := 0; i < len(value); i += fr.Bytes {
+		if err := setCanonical(value[i : i+fr.Bytes]); err != nil {
+			return nil, fmt.Errorf("%w: element %d is not smaller than the modulus", ErrInvalidFieldElement, i/fr.Bytes)
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,263 @@
+package merkle
+
+import (
+	"bytes"
+	"hash"
+	"io"
+	"math/big"
+	"testing"
+	"testing/iotest"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fieldElements(t *testing.T, xs ...int64) [][]byte {
+	t.Helper()
+
+	values := make([][]byte, len(xs))
+	for i, x := range xs {
+		value, err := FieldElement(big.NewInt(x))
+		require.NoError(t, err)
+		values[i] = value
+	}
+	return values
+}
+
+func TestPoseidon2(t *testing.T) {
+	t.Parallel()
+
+	values := fieldElements(t, 1, 2, 3)
+	tree, err := NewTree(values, NewPoseidon2)
+	require.NoError(t, err)
+
+	// Leaves and nodes are absorbed into the zero state
+	// with the Poseidon2 compression function.
+	perm := poseidon2.NewPermutation(2, 6, 50)
+	compress := func(blocks ...[]byte) []byte {
+		state := make([]byte, fr.Bytes)
+		for _, block := range blocks {
+			state, err = perm.Compress(state, block)
+			require.NoError(t, err)
+		}
+		return state
+	}
+	leaves := [][]byte{compress(values[0]), compress(values[1]), compress(values[2])}
+	expRoot := compress(compress(leaves[0], leaves[1]), leaves[2])
+	assert.Equal(t, expRoot, tree.Root.Hash)
+
+	for i, value := range values {
+		proof, err := tree.GenerateProofByIndex(i)
+		require.NoError(t, err)
+
+		isValid, err := tree.VerifyProof(proof, value)
+		require.NoError(t, err)
+		assert.True(t, isValid)
+	}
+}
+
+func TestPoseidon2Func(t *testing.T) {
+	t.Parallel()
+
+	values := fieldElements(t, 1, 2, 3, 4)
+	tree, err := NewTree(values, NewPoseidon2)
+	require.NoError(t, err)
+
+	defaultTree, err := NewTree(values, NewPoseidon2Func(6, 50))
+	require.NoError(t, err)
+	assert.Equal(t, tree.Root.Hash, defaultTree.Root.Hash)
+
+	customTree, err := NewTree(values, NewPoseidon2Func(8, 56))
+	require.NoError(t, err)
+	assert.NotEqual(t, tree.Root.Hash, customTree.Root.Hash)
+}
+
+func TestFieldElement(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		x    *big.Int
+		err  error
+	}{
+		{
+			name: "Zero",
+			x:    big.NewInt(0),
+		},
+		{
+			name: "Largest element",
+			x:    new(big.Int).Sub(fr.Modulus(), big.NewInt(1)),
+		},
+		{
+			name: "Modulus",
+			x:    fr.Modulus(),
+			err:  ErrInvalidFieldElement,
+		},
+		{
+			name: "Negative",
+			x:    big.NewInt(-1),
+			err:  ErrInvalidFieldElement,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			value, err := FieldElement(tc.x)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, value, fr.Bytes)
+			assert.Zero(t, tc.x.Cmp(new(big.Int).SetBytes(value)))
+		})
+	}
+}
+
+func TestPoseidon2NonCanonical(t *testing.T) {
+	t.Parallel()
+
+	modulus := fr.Modulus().FillBytes(make([]byte, fr.Bytes))
+	one := fieldElements(t, 1)[0]
+
+	// Short elements are padded with leading zeros.
+	h := NewPoseidon2()
+	h.Write([]byte{1})
+	expDigest := h.Sum(nil)
+	h.Reset()
+	h.Write(one)
+	assert.Equal(t, expDigest, h.Sum(nil))
+
+	tests := []struct {
+		name  string
+		value []byte
+	}{
+		{
+			name:  "Modulus",
+			value: modulus,
+		},
+		{
+			name:  "Modulus after an element",
+			value: append(bytes.Clone(one), modulus...),
+		},
+		{
+			name:  "Modulus before an element",
+			value: append(bytes.Clone(modulus), one...),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := NewPoseidon2()
+			// Elements that span writes are checked when they are complete.
+			_, err := h.Write(tc.value[:fr.Bytes-1])
+			require.NoError(t, err)
+			_, err = io.Copy(h, bytes.NewReader(tc.value[fr.Bytes-1:]))
+			require.ErrorIs(t, err, ErrInvalidFieldElement)
+
+			// The hash stays failed until it is reset.
+			_, err = h.Write(one)
+			require.ErrorIs(t, err, ErrInvalidFieldElement)
+			assert.Equal(t, bytes.Repeat([]byte{0xff}, fr.Bytes), h.Sum(nil))
+			h.Reset()
+			h.Write(one)
+			assert.Equal(t, expDigest, h.Sum(nil))
+
+			_, err = NewTree([][]byte{tc.value}, NewPoseidon2, WithLeafValidator(ValidateFieldElements))
+			require.ErrorIs(t, err, ErrInvalidFieldElement)
+		})
+	}
+}
+
+func TestValidateFieldElements(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		value []byte
+		err   error
+	}{
+		{
+			name:  "Two elements",
+			value: bytes.Join(fieldElements(t, 1, 2), nil),
+		},
+		{
+			name:  "Largest element",
+			value: new(big.Int).Sub(fr.Modulus(), big.NewInt(1)).FillBytes(make([]byte, fr.Bytes)),
+		},
+		{
+			name:  "Empty",
+			value: []byte{},
+			err:   ErrInvalidFieldElement,
+		},
+		{
+			name:  "Short element",
+			value: []byte{1},
+			err:   ErrInvalidFieldElement,
+		},
+		{
+			name:  "Modulus",
+			value: fr.Modulus().FillBytes(make([]byte, fr.Bytes)),
+			err:   ErrInvalidFieldElement,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			value, err := ValidateFieldElements(tc.value)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.value, value)
+		})
+	}
+}
+
+func TestPoseidon2SplitWrites(t *testing.T) {
+	t.Parallel()
+
+	assertSplitWrites(t, NewPoseidon2)
+}
+
+// assertSplitWrites checks that the digest of a hash that splits its input
+// into field elements only depends on the input, not on how it is written.
+func assertSplitWrites(t *testing.T, newHashFunc func() hash.Hash) {
+	t.Helper()
+
+	// The first byte of every element is zero, so the elements are
+	// smaller than the modulus of both BN254 and BLS12-381.
+	input := make([]byte, 3*fr.Bytes+5)
+	for i := range input {
+		if i%fr.Bytes != 0 {
+			input[i] = byte(i)
+		}
+	}
+	h := newHashFunc()
+	h.Write(input)
+	expDigest := h.Sum(nil)
+
+	for _, split := range []int{1, 3, fr.Bytes - 1, fr.Bytes, fr.Bytes + 1} {
+		h.Reset()
+		for p := input; len(p) > 0; p = p[min(split, len(p)):] {
+			h.Write(p[:min(split, len(p))])
+			// Sum doesn't change the state of the hash.
+			h.Sum(nil)
+		}
+		assert.Equal(t, expDigest, h.Sum(nil), "Writes of %d bytes", split)
+	}
+
+	h.Reset()
+	_, err := io.Copy(h, iotest.OneByteReader(bytes.NewReader(input)))
+	require.NoError(t, err)
+	assert.Equal(t, expDigest, h.Sum(nil), "Reader of one byte at a time")
+}
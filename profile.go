@@ -0,0 +1,149 @@
+package merkle
+
+import (
+	"sync"
+	"time"
+)
+
+// BuildProfile describes where the time of a build went,
+// as reported to the callback of WithBuildProfile.
+type BuildProfile struct {
+	// Levels holds the hashing on every level, from the leaves up.
+	Levels []LevelProfile
+	// BytesHashed is the number of bytes of leaf values hashed.
+	BytesHashed int64
+	// Duration is the wall time of the build.
+	Duration time.Duration
+	// Workers is the number of goroutines that hashed in parallel,
+	// and Busy is the time they spent hashing, summed over all of them.
+	Workers int
+	Busy    time.Duration
+}
+
+// LevelProfile describes the hashing on one level of a tree.
+type LevelProfile struct {
+	// Nodes is the number of leaves or nodes hashed on the level.
+	// Nodes without a sibling are carried up without hashing.
+	Nodes int
+	// Duration is the time spent hashing the level,
+	// summed over all workers.
+	Duration time.Duration
+}
+
+// Utilization returns the fraction of the wall time of the build
+// that the workers spent hashing, between 0 and 1.
+func (p BuildProfile) Utilization() float64 {
+	if p.Duration <= 0 || p.Workers == 0 {
+		return 0
+	}
+	return min(1, float64(p.Busy)/(float64(p.Duration)*float64(p.Workers)))
+}
+
+// BuildProgress describes how far a build has come,
+// as reported to the callback of WithBuildProgress.
+type BuildProgress struct {
+	// LeavesHashed of the Leaves leaves have been hashed.
+	LeavesHashed int
+	Leaves       int
+	// LevelsDone of the Levels levels above the leaves
+	// have been hashed completely.
+	LevelsDone int
+	Levels     int
+}
+
+// buildMonitor collects the profile and progress of a build from its
+// workers. Its methods do nothing on a nil monitor, so builds without
+// monitoring don't read the clock.
+type buildMonitor struct {
+	start   time.Time
+	mu      sync.Mutex
+	profile BuildProfile
+
+	// pending holds the number of nodes left to hash on every level.
+	report   func(BuildProgress)
+	progress BuildProgress
+	pending  []int
+}
+
+// newBuildMonitor starts monitoring a build of a tree with n leaves
+// with the given number of workers. report receives the progress, if set.
+func newBuildMonitor(n, workers int, report func(BuildProgress)) *buildMonitor {
+	m := &buildMonitor{
+		start:    time.Now(),
+		profile:  BuildProfile{Workers: workers},
+		report:   report,
+		progress: BuildProgress{Leaves: n, Levels: treeLevels(n)},
+		pending:  []int{n},
+	}
+	for count := n; count > 1; count = (count + 1) / 2 {
+		m.pending = append(m.pending, count/2)
+	}
+	return m
+}
+
+// now returns the current time, or the zero time without a monitor.
+func (m *buildMonitor) now() time.Time {
+	if m == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// leaves records that n leaves with the given number of bytes
+// have been hashed since start.
+func (m *buildMonitor) leaves(n, bytes int, start time.Time) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.profile.BytesHashed += int64(bytes)
+	m.mu.Unlock()
+	m.level(0, n, start)
+}
+
+// level records that n nodes on the given level have been hashed since start.
+func (m *buildMonitor) level(level, n int, start time.Time) {
+	if m == nil {
+		return
+	}
+	d := time.Since(start)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for len(m.profile.Levels) <= level {
+		m.profile.Levels = append(m.profile.Levels, LevelProfile{})
+	}
+	m.profile.Levels[level].Nodes += n
+	m.profile.Levels[level].Duration += d
+
+	if level == 0 {
+		m.progress.LeavesHashed += n
+	}
+	if level < len(m.pending) {
+		m.pending[level] -= n
+		if level > 0 && m.pending[level] == 0 {
+			m.progress.LevelsDone++
+		}
+	}
+	if m.report != nil {
+		m.report(m.progress)
+	}
+}
+
+// work records that a worker has been hashing since start.
+func (m *buildMonitor) work(start time.Time) {
+	if m == nil {
+		return
+	}
+	d := time.Since(start)
+	m.mu.Lock()
+	m.profile.Busy += d
+	m.mu.Unlock()
+}
+
+// finish returns the profile of the build.
+func (m *buildMonitor) finish() BuildProfile {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.profile.Duration = time.Since(m.start)
+	return m.profile
+}
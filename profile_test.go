@@ -0,0 +1,128 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithBuildProfile(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		size       int
+		opts       []Option
+		expWorkers int
+	}{
+		{
+			name:       "Single leaf",
+			size:       1,
+			opts:       []Option{WithWorkers(4)},
+			expWorkers: 1,
+		},
+		{
+			name:       "Single worker",
+			size:       13,
+			opts:       []Option{WithWorkers(1)},
+			expWorkers: 1,
+		},
+		{
+			name:       "Small tree with default workers",
+			size:       10,
+			expWorkers: 1,
+		},
+		{
+			name:       "Several blocks",
+			size:       2*cacheBlock + 3,
+			opts:       []Option{WithWorkers(4), WithBatchSize(64)},
+			expWorkers: 4,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var profiles []BuildProfile
+			data := generateDummyData(tc.size)
+			opts := append(tc.opts, WithBuildProfile(func(p BuildProfile) {
+				profiles = append(profiles, p)
+			}))
+			_, err := NewTree(data, sha256.New, opts...)
+			require.NoError(t, err)
+			require.Len(t, profiles, 1)
+			profile := profiles[0]
+
+			// Every level hashes half of the nodes below it.
+			expNodes := []int{tc.size}
+			for count := tc.size; count > 1; count = (count + 1) / 2 {
+				expNodes = append(expNodes, count/2)
+			}
+			require.Len(t, profile.Levels, len(expNodes))
+			for i, level := range profile.Levels {
+				assert.Equal(t, expNodes[i], level.Nodes, "Level %d", i)
+			}
+
+			assert.Equal(t, int64(tc.size*32), profile.BytesHashed)
+			assert.Equal(t, tc.expWorkers, profile.Workers)
+			assert.Positive(t, profile.Duration)
+			assert.Positive(t, profile.Busy)
+			assert.InDelta(t, 0.5, profile.Utilization(), 0.5)
+		})
+	}
+}
+
+func TestWithBuildProgress(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		size int
+		opts []Option
+	}{
+		{
+			name: "Single leaf",
+			size: 1,
+		},
+		{
+			name: "Odd size",
+			size: 13,
+			opts: []Option{WithWorkers(1)},
+		},
+		{
+			name: "Several blocks",
+			size: 2*cacheBlock + 3,
+			opts: []Option{WithWorkers(4), WithBatchSize(64)},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// The calls don't overlap, so they can be collected without a lock.
+			var reports []BuildProgress
+			opts := append(tc.opts, WithBuildProgress(func(p BuildProgress) {
+				reports = append(reports, p)
+			}))
+			_, err := NewTree(generateDummyData(tc.size), sha256.New, opts...)
+			require.NoError(t, err)
+			require.NotEmpty(t, reports)
+
+			for i := 1; i < len(reports); i++ {
+				assert.GreaterOrEqual(t, reports[i].LeavesHashed, reports[i-1].LeavesHashed)
+				assert.GreaterOrEqual(t, reports[i].LevelsDone, reports[i-1].LevelsDone)
+			}
+			levels := treeLevels(tc.size)
+			assert.Equal(t, BuildProgress{
+				LeavesHashed: tc.size,
+				Leaves:       tc.size,
+				LevelsDone:   levels,
+				Levels:       levels,
+			}, reports[len(reports)-1])
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package merkle
+
+import "errors"
+
+var ErrLeafPruned = errors.New("leaf has been pruned")
+
+// Prune discards all nodes that are not needed to prove the leaves at the
+// given indices. Only the paths from those leaves to the root and the
+// hashes of their siblings are kept, so proofs for the kept leaves stay
+// the same while the rest of the tree can be garbage collected.
+//
+// The kept leaves can still be proven and updated, but the pruned leaves
+// are nil in Leaves and operations on them fail with ErrLeafPruned.
+func (t *Tree) Prune(indices []int) error {
+	t.flush()
+	keep := make(map[*Node]bool)
+	for _, index := range indices {
+		if err := t.checkLeaf(index); err != nil {
+			return err
+		}
+		for node := t.Leaves[index]; node != nil; node = node.Parent {
+			keep[node] = true
+		}
+	}
+
+	// Siblings of the kept paths only need their hash.
+	if !keep[t.Root] {
+		pruneNode(t.Root)
+	}
+	for node := range keep {
+		if node.Left != nil && !keep[node.Left] {
+			pruneNode(node.Left)
+		}
+		if node.Right != nil && !keep[node.Right] {
+			pruneNode(node.Right)
+		}
+	}
+
+	for i, leaf := range t.Leaves {
+		if leaf != nil && !keep[leaf] {
+			t.removeFromIndex(i, leaf.Hash)
+			if t.keys != nil && leaf.Key != "" {
+				delete(t.keys, leaf.Key)
+			}
+			t.Leaves[i] = nil
+		}
+	}
+
+	return nil
+}
+
+// pruneNode drops everything below the node except its hash.
+func pruneNode(node *Node) {
+	node.Left = nil
+	node.Right = nil
+	node.Value = nil
+}
+
+// checkLeaf returns an error if there is no leaf at the given index.
+func (t *Tree) checkLeaf(index int) error {
+	if index < 0 || index >= len(t.Leaves) {
+		return indexOutOfBounds(index, len(t.Leaves))
+	}
+	if t.Leaves[index] == nil {
+		return &IndexError{Index: index, Size: len(t.Leaves), Err: ErrLeafPruned}
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countNodes returns the number of nodes reachable from the root.
+func countNodes(n *Node) int {
+	if n == nil {
+		return 0
+	}
+	return 1 + countNodes(n.Left) + countNodes(n.Right)
+}
+
+func TestPrune(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		size     int
+		keep     []int
+		expNodes int
+	}{
+		{
+			name:     "Keep one leaf",
+			size:     8,
+			keep:     []int{5},
+			expNodes: 7,
+		},
+		{
+			name:     "Keep sibling leaves",
+			size:     8,
+			keep:     []int{2, 3},
+			expNodes: 7,
+		},
+		{
+			name:     "Keep promoted leaf",
+			size:     5,
+			keep:     []int{4},
+			expNodes: 3,
+		},
+		{
+			name:     "Keep nothing",
+			size:     6,
+			expNodes: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := generateDummyData(tc.size)
+			tree, err := NewTree(data, sha256.New)
+			require.NoError(t, err)
+
+			var expProofs []*Proof
+			for _, i := range tc.keep {
+				proof, err := tree.GenerateProofByIndex(i)
+				require.NoError(t, err)
+				expProofs = append(expProofs, proof)
+			}
+			root := tree.Root.Hash
+
+			require.NoError(t, tree.Prune(tc.keep))
+			assert.Equal(t, root, tree.Root.Hash)
+			assert.Equal(t, tc.expNodes, countNodes(tree.Root))
+			assert.Len(t, tree.Leaves, tc.size)
+
+			for i, index := range tc.keep {
+				proof, err := tree.GenerateProofByIndex(index)
+				require.NoError(t, err)
+				assert.Equal(t, expProofs[i], proof)
+
+				isValid, err := tree.VerifyProof(proof, data[index])
+				require.NoError(t, err)
+				assert.True(t, isValid)
+			}
+		})
+	}
+}
+
+func TestPrunedLeaves(t *testing.T) {
+	t.Parallel()
+
+	data := generateDummyData(8)
+	tree, err := NewTree(data, sha256.New)
+	require.NoError(t, err)
+	require.NoError(t, tree.Prune([]int{1, 6}))
+
+	_, err = tree.GenerateProofByIndex(0)
+	require.ErrorIs(t, err, ErrLeafPruned)
+	_, err = tree.GenerateProof(data[0])
+	require.ErrorIs(t, err, ErrNoVal)
+	require.ErrorIs(t, tree.UpdateLeaf(3, []byte("new")), ErrLeafPruned)
+	require.ErrorIs(t, tree.Prune([]int{2}), ErrLeafPruned)
+	require.ErrorIs(t, tree.Prune([]int{8}), ErrIndexOutOfBounds)
+
+	// Kept leaves can still be updated and proven.
+	require.NoError(t, tree.UpdateLeaf(6, []byte("new")))
+	data[6] = []byte("new")
+	fullTree, err := NewTree(data, sha256.New)
+	require.NoError(t, err)
+	assert.Equal(t, fullTree.Root.Hash, tree.Root.Hash)
+
+	proof, err := tree.GenerateProof(data[1])
+	require.NoError(t, err)
+	isValid, err := fullTree.VerifyProof(proof, data[1])
+	require.NoError(t, err)
+	assert.True(t, isValid)
+}
+
+func TestPrunedLevels(t *testing.T) {
+	t.Parallel()
+
+	data := generateDummyData(8)
+	fullTree, err := NewTree(data, sha256.New)
+	require.NoError(t, err)
+	tree, err := NewTree(data, sha256.New)
+	require.NoError(t, err)
+	// Leaf 0 is kept as the sibling of leaf 1, and the nodes
+	// over leaves 2-3 and 4-7 only keep their hashes.
+	require.NoError(t, tree.Prune([]int{1}))
+
+	full := fullTree.Levels()
+	expLevels := [][][]byte{full[0][:2], full[1][:2], full[2], full[3]}
+	assert.Equal(t, expLevels, tree.Levels())
+	assert.Len(t, tree.LevelOrder(), 7)
+
+	roots, err := tree.GroupRoots(4)
+	require.NoError(t, err)
+	expRoots, err := fullTree.GroupRoots(4)
+	require.NoError(t, err)
+	assert.Equal(t, expRoots, roots)
+	_, err = tree.GroupRoots(2)
+	assert.ErrorIs(t, err, ErrLeafPruned)
+
+	proof, err := tree.GenerateGroupProof(4, 1)
+	require.NoError(t, err)
+	expProof, err := fullTree.GenerateGroupProof(4, 1)
+	require.NoError(t, err)
+	assert.Equal(t, expProof, proof)
+	_, err = tree.GenerateGroupProof(2, 3)
+	assert.ErrorIs(t, err, ErrLeafPruned)
+}
+
+func TestPrunedUpdateWithLevelTags(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		opts  []Option
+		prune []int
+		index int
+	}{
+		{
+			name:  "Last leaf",
+			prune: []int{7},
+			index: 7,
+		},
+		{
+			name:  "Leaf next to pruned subtrees",
+			prune: []int{2, 5},
+			index: 5,
+		},
+		{
+			name:  "Deferred hashing",
+			opts:  []Option{WithDeferredHashing()},
+			prune: []int{7},
+			index: 7,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			opts := append([]Option{WithLevelTags(LevelIndexTag)}, tc.opts...)
+			data := generateDummyData(8)
+			tree, err := NewTree(data, sha256.New, opts...)
+			require.NoError(t, err)
+			require.NoError(t, tree.Prune(tc.prune))
+			require.NoError(t, tree.UpdateLeaf(tc.index, []byte("updated")))
+
+			data[tc.index] = []byte("updated")
+			expTree, err := NewTree(data, sha256.New, opts...)
+			require.NoError(t, err)
+			assert.Equal(t, expTree.RootHash(), tree.RootHash())
+		})
+	}
+}
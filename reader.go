@@ -0,0 +1,45 @@
+package merkle
+
+import (
+	"context"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// NewTreeFromReaders creates a new Merkle tree whose leaves are the
+// contents of the readers. Every reader is hashed as it is read, so large
+// leaves like files are never loaded into memory, and the readers are read
+// concurrently. The leaves only hold their hashes, like the leaves
+// of NewTreeFromHashes, but are hashed like the leaves of NewTree.
+// Leaf validators are not called, and WithLengthPrefixedLeaves has to
+// buffer each leaf, since its length is hashed before its contents.
+func NewTreeFromReaders(readers []io.Reader, newHashFunc func() hash.Hash, opts ...Option) (*Tree, error) {
+	cfg := newConfig(opts, newHashFunc)
+	if len(readers) == 0 && !cfg.allowEmpty {
+		return nil, ErrNoLeaves
+	}
+
+	hashes := make([][]byte, len(readers))
+	err := cfg.parallelism.batchesContext(context.Background(), len(readers), func(ctx context.Context, start, end int) error {
+		hashFunc := cfg.hasher.NewLeafHasher()
+		for i := start; i < end; i++ {
+			hashFunc.Reset()
+			n, err := io.Copy(hashFunc, readers[i])
+			if err != nil {
+				return fmt.Errorf("leaf %d: %w", i, err)
+			}
+			if cfg.fixedLeafSize && n != int64(cfg.leafSize) {
+				return fmt.Errorf("leaf %d: %w: expected %d bytes, but got %d",
+					i, ErrInvalidLeafSize, cfg.leafSize, n)
+			}
+			hashes[i] = hashFunc.Sum(nil)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return newTree(hashes, nil, newHashFunc, cfg), nil
+}
@@ -0,0 +1,96 @@
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTreeFromReaders(t *testing.T) {
+	t.Parallel()
+
+	errRead := errors.New("read failed")
+
+	tests := []struct {
+		name    string
+		values  [][]byte
+		readers func(values [][]byte) []io.Reader
+		opts    []Option
+		err     error
+	}{
+		{
+			name:   "Readers",
+			values: generateDummyData(7),
+		},
+		{
+			name:   "One byte at a time",
+			values: generateDummyData(3),
+			readers: func(values [][]byte) []io.Reader {
+				readers := make([]io.Reader, len(values))
+				for i, value := range values {
+					readers[i] = iotest.OneByteReader(bytes.NewReader(value))
+				}
+				return readers
+			},
+		},
+		{
+			name:   "Domain and length prefixes",
+			values: generateDummyData(5),
+			opts:   []Option{WithDomainPrefixes([]byte{0}, []byte{1}), WithLengthPrefixedLeaves()},
+		},
+		{
+			name:   "Fixed leaf size",
+			values: generateDummyData(2),
+			opts:   []Option{WithFixedLeafSize(16)},
+			err:    ErrInvalidLeafSize,
+		},
+		{
+			name:   "Read error",
+			values: generateDummyData(2),
+			readers: func(values [][]byte) []io.Reader {
+				return []io.Reader{bytes.NewReader(values[0]), iotest.ErrReader(errRead)}
+			},
+			err: errRead,
+		},
+		{
+			name: "No readers",
+			err:  ErrNoLeaves,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			readers := make([]io.Reader, len(tc.values))
+			for i, value := range tc.values {
+				readers[i] = bytes.NewReader(value)
+			}
+			if tc.readers != nil {
+				readers = tc.readers(tc.values)
+			}
+
+			tree, err := NewTreeFromReaders(readers, sha256.New, tc.opts...)
+			require.ErrorIs(t, err, tc.err)
+			if tc.err != nil {
+				return
+			}
+
+			expTree, err := NewTree(tc.values, sha256.New, tc.opts...)
+			require.NoError(t, err)
+			assert.True(t, tree.Equal(expTree), "Tree mismatch")
+
+			proof, err := tree.GenerateProofByIndex(0)
+			require.NoError(t, err)
+			isValid, err := tree.VerifyProof(proof, tc.values[0])
+			require.NoError(t, err)
+			assert.True(t, isValid)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package merkle
+
+import (
+	"errors"
+	"hash"
+)
+
+var ErrNoLeafValues = errors.New("tree has no leaf values")
+
+// ReHash recomputes all leaf and node hashes with the hash function
+// created by newHashFunc, e.g. to migrate a tree to another hash algorithm.
+// Leaf values, their order and options like domain prefixes are kept,
+// but a hasher set with WithHasher is replaced.
+// Trees built from leaf hashes or without leaf values can't be rehashed
+// and return ErrNoLeafValues.
+func (t *Tree) ReHash(newHashFunc func() hash.Hash) error {
+	if t.hashedLeaves {
+		return ErrNoLeafValues
+	}
+	for i, leaf := range t.Leaves {
+		if leaf == nil {
+			return &IndexError{Index: i, Size: len(t.Leaves), Err: ErrLeafPruned}
+		}
+	}
+
+	// Every node is rehashed, so the dirty nodes can be dropped.
+	t.dirty, t.dirtySeen = nil, nil
+	t.cfg.setHasher(StdHasher(newHashFunc))
+	t.HashFunc = t.cfg.hasher.NewNodeHasher()
+	t.leafHashFunc = t.cfg.hasher.NewLeafHasher()
+	t.newHashFunc = newHashFunc
+
+	if len(t.Leaves) == 0 {
+		t.Root = t.emptyRoot()
+		t.buildIndex()
+		t.rootChanged(MutationRehash)
+		return nil
+	}
+
+	t.cfg.parallelism.batches(len(t.Leaves), func(start, end int) {
+		hashFunc := t.cfg.hasher.NewLeafHasher()
+		for _, leaf := range t.Leaves[start:end] {
+			hashFunc.Reset()
+			hashFunc.Write(leaf.Value)
+			leaf.Hash = hashFunc.Sum(nil)
+		}
+	})
+
+	// Rebuild the nodes above the leaves block by block.
+	t.rebuild(t.Leaves)
+	t.rootChanged(MutationRehash)
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReHash(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		numLeaves int
+		opts      []Option
+	}{
+		{
+			name:      "Single leaf",
+			numLeaves: 1,
+		},
+		{
+			name:      "Odd number of leaves",
+			numLeaves: 7,
+		},
+		{
+			name:      "Many leaves",
+			numLeaves: 1000,
+		},
+		{
+			name:      "Empty tree",
+			numLeaves: 0,
+			opts:      []Option{WithEmptyTree()},
+		},
+		{
+			name:      "Domain prefixes",
+			numLeaves: 5,
+			opts:      []Option{WithDomainPrefixes([]byte{0}, []byte{1})},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := generateDummyData(tc.numLeaves)
+			tree, err := NewTree(data, sha256.New, tc.opts...)
+			require.NoError(t, err)
+
+			require.NoError(t, tree.ReHash(sha512.New))
+
+			expTree, err := NewTree(data, sha512.New, tc.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, expTree.Root.Hash, tree.Root.Hash, "Root mismatch")
+			assert.Equal(t, expTree.Levels(), tree.Levels(), "Levels mismatch")
+
+			for i, value := range data {
+				assert.Equal(t, value, tree.Leaves[i].Value)
+
+				proof, err := tree.GenerateProofByIndex(i)
+				require.NoError(t, err)
+				isValid, err := tree.VerifyProof(proof, value)
+				require.NoError(t, err)
+				assert.True(t, isValid)
+			}
+
+			// Updates use the new hash function.
+			if tc.numLeaves > 0 {
+				require.NoError(t, tree.UpdateLeaf(0, []byte("updated")))
+				require.NoError(t, expTree.UpdateLeaf(0, []byte("updated")))
+				assert.Equal(t, expTree.Root.Hash, tree.Root.Hash)
+			}
+		})
+	}
+}
+
+func TestReHashErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		newTree func(t *testing.T) *Tree
+		err     error
+	}{
+		{
+			name: "Tree from hashes",
+			newTree: func(t *testing.T) *Tree {
+				tree, err := NewTreeFromHashes(generateDummyData(4), sha256.New)
+				require.NoError(t, err)
+				return tree
+			},
+			err: ErrNoLeafValues,
+		},
+		{
+			name: "Pruned tree",
+			newTree: func(t *testing.T) *Tree {
+				tree, err := NewTree(generateDummyData(4), sha256.New)
+				require.NoError(t, err)
+				require.NoError(t, tree.Prune([]int{0}))
+				return tree
+			},
+			err: ErrLeafPruned,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree := tc.newTree(t)
+			root := tree.Root.Hash
+
+			err := tree.ReHash(sha512.New)
+			require.ErrorIs(t, err, tc.err)
+			assert.Equal(t, root, tree.Root.Hash, "Root should not change")
+		})
+	}
+}
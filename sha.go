@@ -0,0 +1,26 @@
+package merkle
+
+import (
+	"crypto/sha512"
+	"hash"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// NewSHA512_256 returns a new SHA-512/256 hash, which is SHA-512
+// truncated to 32 bytes with its own initial values. It is faster than
+// SHA-256 on 64-bit CPUs without SHA extensions.
+func NewSHA512_256() hash.Hash {
+	return sha512.New512_256()
+}
+
+// NewSHA3_256 returns a new SHA3-256 hash as defined in FIPS 202.
+// Use NewKeccak256 for trees that must match Ethereum.
+func NewSHA3_256() hash.Hash {
+	return sha3.New256()
+}
+
+// NewSHA3_512 returns a new SHA3-512 hash as defined in FIPS 202.
+func NewSHA3_512() hash.Hash {
+	return sha3.New512()
+}
@@ -0,0 +1,82 @@
+package merkle
+
+import (
+	"encoding/hex"
+	"hash"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSHAVariants(t *testing.T) {
+	t.Parallel()
+
+	values := [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e")}
+	tests := []struct {
+		name         string
+		newHashFunc  func() hash.Hash
+		expLeafHash  string
+		expRoot      string
+		expLastProof []string
+	}{
+		{
+			name:        "SHA-512/256",
+			newHashFunc: NewSHA512_256,
+			expLeafHash: "455e518824bc0601f9fb858ff5c37d417d67c2f8e0df2babe4808858aea830f8",
+			expRoot:     "f19886b97f6381925f15b9ad6c1e2ff2f40057f9b8add7bc4d9a49a1b5dc3369",
+			expLastProof: []string{
+				"2ac69de0f1e02e3a5015fa0ce53bfa8c35a9e5a83aa705b6eb680f9b98fb4c74",
+			},
+		},
+		{
+			name:        "SHA3-256",
+			newHashFunc: NewSHA3_256,
+			expLeafHash: "80084bf2fba02475726feb2cab2d8215eab14bc6bdd8bfb2c8151257032ecd8b",
+			expRoot:     "b8efa384f64647583db7ea069c46ec746d4d8c0c1815040431db4134bc0b41fd",
+			expLastProof: []string{
+				"5267fec4a5327f9d287233f95213afa39d3aad2fee1fa1384b032b79fb3441e8",
+			},
+		},
+		{
+			name:        "SHA3-512",
+			newHashFunc: NewSHA3_512,
+			expLeafHash: "697f2d856172cb8309d6b8b97dac4de344b549d4dee61edfb4962d8698b7fa80" +
+				"3f4f93ff24393586e28b5b957ac3d1d369420ce53332712f997bd336d09ab02a",
+			expRoot: "2f7cde23a25dd6aa06cf414a15544bf083d5bd9f2997a4826d3d60780a661585" +
+				"52169e554a170152186080934e8841d821e23dc672a89173cc52866f86609356",
+			expLastProof: []string{
+				"4a8952a6ead32c88befc1cfd92bad007bdadfb3c88ffcfa4327a36959e94863d" +
+					"5a1e57948bb4a4f644063db638c08bdf6dd39c01d94f55d7dff45959572ff993",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := NewTree(values, tc.newHashFunc)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expLeafHash, hex.EncodeToString(tree.Leaves[0].Hash))
+			assert.Equal(t, tc.expRoot, hex.EncodeToString(tree.Root.Hash))
+
+			proof, err := tree.GenerateProofByIndex(len(values) - 1)
+			require.NoError(t, err)
+			hashes := make([]string, len(proof.Hashes))
+			for i, hash := range proof.Hashes {
+				hashes[i] = hex.EncodeToString(hash)
+			}
+			assert.Equal(t, tc.expLastProof, hashes)
+
+			for i, value := range values {
+				proof, err := tree.GenerateProofByIndex(i)
+				require.NoError(t, err)
+
+				isValid, err := tree.VerifyProof(proof, value)
+				require.NoError(t, err)
+				assert.True(t, isValid)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package merkle
+
+import (
+	"bytes"
+	"fmt"
+	"hash"
+)
+
+// TreeSnapshot is the state of a tree when only its first Size leaves
+// existed. It reuses the complete subtrees of the current tree, so it
+// is only valid as long as those leaves are not modified, as in an
+// append-only log.
+type TreeSnapshot struct {
+	Root []byte
+	Size int
+
+	tree     *Tree
+	hashFunc hash.Hash
+}
+
+// TreeAtSize reconstructs the tree as it was when only the first n leaves
+// existed, without storing explicit versions.
+func (t *Tree) TreeAtSize(n int) (*TreeSnapshot, error) {
+	t.flush()
+	if n <= 0 || n > len(t.Leaves) {
+		return nil, fmt.Errorf("%w: size %d in a tree with %d leaves",
+			ErrIndexOutOfBounds, n, len(t.Leaves))
+	}
+
+	snapshot := &TreeSnapshot{
+		Size:     n,
+		tree:     t,
+		hashFunc: t.cfg.hasher.NewNodeHasher(),
+	}
+
+	root, err := snapshot.nodeHash(treeLevels(n), 0)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.Root = root
+
+	return snapshot, nil
+}
+
+// nodeHash returns the hash of the node at the given level and index
+// in the snapshot.
+func (s *TreeSnapshot) nodeHash(level, index int) ([]byte, error) {
+	start := index << level
+	return s.tree.rangeHash(start, min(s.Size, start+1<<level), s.hashFunc)
+}
+
+// GenerateProofByIndex generates a proof for the leaf at the given index
+// against the root of the snapshot.
+func (s *TreeSnapshot) GenerateProofByIndex(index int) (*Proof, error) {
+	if index < 0 || index >= s.Size {
+		return nil, indexOutOfBounds(index, s.Size)
+	}
+	return proofFromSubtrees(index, s.Size, s.nodeHash)
+}
+
+// VerifyProof verifies the proof for value against the root of the snapshot.
+func (s *TreeSnapshot) VerifyProof(proof *Proof, value []byte) (bool, error) {
+	leafHashFunc := s.tree.cfg.hasher.NewLeafHasher()
+	leafHashFunc.Write(value)
+	leafHash := leafHashFunc.Sum(nil)
+
+	root, ok := rootFromProofWithConfig(leafHash, proof, s.Size, s.hashFunc, &s.tree.cfg)
+	if !ok {
+		return false, &ProofSizeError{Index: proof.Index, NumHashes: len(proof.Hashes), Size: s.Size}
+	}
+
+	if !bytes.Equal(root, s.Root) {
+		return false, &RootMismatchError{Expected: s.Root, Actual: root}
+	}
+
+	return true, nil
+}
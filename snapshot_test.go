@@ -0,0 +1,61 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTreeAtSize(t *testing.T) {
+	t.Parallel()
+
+	data := generateDummyData(13)
+	tree, err := NewTree(data, sha256.New)
+	require.NoError(t, err)
+
+	for size := 1; size <= len(data); size++ {
+		t.Run(fmt.Sprintf("%d leaves", size), func(t *testing.T) {
+			t.Parallel()
+
+			snapshot, err := tree.TreeAtSize(size)
+			require.NoError(t, err)
+
+			// The snapshot must match a tree built from scratch.
+			expTree, err := NewTree(data[:size], sha256.New)
+			require.NoError(t, err)
+			assert.Equal(t, expTree.Root.Hash, snapshot.Root, "Tree root mismatch")
+
+			for i := 0; i < size; i++ {
+				proof, err := snapshot.GenerateProofByIndex(i)
+				require.NoError(t, err)
+
+				expProof, err := expTree.GenerateProofByIndex(i)
+				require.NoError(t, err)
+				assert.Equal(t, expProof.Hashes, proof.Hashes, "Proof mismatch for leaf %d", i)
+
+				isValid, err := snapshot.VerifyProof(proof, data[i])
+				require.NoError(t, err)
+				assert.True(t, isValid)
+			}
+
+			_, err = snapshot.GenerateProofByIndex(size)
+			require.ErrorIs(t, err, ErrIndexOutOfBounds)
+		})
+	}
+}
+
+func TestTreeAtSizeOutOfBounds(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewTree(generateDummyData(4), sha256.New)
+	require.NoError(t, err)
+
+	_, err = tree.TreeAtSize(0)
+	require.ErrorIs(t, err, ErrIndexOutOfBounds)
+
+	_, err = tree.TreeAtSize(5)
+	require.ErrorIs(t, err, ErrIndexOutOfBounds)
+}
@@ -0,0 +1,165 @@
+package merkle
+
+import (
+	"bytes"
+	"fmt"
+	"hash"
+)
+
+// sparseDepth is the number of levels above the leaves
+// in a sparse tree, one for each bit of a slot index.
+const sparseDepth = 64
+
+// sparseKey identifies a node of a sparse tree by its level and index.
+type sparseKey struct {
+	level uint8
+	index uint64
+}
+
+// SparseTree is a vector commitment over 2^64 slots indexed by uint64.
+// Slots that have not been set hold a default value. Only slots and nodes
+// that differ from the default are stored, so memory grows with the number
+// of slots that are set, not with the size of the index space.
+type SparseTree struct {
+	HashFunc hash.Hash
+
+	defaultValue []byte
+	zeroHashes   [][]byte
+	values       map[uint64][]byte
+	nodes        map[sparseKey][]byte
+}
+
+// SparseProof is an inclusion proof for a slot of a sparse tree.
+// Hashes[i] is the sibling on level i, from the leaf to the root.
+type SparseProof struct {
+	Hashes [][]byte
+	Index  uint64
+}
+
+// NewSparseTree creates a new sparse tree where every slot
+// holds defaultValue.
+func NewSparseTree(defaultValue []byte, newHashFunc func() hash.Hash) *SparseTree {
+	s := &SparseTree{
+		HashFunc:     newHashFunc(),
+		defaultValue: defaultValue,
+		values:       make(map[uint64][]byte),
+		nodes:        make(map[sparseKey][]byte),
+	}
+
+	// The hash of an empty subtree only depends on its level.
+	s.zeroHashes = NewZeroHashTable(defaultValue, newHashFunc).Hashes(sparseDepth)
+
+	return s
+}
+
+// Root returns the root hash of the tree.
+func (s *SparseTree) Root() []byte {
+	return s.nodeHash(sparseDepth, 0)
+}
+
+// Len returns the number of slots that don't hold the default value.
+func (s *SparseTree) Len() int {
+	return len(s.values)
+}
+
+// Get returns the value of the slot at index i.
+func (s *SparseTree) Get(i uint64) []byte {
+	if value, ok := s.values[i]; ok {
+		return value
+	}
+	return s.defaultValue
+}
+
+// Set sets the value of the slot at index i and updates
+// the hashes on its path to the root.
+// Setting a slot to the default value removes it from storage.
+func (s *SparseTree) Set(i uint64, value []byte) {
+	if bytes.Equal(value, s.defaultValue) {
+		delete(s.values, i)
+	} else {
+		s.values[i] = value
+	}
+
+	s.HashFunc.Reset()
+	s.HashFunc.Write(value)
+	s.setNodeHash(0, i, s.HashFunc.Sum(nil))
+
+	index := i
+	for level := 1; level <= sparseDepth; level++ {
+		index >>= 1
+		left := s.nodeHash(level-1, index<<1)
+		right := s.nodeHash(level-1, index<<1|1)
+		s.setNodeHash(level, index, combineHashes(left, right, s.HashFunc))
+	}
+}
+
+// nodeHash returns the hash of the node at the given level and index.
+func (s *SparseTree) nodeHash(level int, index uint64) []byte {
+	if hash, ok := s.nodes[sparseKey{level: uint8(level), index: index}]; ok {
+		return hash
+	}
+	return s.zeroHashes[level]
+}
+
+// setNodeHash stores the hash of a node, unless it is the default.
+func (s *SparseTree) setNodeHash(level int, index uint64, hash []byte) {
+	key := sparseKey{level: uint8(level), index: index}
+	if bytes.Equal(hash, s.zeroHashes[level]) {
+		delete(s.nodes, key)
+		return
+	}
+	s.nodes[key] = hash
+}
+
+// GenerateProof generates a proof for the slot at index i,
+// whether it has been set or holds the default value.
+func (s *SparseTree) GenerateProof(i uint64) *SparseProof {
+	hashes := make([][]byte, sparseDepth)
+	index := i
+	for level := 0; level < sparseDepth; level++ {
+		hashes[level] = s.nodeHash(level, index^1)
+		index >>= 1
+	}
+
+	return &SparseProof{
+		Hashes: hashes,
+		Index:  i,
+	}
+}
+
+// VerifyProof verifies the proof for value against the root of the tree.
+func (s *SparseTree) VerifyProof(proof *SparseProof, value []byte) (bool, error) {
+	s.HashFunc.Reset()
+	return verifySparseProof(s.Root(), proof, value, s.HashFunc)
+}
+
+// VerifySparseProof verifies the proof for value against the given root.
+func VerifySparseProof(root []byte, proof *SparseProof, value []byte, newHashFunc func() hash.Hash) (bool, error) {
+	return verifySparseProof(root, proof, value, newHashFunc())
+}
+
+func verifySparseProof(root []byte, proof *SparseProof, value []byte, hashFunc hash.Hash) (bool, error) {
+	if len(proof.Hashes) != sparseDepth {
+		return false, fmt.Errorf("%w: expected %d hashes, but got %d",
+			ErrProofVerificationFailed, sparseDepth, len(proof.Hashes))
+	}
+
+	hashFunc.Write(value)
+	currentHash := hashFunc.Sum(nil)
+
+	index := proof.Index
+	for _, siblingHash := range proof.Hashes {
+		if index&1 == 0 {
+			currentHash = combineHashes(currentHash, siblingHash, hashFunc)
+		} else {
+			currentHash = combineHashes(siblingHash, currentHash, hashFunc)
+		}
+		index >>= 1
+	}
+
+	if !bytes.Equal(currentHash, root) {
+		return false, &RootMismatchError{Expected: root, Actual: currentHash}
+	}
+
+	return true, nil
+}
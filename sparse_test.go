@@ -0,0 +1,104 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSparseTree(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		set     map[uint64][]byte
+		proveAt uint64
+		value   []byte
+	}{
+		{
+			name:    "Default slot in empty tree",
+			set:     map[uint64][]byte{},
+			proveAt: 42,
+			value:   []byte{},
+		},
+		{
+			name:    "Set slot",
+			set:     map[uint64][]byte{1: []byte("a"), 7: []byte("b")},
+			proveAt: 7,
+			value:   []byte("b"),
+		},
+		{
+			name:    "Default slot next to set slot",
+			set:     map[uint64][]byte{1: []byte("a"), 7: []byte("b")},
+			proveAt: 6,
+			value:   []byte{},
+		},
+		{
+			name:    "Highest slot",
+			set:     map[uint64][]byte{math.MaxUint64: []byte("max")},
+			proveAt: math.MaxUint64,
+			value:   []byte("max"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree := NewSparseTree([]byte{}, sha256.New)
+			for i, value := range tc.set {
+				tree.Set(i, value)
+			}
+			assert.Equal(t, len(tc.set), tree.Len())
+			assert.Equal(t, tc.value, tree.Get(tc.proveAt))
+
+			proof := tree.GenerateProof(tc.proveAt)
+			isValid, err := tree.VerifyProof(proof, tc.value)
+			require.NoError(t, err)
+			assert.True(t, isValid)
+
+			isValid, err = VerifySparseProof(tree.Root(), proof, tc.value, sha256.New)
+			require.NoError(t, err)
+			assert.True(t, isValid)
+
+			isValid, err = tree.VerifyProof(proof, []byte("wrong"))
+			require.ErrorIs(t, err, ErrProofVerificationFailed)
+			assert.False(t, isValid)
+		})
+	}
+}
+
+func TestSparseTreeResetToDefault(t *testing.T) {
+	t.Parallel()
+
+	tree := NewSparseTree([]byte{}, sha256.New)
+	emptyRoot := tree.Root()
+
+	tree.Set(3, []byte("a"))
+	tree.Set(1<<40, []byte("b"))
+	assert.NotEqual(t, emptyRoot, tree.Root())
+
+	// Setting slots back to the default frees their storage.
+	tree.Set(3, []byte{})
+	tree.Set(1<<40, []byte{})
+	assert.Equal(t, emptyRoot, tree.Root())
+	assert.Equal(t, 0, tree.Len())
+	assert.Empty(t, tree.nodes)
+}
+
+func TestSparseTreeOrderIndependent(t *testing.T) {
+	t.Parallel()
+
+	first := NewSparseTree([]byte{}, sha256.New)
+	first.Set(1, []byte("a"))
+	first.Set(2, []byte("b"))
+
+	second := NewSparseTree([]byte{}, sha256.New)
+	second.Set(2, []byte("b"))
+	second.Set(1, []byte("a"))
+
+	assert.Equal(t, first.Root(), second.Root())
+}
@@ -0,0 +1,105 @@
+package merkle
+
+import (
+	"fmt"
+	"maps"
+	"math/bits"
+)
+
+// Split splits the tree into independent trees over the leaves
+// before index and the leaves from index on, e.g. to shard a dataset
+// that has outgrown a single tree. Subtrees that keep their shape are
+// copied with their hashes, so only the nodes along the split are hashed.
+// The original tree is not changed.
+func (t *Tree) Split(index int) (*Tree, *Tree, error) {
+	t.flush()
+	if index <= 0 || index >= len(t.Leaves) {
+		return nil, nil, fmt.Errorf("%w: split at %d in a tree with %d leaves",
+			ErrIndexOutOfBounds, index, len(t.Leaves))
+	}
+
+	left, err := t.copyLeaves(0, index)
+	if err != nil {
+		return nil, nil, err
+	}
+	right, err := t.copyLeaves(index, len(t.Leaves))
+	if err != nil {
+		return nil, nil, err
+	}
+	return left, right, nil
+}
+
+// copyLeaves returns a new tree over copies of the leaves in [start, end).
+func (t *Tree) copyLeaves(start, end int) (*Tree, error) {
+	tree := &Tree{
+		HashFunc:     t.cfg.hasher.NewNodeHasher(),
+		Leaves:       make([]*Node, end-start),
+		leafHashFunc: t.cfg.hasher.NewLeafHasher(),
+		newHashFunc:  t.newHashFunc,
+		cfg:          t.cfg,
+		hashedLeaves: t.hashedLeaves,
+		subtreeRoots: newSubtreeCache(t.cfg.subtreeCache),
+	}
+
+	root, err := t.copyRange(tree, start, end, start)
+	if err != nil {
+		return nil, err
+	}
+	tree.Root = root
+	if t.index != nil {
+		tree.buildIndex()
+	}
+	if t.keys != nil {
+		tree.buildKeys()
+	}
+	tree.rootChanged(MutationBuild)
+	return tree, nil
+}
+
+// copyRange builds the nodes of tree over the leaves in [start, end) of t
+// and returns their root. The leaves of tree start at offset in t.
+func (t *Tree) copyRange(tree *Tree, start, end, offset int) (*Node, error) {
+	n := end - start
+	if n&(n-1) == 0 && start%n == 0 {
+		// The range is a complete subtree of t, which can be copied.
+		node := t.findNode(start, end)
+		if node == nil {
+			return nil, &IndexError{Index: start, Size: len(t.Leaves), Err: ErrLeafPruned}
+		}
+		return copyNode(node, t.Leaves[start:end], tree.Leaves[start-offset:end-offset]), nil
+	}
+
+	k := 1 << (bits.Len(uint(n-1)) - 1)
+	left, err := t.copyRange(tree, start, start+k, offset)
+	if err != nil {
+		return nil, err
+	}
+	right, err := t.copyRange(tree, start+k, end, offset)
+	if err != nil {
+		return nil, err
+	}
+	return tree.newParent(bits.Len(uint(k)), left, right), nil
+}
+
+// copyNode copies the complete subtree below node, whose leaves are src,
+// and stores the copied leaves in dst.
+func copyNode(node *Node, src, dst []*Node) *Node {
+	cp := &Node{Hash: node.Hash, Value: node.Value, Metadata: maps.Clone(node.Metadata), Key: node.Key}
+	if len(src) == 1 {
+		if src[0] != nil {
+			dst[0] = cp
+		}
+		return cp
+	}
+	if node.Left == nil {
+		// The subtree has been pruned.
+		return cp
+	}
+
+	half := len(src) / 2
+	cp.Left = copyNode(node.Left, src[:half], dst[:half])
+	cp.Right = copyNode(node.Right, src[half:], dst[half:])
+	cp.Left.Parent = cp
+	cp.Right.Parent = cp
+	return cp
+}
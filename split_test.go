@@ -0,0 +1,96 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplit(t *testing.T) {
+	t.Parallel()
+
+	for size := 2; size <= 17; size++ {
+		for index := 1; index < size; index++ {
+			t.Run(fmt.Sprintf("Split %d leaves at %d", size, index), func(t *testing.T) {
+				t.Parallel()
+
+				data := generateDummyData(size)
+				tree, err := NewTree(data, sha256.New, WithLevelTags(LevelIndexTag))
+				require.NoError(t, err)
+				root := tree.RootHash()
+
+				left, right, err := tree.Split(index)
+				require.NoError(t, err)
+				require.NoError(t, left.Validate())
+				require.NoError(t, right.Validate())
+
+				expLeft, err := NewTree(data[:index], sha256.New, WithLevelTags(LevelIndexTag))
+				require.NoError(t, err)
+				expRight, err := NewTree(data[index:], sha256.New, WithLevelTags(LevelIndexTag))
+				require.NoError(t, err)
+				assert.True(t, left.Equal(expLeft), "Left tree mismatch")
+				assert.True(t, right.Equal(expRight), "Right tree mismatch")
+
+				i, found := right.IndexOf(data[size-1])
+				assert.True(t, found)
+				assert.Equal(t, size-1-index, i)
+
+				// The trees are independent of the original tree.
+				require.NoError(t, left.UpdateLeaf(0, []byte("new")))
+				require.NoError(t, right.AppendLeaf([]byte("new")))
+				assert.Equal(t, root, tree.RootHash())
+				require.NoError(t, tree.Validate())
+			})
+		}
+	}
+}
+
+func TestSplitMetadata(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewTree(generateDummyData(4), sha256.New)
+	require.NoError(t, err)
+	require.NoError(t, tree.SetMetadata(3, map[string]any{"shard": 1}))
+
+	_, right, err := tree.Split(2)
+	require.NoError(t, err)
+	metadata, err := right.Metadata(1)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"shard": 1}, metadata)
+}
+
+func TestSplitErrors(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewTree(generateDummyData(8), sha256.New)
+	require.NoError(t, err)
+	require.NoError(t, tree.Prune([]int{0, 1}))
+
+	tests := []struct {
+		name  string
+		index int
+		err   error
+	}{
+		{name: "Split at start", index: 0, err: ErrIndexOutOfBounds},
+		{name: "Split at end", index: 8, err: ErrIndexOutOfBounds},
+		{name: "Split through pruned leaves", index: 3, err: ErrLeafPruned},
+		{name: "Split at pruned subtree", index: 4},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			left, right, err := tree.Split(tc.index)
+			require.ErrorIs(t, err, tc.err)
+			if tc.err != nil {
+				return
+			}
+			require.NoError(t, left.Validate())
+			require.NoError(t, right.Validate())
+		})
+	}
+}
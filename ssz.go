@@ -0,0 +1,158 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrSSZLimitExceeded = errors.New("ssz limit exceeded")
+	ErrSSZInvalidLength = errors.New("invalid ssz length")
+)
+
+// sszChunkSize is the size of an SSZ chunk in bytes.
+const sszChunkSize = 32
+
+// sszZeroHashes[i] is the root of a tree of depth i over zero chunks.
+var sszZeroHashes = func() [65][sszChunkSize]byte {
+	var hashes [65][sszChunkSize]byte
+	for i := 1; i < len(hashes); i++ {
+		hashes[i] = sha256.Sum256(append(hashes[i-1][:], hashes[i-1][:]...))
+	}
+	return hashes
+}()
+
+// SSZPack packs serialized basic values into 32-byte chunks,
+// right-padding the last chunk with zeros.
+func SSZPack(serialized []byte) [][sszChunkSize]byte {
+	chunks := make([][sszChunkSize]byte, (len(serialized)+sszChunkSize-1)/sszChunkSize)
+	for i := range chunks {
+		copy(chunks[i][:], serialized[i*sszChunkSize:])
+	}
+	return chunks
+}
+
+// SSZMerkleize computes the root of the chunks, padded with zero chunks
+// to the next power of two of limit. A limit below zero pads to the next
+// power of two of the number of chunks instead. Padding is not materialized,
+// so a large limit doesn't cost more than the chunks that are present.
+func SSZMerkleize(chunks [][sszChunkSize]byte, limit int) ([sszChunkSize]byte, error) {
+	if limit < 0 {
+		limit = len(chunks)
+	}
+	if len(chunks) > limit {
+		return [sszChunkSize]byte{}, fmt.Errorf("%w: %d chunks with a limit of %d",
+			ErrSSZLimitExceeded, len(chunks), limit)
+	}
+
+	depth := treeLevels(limit)
+	if len(chunks) == 0 {
+		return sszZeroHashes[depth], nil
+	}
+
+	layer := make([][sszChunkSize]byte, len(chunks))
+	copy(layer, chunks)
+	for level := 0; level < depth; level++ {
+		// Pad odd layers with the root of an empty subtree.
+		if len(layer)%2 == 1 {
+			layer = append(layer, sszZeroHashes[level])
+		}
+
+		parents := layer[:len(layer)/2]
+		var pair [2 * sszChunkSize]byte
+		for i := range parents {
+			copy(pair[:sszChunkSize], layer[2*i][:])
+			copy(pair[sszChunkSize:], layer[2*i+1][:])
+			parents[i] = sha256.Sum256(pair[:])
+		}
+		layer = parents
+	}
+
+	return layer[0], nil
+}
+
+// SSZMixInLength mixes the length of a list into its root.
+func SSZMixInLength(root [sszChunkSize]byte, length uint64) [sszChunkSize]byte {
+	var pair [2 * sszChunkSize]byte
+	copy(pair[:sszChunkSize], root[:])
+	binary.LittleEndian.PutUint64(pair[sszChunkSize:], length)
+	return sha256.Sum256(pair[:])
+}
+
+// SSZHashTreeRootBasic returns the hash_tree_root of a serialized basic
+// value, such as a little endian uintN or a boolean byte.
+func SSZHashTreeRootBasic(serialized []byte) [sszChunkSize]byte {
+	var chunk [sszChunkSize]byte
+	copy(chunk[:], serialized)
+	return chunk
+}
+
+// SSZHashTreeRootUint64 returns the hash_tree_root of a uint64.
+func SSZHashTreeRootUint64(v uint64) [sszChunkSize]byte {
+	return SSZHashTreeRootBasic(binary.LittleEndian.AppendUint64(nil, v))
+}
+
+// SSZHashTreeRootBool returns the hash_tree_root of a boolean.
+func SSZHashTreeRootBool(v bool) [sszChunkSize]byte {
+	if v {
+		return SSZHashTreeRootBasic([]byte{1})
+	}
+	return SSZHashTreeRootBasic([]byte{0})
+}
+
+// SSZHashTreeRootBasicVector returns the hash_tree_root of a vector of
+// basic values, such as a ByteVector, given its serialization.
+func SSZHashTreeRootBasicVector(serialized []byte) [sszChunkSize]byte {
+	chunks := SSZPack(serialized)
+	root, _ := SSZMerkleize(chunks, len(chunks))
+	return root
+}
+
+// SSZHashTreeRootBasicList returns the hash_tree_root of a list of basic
+// values of elemSize bytes each, such as a ByteList, given its serialization.
+// limit is the maximum number of elements in the list.
+func SSZHashTreeRootBasicList(serialized []byte, elemSize, limit int) ([sszChunkSize]byte, error) {
+	if elemSize <= 0 || len(serialized)%elemSize != 0 {
+		return [sszChunkSize]byte{}, fmt.Errorf("%w: %d bytes is not a multiple of element size %d",
+			ErrSSZInvalidLength, len(serialized), elemSize)
+	}
+
+	length := len(serialized) / elemSize
+	if length > limit {
+		return [sszChunkSize]byte{}, fmt.Errorf("%w: %d elements with a limit of %d",
+			ErrSSZLimitExceeded, length, limit)
+	}
+
+	chunkLimit := (limit*elemSize + sszChunkSize - 1) / sszChunkSize
+	root, err := SSZMerkleize(SSZPack(serialized), chunkLimit)
+	if err != nil {
+		return [sszChunkSize]byte{}, err
+	}
+
+	return SSZMixInLength(root, uint64(length)), nil
+}
+
+// SSZHashTreeRootVector returns the hash_tree_root of a vector of
+// composite values, given the roots of its elements.
+func SSZHashTreeRootVector(roots [][sszChunkSize]byte) [sszChunkSize]byte {
+	root, _ := SSZMerkleize(roots, len(roots))
+	return root
+}
+
+// SSZHashTreeRootList returns the hash_tree_root of a list of composite
+// values, given the roots of its elements and its maximum length.
+func SSZHashTreeRootList(roots [][sszChunkSize]byte, limit int) ([sszChunkSize]byte, error) {
+	root, err := SSZMerkleize(roots, limit)
+	if err != nil {
+		return [sszChunkSize]byte{}, err
+	}
+	return SSZMixInLength(root, uint64(len(roots))), nil
+}
+
+// SSZHashTreeRootContainer returns the hash_tree_root of a container,
+// given the roots of its fields in order.
+func SSZHashTreeRootContainer(fieldRoots [][sszChunkSize]byte) [sszChunkSize]byte {
+	return SSZHashTreeRootVector(fieldRoots)
+}
@@ -0,0 +1,136 @@
+package merkle
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSZZeroHashes(t *testing.T) {
+	t.Parallel()
+
+	// Zero hashes as used throughout the consensus specs.
+	assert.Equal(t, "0000000000000000000000000000000000000000000000000000000000000000", hex.EncodeToString(sszZeroHashes[0][:]))
+	assert.Equal(t, "f5a5fd42d16a20302798ef6ed309979b43003d2320d9f0e8ea9831a92759fb4b", hex.EncodeToString(sszZeroHashes[1][:]))
+	assert.Equal(t, "db56114e00fdd4c1f85c892bf35ac9a89289aaecb1ebd0a96cde606a748b5d71", hex.EncodeToString(sszZeroHashes[2][:]))
+}
+
+func TestSSZHashTreeRootBasic(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "0000000000000000000000000000000000000000000000000000000000000000", hexRoot(SSZHashTreeRootUint64(0)))
+	assert.Equal(t, "0807060504030201000000000000000000000000000000000000000000000000", hexRoot(SSZHashTreeRootUint64(0x0102030405060708)))
+	assert.Equal(t, "0100000000000000000000000000000000000000000000000000000000000000", hexRoot(SSZHashTreeRootBool(true)))
+	assert.Equal(t, "0000000000000000000000000000000000000000000000000000000000000000", hexRoot(SSZHashTreeRootBool(false)))
+}
+
+func TestSSZHashTreeRootBasicVector(t *testing.T) {
+	t.Parallel()
+
+	data := make([]byte, 40)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	// ByteVector[40] spans two chunks.
+	assert.Equal(t, "6032bb14a2dc38d055bb806a766a1082c6c56d2b2662c42eabce7c25a3cf157d", hexRoot(SSZHashTreeRootBasicVector(data)))
+}
+
+func TestSSZHashTreeRootBasicList(t *testing.T) {
+	t.Parallel()
+
+	var uint64s []byte
+	for _, v := range []uint64{1, 2, 3} {
+		uint64s = binary.LittleEndian.AppendUint64(uint64s, v)
+	}
+
+	tests := []struct {
+		name       string
+		serialized []byte
+		elemSize   int
+		limit      int
+		exp        string
+		err        error
+	}{
+		{
+			name:       "List[uint64, 1024]",
+			serialized: uint64s,
+			elemSize:   8,
+			limit:      1024,
+			exp:        "7d71cb79deb3cc392afd800f19c07b5733b177b0bcd92f607052a1ffe314efb0",
+		},
+		{
+			name:       "ByteList[256]",
+			serialized: []byte("hello"),
+			elemSize:   1,
+			limit:      256,
+			exp:        "d714c994fb91ed0c822936ddab0934529ab7816e60dc94027e77a4188e2e4459",
+		},
+		{
+			name:       "Empty ByteList[256]",
+			serialized: []byte{},
+			elemSize:   1,
+			limit:      256,
+			exp:        "e8e527e84f666163a90ef900e013f56b0a4d020148b2224057b719f351b003a6",
+		},
+		{
+			name:       "Over limit",
+			serialized: []byte("hello"),
+			elemSize:   1,
+			limit:      4,
+			err:        ErrSSZLimitExceeded,
+		},
+		{
+			name:       "Partial element",
+			serialized: []byte("hello"),
+			elemSize:   2,
+			limit:      4,
+			err:        ErrSSZInvalidLength,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			root, err := SSZHashTreeRootBasicList(tc.serialized, tc.elemSize, tc.limit)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.exp, hexRoot(root))
+		})
+	}
+}
+
+func TestSSZHashTreeRootContainer(t *testing.T) {
+	t.Parallel()
+
+	// Container {
+	//   a: uint64 = 5
+	//   b: Vector[uint16, 3] = [1, 2, 3]
+	//   c: List[uint8, 64] = "abc"
+	// }
+	a := SSZHashTreeRootUint64(5)
+	b := SSZHashTreeRootBasicVector([]byte{1, 0, 2, 0, 3, 0})
+	c, err := SSZHashTreeRootBasicList([]byte("abc"), 1, 64)
+	require.NoError(t, err)
+
+	container := SSZHashTreeRootContainer([][32]byte{a, b, c})
+	assert.Equal(t, "75dceed22912ebd86994225d5b11ed780ca6d1ef0e5fd987299866a0be82eec9", hexRoot(container))
+
+	list, err := SSZHashTreeRootList([][32]byte{container, container}, 16)
+	require.NoError(t, err)
+	assert.Equal(t, "1ae12c73f869d335f62d9d7a7b902018f8c76ab92f10baf6103869c1b05421bf", hexRoot(list))
+
+	_, err = SSZHashTreeRootList([][32]byte{container, container}, 1)
+	require.ErrorIs(t, err, ErrSSZLimitExceeded)
+}
+
+func hexRoot(root [32]byte) string {
+	return hex.EncodeToString(root[:])
+}
@@ -0,0 +1,171 @@
+package merkle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// NodeStore reads the hashes of the nodes of a tree from storage like
+// a disk or a database. Nodes are addressed by their level above the
+// leaves and their index on that level, where a level has half as many
+// nodes as the level below it, rounded up. The last node of a level
+// without a sibling is carried up unchanged, as in a FlatTree.
+type NodeStore interface {
+	// NodeHash returns the hash of the node at the given level and index.
+	NodeHash(level, index int) ([]byte, error)
+}
+
+// ProofFromStore generates an inclusion proof for the leaf at index in
+// a tree with size leaves, reading only the O(log n) siblings on its path
+// from store, so proofs of trees larger than memory can be served.
+func ProofFromStore(store NodeStore, size, index int) (*Proof, error) {
+	if index < 0 || index >= size {
+		return nil, indexOutOfBounds(index, size)
+	}
+
+	proof := &Proof{Index: index}
+	for level, count := 0, size; count > 1; level, count = level+1, (count+1)/2 {
+		// The last node on a level without a sibling is carried up.
+		if sibling := index ^ 1; sibling < count {
+			hash, err := store.NodeHash(level, sibling)
+			if err != nil {
+				return nil, fmt.Errorf("node %d on level %d: %w", sibling, level, err)
+			}
+			proof.Hashes = append(proof.Hashes, hash)
+		}
+		index /= 2
+	}
+	return proof, nil
+}
+
+// FileNodeStore reads node hashes from a file in the flat layout written
+// by FlatTree.WriteTo. Every hash is read with one ReadAt call, so the
+// file is never loaded into memory.
+type FileNodeStore struct {
+	r        io.ReaderAt
+	hashSize int
+	offsets  []int
+}
+
+// NewFileNodeStore creates a store that reads the hashes of a tree with
+// size leaves and hashes of hashSize bytes from r.
+func NewFileNodeStore(r io.ReaderAt, size, hashSize int) (*FileNodeStore, error) {
+	if size <= 0 {
+		return nil, ErrNoLeaves
+	}
+	if hashSize <= 0 {
+		return nil, fmt.Errorf("%w: hashes of %d bytes", ErrHashSizeMismatch, hashSize)
+	}
+	return &FileNodeStore{r: r, hashSize: hashSize, offsets: flatOffsets(size)}, nil
+}
+
+// NodeHash reads the hash of the node at the given level and index.
+func (s *FileNodeStore) NodeHash(level, index int) ([]byte, error) {
+	if level < 0 || level >= len(s.offsets)-1 {
+		return nil, fmt.Errorf("%w: level %d in a tree with %d levels",
+			ErrIndexOutOfBounds, level, len(s.offsets)-1)
+	}
+	if count := s.offsets[level+1] - s.offsets[level]; index < 0 || index >= count {
+		return nil, indexOutOfBounds(index, count)
+	}
+
+	hash := make([]byte, s.hashSize)
+	if _, err := s.r.ReadAt(hash, int64(s.offsets[level]+index)*int64(s.hashSize)); err != nil {
+		return nil, err
+	}
+	return hash, nil
+}
+
+// ioChunkSize is the number of bytes read or written by one call when
+// flat trees are read or written in parallel.
+const ioChunkSize = 1 << 22
+
+// WriteToAt writes the hashes of all nodes to w in the flat layout like
+// WriteTo, but writes chunks of them concurrently at their offsets, so
+// large trees are written as fast as the storage allows.
+func (t *FlatTree) WriteToAt(w io.WriterAt) (int64, error) {
+	if t.stored < t.levels() {
+		return 0, ErrLeafHashesOnly
+	}
+	if err := t.cfg.parallelism.chunksAt(t.hashes, func(chunk []byte, off int64) error {
+		_, err := w.WriteAt(chunk, off)
+		return err
+	}); err != nil {
+		return 0, err
+	}
+	return int64(len(t.hashes)), nil
+}
+
+// ReadFlatTree reads a tree with size leaves in the flat layout written by
+// WriteTo from r. Chunks of the hashes are read concurrently, and nothing
+// is rehashed, so the hashes have to come from a tree built with the same
+// hash function and options. With WithLeafHashesOnly, only the leaf hashes
+// are read and the root is recomputed from them.
+func ReadFlatTree(r io.ReaderAt, size int, newHashFunc func() hash.Hash, opts ...Option) (*FlatTree, error) {
+	cfg := newConfig(opts, newHashFunc)
+	if size < 0 || size == 0 && !cfg.allowEmpty {
+		return nil, ErrNoLeaves
+	}
+
+	t := &FlatTree{
+		hashFunc:     cfg.hasher.NewNodeHasher(),
+		leafHashFunc: cfg.hasher.NewLeafHasher(),
+		cfg:          cfg,
+	}
+	t.size = t.leafHashFunc.Size()
+	if t.hashFunc.Size() != t.size {
+		return nil, fmt.Errorf("%w: %d and %d bytes", ErrHashSizeMismatch, t.size, t.hashFunc.Size())
+	}
+	if size == 0 {
+		t.emptyRoot = t.hashFunc.Sum(nil)
+		return t, nil
+	}
+
+	t.offsets = flatOffsets(size)
+	t.stored = t.levels()
+	if cfg.leafHashesOnly {
+		t.stored = 1
+	}
+	t.hashes = make([]byte, t.offsets[t.stored]*t.size)
+	if err := cfg.parallelism.chunksAt(t.hashes, func(chunk []byte, off int64) error {
+		n, err := r.ReadAt(chunk, off)
+		if n == len(chunk) {
+			return nil
+		}
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("offset %d: %w", off+int64(n), err)
+	}); err != nil {
+		return nil, err
+	}
+
+	if t.stored < t.levels() {
+		t.root = t.computeRoot()
+	}
+	return t, nil
+}
+
+// chunksAt splits data into chunks of ioChunkSize bytes and calls fn for
+// each chunk and its offset in data in parallel. The first error is returned.
+func (p parallelism) chunksAt(data []byte, fn func(chunk []byte, off int64) error) error {
+	numChunks := (len(data) + ioChunkSize - 1) / ioChunkSize
+	// Every chunk is a separate call, so the batch size of the
+	// hashing options doesn't apply.
+	p.batchSize = 1
+	return p.batchesContext(context.Background(), numChunks, func(ctx context.Context, start, end int) error {
+		for i := start; i < end; i++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			chunk := data[i*ioChunkSize : min(len(data), (i+1)*ioChunkSize)]
+			if err := fn(chunk, int64(i)*ioChunkSize); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,205 @@
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingReaderAt counts the reads from r.
+type countingReaderAt struct {
+	r     io.ReaderAt
+	reads int
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	c.reads++
+	return c.r.ReadAt(p, off)
+}
+
+func TestProofFromStore(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		size int
+	}{
+		{
+			name: "Single leaf",
+			size: 1,
+		},
+		{
+			name: "Odd size",
+			size: 13,
+		},
+		{
+			name: "Power of two",
+			size: 64,
+		},
+		{
+			name: "Several blocks",
+			size: 2*cacheBlock + 5,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := generateDummyData(tc.size)
+			tree, err := NewFlatTree(data, sha256.New)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			_, err = tree.WriteTo(&buf)
+			require.NoError(t, err)
+			r := &countingReaderAt{r: bytes.NewReader(buf.Bytes())}
+			store, err := NewFileNodeStore(r, tc.size, sha256.Size)
+			require.NoError(t, err)
+
+			for _, index := range []int{0, tc.size / 2, tc.size - 1} {
+				r.reads = 0
+				proof, err := ProofFromStore(store, tc.size, index)
+				require.NoError(t, err)
+				assert.LessOrEqual(t, r.reads, treeLevels(tc.size))
+
+				expProof, err := tree.GenerateProofByIndex(index)
+				require.NoError(t, err)
+				assert.Equal(t, expProof, proof)
+
+				ok, err := tree.VerifyProof(proof, data[index])
+				require.NoError(t, err)
+				assert.True(t, ok)
+			}
+		})
+	}
+}
+
+func TestProofFromStoreErrors(t *testing.T) {
+	t.Parallel()
+
+	data := generateDummyData(5)
+	tree, err := NewFlatTree(data, sha256.New)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	_, err = tree.WriteTo(&buf)
+	require.NoError(t, err)
+
+	store, err := NewFileNodeStore(bytes.NewReader(buf.Bytes()), len(data), sha256.Size)
+	require.NoError(t, err)
+	_, err = ProofFromStore(store, len(data), len(data))
+	assert.ErrorIs(t, err, ErrIndexOutOfBounds)
+	_, err = store.NodeHash(1, 3)
+	assert.ErrorIs(t, err, ErrIndexOutOfBounds)
+	_, err = store.NodeHash(4, 0)
+	assert.ErrorIs(t, err, ErrIndexOutOfBounds)
+
+	// A store over a truncated file fails to read the upper levels.
+	truncated, err := NewFileNodeStore(bytes.NewReader(buf.Bytes()[:len(data)*sha256.Size]), len(data), sha256.Size)
+	require.NoError(t, err)
+	_, err = ProofFromStore(truncated, len(data), 0)
+	assert.ErrorIs(t, err, io.EOF)
+
+	_, err = NewFileNodeStore(bytes.NewReader(nil), 0, sha256.Size)
+	assert.ErrorIs(t, err, ErrNoLeaves)
+
+	leafOnly, err := NewFlatTree(data, sha256.New, WithLeafHashesOnly())
+	require.NoError(t, err)
+	_, err = leafOnly.WriteTo(&buf)
+	assert.ErrorIs(t, err, ErrLeafHashesOnly)
+}
+
+func TestReadFlatTree(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		size int
+		opts []Option
+	}{
+		{
+			name: "Single leaf",
+			size: 1,
+		},
+		{
+			name: "Odd size",
+			size: 13,
+		},
+		{
+			name: "Several chunks",
+			size: ioChunkSize/sha256.Size + 3,
+			opts: []Option{WithWorkers(4)},
+		},
+		{
+			name: "Leaf hashes only",
+			size: 100,
+			opts: []Option{WithLeafHashesOnly()},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := generateDummyData(tc.size)
+			tree, err := NewFlatTree(data, sha256.New, tc.opts...)
+			require.NoError(t, err)
+			full, err := NewFlatTree(data, sha256.New)
+			require.NoError(t, err)
+
+			f, err := os.Create(filepath.Join(t.TempDir(), "tree"))
+			require.NoError(t, err)
+			defer f.Close()
+			n, err := full.WriteToAt(f)
+			require.NoError(t, err)
+			info, err := f.Stat()
+			require.NoError(t, err)
+			assert.Equal(t, info.Size(), n)
+
+			read, err := ReadFlatTree(f, tc.size, sha256.New, tc.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, tree.RootHash(), read.RootHash())
+			for _, index := range []int{0, tc.size / 2, tc.size - 1} {
+				expProof, err := tree.GenerateProofByIndex(index)
+				require.NoError(t, err)
+				proof, err := read.GenerateProofByIndex(index)
+				require.NoError(t, err)
+				assert.Equal(t, expProof, proof)
+			}
+		})
+	}
+}
+
+func TestReadFlatTreeErrors(t *testing.T) {
+	t.Parallel()
+
+	data := generateDummyData(5)
+	tree, err := NewFlatTree(data, sha256.New)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	_, err = tree.WriteTo(&buf)
+	require.NoError(t, err)
+
+	_, err = ReadFlatTree(bytes.NewReader(buf.Bytes()[:buf.Len()-1]), len(data), sha256.New)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	_, err = ReadFlatTree(bytes.NewReader(buf.Bytes()), 0, sha256.New)
+	assert.ErrorIs(t, err, ErrNoLeaves)
+
+	empty, err := ReadFlatTree(bytes.NewReader(nil), 0, sha256.New, WithEmptyTree())
+	require.NoError(t, err)
+	assert.Equal(t, 0, empty.Len())
+
+	leafOnly, err := NewFlatTree(data, sha256.New, WithLeafHashesOnly())
+	require.NoError(t, err)
+	f, err := os.Create(filepath.Join(t.TempDir(), "tree"))
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = leafOnly.WriteToAt(f)
+	assert.ErrorIs(t, err, ErrLeafHashesOnly)
+}
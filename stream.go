@@ -0,0 +1,53 @@
+package merkle
+
+import (
+	"fmt"
+	"hash"
+	"iter"
+)
+
+// NewTreeFromSeq creates a new Merkle tree from the values yielded by seq.
+// Values are hashed as they arrive, so the input doesn't have to be
+// materialized up front. The tree keeps references to the values,
+// so they must not be modified after they have been yielded.
+func NewTreeFromSeq(seq iter.Seq[[]byte], newHashFunc func() hash.Hash, opts ...Option) (*Tree, error) {
+	cfg := newConfig(opts, newHashFunc)
+	hashFunc := cfg.hasher.NewLeafHasher()
+
+	var nodes []*Node
+	for value := range seq {
+		value, err := cfg.leafValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("leaf %d: %w", len(nodes), err)
+		}
+
+		hashFunc.Reset()
+		hashFunc.Write(value)
+		nodes = append(nodes, NewNode(hashFunc.Sum(nil), value))
+	}
+
+	if len(nodes) == 0 && !cfg.allowEmpty {
+		return nil, ErrNoLeaves
+	}
+
+	tree := newTreeFromNodes(nodes, newHashFunc, cfg)
+	tree.buildIndex()
+	return tree, nil
+}
+
+// NewTreeFromChan creates a new Merkle tree from the values received on ch.
+// The tree is built once ch is closed. If a value is invalid, the rest
+// of ch is received and dropped until it is closed, so the sender isn't
+// blocked, and the error is returned after that.
+func NewTreeFromChan(ch <-chan []byte, newHashFunc func() hash.Hash, opts ...Option) (*Tree, error) {
+	return NewTreeFromSeq(func(yield func([]byte) bool) {
+		for value := range ch {
+			if !yield(value) {
+				// Drain ch so the sender can finish.
+				for range ch {
+				}
+				return
+			}
+		}
+	}, newHashFunc, opts...)
+}
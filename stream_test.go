@@ -0,0 +1,117 @@
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTreeFromSeq(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		values [][]byte
+		err    error
+	}{
+		{
+			name:   "No values should fail",
+			values: [][]byte{},
+			err:    ErrNoLeaves,
+		},
+		{
+			name:   "One leaf should succeed",
+			values: [][]byte{[]byte("yolo")},
+		},
+		{
+			name:   "Five values should succeed",
+			values: [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e")},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := NewTreeFromSeq(slices.Values(tc.values), sha256.New)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+
+			expTree, err := NewTree(tc.values, sha256.New)
+			require.NoError(t, err)
+			assert.Equal(t, expTree.Root.Hash, tree.Root.Hash, "Tree root mismatch")
+			require.Len(t, tree.Leaves, len(tc.values))
+			for i, leaf := range tree.Leaves {
+				assert.Equal(t, tc.values[i], leaf.Value)
+			}
+		})
+	}
+}
+
+func TestNewTreeFromChan(t *testing.T) {
+	t.Parallel()
+
+	values := generateDummyData(100)
+	ch := make(chan []byte)
+	go func() {
+		defer close(ch)
+		for _, value := range values {
+			ch <- value
+		}
+	}()
+
+	tree, err := NewTreeFromChan(ch, sha256.New)
+	require.NoError(t, err)
+
+	expTree, err := NewTree(values, sha256.New)
+	require.NoError(t, err)
+	assert.Equal(t, expTree.Root.Hash, tree.Root.Hash, "Tree root mismatch")
+
+	proof, err := tree.GenerateProof(values[42])
+	require.NoError(t, err)
+	isValid, err := tree.VerifyProof(proof, values[42])
+	require.NoError(t, err)
+	assert.True(t, isValid)
+}
+
+func TestNewTreeFromChanInvalidValue(t *testing.T) {
+	t.Parallel()
+
+	values := generateDummyData(100)
+	errInvalid := errors.New("invalid value")
+	validate := func(value []byte) ([]byte, error) {
+		if bytes.Equal(value, values[3]) {
+			return nil, errInvalid
+		}
+		return value, nil
+	}
+
+	ch := make(chan []byte)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer close(ch)
+		for _, value := range values {
+			ch <- value
+		}
+	}()
+
+	_, err := NewTreeFromChan(ch, sha256.New, WithLeafValidator(validate))
+	require.ErrorIs(t, err, errInvalid)
+
+	// The producer isn't left blocked on a send.
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Producer did not exit")
+	}
+}
@@ -0,0 +1,35 @@
+package merkle
+
+import "hash"
+
+// NewTreeFromStrings creates a new Merkle tree with the bytes
+// of the given strings as leaf values.
+func NewTreeFromStrings(values []string, newHashFunc func() hash.Hash, opts ...Option) (*Tree, error) {
+	leaves := make([][]byte, len(values))
+	for i, value := range values {
+		leaves[i] = []byte(value)
+	}
+	return NewTree(leaves, newHashFunc, opts...)
+}
+
+// GenerateProofString generates an inclusion proof for the first leaf
+// with the bytes of value.
+func (t *Tree) GenerateProofString(value string) (*Proof, error) {
+	return t.GenerateProof([]byte(value))
+}
+
+// VerifyProofString verifies that the bytes of value are part of the tree.
+func (t *Tree) VerifyProofString(proof *Proof, value string) (bool, error) {
+	return t.VerifyProof(proof, []byte(value))
+}
+
+// UpdateLeafString updates the value of the leaf at the given index
+// to the bytes of value.
+func (t *Tree) UpdateLeafString(index int, value string) error {
+	return t.UpdateLeaf(index, []byte(value))
+}
+
+// AppendLeafString appends a leaf with the bytes of value.
+func (t *Tree) AppendLeafString(value string) error {
+	return t.AppendLeaf([]byte(value))
+}
@@ -0,0 +1,76 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTreeFromStrings(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		values []string
+		err    error
+	}{
+		{
+			name:   "Strings",
+			values: []string{"alice", "bob", "carol"},
+		},
+		{
+			name:   "Empty string leaf",
+			values: []string{"alice", ""},
+		},
+		{
+			name: "No strings",
+			err:  ErrNoLeaves,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := NewTreeFromStrings(tc.values, sha256.New)
+			require.ErrorIs(t, err, tc.err)
+			if tc.err != nil {
+				return
+			}
+
+			values := make([][]byte, len(tc.values))
+			for i, value := range tc.values {
+				values[i] = []byte(value)
+			}
+			expTree, err := NewTree(values, sha256.New)
+			require.NoError(t, err)
+			assert.Equal(t, expTree.Root.Hash, tree.Root.Hash)
+
+			for _, value := range tc.values {
+				proof, err := tree.GenerateProofString(value)
+				require.NoError(t, err)
+				isValid, err := tree.VerifyProofString(proof, value)
+				require.NoError(t, err)
+				assert.True(t, isValid)
+			}
+		})
+	}
+}
+
+func TestStringMutations(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewTreeFromStrings([]string{"alice", "bob"}, sha256.New)
+	require.NoError(t, err)
+	require.NoError(t, tree.UpdateLeafString(1, "dave"))
+	require.NoError(t, tree.AppendLeafString("erin"))
+
+	expTree, err := NewTreeFromStrings([]string{"alice", "dave", "erin"}, sha256.New)
+	require.NoError(t, err)
+	assert.True(t, tree.Equal(expTree), "Tree mismatch")
+
+	_, err = tree.GenerateProofString("bob")
+	require.ErrorIs(t, err, ErrNoVal)
+}
@@ -0,0 +1,154 @@
+package merkle
+
+import (
+	"fmt"
+	"hash"
+	"math/bits"
+	"sync"
+)
+
+// SubtreeRoot returns the root hash of the leaves in [i, j), as defined
+// by the subtree decomposition of RFC 6962: the leaves are split after
+// the largest power of two smaller than their number, and both sides
+// are hashed recursively. Nodes of the tree are reused where the range
+// covers them, so only the edges of the range are hashed.
+func (t *Tree) SubtreeRoot(i, j int) ([]byte, error) {
+	t.flush()
+	if i < 0 || j > len(t.Leaves) || i >= j {
+		return nil, fmt.Errorf("%w: range [%d, %d) in a tree with %d leaves",
+			ErrIndexOutOfBounds, i, j, len(t.Leaves))
+	}
+	return t.rangeHash(i, j, t.cfg.hasher.NewNodeHasher())
+}
+
+// rangeHash returns the hash of the leaves in [start, end).
+func (t *Tree) rangeHash(start, end int, hashFunc hash.Hash) ([]byte, error) {
+	n := end - start
+	if n&(n-1) == 0 && start%n == 0 {
+		// The range is a complete subtree of the tree.
+		node := t.findNode(start, end)
+		if node == nil {
+			return nil, &IndexError{Index: start, Size: len(t.Leaves), Err: ErrLeafPruned}
+		}
+		return node.Hash, nil
+	}
+
+	key := [2]int{start, end}
+	if hash, ok := t.subtreeRoots.get(key); ok {
+		return hash, nil
+	}
+
+	k := 1 << (bits.Len(uint(n-1)) - 1)
+	left, err := t.rangeHash(start, start+k, hashFunc)
+	if err != nil {
+		return nil, err
+	}
+	right, err := t.rangeHash(start+k, end, hashFunc)
+	if err != nil {
+		return nil, err
+	}
+	hash := combineLevelHashes(bits.Len(uint(k)), left, right, hashFunc, &t.cfg)
+	t.subtreeRoots.add(key, hash)
+	return hash, nil
+}
+
+// subtreeCache caches the hashes of ranges of leaves that aren't nodes
+// of a tree by [start, end). It is filled by reads, so it is safe for
+// concurrent use. A nil cache caches nothing.
+type subtreeCache struct {
+	mu    sync.Mutex
+	size  int
+	roots map[[2]int][]byte
+}
+
+// newSubtreeCache returns a cache of up to size roots,
+// or nil if size is 0.
+func newSubtreeCache(size int) *subtreeCache {
+	if size <= 0 {
+		return nil
+	}
+	return &subtreeCache{size: size, roots: make(map[[2]int][]byte)}
+}
+
+// get returns the cached root of the range.
+func (c *subtreeCache) get(key [2]int) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	hash, ok := c.roots[key]
+	return hash, ok
+}
+
+// add caches the root of the range.
+func (c *subtreeCache) add(key [2]int, hash []byte) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.roots) >= c.size {
+		// Start over rather than track which entries are used.
+		clear(c.roots)
+	}
+	c.roots[key] = hash
+}
+
+// invalidate removes the cached roots of the ranges
+// that hold the leaf at index.
+func (c *subtreeCache) invalidate(index int) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.roots {
+		if key[0] <= index && index < key[1] {
+			delete(c.roots, key)
+		}
+	}
+}
+
+// clear removes all cached roots.
+func (c *subtreeCache) clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.roots)
+}
+
+// len returns the number of cached roots.
+func (c *subtreeCache) len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.roots)
+}
+
+// findNode returns the node that holds exactly the leaves in [start, end),
+// or nil if there is no such node or it has been pruned.
+func (t *Tree) findNode(start, end int) *Node {
+	node, lo, size := t.Root, 0, len(t.Leaves)
+	for node != nil {
+		if lo == start && size == end-start {
+			return node
+		}
+		if size == 1 {
+			return nil
+		}
+
+		// The left child is the largest complete subtree.
+		half := 1 << (bits.Len(uint(size-1)) - 1)
+		if start < lo+half {
+			node, size = node.Left, half
+		} else {
+			node, lo, size = node.Right, lo+half, size-half
+		}
+	}
+	return nil
+}
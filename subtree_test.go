@@ -0,0 +1,162 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubtreeRoot(t *testing.T) {
+	t.Parallel()
+
+	for _, size := range []int{1, 2, 5, 8, 13} {
+		t.Run(fmt.Sprintf("%d leaves", size), func(t *testing.T) {
+			t.Parallel()
+
+			data := generateDummyData(size)
+			tree, err := NewTree(data, sha256.New, WithLevelTags(LevelIndexTag))
+			require.NoError(t, err)
+
+			for i := 0; i < size; i++ {
+				for j := i + 1; j <= size; j++ {
+					// The root of a range is the root of a tree over it.
+					expRoot, err := ComputeRoot(data[i:j], sha256.New, WithLevelTags(LevelIndexTag))
+					require.NoError(t, err)
+
+					root, err := tree.SubtreeRoot(i, j)
+					require.NoError(t, err)
+					assert.Equal(t, expRoot, root, "Root of [%d, %d)", i, j)
+				}
+			}
+		})
+	}
+}
+
+func TestSubtreeRootErrors(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewTree(generateDummyData(8), sha256.New)
+	require.NoError(t, err)
+	require.NoError(t, tree.Prune([]int{0, 1}))
+
+	tests := []struct {
+		name string
+		i    int
+		j    int
+		err  error
+	}{
+		{name: "Negative start", i: -1, j: 2, err: ErrIndexOutOfBounds},
+		{name: "End past the leaves", i: 0, j: 9, err: ErrIndexOutOfBounds},
+		{name: "Empty range", i: 3, j: 3, err: ErrIndexOutOfBounds},
+		{name: "Pruned leaves", i: 2, j: 3, err: ErrLeafPruned},
+		{name: "Pruned subtree", i: 4, j: 8},
+		{name: "Kept leaves", i: 0, j: 2},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := tree.SubtreeRoot(tc.i, tc.j)
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestWithSubtreeCache(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+	combine := func(left, right []byte) []byte {
+		calls++
+		return combineHashes(left, right, sha256.New())
+	}
+	opts := []Option{WithCombine(combine), WithWorkers(1)}
+
+	data := generateDummyData(13)
+	tree, err := NewTree(data, sha256.New, append(opts, WithSubtreeCache(64))...)
+	require.NoError(t, err)
+
+	checkRange := func(i, j int) {
+		t.Helper()
+		expRoot, err := ComputeRoot(data[i:j], sha256.New, opts...)
+		require.NoError(t, err)
+		root, err := tree.SubtreeRoot(i, j)
+		require.NoError(t, err)
+		assert.Equal(t, expRoot, root, "Range [%d, %d)", i, j)
+	}
+
+	checkRange(0, 7)
+	calls = 0
+	checkRange(0, 7)
+	// Only the expected root over 7 leaves was computed.
+	assert.Equal(t, 6, calls)
+
+	// Appending keeps the cached ranges.
+	data = append(data, []byte("x"))
+	require.NoError(t, tree.AppendLeaf(data[13]))
+	calls = 0
+	root, err := tree.SubtreeRoot(0, 7)
+	require.NoError(t, err)
+	assert.Zero(t, calls)
+	snapshot, err := tree.TreeAtSize(7)
+	require.NoError(t, err)
+	assert.Equal(t, root, snapshot.Root)
+
+	// Updates drop the ranges that hold the updated leaf.
+	data[5] = []byte("y")
+	require.NoError(t, tree.UpdateLeaf(5, data[5]))
+	checkRange(0, 7)
+	checkRange(4, 11)
+
+	// Removals drop every range.
+	data = slices.Delete(data, 0, 1)
+	require.NoError(t, tree.RemoveLeaf(0))
+	checkRange(0, 7)
+	checkRange(3, 10)
+}
+
+func TestWithSubtreeCacheLimit(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewTree(generateDummyData(32), sha256.New, WithSubtreeCache(2))
+	require.NoError(t, err)
+
+	for j := 2; j <= 32; j++ {
+		_, err := tree.SubtreeRoot(1, j)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, tree.subtreeRoots.len(), 2)
+	}
+}
+
+func TestWithSubtreeCacheConcurrentReads(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewTree(generateDummyData(64), sha256.New, WithSubtreeCache(8))
+	require.NoError(t, err)
+	expRoots := make([][]byte, 64)
+	for j := 1; j <= 64; j++ {
+		expRoots[j-1], err = ComputeRoot(generateDummyData(j), sha256.New)
+		require.NoError(t, err)
+	}
+
+	// Readers fill and clear the cache at the same time.
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 1; j <= 64; j++ {
+				root, err := tree.SubtreeRoot(0, j)
+				assert.NoError(t, err)
+				assert.Equal(t, expRoots[j-1], root)
+			}
+		}()
+	}
+	wg.Wait()
+}
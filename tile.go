@@ -0,0 +1,186 @@
+package merkle
+
+import (
+	"errors"
+	"fmt"
+	"hash"
+)
+
+var ErrInvalidTile = errors.New("invalid tile")
+
+// Tile is a fixed size piece of the tree, following the layout of
+// Go's tlog tiles. A tile of height H at level L holds the hashes of
+// up to 2^H complete nodes on tree level L*H, starting at node index N*2^H.
+// W is the number of hashes in the tile. Tiles with W < 2^H are partial
+// and are replaced by wider tiles as the tree grows.
+type Tile struct {
+	H int
+	L int
+	N int
+	W int
+}
+
+// Path returns a path that identifies the tile, e.g. tile/8/0/12
+// for a full tile and tile/8/0/12.p/5 for a partial tile.
+func (tile Tile) Path() string {
+	path := fmt.Sprintf("tile/%d/%d/%d", tile.H, tile.L, tile.N)
+	if tile.W < 1<<tile.H {
+		path += fmt.Sprintf(".p/%d", tile.W)
+	}
+	return path
+}
+
+// ReadTileFunc returns the hashes stored in the given tile.
+type ReadTileFunc func(tile Tile) ([][]byte, error)
+
+// TilesForSize returns all tiles of the given height that make up
+// a tree with size leaves.
+func TilesForSize(height, size int) ([]Tile, error) {
+	if height <= 0 || height > 30 {
+		return nil, fmt.Errorf("%w: height %d", ErrInvalidTile, height)
+	}
+
+	var tiles []Tile
+	for level := 0; ; level++ {
+		// Only complete nodes are stored in tiles.
+		count := size >> (level * height)
+		if count == 0 {
+			break
+		}
+
+		full := count >> height
+		for n := 0; n < full; n++ {
+			tiles = append(tiles, Tile{H: height, L: level, N: n, W: 1 << height})
+		}
+		if width := count & (1<<height - 1); width > 0 {
+			tiles = append(tiles, Tile{H: height, L: level, N: full, W: width})
+		}
+	}
+
+	return tiles, nil
+}
+
+// Tiles returns all tiles of the given height for the current tree.
+func (t *Tree) Tiles(height int) ([]Tile, error) {
+	return TilesForSize(height, len(t.Leaves))
+}
+
+// TileHashes returns the hashes stored in the given tile.
+func (t *Tree) TileHashes(tile Tile) ([][]byte, error) {
+	t.flush()
+	if tile.H <= 0 || tile.H > 30 || tile.L < 0 || tile.N < 0 || tile.W <= 0 || tile.W > 1<<tile.H {
+		return nil, fmt.Errorf("%w: %+v", ErrInvalidTile, tile)
+	}
+
+	level := tile.L * tile.H
+	start := tile.N << tile.H
+	if (start+tile.W)<<level > len(t.Leaves) {
+		return nil, fmt.Errorf("%w: %s is not in a tree with %d leaves",
+			ErrInvalidTile, tile.Path(), len(t.Leaves))
+	}
+
+	hashes := make([][]byte, tile.W)
+	for i := range hashes {
+		hashes[i] = t.completeNode(level, start+i).Hash
+	}
+
+	return hashes, nil
+}
+
+// tileHashReader computes node hashes of a tree from its tiles.
+type tileHashReader struct {
+	height   int
+	size     int
+	readTile ReadTileFunc
+	hashFunc hash.Hash
+	cfg      config
+	cache    map[Tile][][]byte
+}
+
+func newTileHashReader(height, size int, readTile ReadTileFunc, newHashFunc func() hash.Hash, opts []Option) (*tileHashReader, error) {
+	if height <= 0 || height > 30 {
+		return nil, fmt.Errorf("%w: height %d", ErrInvalidTile, height)
+	}
+	if size <= 0 {
+		return nil, ErrNoLeaves
+	}
+
+	cfg := newConfig(opts, newHashFunc)
+	return &tileHashReader{
+		height:   height,
+		size:     size,
+		readTile: readTile,
+		hashFunc: cfg.hasher.NewNodeHasher(),
+		cfg:      cfg,
+		cache:    make(map[Tile][][]byte),
+	}, nil
+}
+
+// nodeHash returns the hash of the node at the given level and index.
+func (r *tileHashReader) nodeHash(level, index int) ([]byte, error) {
+	return subtreeHash(level, index, r.size, r.completeHash, r.hashFunc, &r.cfg)
+}
+
+// completeHash reads the hash of a complete node from its tile,
+// hashing up from the bottom row of the tile if needed.
+func (r *tileHashReader) completeHash(level, index int) ([]byte, error) {
+	tileLevel := level / r.height
+	depth := level - tileLevel*r.height
+
+	first := index << depth
+	tileIndex := first >> r.height
+	count := r.size >> (tileLevel * r.height)
+	width := min(count-tileIndex<<r.height, 1<<r.height)
+
+	tile := Tile{H: r.height, L: tileLevel, N: tileIndex, W: width}
+	hashes, ok := r.cache[tile]
+	if !ok {
+		var err error
+		hashes, err = r.readTile(tile)
+		if err != nil {
+			return nil, err
+		}
+		if len(hashes) != width {
+			return nil, fmt.Errorf("%w: %s has %d hashes", ErrInvalidTile, tile.Path(), len(hashes))
+		}
+		r.cache[tile] = hashes
+	}
+
+	offset := first - tileIndex<<r.height
+	row := hashes[offset : offset+1<<depth]
+	for rowLevel := tileLevel*r.height + 1; len(row) > 1; rowLevel++ {
+		parents := make([][]byte, len(row)/2)
+		for i := range parents {
+			parents[i] = combineLevelHashes(rowLevel, row[2*i], row[2*i+1], r.hashFunc, &r.cfg)
+		}
+		row = parents
+	}
+
+	return row[0], nil
+}
+
+// RootFromTiles computes the root hash of a tree with size leaves
+// from its tiles. The options have to match the options of the tree.
+func RootFromTiles(height, size int, readTile ReadTileFunc, newHashFunc func() hash.Hash, opts ...Option) ([]byte, error) {
+	r, err := newTileHashReader(height, size, readTile, newHashFunc, opts)
+	if err != nil {
+		return nil, err
+	}
+	return r.nodeHash(treeLevels(size), 0)
+}
+
+// ProofFromTiles generates an inclusion proof for the leaf at index
+// in a tree with size leaves, reading only the tiles on its path.
+// The options have to match the options of the tree.
+func ProofFromTiles(height, size, index int, readTile ReadTileFunc, newHashFunc func() hash.Hash, opts ...Option) (*Proof, error) {
+	if index < 0 || index >= size {
+		return nil, indexOutOfBounds(index, size)
+	}
+
+	r, err := newTileHashReader(height, size, readTile, newHashFunc, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return proofFromSubtrees(index, size, r.nodeHash)
+}
@@ -0,0 +1,174 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTilesForSize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		height   int
+		size     int
+		expTiles []Tile
+		err      error
+	}{
+		{
+			name:   "Single partial tile",
+			height: 2,
+			size:   3,
+			expTiles: []Tile{
+				{H: 2, L: 0, N: 0, W: 3},
+			},
+		},
+		{
+			name:   "Full and partial tiles",
+			height: 2,
+			size:   9,
+			expTiles: []Tile{
+				{H: 2, L: 0, N: 0, W: 4},
+				{H: 2, L: 0, N: 1, W: 4},
+				{H: 2, L: 0, N: 2, W: 1},
+				{H: 2, L: 1, N: 0, W: 2},
+			},
+		},
+		{
+			name:   "Full tiles on two levels",
+			height: 1,
+			size:   4,
+			expTiles: []Tile{
+				{H: 1, L: 0, N: 0, W: 2},
+				{H: 1, L: 0, N: 1, W: 2},
+				{H: 1, L: 1, N: 0, W: 2},
+				{H: 1, L: 2, N: 0, W: 1},
+			},
+		},
+		{
+			name:   "Invalid height",
+			height: 0,
+			size:   4,
+			err:    ErrInvalidTile,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tiles, err := TilesForSize(tc.height, tc.size)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expTiles, tiles)
+		})
+	}
+}
+
+func TestTilePath(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "tile/8/0/12", Tile{H: 8, L: 0, N: 12, W: 256}.Path())
+	assert.Equal(t, "tile/8/1/0.p/5", Tile{H: 8, L: 1, N: 0, W: 5}.Path())
+}
+
+func TestTileHashesOutOfRange(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewTree(generateDummyData(5), sha256.New)
+	require.NoError(t, err)
+
+	_, err = tree.TileHashes(Tile{H: 2, L: 0, N: 1, W: 2})
+	require.ErrorIs(t, err, ErrInvalidTile)
+
+	_, err = tree.TileHashes(Tile{H: 2, L: 0, N: 0, W: 5})
+	require.ErrorIs(t, err, ErrInvalidTile)
+}
+
+func TestProofFromTiles(t *testing.T) {
+	t.Parallel()
+
+	for _, height := range []int{1, 2, 3} {
+		for size := 1; size <= 20; size++ {
+			t.Run(fmt.Sprintf("height %d, %d leaves", height, size), func(t *testing.T) {
+				t.Parallel()
+
+				data := generateDummyData(size)
+				tree, err := NewTree(data, sha256.New)
+				require.NoError(t, err)
+
+				// Serve tiles from a store to make sure only
+				// advertised tiles are read.
+				tiles, err := tree.Tiles(height)
+				require.NoError(t, err)
+				store := make(map[Tile][][]byte)
+				for _, tile := range tiles {
+					store[tile], err = tree.TileHashes(tile)
+					require.NoError(t, err)
+				}
+				readTile := func(tile Tile) ([][]byte, error) {
+					hashes, ok := store[tile]
+					if !ok {
+						return nil, fmt.Errorf("%w: %s not found", ErrInvalidTile, tile.Path())
+					}
+					return hashes, nil
+				}
+
+				root, err := RootFromTiles(height, size, readTile, sha256.New)
+				require.NoError(t, err)
+				assert.Equal(t, tree.Root.Hash, root, "Tree root mismatch")
+
+				for i := 0; i < size; i++ {
+					proof, err := ProofFromTiles(height, size, i, readTile, sha256.New)
+					require.NoError(t, err)
+
+					expProof, err := tree.GenerateProofByIndex(i)
+					require.NoError(t, err)
+					assert.Equal(t, expProof.Hashes, proof.Hashes, "Proof mismatch for leaf %d", i)
+
+					isValid, err := tree.VerifyProof(proof, data[i])
+					require.NoError(t, err)
+					assert.True(t, isValid)
+				}
+
+				_, err = ProofFromTiles(height, size, size, readTile, sha256.New)
+				require.ErrorIs(t, err, ErrIndexOutOfBounds)
+			})
+		}
+	}
+}
+
+func TestTilesWithOptions(t *testing.T) {
+	t.Parallel()
+
+	opts := []Option{WithLevelTags(LevelIndexTag), WithDomainPrefixes([]byte{0}, []byte{1})}
+	tree, err := NewTree(generateDummyData(13), sha256.New, opts...)
+	require.NoError(t, err)
+	readTile := func(tile Tile) ([][]byte, error) {
+		return tree.TileHashes(tile)
+	}
+
+	root, err := RootFromTiles(2, 13, readTile, sha256.New, opts...)
+	require.NoError(t, err)
+	assert.Equal(t, tree.Root.Hash, root)
+
+	for i := 0; i < 13; i++ {
+		proof, err := ProofFromTiles(2, 13, i, readTile, sha256.New, opts...)
+		require.NoError(t, err)
+		expProof, err := tree.GenerateProofByIndex(i)
+		require.NoError(t, err)
+		assert.Equal(t, expProof.Hashes, proof.Hashes, "Proof mismatch for leaf %d", i)
+	}
+
+	// Without the options, the nodes above the tiles are hashed differently.
+	root, err = RootFromTiles(2, 13, readTile, sha256.New)
+	require.NoError(t, err)
+	assert.NotEqual(t, tree.Root.Hash, root)
+}
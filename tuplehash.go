@@ -0,0 +1,109 @@
+package merkle
+
+import (
+	"hash"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// tupleHash is TupleHash256 as defined in NIST SP 800-185.
+// Every call to Write adds one element to the tuple.
+type tupleHash struct {
+	cshake sha3.ShakeHash
+	size   int
+}
+
+func newTupleHash(customization []byte, size int) *tupleHash {
+	return &tupleHash{
+		cshake: sha3.NewCShake256([]byte("TupleHash"), customization),
+		size:   size,
+	}
+}
+
+func (h *tupleHash) Write(p []byte) (int, error) {
+	h.cshake.Write(leftEncode(uint64(len(p)) * 8))
+	h.cshake.Write(p)
+	return len(p), nil
+}
+
+func (h *tupleHash) Sum(b []byte) []byte {
+	cshake := h.cshake.Clone()
+	cshake.Write(rightEncode(uint64(h.size) * 8))
+
+	digest := make([]byte, h.size)
+	cshake.Read(digest)
+	return append(b, digest...)
+}
+
+func (h *tupleHash) Reset() {
+	h.cshake.Reset()
+}
+
+func (h *tupleHash) Size() int {
+	return h.size
+}
+
+func (h *tupleHash) BlockSize() int {
+	return h.cshake.BlockSize()
+}
+
+// leftEncode encodes x as in NIST SP 800-185, with the number
+// of bytes of x before its big endian bytes.
+func leftEncode(x uint64) []byte {
+	b := bigEndianBytes(x)
+	return append([]byte{byte(len(b))}, b...)
+}
+
+// rightEncode encodes x as in NIST SP 800-185, with the number
+// of bytes of x after its big endian bytes.
+func rightEncode(x uint64) []byte {
+	b := bigEndianBytes(x)
+	return append(b, byte(len(b)))
+}
+
+// bigEndianBytes returns x in as few big endian bytes as possible,
+// but at least one.
+func bigEndianBytes(x uint64) []byte {
+	var b []byte
+	for x > 0 || len(b) == 0 {
+		b = append([]byte{byte(x)}, b...)
+		x >>= 8
+	}
+	return b
+}
+
+// TupleHash256 returns the size byte TupleHash256 of the elements
+// with the given customization string, as defined in NIST SP 800-185.
+func TupleHash256(customization string, size int, elements ...[]byte) []byte {
+	h := newTupleHash([]byte(customization), size)
+	for _, element := range elements {
+		h.Write(element)
+	}
+	return h.Sum(nil)
+}
+
+// tupleHasher hashes leaves and nodes with TupleHash256.
+type tupleHasher struct {
+	customization []byte
+	size          int
+}
+
+// TupleHasher returns a Hasher that hashes leaves and nodes with
+// TupleHash256, a framing of cSHAKE256, using the customization string
+// and size byte digests. A leaf is hashed as the tuple (value) and
+// a node as the tuple (left, right), so the encoding is unambiguous by
+// construction and leaves and nodes can't be confused.
+//
+// The hash functions add one tuple element per call to Write,
+// so they are only meant to be used by the tree.
+func TupleHasher(customization string, size int) Hasher {
+	return tupleHasher{customization: []byte(customization), size: size}
+}
+
+func (h tupleHasher) NewLeafHasher() hash.Hash {
+	return newTupleHash(h.customization, h.size)
+}
+
+func (h tupleHasher) NewNodeHasher() hash.Hash {
+	return newTupleHash(h.customization, h.size)
+}
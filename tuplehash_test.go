@@ -0,0 +1,107 @@
+package merkle
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTupleHash256(t *testing.T) {
+	t.Parallel()
+
+	// Samples from NIST SP 800-185.
+	tests := []struct {
+		name          string
+		customization string
+		elements      []string
+		expHash       string
+	}{
+		{
+			name:     "Two elements",
+			elements: []string{"000102", "101112131415"},
+			expHash: "cfb7058caca5e668f81a12a20a2195ce97a925f1dba3e7449a56f82201ec6073" +
+				"11ac2696b1ab5ea2352df1423bde7bd4bb78c9aed1a853c78672f9eb23bbe194",
+		},
+		{
+			name:          "Two elements with customization",
+			customization: "My Tuple App",
+			elements:      []string{"000102", "101112131415"},
+			expHash: "147c2191d5ed7efd98dbd96d7ab5a11692576f5fe2a5065f3e33de6bba9f3aa1" +
+				"c4e9a068a289c61c95aab30aee1e410b0b607de3620e24a4e3bf9852a1d4367e",
+		},
+		{
+			name:          "Three elements with customization",
+			customization: "My Tuple App",
+			elements:      []string{"000102", "101112131415", "202122232425262728"},
+			expHash: "45000be63f9b6bfd89f54717670f69a9bc763591a4f05c50d68891a744bcc6e7" +
+				"d6d5b5e82c018da999ed35b0bb49c9678e526abd8e85c13ed254021db9e790ce",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			elements := make([][]byte, len(tc.elements))
+			for i, element := range tc.elements {
+				var err error
+				elements[i], err = hex.DecodeString(element)
+				require.NoError(t, err)
+			}
+
+			hash := TupleHash256(tc.customization, 64, elements...)
+			assert.Equal(t, tc.expHash, hex.EncodeToString(hash))
+		})
+	}
+}
+
+func TestTupleHasher(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		customization string
+		expRoot       string
+	}{
+		{
+			name:    "No customization",
+			expRoot: "5d6ad8f8238cb70be899056a11004ed267fe326087f95b91df886181d730a2e9",
+		},
+		{
+			name:          "Customization",
+			customization: "merkle",
+			expRoot:       "d1a113b354d6b94ce28544b2ca5b3472184d7579185dbae0dc87af55e25d6689",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			values := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+			tree, err := NewTree(values, nil, WithHasher(TupleHasher(tc.customization, 32)))
+			require.NoError(t, err)
+			assert.Equal(t, tc.expRoot, hex.EncodeToString(tree.Root.Hash))
+
+			leafHash := func(value []byte) []byte {
+				return TupleHash256(tc.customization, 32, value)
+			}
+			nodeHash := func(left, right []byte) []byte {
+				return TupleHash256(tc.customization, 32, left, right)
+			}
+			expRoot := nodeHash(nodeHash(leafHash(values[0]), leafHash(values[1])), leafHash(values[2]))
+			assert.Equal(t, expRoot, tree.Root.Hash)
+
+			for i, value := range values {
+				proof, err := tree.GenerateProofByIndex(i)
+				require.NoError(t, err)
+
+				isValid, err := tree.VerifyProof(proof, value)
+				require.NoError(t, err)
+				assert.True(t, isValid)
+			}
+		})
+	}
+}
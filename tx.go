@@ -0,0 +1,236 @@
+package merkle
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+var (
+	ErrTxDone     = errors.New("transaction has already been committed or rolled back")
+	ErrTxConflict = errors.New("tree changed since the transaction began")
+)
+
+// Tx queues changes to a tree, which are applied together by Commit.
+// The tree is unchanged until Commit, and stays unchanged if any
+// of the queued changes is invalid.
+type Tx struct {
+	tree *Tree
+	ops  []txOp
+	// size is the number of leaves after the queued changes.
+	size int
+	// generation is the generation of the tree at Begin.
+	generation uint64
+	done       bool
+}
+
+type txOpKind int
+
+const (
+	txUpdate txOpKind = iota
+	txAppend
+	txRemove
+)
+
+type txOp struct {
+	kind  txOpKind
+	index int
+	value []byte
+}
+
+// txLeaf is a leaf of the tree as it will be after the queued changes.
+type txLeaf struct {
+	// index is the index of the leaf in the tree, or -1 for appended leaves.
+	index   int
+	value   []byte
+	changed bool
+}
+
+// Begin starts a transaction on the tree. The tree must not be
+// changed outside of the transaction until it is committed.
+func (t *Tree) Begin() *Tx {
+	t.flush()
+	return &Tx{tree: t, size: len(t.Leaves), generation: t.generation}
+}
+
+// Update queues an update of the leaf at index. The index refers
+// to the leaves as they are after the changes queued before it.
+func (tx *Tx) Update(index int, value []byte) error {
+	if tx.done {
+		return ErrTxDone
+	}
+	if index < 0 || index >= tx.size {
+		return indexOutOfBounds(index, tx.size)
+	}
+	tx.ops = append(tx.ops, txOp{kind: txUpdate, index: index, value: value})
+	return nil
+}
+
+// Append queues appending a leaf with the given value.
+func (tx *Tx) Append(value []byte) error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.ops = append(tx.ops, txOp{kind: txAppend, value: value})
+	tx.size++
+	return nil
+}
+
+// Remove queues the removal of the leaf at index. The index refers
+// to the leaves as they are after the changes queued before it.
+func (tx *Tx) Remove(index int) error {
+	if tx.done {
+		return ErrTxDone
+	}
+	if index < 0 || index >= tx.size {
+		return indexOutOfBounds(index, tx.size)
+	}
+	tx.ops = append(tx.ops, txOp{kind: txRemove, index: index})
+	tx.size--
+	return nil
+}
+
+// Rollback discards the queued changes.
+func (tx *Tx) Rollback() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	tx.ops = nil
+	return nil
+}
+
+// Commit applies the queued changes to the tree. Every changed node
+// is only hashed once. If any change is invalid, Commit returns
+// its error and the tree is left unchanged. If the tree was changed
+// since Begin, the queued indices may no longer be valid, so Commit
+// returns ErrTxConflict. The transaction is done after Commit either way.
+func (tx *Tx) Commit() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+
+	t := tx.tree
+	t.flush()
+	if t.generation != tx.generation {
+		return ErrTxConflict
+	}
+	leaves, removed, err := tx.replay()
+	if err != nil {
+		return err
+	}
+
+	if removed {
+		// Removing leaves shifts the following leaves to other subtrees,
+		// so the tree is rebuilt from the first changed leaf on.
+		first := 0
+		for first < len(leaves) && leaves[first].index == first && !leaves[first].changed {
+			first++
+		}
+		for _, leaf := range leaves[first:] {
+			if leaf.index >= 0 && t.Leaves[leaf.index] == nil {
+				return &IndexError{Index: leaf.index, Size: len(t.Leaves), Err: ErrLeafPruned}
+			}
+		}
+		t.replaceLeaves(first, tx.leafNodes(leaves[first:]))
+		t.rootChanged(MutationCommit)
+		return nil
+	}
+
+	updates := make(map[int][]byte)
+	var appended []*Node
+	for _, leaf := range leaves {
+		switch {
+		case leaf.index < 0:
+			appended = append(appended, NewNode(t.LeafHash(leaf.value), t.storedValue(leaf.value)))
+		case leaf.changed:
+			updates[leaf.index] = leaf.value
+		}
+	}
+	if len(appended) > 0 {
+		if err := t.checkAppend(); err != nil {
+			return err
+		}
+	}
+	t.setLeaves(slices.Sorted(maps.Keys(updates)), updates)
+	if len(appended) > 0 {
+		t.appendNodes(appended)
+	}
+	t.rootChanged(MutationCommit)
+	return nil
+}
+
+// replay applies the queued changes to a copy of the leaf list and
+// checks that they are valid. It reports whether any leaf is removed.
+func (tx *Tx) replay() ([]txLeaf, bool, error) {
+	t := tx.tree
+	leaves := make([]txLeaf, len(t.Leaves))
+	for i := range leaves {
+		leaves[i] = txLeaf{index: i}
+	}
+
+	removed := false
+	for i, op := range tx.ops {
+		switch op.kind {
+		case txUpdate:
+			leaf := &leaves[op.index]
+			if leaf.index >= 0 {
+				if err := t.checkLeaf(leaf.index); err != nil {
+					return nil, false, fmt.Errorf("change %d: %w", i, err)
+				}
+			}
+			value, err := t.cfg.leafValue(op.value)
+			if err != nil {
+				return nil, false, fmt.Errorf("change %d: %w", i, err)
+			}
+			leaf.value = value
+			leaf.changed = true
+		case txAppend:
+			value, err := t.cfg.leafValue(op.value)
+			if err != nil {
+				return nil, false, fmt.Errorf("change %d: %w", i, err)
+			}
+			leaves = append(leaves, txLeaf{index: -1, value: value, changed: true})
+		case txRemove:
+			leaves = slices.Delete(leaves, op.index, op.index+1)
+			removed = true
+		}
+	}
+	return leaves, removed, nil
+}
+
+// leafNodes returns the nodes of the given leaves after the changes.
+// Unchanged leaves keep their nodes and changed leaves get new ones.
+func (tx *Tx) leafNodes(leaves []txLeaf) []*Node {
+	t := tx.tree
+	nodes := make([]*Node, len(leaves))
+	for i, leaf := range leaves {
+		if leaf.changed {
+			nodes[i] = NewNode(t.LeafHash(leaf.value), t.storedValue(leaf.value))
+		} else {
+			nodes[i] = t.Leaves[leaf.index]
+		}
+	}
+	return nodes
+}
+
+// rebuild replaces the leaves of the tree and builds the tree on top of them.
+func (t *Tree) rebuild(leaves []*Node) {
+	for _, leaf := range leaves {
+		leaf.Parent = nil
+	}
+	t.Leaves = leaves
+	t.subtreeRoots.clear()
+	t.Root = buildTree(leaves, t.HashFunc, &t.cfg)
+	if t.Root == nil && t.cfg.allowEmpty {
+		t.Root = t.emptyRoot()
+	}
+	if t.index != nil {
+		t.buildIndex()
+	}
+	if t.keys != nil {
+		t.buildKeys()
+	}
+}
@@ -0,0 +1,325 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxCommit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		size      int
+		changes   func(tx *Tx) error
+		expValues [][]byte
+	}{
+		{
+			name: "Updates",
+			size: 5,
+			changes: func(tx *Tx) error {
+				if err := tx.Update(1, []byte("x")); err != nil {
+					return err
+				}
+				return tx.Update(4, []byte("y"))
+			},
+			expValues: [][]byte{[]byte("0"), []byte("x"), []byte("2"), []byte("3"), []byte("y")},
+		},
+		{
+			name: "Updates and appends",
+			size: 3,
+			changes: func(tx *Tx) error {
+				if err := tx.Append([]byte("x")); err != nil {
+					return err
+				}
+				if err := tx.Update(0, []byte("y")); err != nil {
+					return err
+				}
+				// Update the appended leaf.
+				return tx.Update(3, []byte("z"))
+			},
+			expValues: [][]byte{[]byte("y"), []byte("1"), []byte("2"), []byte("z")},
+		},
+		{
+			name: "Removes",
+			size: 6,
+			changes: func(tx *Tx) error {
+				if err := tx.Remove(0); err != nil {
+					return err
+				}
+				// Leaf 3 was leaf 4 before the first removal.
+				return tx.Remove(3)
+			},
+			expValues: [][]byte{[]byte("1"), []byte("2"), []byte("3"), []byte("5")},
+		},
+		{
+			name: "Mixed",
+			size: 4,
+			changes: func(tx *Tx) error {
+				if err := tx.Update(2, []byte("x")); err != nil {
+					return err
+				}
+				if err := tx.Append([]byte("y")); err != nil {
+					return err
+				}
+				return tx.Remove(1)
+			},
+			expValues: [][]byte{[]byte("0"), []byte("x"), []byte("3"), []byte("y")},
+		},
+		{
+			name: "Remove last",
+			size: 7,
+			changes: func(tx *Tx) error {
+				return tx.Remove(6)
+			},
+			expValues: [][]byte{[]byte("0"), []byte("1"), []byte("2"), []byte("3"), []byte("4"), []byte("5")},
+		},
+		{
+			name: "Remove all",
+			size: 2,
+			changes: func(tx *Tx) error {
+				if err := tx.Remove(0); err != nil {
+					return err
+				}
+				return tx.Remove(0)
+			},
+			expValues: [][]byte{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			values := make([][]byte, tc.size)
+			for i := range values {
+				values[i] = []byte{byte('0' + i)}
+			}
+			tree, err := NewTree(values, sha256.New)
+			require.NoError(t, err)
+
+			tx := tree.Begin()
+			require.NoError(t, tc.changes(tx))
+			require.NoError(t, tx.Commit())
+
+			if len(tc.expValues) == 0 {
+				assert.Zero(t, tree.Len())
+				assert.Nil(t, tree.Root)
+				return
+			}
+			expTree, err := NewTree(tc.expValues, sha256.New)
+			require.NoError(t, err)
+			assert.True(t, tree.Equal(expTree), "Tree mismatch")
+
+			for i, value := range tc.expValues {
+				index, found := tree.IndexOf(value)
+				assert.True(t, found)
+				assert.Equal(t, i, index)
+
+				proof, err := tree.GenerateProofByIndex(i)
+				require.NoError(t, err)
+				isValid, err := tree.VerifyProof(proof, value)
+				require.NoError(t, err)
+				assert.True(t, isValid)
+			}
+		})
+	}
+}
+
+func TestTxCommitRemoveKeepsPrefix(t *testing.T) {
+	t.Parallel()
+
+	opts := []Option{WithLevelTags(LevelIndexTag)}
+	tree, err := NewTree(generateDummyData(8), sha256.New, opts...)
+	require.NoError(t, err)
+	prefix := tree.Root.Left
+	leaf := tree.Leaves[6]
+	leafHash := slices.Clone(leaf.Hash)
+
+	tx := tree.Begin()
+	require.NoError(t, tx.Update(6, []byte("x")))
+	require.NoError(t, tx.Remove(7))
+	require.NoError(t, tx.Commit())
+
+	// The complete subtree before the first changed leaf is kept,
+	// and the replaced leaf node is not changed.
+	assert.Same(t, prefix, tree.Root.Left)
+	assert.Equal(t, leafHash, leaf.Hash)
+
+	expTree, err := NewTree(append(generateDummyData(6), []byte("x")), sha256.New, opts...)
+	require.NoError(t, err)
+	assert.True(t, tree.Equal(expTree), "Tree mismatch")
+	require.NoError(t, tree.Validate())
+}
+
+func TestTxInvalidChange(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		prune   []int
+		changes func(tx *Tx) error
+		err     error
+	}{
+		{
+			name:  "Update pruned leaf",
+			prune: []int{0},
+			changes: func(tx *Tx) error {
+				if err := tx.Update(0, []byte("x")); err != nil {
+					return err
+				}
+				return tx.Update(1, []byte("y"))
+			},
+			err: ErrLeafPruned,
+		},
+		{
+			name:  "Remove from pruned tree",
+			prune: []int{0},
+			changes: func(tx *Tx) error {
+				if err := tx.Update(0, []byte("x")); err != nil {
+					return err
+				}
+				return tx.Remove(0)
+			},
+			err: ErrLeafPruned,
+		},
+		{
+			name:  "Append to pruned right edge",
+			prune: []int{0},
+			changes: func(tx *Tx) error {
+				if err := tx.Update(0, []byte("x")); err != nil {
+					return err
+				}
+				return tx.Append([]byte("y"))
+			},
+			err: ErrLeafPruned,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := NewTree(generateDummyData(4), sha256.New)
+			require.NoError(t, err)
+			require.NoError(t, tree.Prune(tc.prune))
+			root := tree.RootHash()
+			leaves := append([]*Node(nil), tree.Leaves...)
+			hashes := make([][]byte, len(leaves))
+			for i, leaf := range leaves {
+				if leaf != nil {
+					hashes[i] = leaf.Hash
+				}
+			}
+
+			tx := tree.Begin()
+			require.NoError(t, tc.changes(tx))
+			require.ErrorIs(t, tx.Commit(), tc.err)
+
+			// The tree is unchanged.
+			assert.Equal(t, root, tree.RootHash())
+			assert.Equal(t, leaves, tree.Leaves)
+			for i, leaf := range tree.Leaves {
+				if leaf != nil {
+					assert.Equal(t, hashes[i], leaf.Hash)
+				}
+			}
+		})
+	}
+}
+
+func TestTxIndexOutOfBounds(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewTree(generateDummyData(2), sha256.New)
+	require.NoError(t, err)
+
+	tx := tree.Begin()
+	require.ErrorIs(t, tx.Update(2, []byte("x")), ErrIndexOutOfBounds)
+	require.NoError(t, tx.Append([]byte("x")))
+	require.NoError(t, tx.Update(2, []byte("y")))
+	require.NoError(t, tx.Remove(0))
+	require.ErrorIs(t, tx.Remove(2), ErrIndexOutOfBounds)
+	require.ErrorIs(t, tx.Update(-1, []byte("x")), ErrIndexOutOfBounds)
+}
+
+func TestTxRollback(t *testing.T) {
+	t.Parallel()
+
+	tree, err := NewTree(generateDummyData(4), sha256.New)
+	require.NoError(t, err)
+	root := tree.RootHash()
+
+	tx := tree.Begin()
+	require.NoError(t, tx.Update(0, []byte("x")))
+	require.NoError(t, tx.Remove(1))
+	require.NoError(t, tx.Rollback())
+	assert.Equal(t, root, tree.RootHash())
+	assert.Equal(t, 4, tree.Len())
+
+	require.ErrorIs(t, tx.Update(0, []byte("x")), ErrTxDone)
+	require.ErrorIs(t, tx.Append([]byte("x")), ErrTxDone)
+	require.ErrorIs(t, tx.Remove(0), ErrTxDone)
+	require.ErrorIs(t, tx.Commit(), ErrTxDone)
+	require.ErrorIs(t, tx.Rollback(), ErrTxDone)
+}
+
+func TestTxConflict(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		opts   []Option
+		change func(tree *Tree) error
+	}{
+		{
+			name:   "Removed leaf",
+			change: func(tree *Tree) error { return tree.RemoveLeaf(0) },
+		},
+		{
+			name:   "Updated leaf",
+			change: func(tree *Tree) error { return tree.UpdateLeaf(1, []byte("x")) },
+		},
+		{
+			name:   "Appended leaf",
+			change: func(tree *Tree) error { return tree.AppendLeaf([]byte("x")) },
+		},
+		{
+			name:   "Deferred update",
+			opts:   []Option{WithDeferredHashing()},
+			change: func(tree *Tree) error { return tree.UpdateLeaf(1, []byte("x")) },
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := NewTree(generateDummyData(5), sha256.New, tc.opts...)
+			require.NoError(t, err)
+
+			tx := tree.Begin()
+			require.NoError(t, tx.Update(4, []byte("y")))
+			require.NoError(t, tc.change(tree))
+			root := tree.RootHash()
+			size := tree.Len()
+
+			require.ErrorIs(t, tx.Commit(), ErrTxConflict)
+			assert.Equal(t, root, tree.RootHash())
+			assert.Equal(t, size, tree.Len())
+			require.ErrorIs(t, tx.Commit(), ErrTxDone)
+		})
+	}
+
+	// Deferred updates before Begin are not a conflict.
+	tree, err := NewTree(generateDummyData(5), sha256.New, WithDeferredHashing())
+	require.NoError(t, err)
+	require.NoError(t, tree.UpdateLeaf(1, []byte("x")))
+	tx := tree.Begin()
+	require.NoError(t, tx.Update(4, []byte("y")))
+	require.NoError(t, tx.Commit())
+}
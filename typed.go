@@ -0,0 +1,61 @@
+package merkle
+
+import "hash"
+
+// TypedTree is a Merkle tree over items of type T.
+// Items are encoded to leaf values with the encode function of the tree,
+// so the same item must always encode to the same bytes.
+type TypedTree[T any] struct {
+	Tree *Tree
+
+	encode func(T) []byte
+}
+
+// NewTypedTree creates a new Merkle tree over the items,
+// using encode to turn each item into a leaf value.
+func NewTypedTree[T any](items []T, encode func(T) []byte, newHashFunc func() hash.Hash, opts ...Option) (*TypedTree[T], error) {
+	values := make([][]byte, len(items))
+	for i, item := range items {
+		values[i] = encode(item)
+	}
+
+	tree, err := NewTree(values, newHashFunc, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TypedTree[T]{
+		Tree:   tree,
+		encode: encode,
+	}, nil
+}
+
+// GenerateProof generates an inclusion proof for the first leaf holding item.
+func (t *TypedTree[T]) GenerateProof(item T) (*Proof, error) {
+	return t.Tree.GenerateProof(t.encode(item))
+}
+
+// VerifyProof verifies that item is part of the tree.
+func (t *TypedTree[T]) VerifyProof(proof *Proof, item T) (bool, error) {
+	return t.Tree.VerifyProof(proof, t.encode(item))
+}
+
+// UpdateLeaf replaces the item at the given index and recalculates the tree.
+func (t *TypedTree[T]) UpdateLeaf(index int, item T) error {
+	return t.Tree.UpdateLeaf(index, t.encode(item))
+}
+
+// AppendLeaf appends item to the tree.
+func (t *TypedTree[T]) AppendLeaf(item T) error {
+	return t.Tree.AppendLeaf(t.encode(item))
+}
+
+// IndexOf returns the index of the first leaf holding item.
+func (t *TypedTree[T]) IndexOf(item T) (int, bool) {
+	return t.Tree.IndexOf(t.encode(item))
+}
+
+// Contains reports whether a leaf holds item.
+func (t *TypedTree[T]) Contains(item T) bool {
+	return t.Tree.Contains(t.encode(item))
+}
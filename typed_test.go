@@ -0,0 +1,106 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type account struct {
+	ID      uint64
+	Balance uint64
+}
+
+func encodeAccount(a account) []byte {
+	buf := binary.BigEndian.AppendUint64(nil, a.ID)
+	return binary.BigEndian.AppendUint64(buf, a.Balance)
+}
+
+func TestTypedTree(t *testing.T) {
+	t.Parallel()
+
+	accounts := []account{
+		{ID: 1, Balance: 100},
+		{ID: 2, Balance: 50},
+		{ID: 3, Balance: 0},
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(t *testing.T, tree *TypedTree[account]) []account
+	}{
+		{
+			name: "New tree",
+			mutate: func(t *testing.T, tree *TypedTree[account]) []account {
+				return accounts
+			},
+		},
+		{
+			name: "Update item",
+			mutate: func(t *testing.T, tree *TypedTree[account]) []account {
+				updated := account{ID: 2, Balance: 75}
+				require.NoError(t, tree.UpdateLeaf(1, updated))
+				return []account{accounts[0], updated, accounts[2]}
+			},
+		},
+		{
+			name: "Append item",
+			mutate: func(t *testing.T, tree *TypedTree[account]) []account {
+				added := account{ID: 4, Balance: 10}
+				require.NoError(t, tree.AppendLeaf(added))
+				return append(accounts[:3:3], added)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := NewTypedTree(accounts, encodeAccount, sha256.New)
+			require.NoError(t, err)
+			expItems := tc.mutate(t, tree)
+
+			values := make([][]byte, len(expItems))
+			for i, item := range expItems {
+				values[i] = encodeAccount(item)
+			}
+			expTree, err := NewTree(values, sha256.New)
+			require.NoError(t, err)
+			assert.Equal(t, expTree.Root.Hash, tree.Tree.Root.Hash)
+
+			for i, item := range expItems {
+				index, found := tree.IndexOf(item)
+				require.True(t, found)
+				assert.Equal(t, i, index)
+
+				proof, err := tree.GenerateProof(item)
+				require.NoError(t, err)
+
+				isValid, err := tree.VerifyProof(proof, item)
+				require.NoError(t, err)
+				assert.True(t, isValid)
+			}
+
+			missing := account{ID: 99}
+			assert.False(t, tree.Contains(missing))
+			_, err = tree.GenerateProof(missing)
+			require.ErrorIs(t, err, ErrNoVal)
+		})
+	}
+}
+
+func TestTypedTreeNoItems(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewTypedTree(nil, encodeAccount, sha256.New)
+	require.ErrorIs(t, err, ErrNoLeaves)
+
+	tree, err := NewTypedTree(nil, encodeAccount, sha256.New, WithEmptyTree())
+	require.NoError(t, err)
+	require.NoError(t, tree.AppendLeaf(account{ID: 1}))
+	assert.True(t, tree.Contains(account{ID: 1}))
+}
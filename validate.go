@@ -0,0 +1,133 @@
+package merkle
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash"
+	"math/bits"
+)
+
+var ErrInvalidTree = errors.New("invalid tree")
+
+// Validate recomputes every hash of the tree from the leaves up and checks
+// that the nodes are linked to their parents and children as in a tree
+// built from the leaves. It returns the first inconsistency it finds,
+// e.g. after deserializing a tree or when memory corruption is suspected.
+// Pruned subtrees are only checked up to their hashes.
+func (t *Tree) Validate() error {
+	t.flush()
+	if len(t.Leaves) == 0 {
+		if t.Root != nil && !bytes.Equal(t.Root.Hash, t.emptyRoot().Hash) {
+			return fmt.Errorf("%w: root of empty tree is not the empty hash", ErrInvalidTree)
+		}
+		return nil
+	}
+	if t.Root == nil {
+		return fmt.Errorf("%w: tree with %d leaves has no root", ErrInvalidTree, len(t.Leaves))
+	}
+	if t.Root.Parent != nil {
+		return fmt.Errorf("%w: root has a parent", ErrInvalidTree)
+	}
+
+	leafHashFunc := t.cfg.hasher.NewLeafHasher()
+	nodeHashFunc := t.cfg.hasher.NewNodeHasher()
+	if err := t.validateNode(t.Root, 0, len(t.Leaves), leafHashFunc, nodeHashFunc); err != nil {
+		return err
+	}
+	return t.validateIndex()
+}
+
+// validateNode checks the subtree below node, which holds the size leaves
+// starting at start.
+func (t *Tree) validateNode(node *Node, start, size int, leafHashFunc, nodeHashFunc hash.Hash) error {
+	if node.Left == nil && node.Right == nil {
+		if size == 1 && t.Leaves[start] != nil {
+			return t.validateLeaf(node, start, leafHashFunc)
+		}
+		// Only pruned subtrees have no children.
+		for i := start; i < start+size; i++ {
+			if t.Leaves[i] != nil {
+				return fmt.Errorf("%w: leaf %d is below a pruned node", ErrInvalidTree, i)
+			}
+		}
+		return nil
+	}
+	if size == 1 {
+		return fmt.Errorf("%w: leaf %d has children", ErrInvalidTree, start)
+	}
+
+	half := 1 << (bits.Len(uint(size-1)) - 1)
+	if node.Left == nil || node.Right == nil {
+		return fmt.Errorf("%w: node over leaves [%d, %d) is missing a child",
+			ErrInvalidTree, start, start+size)
+	}
+	if node.Left.Parent != node || node.Right.Parent != node {
+		return fmt.Errorf("%w: child of node over leaves [%d, %d) doesn't link back to it",
+			ErrInvalidTree, start, start+size)
+	}
+	if err := t.validateNode(node.Left, start, half, leafHashFunc, nodeHashFunc); err != nil {
+		return err
+	}
+	if err := t.validateNode(node.Right, start+half, size-half, leafHashFunc, nodeHashFunc); err != nil {
+		return err
+	}
+
+	// The left child is a complete subtree with 2^(level-1) leaves.
+	level := bits.Len(uint(half))
+	hash := combineLevelHashes(level, node.Left.Hash, node.Right.Hash, nodeHashFunc, &t.cfg)
+	if !bytes.Equal(node.Hash, hash) {
+		return fmt.Errorf("%w: hash mismatch in node over leaves [%d, %d)",
+			ErrInvalidTree, start, start+size)
+	}
+	return nil
+}
+
+// validateLeaf checks that node is the leaf at index and that its hash
+// matches its value.
+func (t *Tree) validateLeaf(node *Node, index int, leafHashFunc hash.Hash) error {
+	if node != t.Leaves[index] {
+		return fmt.Errorf("%w: leaf %d is not at its position in the tree", ErrInvalidTree, index)
+	}
+	if t.hashedLeaves {
+		return nil
+	}
+
+	leafHashFunc.Reset()
+	leafHashFunc.Write(node.Value)
+	if !bytes.Equal(node.Hash, leafHashFunc.Sum(nil)) {
+		return fmt.Errorf("%w: hash mismatch in leaf %d", ErrInvalidTree, index)
+	}
+	return nil
+}
+
+// validateIndex checks that the index of leaf hashes matches the leaves.
+func (t *Tree) validateIndex() error {
+	if t.index == nil {
+		return nil
+	}
+
+	refs := make(map[string]leafRef, len(t.index))
+	for i, leaf := range t.Leaves {
+		if leaf == nil {
+			continue
+		}
+		ref, ok := refs[string(leaf.Hash)]
+		if !ok {
+			ref.index = i
+		}
+		ref.count++
+		refs[string(leaf.Hash)] = ref
+	}
+
+	if len(refs) != len(t.index) {
+		return fmt.Errorf("%w: index has %d hashes, but the leaves have %d",
+			ErrInvalidTree, len(t.index), len(refs))
+	}
+	for hash, ref := range refs {
+		if t.index[hash] != ref {
+			return fmt.Errorf("%w: index is out of date for leaf %d", ErrInvalidTree, ref.index)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	for size := 0; size <= 17; size++ {
+		t.Run(fmt.Sprintf("%d leaves", size), func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := NewTree(generateDummyData(size), sha256.New, WithEmptyTree(), WithLevelTags(LevelIndexTag))
+			require.NoError(t, err)
+			require.NoError(t, tree.Validate())
+
+			require.NoError(t, tree.AppendLeaf([]byte("new")))
+			require.NoError(t, tree.UpdateLeaf(0, []byte("updated")))
+			require.NoError(t, tree.RemoveLeaf(tree.Len()/2))
+			require.NoError(t, tree.Validate())
+		})
+	}
+
+	t.Run("Pruned tree", func(t *testing.T) {
+		t.Parallel()
+
+		tree, err := NewTree(generateDummyData(9), sha256.New)
+		require.NoError(t, err)
+		require.NoError(t, tree.Prune([]int{2, 8}))
+		require.NoError(t, tree.Validate())
+	})
+
+	t.Run("Tree from hashes", func(t *testing.T) {
+		t.Parallel()
+
+		tree, err := NewTreeFromHashes(generateDummyData(5), sha256.New)
+		require.NoError(t, err)
+		require.NoError(t, tree.Validate())
+	})
+}
+
+func TestValidateCorruption(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		corrupt func(tree *Tree)
+	}{
+		{
+			name: "Leaf value",
+			corrupt: func(tree *Tree) {
+				tree.Leaves[3].Value = []byte("corrupt")
+			},
+		},
+		{
+			name: "Node hash",
+			corrupt: func(tree *Tree) {
+				tree.Root.Left.Right.Hash[0] ^= 0xff
+			},
+		},
+		{
+			name: "Root hash",
+			corrupt: func(tree *Tree) {
+				tree.Root.Hash = []byte("corrupt")
+			},
+		},
+		{
+			name: "Parent link",
+			corrupt: func(tree *Tree) {
+				tree.Leaves[1].Parent = tree.Root
+			},
+		},
+		{
+			name: "Missing child",
+			corrupt: func(tree *Tree) {
+				tree.Root.Left.Left = nil
+			},
+		},
+		{
+			name: "Swapped leaves",
+			corrupt: func(tree *Tree) {
+				tree.Leaves[0], tree.Leaves[1] = tree.Leaves[1], tree.Leaves[0]
+			},
+		},
+		{
+			name: "Missing leaf",
+			corrupt: func(tree *Tree) {
+				tree.Leaves = tree.Leaves[:4]
+			},
+		},
+		{
+			name: "Stale index",
+			corrupt: func(tree *Tree) {
+				tree.Leaves[2].Value = []byte("new")
+				tree.Leaves[2].Hash = tree.LeafHash([]byte("new"))
+				tree.updateParentHashes(2)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := NewTree(generateDummyData(5), sha256.New)
+			require.NoError(t, err)
+			tc.corrupt(tree)
+
+			err = tree.Validate()
+			require.ErrorIs(t, err, ErrInvalidTree)
+			assert.NotEmpty(t, err.Error())
+		})
+	}
+}
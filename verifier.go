@@ -0,0 +1,50 @@
+package merkle
+
+import (
+	"bytes"
+	"hash"
+)
+
+// maxScratchDigest is the largest digest a Verifier hashes without
+// allocating, which covers SHA-512 and BLAKE2b-512.
+const maxScratchDigest = 64
+
+// Verifier verifies inclusion proofs against a known root without the
+// tree, e.g. in services that verify proofs from many clients. It keeps
+// its hash functions and a scratch digest across calls, so verifying
+// a proof doesn't allocate. A Verifier is not safe for concurrent use,
+// so every goroutine should have its own.
+type Verifier struct {
+	cfg          config
+	leafHashFunc hash.Hash
+	nodeHashFunc hash.Hash
+	scratch      [maxScratchDigest]byte
+}
+
+// NewVerifier creates a verifier for proofs of trees built
+// with the given hash function and options.
+func NewVerifier(newHashFunc func() hash.Hash, opts ...Option) *Verifier {
+	cfg := newConfig(opts, newHashFunc)
+	return &Verifier{
+		cfg:          cfg,
+		leafHashFunc: cfg.hasher.NewLeafHasher(),
+		nodeHashFunc: cfg.hasher.NewNodeHasher(),
+	}
+}
+
+// Verify verifies that value is the leaf at proof.Index of a tree
+// with size leaves and the given root.
+func (v *Verifier) Verify(root []byte, size int, proof *Proof, value []byte) (bool, error) {
+	v.leafHashFunc.Reset()
+	v.leafHashFunc.Write(value)
+	leafHash := v.leafHashFunc.Sum(v.scratch[:0])
+
+	computed, ok := rootFromProofInto(leafHash, leafHash, proof, size, v.nodeHashFunc, &v.cfg)
+	if !ok {
+		return false, &ProofSizeError{Index: proof.Index, NumHashes: len(proof.Hashes), Size: size}
+	}
+	if !bytes.Equal(computed, root) {
+		return false, &RootMismatchError{Expected: bytes.Clone(root), Actual: bytes.Clone(computed)}
+	}
+	return true, nil
+}
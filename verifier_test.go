@@ -0,0 +1,103 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifier(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		size        int
+		newHashFunc func() hash.Hash
+		opts        []Option
+	}{
+		{
+			name:        "Single leaf",
+			size:        1,
+			newHashFunc: sha256.New,
+		},
+		{
+			name:        "Odd size",
+			size:        13,
+			newHashFunc: sha256.New,
+		},
+		{
+			name:        "Domain prefixes",
+			size:        16,
+			newHashFunc: sha256.New,
+			opts:        []Option{WithDomainPrefixes([]byte{0}, []byte{1})},
+		},
+		{
+			name:        "SHA-512",
+			size:        7,
+			newHashFunc: sha512.New,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := generateDummyData(tc.size)
+			tree, err := NewTree(data, tc.newHashFunc, tc.opts...)
+			require.NoError(t, err)
+			v := NewVerifier(tc.newHashFunc, tc.opts...)
+
+			for i, value := range data {
+				proof, err := tree.GenerateProofByIndex(i)
+				require.NoError(t, err)
+				ok, err := v.Verify(tree.RootHash(), tc.size, proof, value)
+				require.NoError(t, err)
+				assert.True(t, ok)
+
+				ok, err = v.Verify(tree.RootHash(), tc.size, proof, []byte("wrong"))
+				var mismatchErr *RootMismatchError
+				require.ErrorAs(t, err, &mismatchErr)
+				assert.False(t, ok)
+
+				_, err = v.Verify(tree.RootHash(), tc.size, &Proof{Index: tc.size, Hashes: proof.Hashes}, value)
+				var sizeErr *ProofSizeError
+				assert.ErrorAs(t, err, &sizeErr)
+			}
+		})
+	}
+}
+
+func TestVerifierAllocations(t *testing.T) {
+	data := generateDummyData(1000)
+	tree, err := NewTree(data, sha256.New)
+	require.NoError(t, err)
+	proof, err := tree.GenerateProofByIndex(len(data) / 2)
+	require.NoError(t, err)
+	root := tree.RootHash()
+
+	v := NewVerifier(sha256.New)
+	allocs := testing.AllocsPerRun(100, func() {
+		if ok, err := v.Verify(root, len(data), proof, data[len(data)/2]); !ok {
+			t.Fatal(err)
+		}
+	})
+	assert.Zero(t, allocs)
+}
+
+func BenchmarkVerifier(b *testing.B) {
+	data := generateDummyData(100000)
+	tree, _ := NewTree(data, sha256.New)
+	proof, _ := tree.GenerateProofByIndex(len(data) / 2)
+	root := tree.RootHash()
+	v := NewVerifier(sha256.New)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = v.Verify(root, len(data), proof, data[len(data)/2])
+	}
+}
@@ -0,0 +1,208 @@
+package merkle
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash"
+)
+
+var ErrVersionNotFound = errors.New("version not found")
+
+// version is a snapshot of the leaves and root of a tree.
+// Hashes, values and metadata are shared with the tree, since
+// mutations replace them instead of modifying them in place.
+type version struct {
+	root       []byte
+	leafHashes [][]byte
+	values     [][]byte
+	keys       []string
+	metadata   []map[string]any
+
+	// tree is built from the snapshot by the first proof
+	// at this version and reused by later proofs.
+	tree *Tree
+}
+
+// VersionedTree is a Merkle tree that records a new version
+// for every batch of mutations, so roots and proofs can be
+// served for older versions of the tree.
+type VersionedTree struct {
+	tree        *Tree
+	newHashFunc func() hash.Hash
+
+	// versions[i] holds version oldest+i.
+	versions []version
+	oldest   int
+}
+
+// NewVersionedTree creates a new versioned Merkle tree.
+// The initial leaves are recorded as version 0.
+func NewVersionedTree(values [][]byte, newHashFunc func() hash.Hash, opts ...Option) (*VersionedTree, error) {
+	tree, err := NewTree(values, newHashFunc, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &VersionedTree{
+		tree:        tree,
+		newHashFunc: newHashFunc,
+	}
+	v.record()
+
+	return v, nil
+}
+
+// Tree returns the tree at the latest version.
+// Mutations should go through Apply so they are recorded.
+func (v *VersionedTree) Tree() *Tree {
+	return v.tree
+}
+
+// Version returns the latest version.
+func (v *VersionedTree) Version() int {
+	return v.oldest + len(v.versions) - 1
+}
+
+// Apply runs a batch of mutations on the tree and records the result
+// as a new version. If mutate returns an error, the leaves of the tree
+// are restored to the latest version and no version is recorded.
+// The tree keeps its subscribers and root history.
+func (v *VersionedTree) Apply(mutate func(tree *Tree) error) (int, error) {
+	if err := mutate(v.tree); err != nil {
+		v.restore()
+		return v.Version(), err
+	}
+
+	v.record()
+	return v.Version(), nil
+}
+
+// record takes a snapshot of the tree as a new version.
+func (v *VersionedTree) record() {
+	// The mutations may have deferred rehashing the tree.
+	v.tree.flush()
+	snapshot := version{
+		leafHashes: make([][]byte, len(v.tree.Leaves)),
+		values:     make([][]byte, len(v.tree.Leaves)),
+		keys:       make([]string, len(v.tree.Leaves)),
+		metadata:   make([]map[string]any, len(v.tree.Leaves)),
+	}
+	if v.tree.Root != nil {
+		snapshot.root = v.tree.Root.Hash
+	}
+	for i, leaf := range v.tree.Leaves {
+		snapshot.leafHashes[i] = leaf.Hash
+		snapshot.values[i] = leaf.Value
+		snapshot.keys[i] = leaf.Key
+		snapshot.metadata[i] = leaf.Metadata
+	}
+
+	v.versions = append(v.versions, snapshot)
+}
+
+// restore rebuilds the tree in place on the leaves of the latest version.
+func (v *VersionedTree) restore() {
+	latest := v.versions[len(v.versions)-1]
+	leaves := make([]*Node, len(latest.leafHashes))
+	for i, hash := range latest.leafHashes {
+		leaves[i] = &Node{
+			Hash:     hash,
+			Value:    latest.values[i],
+			Key:      latest.keys[i],
+			Metadata: latest.metadata[i],
+		}
+	}
+
+	// Deferred hashes of the failed mutations are dropped with their nodes.
+	t := v.tree
+	t.dirty = nil
+	t.dirtySeen = nil
+	t.rebuild(leaves)
+	t.rootChanged(MutationRollback)
+}
+
+// Prune discards all versions older than the given version.
+// The latest version is always retained.
+func (v *VersionedTree) Prune(before int) {
+	before = min(before, v.Version())
+	if before <= v.oldest {
+		return
+	}
+
+	v.versions = v.versions[before-v.oldest:]
+	v.oldest = before
+}
+
+func (v *VersionedTree) at(ver int) (*version, error) {
+	if ver < v.oldest || ver > v.Version() {
+		return nil, fmt.Errorf("%w: %d", ErrVersionNotFound, ver)
+	}
+	return &v.versions[ver-v.oldest], nil
+}
+
+// RootAt returns the root hash of the tree at the given version.
+func (v *VersionedTree) RootAt(ver int) ([]byte, error) {
+	snapshot, err := v.at(ver)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.Clone(snapshot.root), nil
+}
+
+// SizeAt returns the number of leaves of the tree at the given version.
+func (v *VersionedTree) SizeAt(ver int) (int, error) {
+	snapshot, err := v.at(ver)
+	if err != nil {
+		return 0, err
+	}
+	return len(snapshot.leafHashes), nil
+}
+
+// GenerateProofAt generates a proof for the leaf at the given index
+// in the tree at the given version. The tree of an older version is
+// rebuilt once and kept until the version is pruned.
+func (v *VersionedTree) GenerateProofAt(ver, index int) (*Proof, error) {
+	if ver == v.Version() {
+		return v.tree.GenerateProofByIndex(index)
+	}
+
+	snapshot, err := v.at(ver)
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index >= len(snapshot.leafHashes) {
+		return nil, indexOutOfBounds(index, len(snapshot.leafHashes))
+	}
+
+	if snapshot.tree == nil {
+		snapshot.tree = newTree(snapshot.leafHashes, snapshot.values, v.newHashFunc, v.tree.cfg)
+	}
+	return snapshot.tree.GenerateProofByIndex(index)
+}
+
+// VerifyProofAt verifies a proof for value against the tree
+// at the given version.
+func (v *VersionedTree) VerifyProofAt(ver int, proof *Proof, value []byte) (bool, error) {
+	snapshot, err := v.at(ver)
+	if err != nil {
+		return false, err
+	}
+
+	leafHashFunc := v.tree.cfg.hasher.NewLeafHasher()
+	leafHashFunc.Write(value)
+	leafHash := leafHashFunc.Sum(nil)
+
+	root, ok := rootFromProofWithConfig(leafHash, proof, len(snapshot.leafHashes),
+		v.tree.cfg.hasher.NewNodeHasher(), &v.tree.cfg)
+	if !ok {
+		return false, fmt.Errorf("version %d: %w", ver,
+			&ProofSizeError{Index: proof.Index, NumHashes: len(proof.Hashes), Size: len(snapshot.leafHashes)})
+	}
+
+	if !bytes.Equal(root, snapshot.root) {
+		return false, &RootMismatchError{Expected: snapshot.root, Actual: root}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,205 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionedTree(t *testing.T) {
+	t.Parallel()
+
+	values := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+	v, err := NewVersionedTree(values, sha256.New)
+	require.NoError(t, err)
+	assert.Equal(t, 0, v.Version())
+	root0 := v.Tree().Root.Hash
+
+	ver, err := v.Apply(func(tree *Tree) error {
+		if err := tree.UpdateLeaf(0, []byte("x")); err != nil {
+			return err
+		}
+		return tree.UpdateLeaf(2, []byte("z"))
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, ver)
+	root1 := v.Tree().Root.Hash
+	assert.NotEqual(t, root0, root1)
+
+	root, err := v.RootAt(0)
+	require.NoError(t, err)
+	assert.Equal(t, root0, root)
+
+	root, err = v.RootAt(1)
+	require.NoError(t, err)
+	assert.Equal(t, root1, root)
+
+	// Proofs against an old version verify against that version only.
+	proof, err := v.GenerateProofAt(0, 2)
+	require.NoError(t, err)
+	isValid, err := v.VerifyProofAt(0, proof, []byte("c"))
+	require.NoError(t, err)
+	assert.True(t, isValid)
+
+	isValid, err = v.VerifyProofAt(1, proof, []byte("c"))
+	require.ErrorIs(t, err, ErrProofVerificationFailed)
+	assert.False(t, isValid)
+
+	proof, err = v.GenerateProofAt(1, 2)
+	require.NoError(t, err)
+	isValid, err = v.VerifyProofAt(1, proof, []byte("z"))
+	require.NoError(t, err)
+	assert.True(t, isValid)
+
+	_, err = v.RootAt(2)
+	require.ErrorIs(t, err, ErrVersionNotFound)
+
+	_, err = v.GenerateProofAt(0, 3)
+	require.ErrorIs(t, err, ErrIndexOutOfBounds)
+}
+
+func TestVersionedTreeFailedApply(t *testing.T) {
+	t.Parallel()
+
+	v, err := NewVersionedTree([][]byte{[]byte("a"), []byte("b")}, sha256.New)
+	require.NoError(t, err)
+	root0 := v.Tree().Root.Hash
+
+	errBoom := errors.New("boom")
+	ver, err := v.Apply(func(tree *Tree) error {
+		if err := tree.UpdateLeaf(0, []byte("x")); err != nil {
+			return err
+		}
+		return errBoom
+	})
+	require.ErrorIs(t, err, errBoom)
+	assert.Equal(t, 0, ver)
+	assert.Equal(t, root0, v.Tree().Root.Hash, "Failed batch should be rolled back")
+	assert.Equal(t, []byte("a"), v.Tree().Leaves[0].Value)
+}
+
+func TestVersionedTreeFailedApplyKeepsState(t *testing.T) {
+	t.Parallel()
+
+	v, err := NewVersionedTree(generateDummyData(5), sha256.New, WithRootHistory(), WithDeferredHashing())
+	require.NoError(t, err)
+	_, err = v.Apply(func(tree *Tree) error {
+		return tree.SetMetadata(1, map[string]any{"owner": "a"})
+	})
+	require.NoError(t, err)
+	tree := v.Tree()
+	root0 := tree.RootHash()
+
+	var changes []RootChange
+	tree.OnRootChange(func(c RootChange) {
+		changes = append(changes, c)
+	})
+
+	errBoom := errors.New("boom")
+	_, err = v.Apply(func(tree *Tree) error {
+		if err := tree.UpdateLeaf(0, []byte("x")); err != nil {
+			return err
+		}
+		if err := tree.SetMetadata(1, map[string]any{"owner": "b"}); err != nil {
+			return err
+		}
+		if err := tree.RemoveLeaf(4); err != nil {
+			return err
+		}
+		return errBoom
+	})
+	require.ErrorIs(t, err, errBoom)
+
+	// The tree is restored in place, so it keeps its subscribers,
+	// history and metadata.
+	assert.Same(t, tree, v.Tree())
+	assert.Equal(t, root0, tree.RootHash())
+	assert.Equal(t, 5, tree.Len())
+	metadata, err := tree.Metadata(1)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"owner": "a"}, metadata)
+	require.NoError(t, tree.Validate())
+
+	require.NotEmpty(t, changes)
+	last := changes[len(changes)-1]
+	assert.Equal(t, MutationRollback, last.Cause)
+	assert.Equal(t, root0, last.New)
+	history := tree.RootHistory()
+	assert.Equal(t, MutationRollback, history[len(history)-1].Cause)
+
+	numChanges := len(changes)
+	ver, err := v.Apply(func(tree *Tree) error {
+		return tree.UpdateLeaf(2, []byte("y"))
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, ver)
+	assert.Len(t, changes, numChanges+1, "Subscribers should be notified after a failed Apply")
+}
+
+func TestVersionedTreePrune(t *testing.T) {
+	t.Parallel()
+
+	v, err := NewVersionedTree([][]byte{[]byte("a"), []byte("b")}, sha256.New)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err := v.Apply(func(tree *Tree) error {
+			return tree.UpdateLeaf(0, []byte{byte(i)})
+		})
+		require.NoError(t, err)
+	}
+	require.Equal(t, 3, v.Version())
+
+	v.Prune(2)
+	_, err = v.RootAt(1)
+	require.ErrorIs(t, err, ErrVersionNotFound)
+	_, err = v.RootAt(2)
+	require.NoError(t, err)
+
+	// The latest version is never pruned.
+	v.Prune(10)
+	root, err := v.RootAt(3)
+	require.NoError(t, err)
+	assert.Equal(t, v.Tree().Root.Hash, root)
+	_, err = v.RootAt(2)
+	require.ErrorIs(t, err, ErrVersionNotFound)
+}
+
+func TestVersionedTreeOldVersions(t *testing.T) {
+	t.Parallel()
+
+	values := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+	v, err := NewVersionedTree(values, sha256.New)
+	require.NoError(t, err)
+	_, err = v.Apply(func(tree *Tree) error {
+		return tree.UpdateLeaf(1, []byte("y"))
+	})
+	require.NoError(t, err)
+
+	// The tree of version 0 is built by the first proof and reused.
+	for i, value := range values {
+		proof, err := v.GenerateProofAt(0, i)
+		require.NoError(t, err)
+		isValid, err := v.VerifyProofAt(0, proof, value)
+		require.NoError(t, err)
+		assert.True(t, isValid)
+	}
+	cached := v.versions[0].tree
+	require.NotNil(t, cached)
+	_, err = v.GenerateProofAt(0, 0)
+	require.NoError(t, err)
+	assert.Same(t, cached, v.versions[0].tree)
+
+	// Callers can't modify the recorded root.
+	root, err := v.RootAt(0)
+	require.NoError(t, err)
+	root[0] ^= 0xff
+	proof, err := v.GenerateProofAt(0, 0)
+	require.NoError(t, err)
+	isValid, err := v.VerifyProofAt(0, proof, values[0])
+	require.NoError(t, err)
+	assert.True(t, isValid)
+}
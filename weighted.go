@@ -0,0 +1,187 @@
+package merkle
+
+import (
+	"bytes"
+	"container/heap"
+	"errors"
+	"fmt"
+	"hash"
+)
+
+var ErrWeightsMismatch = errors.New("number of weights does not match number of values")
+
+// WeightedTree is a Merkle tree shaped like a Huffman tree,
+// so leaves with a higher weight end up closer to the root
+// and get shorter proofs. The tree isn't shaped like a Tree,
+// so only the methods of WeightedTree apply to it.
+type WeightedTree struct {
+	tree    *Tree
+	Weights []uint64
+}
+
+// WeightedProof is an inclusion proof for a leaf of a weighted tree.
+// Since the shape of the tree doesn't follow from the leaf index,
+// IsLeft[i] records whether Hashes[i] is the left sibling.
+type WeightedProof struct {
+	Hashes [][]byte
+	IsLeft []bool
+	Index  int
+}
+
+// weightedItem is a subtree waiting to be merged.
+// Ties on weight are broken by seq, so the shape only
+// depends on the weights and the order of the leaves.
+type weightedItem struct {
+	node   *Node
+	weight uint64
+	seq    int
+}
+
+type weightedQueue []weightedItem
+
+func (q weightedQueue) Len() int { return len(q) }
+
+func (q weightedQueue) Less(i, j int) bool {
+	if q[i].weight != q[j].weight {
+		return q[i].weight < q[j].weight
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q weightedQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *weightedQueue) Push(x any) { *q = append(*q, x.(weightedItem)) }
+
+func (q *weightedQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// NewWeightedTree creates a new Merkle tree where each leaf has a weight,
+// e.g. how often it is proven. The two lightest subtrees are merged
+// repeatedly, with the first one on the left, until one root remains.
+func NewWeightedTree(values [][]byte, weights []uint64, newHashFunc func() hash.Hash) (*WeightedTree, error) {
+	if len(values) == 0 {
+		return nil, ErrNoLeaves
+	}
+	if len(values) != len(weights) {
+		return nil, fmt.Errorf("%w: %d values and %d weights",
+			ErrWeightsMismatch, len(values), len(weights))
+	}
+
+	preHashedLeaves := preHashLeaves(values, newHashFunc)
+
+	leaves := make([]*Node, len(values))
+	queue := make(weightedQueue, len(values))
+	for i, hash := range preHashedLeaves {
+		leaves[i] = NewNode(hash, values[i])
+		queue[i] = weightedItem{node: leaves[i], weight: weights[i], seq: i}
+	}
+	heap.Init(&queue)
+
+	hashFunc := newHashFunc()
+	seq := len(values)
+	for queue.Len() > 1 {
+		left := heap.Pop(&queue).(weightedItem)
+		right := heap.Pop(&queue).(weightedItem)
+
+		parent := &Node{
+			Hash:  combineHashes(left.node.Hash, right.node.Hash, hashFunc),
+			Left:  left.node,
+			Right: right.node,
+		}
+		left.node.Parent = parent
+		right.node.Parent = parent
+
+		heap.Push(&queue, weightedItem{
+			node:   parent,
+			weight: left.weight + right.weight,
+			seq:    seq,
+		})
+		seq++
+	}
+
+	return &WeightedTree{
+		tree: &Tree{
+			Root:         queue[0].node,
+			HashFunc:     hashFunc,
+			Leaves:       leaves,
+			leafHashFunc: newHashFunc(),
+			newHashFunc:  newHashFunc,
+			cfg:          newConfig(nil, newHashFunc),
+		},
+		Weights: weights,
+	}, nil
+}
+
+// Len returns the number of leaves in the tree.
+func (w *WeightedTree) Len() int {
+	return len(w.tree.Leaves)
+}
+
+// RootHash returns the root hash of the tree.
+func (w *WeightedTree) RootHash() []byte {
+	return w.tree.Root.Hash
+}
+
+// LeafHash returns the hash of the leaf at the given index.
+func (w *WeightedTree) LeafHash(index int) ([]byte, error) {
+	if index < 0 || index >= len(w.tree.Leaves) {
+		return nil, indexOutOfBounds(index, len(w.tree.Leaves))
+	}
+	return w.tree.Leaves[index].Hash, nil
+}
+
+// GenerateProof generates a proof for the leaf at the given index.
+func (w *WeightedTree) GenerateProof(index int) (*WeightedProof, error) {
+	if index < 0 || index >= len(w.tree.Leaves) {
+		return nil, indexOutOfBounds(index, len(w.tree.Leaves))
+	}
+
+	proof := &WeightedProof{Index: index}
+	for current := w.tree.Leaves[index]; current.Parent != nil; current = current.Parent {
+		parent := current.Parent
+		if parent.Left == current {
+			proof.Hashes = append(proof.Hashes, parent.Right.Hash)
+			proof.IsLeft = append(proof.IsLeft, false)
+		} else {
+			proof.Hashes = append(proof.Hashes, parent.Left.Hash)
+			proof.IsLeft = append(proof.IsLeft, true)
+		}
+	}
+
+	return proof, nil
+}
+
+// VerifyProof verifies the proof for value against the root of the tree.
+func (w *WeightedTree) VerifyProof(proof *WeightedProof, value []byte) (bool, error) {
+	return VerifyWeightedProof(w.tree.Root.Hash, proof, value, w.tree.newHashFunc)
+}
+
+// VerifyWeightedProof verifies the proof for value against the given root.
+func VerifyWeightedProof(root []byte, proof *WeightedProof, value []byte, newHashFunc func() hash.Hash) (bool, error) {
+	if len(proof.Hashes) != len(proof.IsLeft) {
+		return false, fmt.Errorf("%w: %d hashes and %d sides",
+			ErrProofVerificationFailed, len(proof.Hashes), len(proof.IsLeft))
+	}
+
+	hashFunc := newHashFunc()
+	hashFunc.Write(value)
+	currentHash := hashFunc.Sum(nil)
+
+	for i, siblingHash := range proof.Hashes {
+		if proof.IsLeft[i] {
+			currentHash = combineHashes(siblingHash, currentHash, hashFunc)
+		} else {
+			currentHash = combineHashes(currentHash, siblingHash, hashFunc)
+		}
+	}
+
+	if !bytes.Equal(currentHash, root) {
+		return false, &RootMismatchError{Expected: root, Actual: currentHash}
+	}
+
+	return true, nil
+}
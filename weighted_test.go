@@ -0,0 +1,107 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWeightedTree(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		values       [][]byte
+		weights      []uint64
+		expProofLens []int
+		err          error
+	}{
+		{
+			name:    "No values should fail",
+			values:  [][]byte{},
+			weights: []uint64{},
+			err:     ErrNoLeaves,
+		},
+		{
+			name:    "Mismatched weights should fail",
+			values:  [][]byte{[]byte("a"), []byte("b")},
+			weights: []uint64{1},
+			err:     ErrWeightsMismatch,
+		},
+		{
+			name:         "Single leaf",
+			values:       [][]byte{[]byte("a")},
+			weights:      []uint64{1},
+			expProofLens: []int{0},
+		},
+		{
+			name:         "Equal weights give a balanced tree",
+			values:       [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d")},
+			weights:      []uint64{1, 1, 1, 1},
+			expProofLens: []int{2, 2, 2, 2},
+		},
+		{
+			name:         "Heavy leaf gets a short proof",
+			values:       [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d")},
+			weights:      []uint64{1, 100, 1, 1},
+			expProofLens: []int{3, 1, 3, 2},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := NewWeightedTree(tc.values, tc.weights, sha256.New)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, len(tc.values), tree.Len())
+
+			for i, value := range tc.values {
+				leafHash, err := tree.LeafHash(i)
+				require.NoError(t, err)
+				expHash := sha256.Sum256(value)
+				assert.Equal(t, expHash[:], leafHash)
+
+				proof, err := tree.GenerateProof(i)
+				require.NoError(t, err)
+				assert.Len(t, proof.Hashes, tc.expProofLens[i], "Proof length mismatch for leaf %d", i)
+
+				isValid, err := tree.VerifyProof(proof, value)
+				require.NoError(t, err)
+				assert.True(t, isValid)
+
+				isValid, err = VerifyWeightedProof(tree.RootHash(), proof, []byte("x"), sha256.New)
+				require.ErrorIs(t, err, ErrProofVerificationFailed)
+				assert.False(t, isValid)
+			}
+
+			_, err = tree.GenerateProof(len(tc.values))
+			require.ErrorIs(t, err, ErrIndexOutOfBounds)
+			_, err = tree.LeafHash(len(tc.values))
+			require.ErrorIs(t, err, ErrIndexOutOfBounds)
+		})
+	}
+}
+
+func TestNewWeightedTreeDeterministic(t *testing.T) {
+	t.Parallel()
+
+	values := generateDummyData(50)
+	weights := make([]uint64, len(values))
+	for i := range weights {
+		weights[i] = uint64(i % 7)
+	}
+
+	first, err := NewWeightedTree(values, weights, sha256.New)
+	require.NoError(t, err)
+	second, err := NewWeightedTree(values, weights, sha256.New)
+	require.NoError(t, err)
+
+	assert.Equal(t, first.RootHash(), second.RootHash())
+}
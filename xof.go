@@ -0,0 +1,53 @@
+package merkle
+
+import (
+	"errors"
+	"fmt"
+	"hash"
+
+	"golang.org/x/crypto/sha3"
+)
+
+var ErrInvalidDigestSize = errors.New("invalid digest size")
+
+// xofHash reads a fixed size digest from an extendable output function.
+type xofHash struct {
+	sha3.ShakeHash
+	size int
+}
+
+// NewXOFFunc returns a constructor for hashes that read size byte digests
+// from the extendable output functions created by newXOF, such as
+// sha3.NewShake256 or a cSHAKE with a customization string.
+// It allows picking digest sizes that match storage or proof size budgets.
+func NewXOFFunc(newXOF func() sha3.ShakeHash, size int) (func() hash.Hash, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidDigestSize, size)
+	}
+
+	return func() hash.Hash {
+		return &xofHash{ShakeHash: newXOF(), size: size}
+	}, nil
+}
+
+// NewSHAKE128Func returns a constructor for SHAKE128 hashes
+// with size byte digests.
+func NewSHAKE128Func(size int) (func() hash.Hash, error) {
+	return NewXOFFunc(sha3.NewShake128, size)
+}
+
+// NewSHAKE256Func returns a constructor for SHAKE256 hashes
+// with size byte digests.
+func NewSHAKE256Func(size int) (func() hash.Hash, error) {
+	return NewXOFFunc(sha3.NewShake256, size)
+}
+
+func (h *xofHash) Sum(b []byte) []byte {
+	digest := make([]byte, h.size)
+	h.ShakeHash.Clone().Read(digest)
+	return append(b, digest...)
+}
+
+func (h *xofHash) Size() int {
+	return h.size
+}
@@ -0,0 +1,89 @@
+package merkle
+
+import (
+	"encoding/hex"
+	"hash"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/sha3"
+)
+
+func TestXOF(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		newHashFunc func(size int) (func() hash.Hash, error)
+		size        int
+		expRoot     string
+	}{
+		{
+			name:        "SHAKE128 16 bytes",
+			newHashFunc: NewSHAKE128Func,
+			size:        16,
+			expRoot:     "94ff81b9931a3910cc8b34f5fc926980",
+		},
+		{
+			name:        "SHAKE256 20 bytes",
+			newHashFunc: NewSHAKE256Func,
+			size:        20,
+			expRoot:     "caf0875614d18014944210beadcd5755bf1557d6",
+		},
+		{
+			name:        "SHAKE256 64 bytes",
+			newHashFunc: NewSHAKE256Func,
+			size:        64,
+			expRoot: "08b68ba5090fc37c6b2809491b3ac5e0ac2e513abeb710f1d0d6bf886d4d9cb9" +
+				"c8de70da069d6f6f8b826428d689d60b60166fcdace7350f3f259f2ed6745aa5",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			newHashFunc, err := tc.newHashFunc(tc.size)
+			require.NoError(t, err)
+			assert.Equal(t, tc.size, newHashFunc().Size())
+
+			values := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+			tree, err := NewTree(values, newHashFunc)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expRoot, hex.EncodeToString(tree.Root.Hash))
+
+			for i, value := range values {
+				proof, err := tree.GenerateProofByIndex(i)
+				require.NoError(t, err)
+
+				isValid, err := tree.VerifyProof(proof, value)
+				require.NoError(t, err)
+				assert.True(t, isValid)
+			}
+		})
+	}
+}
+
+func TestXOFFunc(t *testing.T) {
+	t.Parallel()
+
+	newHashFunc, err := NewXOFFunc(func() sha3.ShakeHash {
+		return sha3.NewCShake256(nil, []byte("merkle"))
+	}, 32)
+	require.NoError(t, err)
+
+	h := newHashFunc()
+	h.Write([]byte("abc"))
+	first := h.Sum(nil)
+	assert.Equal(t, first, h.Sum(nil), "Sum should not change the state")
+
+	shake, err := NewSHAKE256Func(32)
+	require.NoError(t, err)
+	s := shake()
+	s.Write([]byte("abc"))
+	assert.NotEqual(t, first, s.Sum(nil), "Customization should change the digest")
+
+	_, err = NewSHAKE128Func(0)
+	require.ErrorIs(t, err, ErrInvalidDigestSize)
+}
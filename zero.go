@@ -0,0 +1,56 @@
+package merkle
+
+import (
+	"hash"
+	"sync"
+)
+
+// ZeroHashTable caches the roots of empty subtrees, as used by padded,
+// fixed depth and sparse trees. The root of an empty subtree of height 0
+// is the hash of the zero leaf, and the root of height i is
+// H(root(i-1) || root(i-1)). It is safe for concurrent use.
+type ZeroHashTable struct {
+	mu       sync.Mutex
+	hashFunc hash.Hash
+	hashes   [][]byte
+}
+
+// NewZeroHashTable creates a table of empty subtree roots over leaves
+// holding zeroLeaf, hashed with the hash function created by newHashFunc.
+func NewZeroHashTable(zeroLeaf []byte, newHashFunc func() hash.Hash) *ZeroHashTable {
+	hashFunc := newHashFunc()
+	hashFunc.Write(zeroLeaf)
+
+	return &ZeroHashTable{
+		hashFunc: hashFunc,
+		hashes:   [][]byte{hashFunc.Sum(nil)},
+	}
+}
+
+// Hash returns the root of an empty subtree of the given height,
+// computing and caching missing levels. It panics if height is negative.
+func (z *ZeroHashTable) Hash(height int) []byte {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	z.grow(height)
+	return z.hashes[height]
+}
+
+// Hashes returns the roots of empty subtrees of every height
+// from 0 to depth. The returned hashes must not be modified.
+func (z *ZeroHashTable) Hashes(depth int) [][]byte {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	z.grow(depth)
+	return z.hashes[: depth+1 : depth+1]
+}
+
+// grow computes the roots up to height.
+func (z *ZeroHashTable) grow(height int) {
+	for len(z.hashes) <= height {
+		last := z.hashes[len(z.hashes)-1]
+		z.hashes = append(z.hashes, combineHashes(last, last, z.hashFunc))
+	}
+}
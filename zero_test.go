@@ -0,0 +1,86 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZeroHashTable(t *testing.T) {
+	t.Parallel()
+
+	zeroLeaf := make([]byte, 32)
+
+	tests := []struct {
+		name   string
+		height int
+	}{
+		{
+			name:   "Leaf",
+			height: 0,
+		},
+		{
+			name:   "One level",
+			height: 1,
+		},
+		{
+			name:   "Several levels",
+			height: 6,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			table := NewZeroHashTable(zeroLeaf, sha256.New)
+
+			// The root of an empty subtree is the root of a tree
+			// with only zero leaves.
+			data := make([][]byte, 1<<tc.height)
+			for i := range data {
+				data[i] = zeroLeaf
+			}
+			tree, err := NewTree(data, sha256.New)
+			require.NoError(t, err)
+
+			assert.Equal(t, tree.Root.Hash, table.Hash(tc.height))
+
+			hashes := table.Hashes(tc.height)
+			require.Len(t, hashes, tc.height+1)
+			assert.Equal(t, tree.Levels()[tc.height][0], hashes[tc.height])
+		})
+	}
+}
+
+func TestZeroHashTableGolden(t *testing.T) {
+	t.Parallel()
+
+	table := NewZeroHashTable(make([]byte, 32), sha256.New)
+	assert.Equal(t, "66687aadf862bd776c8fc18b8e9f8e20089714856ee233b3902a591d0d5f2925",
+		hex.EncodeToString(table.Hash(0)))
+
+	h := sha256.Sum256(append(table.Hash(0), table.Hash(0)...))
+	assert.Equal(t, h[:], table.Hash(1))
+}
+
+func TestZeroHashTableConcurrent(t *testing.T) {
+	t.Parallel()
+
+	table := NewZeroHashTable(nil, sha256.New)
+	exp := NewZeroHashTable(nil, sha256.New).Hashes(64)
+
+	var wg sync.WaitGroup
+	for height := 0; height <= 64; height++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, exp[height], table.Hash(height))
+		}()
+	}
+	wg.Wait()
+}